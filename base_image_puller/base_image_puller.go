@@ -33,7 +33,8 @@ type UnpackSpec struct {
 }
 
 type VolumeMeta struct {
-	Size int64
+	Size     int64
+	LastUsed int64
 }
 
 type Fetcher interface {
@@ -62,6 +63,7 @@ type VolumeDriver interface {
 	Volumes(logger lager.Logger) ([]string, error)
 	MoveVolume(logger lager.Logger, from, to string) error
 	WriteVolumeMeta(logger lager.Logger, id string, data VolumeMeta) error
+	TouchVolume(logger lager.Logger, id string) error
 	HandleOpaqueWhiteouts(logger lager.Logger, id string, opaqueWhiteouts []string) error
 }
 
@@ -135,6 +137,34 @@ func (p *BaseImagePuller) volumeExists(logger lager.Logger, chainID string) bool
 	return false
 }
 
+func (p *BaseImagePuller) TouchVolumes(logger lager.Logger, volumeIDs []string) error {
+	logger = logger.Session("touching-volumes", lager.Data{"volumeIDs": volumeIDs})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	var touchErr error
+	for _, volumeID := range volumeIDs {
+		if err := p.touchVolume(logger, volumeID); err != nil {
+			logger.Error("touching-volume-failed", err, lager.Data{"volumeID": volumeID})
+			touchErr = errorspkg.Wrapf(err, "touching volume `%s`", volumeID)
+		}
+	}
+
+	return touchErr
+}
+
+// touchVolume holds the volume lock so that the read-modify-write of the
+// volume meta doesn't race with another image using the same layer.
+func (p *BaseImagePuller) touchVolume(logger lager.Logger, volumeID string) error {
+	lockFile, err := p.locksmith.Lock(volumeID)
+	if err != nil {
+		return errorspkg.Wrap(err, "acquiring lock")
+	}
+	defer p.locksmith.Unlock(lockFile)
+
+	return p.volumeDriver.TouchVolume(logger, volumeID)
+}
+
 func (p *BaseImagePuller) buildLayer(logger lager.Logger, index int, layerInfos []groot.LayerInfo, spec groot.BaseImageSpec) error {
 	if index < 0 {
 		return nil
@@ -146,8 +176,10 @@ func (p *BaseImagePuller) buildLayer(logger lager.Logger, index int, layerInfos
 		"chainID":       layerInfo.ChainID,
 		"parentChainID": layerInfo.ParentChainID,
 	})
+	// Unused volumes are evicted in least recently used order, so a lower
+	// layer may be gone even though the layers on top of it still exist.
 	if p.volumeExists(logger, layerInfo.ChainID) {
-		return nil
+		return p.buildLayer(logger, index-1, layerInfos, spec)
 	}
 
 	lockFile, err := p.locksmith.Lock(layerInfo.ChainID)
@@ -157,7 +189,7 @@ func (p *BaseImagePuller) buildLayer(logger lager.Logger, index int, layerInfos
 	defer p.locksmith.Unlock(lockFile)
 
 	if p.volumeExists(logger, layerInfo.ChainID) {
-		return nil
+		return p.buildLayer(logger, index-1, layerInfos, spec)
 	}
 
 	if err := p.buildLayer(logger, index-1, layerInfos, spec); err != nil {
@@ -269,7 +301,7 @@ func (p *BaseImagePuller) unpackLayerToTemporaryDirectory(logger lager.Logger, u
 }
 
 func (p *BaseImagePuller) finalizeVolume(logger lager.Logger, tempVolumeName, volumePath, chainID string, volSize int64) error {
-	if err := p.volumeDriver.WriteVolumeMeta(logger, chainID, VolumeMeta{Size: volSize}); err != nil {
+	if err := p.volumeDriver.WriteVolumeMeta(logger, chainID, VolumeMeta{Size: volSize, LastUsed: time.Now().UnixNano()}); err != nil {
 		return errorspkg.Wrapf(err, "writing volume `%s` metadata", chainID)
 	}
 
@@ -349,15 +349,27 @@ var _ = Describe("Base Image Puller", func() {
 			Expect(fakeVolumeDriver.WriteVolumeMetaCallCount()).To(Equal(3))
 			_, id, metadata := fakeVolumeDriver.WriteVolumeMetaArgsForCall(0)
 			Expect(id).To(Equal("layer-111"))
-			Expect(metadata).To(Equal(base_image_puller.VolumeMeta{Size: 100}))
+			Expect(metadata.Size).To(BeEquivalentTo(100))
 
 			_, id, metadata = fakeVolumeDriver.WriteVolumeMetaArgsForCall(1)
 			Expect(id).To(Equal("chain-222"))
-			Expect(metadata).To(Equal(base_image_puller.VolumeMeta{Size: 200}))
+			Expect(metadata.Size).To(BeEquivalentTo(200))
 
 			_, id, metadata = fakeVolumeDriver.WriteVolumeMetaArgsForCall(2)
 			Expect(id).To(Equal("chain-333"))
-			Expect(metadata).To(Equal(base_image_puller.VolumeMeta{Size: 300}))
+			Expect(metadata.Size).To(BeEquivalentTo(300))
+		})
+
+		It("records the volumes as used when they are created", func() {
+			startTime := time.Now().UnixNano()
+			err := baseImagePuller.Pull(logger, baseImageInfo, groot.BaseImageSpec{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVolumeDriver.WriteVolumeMetaCallCount()).To(Equal(3))
+			for i := 0; i < 3; i++ {
+				_, _, metadata := fakeVolumeDriver.WriteVolumeMetaArgsForCall(i)
+				Expect(metadata.LastUsed).To(BeNumerically(">=", startTime))
+			}
 		})
 
 		It("emits a metric with the unpack and download time for each layer", func() {
@@ -575,13 +587,20 @@ var _ = Describe("Base Image Puller", func() {
 				Expect(fakeLocksmith.LockCallCount()).To(Equal(0))
 				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(0))
 			})
+
+			It("leaves recording the volume use to the caller", func() {
+				err := baseImagePuller.Pull(logger, baseImageInfo, groot.BaseImageSpec{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVolumeDriver.TouchVolumeCallCount()).To(Equal(0))
+			})
 		})
 
-		Context("when one volume exists", func() {
+		Context("when the bottom volumes exist", func() {
 			BeforeEach(func() {
 				fakeVolumeDriver.VolumePathStub = func(_ lager.Logger, id string) (string, error) {
-					if id == "chain-222" {
-						return "/path/to/chain-222", nil
+					if id == "layer-111" || id == "chain-222" {
+						return "/path/to/" + id, nil
 					}
 					return "", errors.New("not here")
 				}
@@ -607,6 +626,26 @@ var _ = Describe("Base Image Puller", func() {
 			})
 		})
 
+		Context("when a lower volume is missing", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.VolumePathStub = func(_ lager.Logger, id string) (string, error) {
+					if id == "chain-222" || id == "chain-333" {
+						return "/path/to/" + id, nil
+					}
+					return "", errors.New("not here")
+				}
+			})
+
+			It("recreates the missing volume", func() {
+				err := baseImagePuller.Pull(logger, baseImageInfo, groot.BaseImageSpec{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeVolumeDriver.CreateVolumeCallCount()).To(Equal(1))
+				_, _, volID := fakeVolumeDriver.CreateVolumeArgsForCall(0)
+				Expect(volID).To(MatchRegexp("layer-111-incomplete-(\\d*)-(\\d*)"))
+			})
+		})
+
 		Context("when creating a volume fails", func() {
 			BeforeEach(func() {
 				fakeVolumeDriver.CreateVolumeReturns("", errors.New("failed to create volume"))
@@ -721,6 +760,62 @@ var _ = Describe("Base Image Puller", func() {
 			})
 		})
 	})
+
+	Describe("TouchVolumes", func() {
+		It("records each volume as used", func() {
+			Expect(baseImagePuller.TouchVolumes(logger, []string{"layer-111", "chain-222"})).To(Succeed())
+
+			Expect(fakeVolumeDriver.TouchVolumeCallCount()).To(Equal(2))
+			_, id := fakeVolumeDriver.TouchVolumeArgsForCall(0)
+			Expect(id).To(Equal("layer-111"))
+			_, id = fakeVolumeDriver.TouchVolumeArgsForCall(1)
+			Expect(id).To(Equal("chain-222"))
+		})
+
+		It("holds the volume lock while touching it", func() {
+			fakeVolumeDriver.TouchVolumeStub = func(_ lager.Logger, id string) error {
+				Expect(fakeLocksmith.LockCallCount()).To(Equal(fakeVolumeDriver.TouchVolumeCallCount()))
+				Expect(fakeLocksmith.LockArgsForCall(fakeLocksmith.LockCallCount() - 1)).To(Equal(id))
+				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(fakeVolumeDriver.TouchVolumeCallCount() - 1))
+				return nil
+			}
+
+			Expect(baseImagePuller.TouchVolumes(logger, []string{"layer-111", "chain-222"})).To(Succeed())
+			Expect(fakeLocksmith.UnlockCallCount()).To(Equal(2))
+		})
+
+		Context("when acquiring the lock fails", func() {
+			BeforeEach(func() {
+				fakeLocksmith.LockReturns(nil, errors.New("failed to lock"))
+			})
+
+			It("returns an error without touching the volume", func() {
+				err := baseImagePuller.TouchVolumes(logger, []string{"layer-111"})
+				Expect(err).To(MatchError(ContainSubstring("failed to lock")))
+
+				Expect(fakeVolumeDriver.TouchVolumeCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when touching a volume fails", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.TouchVolumeStub = func(_ lager.Logger, id string) error {
+					if id == "layer-111" {
+						return errors.New("failed to touch")
+					}
+					return nil
+				}
+			})
+
+			It("still touches the other volumes", func() {
+				err := baseImagePuller.TouchVolumes(logger, []string{"layer-111", "chain-222"})
+				Expect(err).To(MatchError(ContainSubstring("failed to touch")))
+
+				Expect(fakeVolumeDriver.TouchVolumeCallCount()).To(Equal(2))
+			})
+		})
+	})
+
 })
 
 func chainIDs(layerInfos []groot.LayerInfo) []string {
@@ -89,6 +89,18 @@ type FakeVolumeDriver struct {
 	writeVolumeMetaReturnsOnCall map[int]struct {
 		result1 error
 	}
+	TouchVolumeStub        func(logger lager.Logger, id string) error
+	touchVolumeMutex       sync.RWMutex
+	touchVolumeArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	touchVolumeReturns struct {
+		result1 error
+	}
+	touchVolumeReturnsOnCall map[int]struct {
+		result1 error
+	}
 	HandleOpaqueWhiteoutsStub        func(logger lager.Logger, id string, opaqueWhiteouts []string) error
 	handleOpaqueWhiteoutsMutex       sync.RWMutex
 	handleOpaqueWhiteoutsArgsForCall []struct {
@@ -411,6 +423,55 @@ func (fake *FakeVolumeDriver) WriteVolumeMetaReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
+func (fake *FakeVolumeDriver) TouchVolume(logger lager.Logger, id string) error {
+	fake.touchVolumeMutex.Lock()
+	ret, specificReturn := fake.touchVolumeReturnsOnCall[len(fake.touchVolumeArgsForCall)]
+	fake.touchVolumeArgsForCall = append(fake.touchVolumeArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("TouchVolume", []interface{}{logger, id})
+	fake.touchVolumeMutex.Unlock()
+	if fake.TouchVolumeStub != nil {
+		return fake.TouchVolumeStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.touchVolumeReturns.result1
+}
+
+func (fake *FakeVolumeDriver) TouchVolumeCallCount() int {
+	fake.touchVolumeMutex.RLock()
+	defer fake.touchVolumeMutex.RUnlock()
+	return len(fake.touchVolumeArgsForCall)
+}
+
+func (fake *FakeVolumeDriver) TouchVolumeArgsForCall(i int) (lager.Logger, string) {
+	fake.touchVolumeMutex.RLock()
+	defer fake.touchVolumeMutex.RUnlock()
+	return fake.touchVolumeArgsForCall[i].logger, fake.touchVolumeArgsForCall[i].id
+}
+
+func (fake *FakeVolumeDriver) TouchVolumeReturns(result1 error) {
+	fake.TouchVolumeStub = nil
+	fake.touchVolumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeVolumeDriver) TouchVolumeReturnsOnCall(i int, result1 error) {
+	fake.TouchVolumeStub = nil
+	if fake.touchVolumeReturnsOnCall == nil {
+		fake.touchVolumeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.touchVolumeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeVolumeDriver) HandleOpaqueWhiteouts(logger lager.Logger, id string, opaqueWhiteouts []string) error {
 	var opaqueWhiteoutsCopy []string
 	if opaqueWhiteouts != nil {
@@ -481,6 +542,8 @@ func (fake *FakeVolumeDriver) Invocations() map[string][][]interface{} {
 	defer fake.moveVolumeMutex.RUnlock()
 	fake.writeVolumeMetaMutex.RLock()
 	defer fake.writeVolumeMetaMutex.RUnlock()
+	fake.touchVolumeMutex.RLock()
+	defer fake.touchVolumeMutex.RUnlock()
 	fake.handleOpaqueWhiteoutsMutex.RLock()
 	defer fake.handleOpaqueWhiteoutsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
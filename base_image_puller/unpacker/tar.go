@@ -19,6 +19,7 @@ import (
 
 	"github.com/containers/storage/pkg/reexec"
 	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
 
 	"code.cloudfoundry.org/grootfs/base_image_puller"
 	"code.cloudfoundry.org/grootfs/groot"
@@ -70,6 +71,9 @@ func init() {
 type UnpackStrategy struct {
 	Name               string
 	WhiteoutDevicePath string
+	// PreserveDevices recreates the block and character devices of the layers
+	// instead of skipping them. Only root can create devices.
+	PreserveDevices bool
 }
 
 type TarUnpacker struct {
@@ -247,8 +251,14 @@ func (u *TarUnpacker) unpack(logger lager.Logger, spec base_image_puller.UnpackS
 func (u *TarUnpacker) handleEntry(entryPath string, tarReader *tar.Reader, tarHeader *tar.Header, spec base_image_puller.UnpackSpec) (entrySize int64, err error) {
 	switch tarHeader.Typeflag {
 	case tar.TypeBlock, tar.TypeChar:
-		// ignore devices
-		return 0, nil
+		if !u.strategy.PreserveDevices {
+			// ignore devices
+			return 0, nil
+		}
+
+		if err = u.createDevice(entryPath, tarHeader, spec); err != nil {
+			return 0, err
+		}
 
 	case tar.TypeLink:
 		if err = u.createLink(entryPath, tarHeader); err != nil {
@@ -335,6 +345,43 @@ func (u *TarUnpacker) createSymlink(path string, tarHeader *tar.Header, spec bas
 	return nil
 }
 
+func (u *TarUnpacker) createDevice(path string, tarHeader *tar.Header, spec base_image_puller.UnpackSpec) error {
+	if _, err := os.Lstat(path); err == nil {
+		if err := os.Remove(path); err != nil {
+			return errors.Wrapf(err, "removing file `%s`", path)
+		}
+	}
+
+	mode := uint32(tarHeader.Mode & 07777)
+	if tarHeader.Typeflag == tar.TypeBlock {
+		mode |= unix.S_IFBLK
+	} else {
+		mode |= unix.S_IFCHR
+	}
+
+	dev := unix.Mkdev(uint32(tarHeader.Devmajor), uint32(tarHeader.Devminor))
+	if err := unix.Mknod(path, mode, int(dev)); err != nil {
+		return errors.Wrapf(err, "creating device %d:%d `%s`", tarHeader.Devmajor, tarHeader.Devminor, path)
+	}
+
+	uid := u.translateID(tarHeader.Uid, spec.UIDMappings)
+	gid := u.translateID(tarHeader.Gid, spec.GIDMappings)
+	if err := os.Lchown(path, uid, gid); err != nil {
+		return errors.Wrapf(err, "chowning device %d:%d `%s`", uid, gid, path)
+	}
+
+	// we need to explicitly apply perms because mknod is subject to umask
+	if err := os.Chmod(path, tarHeader.FileInfo().Mode()); err != nil {
+		return errors.Wrapf(err, "chmoding device `%s`", path)
+	}
+
+	if err := changeModTime(path, tarHeader.ModTime); err != nil {
+		return errors.Wrapf(err, "setting the modtime for device `%s`", path)
+	}
+
+	return nil
+}
+
 func (u *TarUnpacker) createLink(path string, tarHeader *tar.Header) error {
 	return os.Link(tarHeader.Linkname, path)
 }
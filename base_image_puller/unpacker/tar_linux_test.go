@@ -5,16 +5,19 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"syscall"
 	"time"
 
 	"code.cloudfoundry.org/grootfs/base_image_puller"
 	"code.cloudfoundry.org/grootfs/base_image_puller/unpacker"
+	"code.cloudfoundry.org/grootfs/groot"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
+	"golang.org/x/sys/unix"
 )
 
 var _ = Describe("Tar unpacker - Linux tests", func() {
@@ -67,6 +70,41 @@ var _ = Describe("Tar unpacker - Linux tests", func() {
 			filePath := path.Join(targetPath, "a_device")
 			Expect(filePath).ToNot(BeAnExistingFile())
 		})
+
+		Context("when devices are preserved", func() {
+			BeforeEach(func() {
+				var err error
+				tarUnpacker, err = unpacker.NewTarUnpacker(unpacker.UnpackStrategy{Name: "defaultfs", PreserveDevices: true})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(exec.Command("sudo", "chmod", "0620", path.Join(baseImagePath, "a_device")).Run()).To(Succeed())
+				Expect(exec.Command("sudo", "chown", "1000:1000", path.Join(baseImagePath, "a_device")).Run()).To(Succeed())
+			})
+
+			It("recreates them with the same device numbers, mode and owner", func() {
+				_, err := tarUnpacker.Unpack(logger, base_image_puller.UnpackSpec{
+					Stream:     stream,
+					TargetPath: targetPath,
+					UIDMappings: []groot.IDMappingSpec{
+						{HostID: 100000, NamespaceID: 1, Size: 65000},
+					},
+					GIDMappings: []groot.IDMappingSpec{
+						{HostID: 100000, NamespaceID: 1, Size: 65000},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				fi, err := os.Lstat(path.Join(targetPath, "a_device"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fi.Mode() & os.ModeCharDevice).NotTo(BeZero())
+				Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0620)))
+
+				stat := fi.Sys().(*syscall.Stat_t)
+				Expect(stat.Rdev).To(Equal(uint64(unix.Mkdev(1, 8))))
+				Expect(stat.Uid).To(Equal(uint32(100999)))
+				Expect(stat.Gid).To(Equal(uint32(100999)))
+			})
+		})
 	})
 
 	Describe("modification time", func() {
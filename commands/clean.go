@@ -1,24 +1,14 @@
 package commands // import "code.cloudfoundry.org/grootfs/commands"
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 
-	"code.cloudfoundry.org/commandrunner/linux_command_runner"
-	"code.cloudfoundry.org/lager"
-
-	unpackerpkg "code.cloudfoundry.org/grootfs/base_image_puller/unpacker"
 	"code.cloudfoundry.org/grootfs/commands/config"
 	"code.cloudfoundry.org/grootfs/groot"
-	"code.cloudfoundry.org/grootfs/metrics"
-	storepkg "code.cloudfoundry.org/grootfs/store"
-	"code.cloudfoundry.org/grootfs/store/dependency_manager"
-	"code.cloudfoundry.org/grootfs/store/filesystems/namespaced"
-	"code.cloudfoundry.org/grootfs/store/garbage_collector"
-	imageClonerpkg "code.cloudfoundry.org/grootfs/store/image_cloner"
-	locksmithpkg "code.cloudfoundry.org/grootfs/store/locksmith"
-	errorspkg "github.com/pkg/errors"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/lager"
 
 	"github.com/urfave/cli"
 )
@@ -33,6 +23,22 @@ var CleanCommand = cli.Command{
 			Name:  "threshold-bytes",
 			Usage: "Disk usage of the store directory at which cleanup should trigger",
 		},
+		cli.Int64Flag{
+			Name:  "target-bytes",
+			Usage: "Disk usage of the store directory that cleanup should bring it down to, evicting least recently used layers first",
+		},
+		cli.StringSliceFlag{
+			Name:  "retain-label",
+			Usage: "Keep the layers of deleted images with this label (key or key=value)",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Report the unused layers that would be removed without removing them",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the clean report as JSON",
+		},
 	},
 
 	Action: func(ctx *cli.Context) error {
@@ -41,7 +47,11 @@ var CleanCommand = cli.Command{
 
 		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
 		configBuilder.WithCleanThresholdBytes(ctx.Int64("threshold-bytes"),
-			ctx.IsSet("threshold-bytes"))
+			ctx.IsSet("threshold-bytes")).
+			WithCleanTargetBytes(ctx.Int64("target-bytes"),
+				ctx.IsSet("target-bytes")).
+			WithCleanRetainLabels(ctx.StringSlice("retain-label"),
+				ctx.IsSet("retain-label"))
 
 		cfg, err := configBuilder.Build()
 		logger.Debug("clean-config", lager.Data{"currentConfig": cfg})
@@ -50,70 +60,58 @@ var CleanCommand = cli.Command{
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		storePath := cfg.StorePath
-		if _, err = os.Stat(storePath); os.IsNotExist(err) {
-			err = errorspkg.Errorf("no store found at %s", storePath)
-			logger.Error("store-path-failed", err, nil)
-			return cli.NewExitError(err.Error(), 0)
-		}
-
-		fsDriver, err := createFileSystemDriver(cfg)
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
 		if err != nil {
-			logger.Error("failed-to-initialise-filesystem-driver", err)
+			logger.Error("failed-to-initialise-store", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		imageCloner := imageClonerpkg.NewImageCloner(fsDriver, storePath)
-		metricsEmitter := metrics.NewEmitter(logger, cfg.MetronEndpoint)
-
-		locksmith := locksmithpkg.NewExclusiveFileSystem(storePath, metricsEmitter)
-		dependencyManager := dependency_manager.NewDependencyManager(
-			filepath.Join(storePath, storepkg.MetaDirName, "dependencies"),
-		)
-
-		storeNamespacer := groot.NewStoreNamespacer(storePath)
-		idMappings, err := storeNamespacer.Read()
-		if err != nil {
-			logger.Error("reading-namespace-file", err)
-			return cli.NewExitError(err.Error(), 1)
+		cleanSpec := store.DefaultCleanSpec()
+		cleanSpec.DryRun = ctx.Bool("dry-run")
+		report, err := store.Clean(cleanSpec)
+		if grootfs.IsStoreNotFound(err) {
+			logger.Error("store-path-failed", err, nil)
+			return cli.NewExitError(err.Error(), 0)
 		}
-
-		runner := linux_command_runner.New()
-		idMapper := unpackerpkg.NewIDMapper(cfg.NewuidmapBin, cfg.NewgidmapBin, runner)
-		nsFsDriver := namespaced.New(fsDriver, idMappings, idMapper, runner)
-		gc := garbage_collector.NewGC(nsFsDriver, imageCloner, dependencyManager)
-		sm := storepkg.NewStoreMeasurer(storePath, fsDriver, gc)
-
-		cleaner := groot.IamCleaner(locksmith, sm, gc, metricsEmitter)
-
-		defer func() {
-			unusedVolumesSize, err := sm.UnusedVolumesSize(logger)
-			if err != nil {
-				logger.Error("getting-unused-volumes-size", err)
-			}
-			metricsEmitter.TryEmitUsage(logger, "UnusedLayersSize", unusedVolumesSize, "bytes")
-		}()
-
-		noop, err := cleaner.Clean(logger, cfg.Clean.ThresholdBytes)
 		if err != nil {
 			logger.Error("cleaning-up-unused-resources", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		if noop {
-			fmt.Println("threshold not reached: skipping clean")
-			return nil
+		if ctx.Bool("json") {
+			_ = json.NewEncoder(os.Stdout).Encode(report)
+		} else {
+			printCleanReport(report)
 		}
 
-		fmt.Println("clean completed")
-
-		usage, err := sm.Usage(logger)
-		if err != nil {
-			logger.Error("measuring-store", err)
-			return cli.NewExitError(err.Error(), 1)
-		}
-
-		metricsEmitter.TryEmitUsage(logger, "StoreUsage", usage, "bytes")
 		return nil
 	},
 }
+
+func printCleanReport(report groot.CleanReport) {
+	if report.Noop {
+		fmt.Println("threshold not reached: skipping clean")
+		return
+	}
+
+	action := "removed"
+	if report.DryRun {
+		action = "would remove"
+	}
+
+	for _, volume := range report.Volumes {
+		fmt.Printf("%s %s (%d bytes)\n", action, volume.ID, volume.Size)
+	}
+	if report.Measured {
+		fmt.Printf("reclaimed %d bytes: usage %d -> %d bytes\n", report.ReclaimedBytes, report.UsageBefore, report.UsageAfter)
+	} else {
+		fmt.Printf("reclaimed %d bytes\n", report.ReclaimedBytes)
+	}
+
+	if report.DryRun {
+		fmt.Println("dry run: nothing was removed")
+		return
+	}
+
+	fmt.Println("clean completed")
+}
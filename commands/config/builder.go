@@ -2,24 +2,54 @@ package config
 
 import (
 	"io/ioutil"
+	"strings"
 
 	errorspkg "github.com/pkg/errors"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
+const (
+	// SpecFormatMinimal specs only have the rootfs, environment and mounts.
+	SpecFormatMinimal = "minimal"
+	// SpecFormatFull specs also have the process and annotations from the
+	// image config.
+	SpecFormatFull = "full"
+)
+
+const (
+	// RootFSConfigurerWorkingDir creates the image's working directory.
+	RootFSConfigurerWorkingDir = "working_dir"
+	// RootFSConfigurerVolumes creates the mount points of the image's volumes.
+	RootFSConfigurerVolumes = "volumes"
+	// RootFSConfigurerPasswd adds an /etc/passwd entry for a numeric image user.
+	RootFSConfigurerPasswd = "passwd"
+)
+
+// overlayMountOptions are the overlay mount options images can be created
+// with, and the values they accept. Options without values are flags.
+var overlayMountOptions = map[string][]string{
+	"metacopy":     {"on", "off"},
+	"index":        {"on", "off"},
+	"redirect_dir": {"on", "off", "follow", "nofollow"},
+	"volatile":     nil,
+}
+
 type Config struct {
-	StorePath      string `yaml:"store"`
-	FSDriver       string `yaml:"driver"`
-	TardisBin      string `yaml:"tardis_bin"`
-	NewuidmapBin   string `yaml:"newuidmap_bin"`
-	NewgidmapBin   string `yaml:"newgidmap_bin"`
-	MetronEndpoint string `yaml:"metron_endpoint"`
-	LogLevel       string `yaml:"log_level"`
-	LogFile        string `yaml:"log_file"`
-	Create         Create `yaml:"create"`
-	Clean          Clean  `yaml:"clean"`
-	Init           Init   `yaml:"-"`
+	StorePath       string      `yaml:"store"`
+	FSDriver        string      `yaml:"driver"`
+	TardisBin       string      `yaml:"tardis_bin"`
+	NewuidmapBin    string      `yaml:"newuidmap_bin"`
+	NewgidmapBin    string      `yaml:"newgidmap_bin"`
+	MetronEndpoint  string      `yaml:"metron_endpoint"`
+	LogLevel        string      `yaml:"log_level"`
+	LogFile         string      `yaml:"log_file"`
+	PreserveDevices bool        `yaml:"preserve_devices"`
+	Create          Create      `yaml:"create"`
+	Clean           Clean       `yaml:"clean"`
+	Serve           Serve       `yaml:"serve"`
+	Snapshotter     Snapshotter `yaml:"snapshotter"`
+	Init            Init        `yaml:"-"`
 }
 
 type Create struct {
@@ -28,12 +58,28 @@ type Create struct {
 	WithClean                         bool     `yaml:"with_clean"`
 	WithoutMount                      bool     `yaml:"without_mount"`
 	DiskLimitSizeBytes                int64    `yaml:"disk_limit_size_bytes"`
+	MaxLayerDepth                     int      `yaml:"max_layer_depth"`
+	SpecFormat                        string   `yaml:"spec_format"`
+	RootFSConfigurers                 []string `yaml:"rootfs_configurers"`
+	OverlayMountOptions               []string `yaml:"overlay_mount_options"`
 	InsecureRegistries                []string `yaml:"insecure_registries"`
 	RemoteLayerClientCertificatesPath string   `yaml:"remote_layer_client_certificates_path"`
 }
 
 type Clean struct {
-	ThresholdBytes int64 `yaml:"threshold_bytes"`
+	ThresholdBytes int64    `yaml:"threshold_bytes"`
+	TargetBytes    int64    `yaml:"target_bytes"`
+	RetainLabels   []string `yaml:"retain_labels"`
+}
+
+type Serve struct {
+	SocketPath               string `yaml:"socket"`
+	CleanIntervalSeconds     int64  `yaml:"clean_interval_seconds"`
+	ImageInfoCacheTTLSeconds int64  `yaml:"image_info_cache_ttl_seconds"`
+}
+
+type Snapshotter struct {
+	Address string `yaml:"address"`
 }
 
 type Init struct {
@@ -69,10 +115,49 @@ func (b *Builder) Build() (Config, error) {
 		return *b.config, errorspkg.New("invalid argument: disk limit cannot be negative")
 	}
 
+	if b.config.Create.MaxLayerDepth < 0 || b.config.Create.MaxLayerDepth == 1 {
+		return *b.config, errorspkg.New("invalid argument: max layer depth must be 0 (disabled) or at least 2")
+	}
+
+	switch b.config.Create.SpecFormat {
+	case "", SpecFormatMinimal, SpecFormatFull:
+	default:
+		return *b.config, errorspkg.Errorf("invalid argument: spec format must be %s or %s", SpecFormatMinimal, SpecFormatFull)
+	}
+
+	for _, configurer := range b.config.Create.RootFSConfigurers {
+		switch configurer {
+		case RootFSConfigurerWorkingDir, RootFSConfigurerVolumes, RootFSConfigurerPasswd:
+		default:
+			return *b.config, errorspkg.Errorf("invalid argument: unknown rootfs configurer `%s`, must be %s, %s or %s",
+				configurer, RootFSConfigurerWorkingDir, RootFSConfigurerVolumes, RootFSConfigurerPasswd)
+		}
+	}
+
+	if err := ValidateOverlayMountOptions(b.config.Create.OverlayMountOptions); err != nil {
+		return *b.config, err
+	}
+
 	if b.config.Clean.ThresholdBytes < 0 {
 		return *b.config, errorspkg.New("invalid argument: clean threshold cannot be negative")
 	}
 
+	if b.config.Clean.TargetBytes < 0 {
+		return *b.config, errorspkg.New("invalid argument: clean target cannot be negative")
+	}
+
+	if b.config.Clean.ThresholdBytes > 0 && b.config.Clean.TargetBytes > b.config.Clean.ThresholdBytes {
+		return *b.config, errorspkg.New("invalid argument: clean target cannot be greater than the clean threshold")
+	}
+
+	if b.config.Serve.CleanIntervalSeconds < 0 {
+		return *b.config, errorspkg.New("invalid argument: clean interval cannot be negative")
+	}
+
+	if b.config.Serve.ImageInfoCacheTTLSeconds < 0 {
+		return *b.config, errorspkg.New("invalid argument: image info cache ttl cannot be negative")
+	}
+
 	return *b.config, nil
 }
 
@@ -122,6 +207,13 @@ func (b *Builder) WithNewgidmapBin(newgidmapBin string, isSet bool) *Builder {
 	return b
 }
 
+func (b *Builder) WithPreserveDevices(preserve, isSet bool) *Builder {
+	if isSet {
+		b.config.PreserveDevices = preserve
+	}
+	return b
+}
+
 func (b *Builder) WithMetronEndpoint(metronEndpoint string) *Builder {
 	if metronEndpoint == "" {
 		return b
@@ -138,6 +230,27 @@ func (b *Builder) WithDiskLimitSizeBytes(limit int64, isSet bool) *Builder {
 	return b
 }
 
+func (b *Builder) WithSpecFormat(format string, isSet bool) *Builder {
+	if isSet {
+		b.config.Create.SpecFormat = format
+	}
+	return b
+}
+
+func (b *Builder) WithOverlayMountOptions(options []string, isSet bool) *Builder {
+	if isSet {
+		b.config.Create.OverlayMountOptions = options
+	}
+	return b
+}
+
+func (b *Builder) WithMaxLayerDepth(depth int, isSet bool) *Builder {
+	if isSet {
+		b.config.Create.MaxLayerDepth = depth
+	}
+	return b
+}
+
 func (b *Builder) WithExcludeImageFromQuota(exclude, isSet bool) *Builder {
 	if isSet {
 		b.config.Create.ExcludeImageFromQuota = exclude
@@ -159,6 +272,48 @@ func (b *Builder) WithCleanThresholdBytes(threshold int64, isSet bool) *Builder
 	return b
 }
 
+func (b *Builder) WithCleanTargetBytes(target int64, isSet bool) *Builder {
+	if isSet {
+		b.config.Clean.TargetBytes = target
+	}
+	return b
+}
+
+func (b *Builder) WithCleanRetainLabels(labels []string, isSet bool) *Builder {
+	if isSet {
+		b.config.Clean.RetainLabels = labels
+	}
+	return b
+}
+
+func (b *Builder) WithServeSocketPath(socketPath string, isSet bool) *Builder {
+	if isSet {
+		b.config.Serve.SocketPath = socketPath
+	}
+	return b
+}
+
+func (b *Builder) WithServeCleanIntervalSeconds(interval int64, isSet bool) *Builder {
+	if isSet {
+		b.config.Serve.CleanIntervalSeconds = interval
+	}
+	return b
+}
+
+func (b *Builder) WithServeImageInfoCacheTTLSeconds(ttl int64, isSet bool) *Builder {
+	if isSet {
+		b.config.Serve.ImageInfoCacheTTLSeconds = ttl
+	}
+	return b
+}
+
+func (b *Builder) WithSnapshotterAddress(address string, isSet bool) *Builder {
+	if isSet {
+		b.config.Snapshotter.Address = address
+	}
+	return b
+}
+
 func (b *Builder) WithLogLevel(level string, isSet bool) *Builder {
 	if isSet {
 		b.config.LogLevel = level
@@ -202,6 +357,48 @@ func (b *Builder) WithStoreSizeBytes(size int64) *Builder {
 	return b
 }
 
+// ValidateOverlayMountOptions only accepts the overlay mount options in
+// overlayMountOptions. Whether the kernel supports them is checked by
+// init-store.
+func ValidateOverlayMountOptions(options []string) error {
+	for _, option := range options {
+		name, value := option, ""
+		hasValue := strings.Contains(option, "=")
+		if hasValue {
+			parts := strings.SplitN(option, "=", 2)
+			name, value = parts[0], parts[1]
+		}
+
+		values, ok := overlayMountOptions[name]
+		if !ok {
+			return errorspkg.Errorf("invalid argument: unsupported overlay mount option `%s`, must be metacopy, index, redirect_dir or volatile", option)
+		}
+
+		if values == nil {
+			if hasValue {
+				return errorspkg.Errorf("invalid argument: overlay mount option `%s` doesn't take a value", name)
+			}
+			continue
+		}
+
+		if !contains(values, value) {
+			return errorspkg.Errorf("invalid argument: overlay mount option `%s` must be one of %s", name, strings.Join(values, ", "))
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
 func load(configPath string) (Config, error) {
 	configContent, err := ioutil.ReadFile(configPath)
 	if err != nil {
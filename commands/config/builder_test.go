@@ -17,6 +17,8 @@ var _ = Describe("Builder", func() {
 		cfg            config.Config
 		createCfg      config.Create
 		cleanCfg       config.Clean
+		serveCfg       config.Serve
+		snapshotterCfg config.Snapshotter
 		configDir      string
 		configFilePath string
 		builder        *config.Builder
@@ -30,15 +32,32 @@ var _ = Describe("Builder", func() {
 			SkipLayerValidation:   true,
 			InsecureRegistries:    []string{"http://example.org"},
 			DiskLimitSizeBytes:    int64(1000),
+			MaxLayerDepth:         8,
+			SpecFormat:            "minimal",
+			RootFSConfigurers:     []string{"working_dir", "passwd"},
+			OverlayMountOptions:   []string{"metacopy=on"},
 		}
 
 		cleanCfg = config.Clean{
 			ThresholdBytes: int64(0),
+			RetainLabels:   []string{"tenant=acme"},
+		}
+
+		serveCfg = config.Serve{
+			SocketPath:               "/config/grootfs.sock",
+			CleanIntervalSeconds:     300,
+			ImageInfoCacheTTLSeconds: 60,
+		}
+
+		snapshotterCfg = config.Snapshotter{
+			Address: "/config/snapshotter.sock",
 		}
 
 		cfg = config.Config{
 			Create:         createCfg,
 			Clean:          cleanCfg,
+			Serve:          serveCfg,
+			Snapshotter:    snapshotterCfg,
 			StorePath:      "/hello",
 			FSDriver:       "kitten-fs",
 			TardisBin:      "/config/tardis",
@@ -294,6 +313,28 @@ var _ = Describe("Builder", func() {
 		})
 	})
 
+	Describe("WithPreserveDevices", func() {
+		It("overrides the config's entry when the command line flag is set", func() {
+			builder = builder.WithPreserveDevices(true, true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.PreserveDevices).To(BeTrue())
+		})
+
+		Context("when the flag is not set", func() {
+			BeforeEach(func() {
+				cfg.PreserveDevices = true
+			})
+
+			It("uses the config's entry", func() {
+				builder = builder.WithPreserveDevices(false, false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.PreserveDevices).To(BeTrue())
+			})
+		})
+	})
+
 	Describe("WithMetronEndpoint", func() {
 		It("overrides the config's metron endpoint entry", func() {
 			builder = builder.WithMetronEndpoint("127.0.0.1:5555")
@@ -340,6 +381,136 @@ var _ = Describe("Builder", func() {
 		})
 	})
 
+	Describe("WithSpecFormat", func() {
+		It("overrides the config's SpecFormat entry when flag is set", func() {
+			builder = builder.WithSpecFormat("full", true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Create.SpecFormat).To(Equal("full"))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithSpecFormat("full", false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Create.SpecFormat).To(Equal("minimal"))
+			})
+		})
+
+		Context("when the format is unknown", func() {
+			It("returns an error", func() {
+				builder = builder.WithSpecFormat("partial", true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: spec format must be minimal or full"))
+			})
+		})
+	})
+
+	Describe("rootfs configurers", func() {
+		It("uses the config entry", func() {
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Create.RootFSConfigurers).To(Equal([]string{"working_dir", "passwd"}))
+		})
+
+		Context("when a configurer is unknown", func() {
+			BeforeEach(func() {
+				cfg.Create.RootFSConfigurers = []string{"volumes", "hostname"}
+			})
+
+			It("returns an error", func() {
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: unknown rootfs configurer `hostname`, must be working_dir, volumes or passwd"))
+			})
+		})
+	})
+
+	Describe("WithOverlayMountOptions", func() {
+		It("overrides the config's OverlayMountOptions entry when flag is set", func() {
+			builder = builder.WithOverlayMountOptions([]string{"volatile", "index=off"}, true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Create.OverlayMountOptions).To(Equal([]string{"volatile", "index=off"}))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithOverlayMountOptions([]string{"volatile"}, false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Create.OverlayMountOptions).To(Equal([]string{"metacopy=on"}))
+			})
+		})
+
+		Context("when an option is unsupported", func() {
+			It("returns an error", func() {
+				builder = builder.WithOverlayMountOptions([]string{"upperdir=/tmp"}, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: unsupported overlay mount option `upperdir=/tmp`, must be metacopy, index, redirect_dir or volatile"))
+			})
+		})
+
+		Context("when an option has an invalid value", func() {
+			It("returns an error", func() {
+				builder = builder.WithOverlayMountOptions([]string{"redirect_dir=maybe"}, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: overlay mount option `redirect_dir` must be one of on, off, follow, nofollow"))
+			})
+		})
+
+		Context("when a flag option has a value", func() {
+			It("returns an error", func() {
+				builder = builder.WithOverlayMountOptions([]string{"volatile=on"}, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: overlay mount option `volatile` doesn't take a value"))
+			})
+		})
+	})
+
+	Describe("WithMaxLayerDepth", func() {
+		It("overrides the config's MaxLayerDepth entry when flag is set", func() {
+			builder = builder.WithMaxLayerDepth(16, true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Create.MaxLayerDepth).To(Equal(16))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithMaxLayerDepth(16, false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Create.MaxLayerDepth).To(Equal(8))
+			})
+		})
+
+		Context("when zero", func() {
+			It("disables flattening", func() {
+				builder = builder.WithMaxLayerDepth(0, true)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Create.MaxLayerDepth).To(Equal(0))
+			})
+		})
+
+		Context("when negative", func() {
+			It("returns an error", func() {
+				builder = builder.WithMaxLayerDepth(-1, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: max layer depth must be 0 (disabled) or at least 2"))
+			})
+		})
+
+		Context("when one", func() {
+			It("returns an error", func() {
+				builder = builder.WithMaxLayerDepth(1, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: max layer depth must be 0 (disabled) or at least 2"))
+			})
+		})
+	})
+
 	Describe("WithExcludeImageFromQuota", func() {
 		It("overrides the config's ExcludeImageFromQuota when the flag is set", func() {
 			builder = builder.WithExcludeImageFromQuota(false, true)
@@ -394,6 +565,155 @@ var _ = Describe("Builder", func() {
 		})
 	})
 
+	Describe("WithServeSocketPath", func() {
+		It("overrides the config's socket entry when the flag is set", func() {
+			builder = builder.WithServeSocketPath("/flag/grootfs.sock", true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Serve.SocketPath).To(Equal("/flag/grootfs.sock"))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithServeSocketPath("/flag/grootfs.sock", false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Serve.SocketPath).To(Equal(serveCfg.SocketPath))
+			})
+		})
+	})
+
+	Describe("WithServeCleanIntervalSeconds", func() {
+		It("overrides the config's clean interval entry when the flag is set", func() {
+			builder = builder.WithServeCleanIntervalSeconds(60, true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Serve.CleanIntervalSeconds).To(Equal(int64(60)))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithServeCleanIntervalSeconds(60, false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Serve.CleanIntervalSeconds).To(Equal(serveCfg.CleanIntervalSeconds))
+			})
+		})
+
+		Context("when negative", func() {
+			It("returns an error", func() {
+				builder = builder.WithServeCleanIntervalSeconds(-1, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: clean interval cannot be negative"))
+			})
+		})
+	})
+
+	Describe("WithServeImageInfoCacheTTLSeconds", func() {
+		It("overrides the config's image info cache ttl entry when the flag is set", func() {
+			builder = builder.WithServeImageInfoCacheTTLSeconds(10, true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Serve.ImageInfoCacheTTLSeconds).To(Equal(int64(10)))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithServeImageInfoCacheTTLSeconds(10, false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Serve.ImageInfoCacheTTLSeconds).To(Equal(serveCfg.ImageInfoCacheTTLSeconds))
+			})
+		})
+
+		Context("when negative", func() {
+			It("returns an error", func() {
+				builder = builder.WithServeImageInfoCacheTTLSeconds(-1, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: image info cache ttl cannot be negative"))
+			})
+		})
+	})
+
+	Describe("WithSnapshotterAddress", func() {
+		It("overrides the config's address entry when the flag is set", func() {
+			builder = builder.WithSnapshotterAddress("/flag/snapshotter.sock", true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Snapshotter.Address).To(Equal("/flag/snapshotter.sock"))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithSnapshotterAddress("/flag/snapshotter.sock", false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Snapshotter.Address).To(Equal(snapshotterCfg.Address))
+			})
+		})
+	})
+
+	Describe("WithCleanRetainLabels", func() {
+		It("overrides the config's RetainLabels entry when the flag is set", func() {
+			builder = builder.WithCleanRetainLabels([]string{"keep"}, true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Clean.RetainLabels).To(Equal([]string{"keep"}))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithCleanRetainLabels([]string{"keep"}, false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Clean.RetainLabels).To(Equal([]string{"tenant=acme"}))
+			})
+		})
+	})
+
+	Describe("WithCleanTargetBytes", func() {
+		It("overrides the config's CleanTargetBytes entry when the flag is set", func() {
+			builder = builder.WithCleanTargetBytes(512, true)
+			config, err := builder.Build()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Clean.TargetBytes).To(Equal(int64(512)))
+		})
+
+		Context("when flag is not set", func() {
+			It("uses the config entry", func() {
+				builder = builder.WithCleanTargetBytes(512, false)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Clean.TargetBytes).To(Equal(cfg.Clean.TargetBytes))
+			})
+		})
+
+		Context("when negative", func() {
+			It("returns an error", func() {
+				builder = builder.WithCleanTargetBytes(-1, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: clean target cannot be negative"))
+			})
+		})
+
+		Context("when greater than the threshold", func() {
+			It("returns an error", func() {
+				builder = builder.WithCleanThresholdBytes(1024, true).WithCleanTargetBytes(2048, true)
+				_, err := builder.Build()
+				Expect(err).To(MatchError("invalid argument: clean target cannot be greater than the clean threshold"))
+			})
+		})
+
+		Context("when there is no threshold", func() {
+			It("accepts any target", func() {
+				builder = builder.WithCleanThresholdBytes(0, true).WithCleanTargetBytes(2048, true)
+				config, err := builder.Build()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Clean.TargetBytes).To(Equal(int64(2048)))
+			})
+		})
+	})
+
 	Describe("WithLogLevel", func() {
 		It("overrides the config's Log Level entry", func() {
 			builder = builder.WithLogLevel("debug", true)
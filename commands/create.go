@@ -3,35 +3,16 @@ package commands // import "code.cloudfoundry.org/grootfs/commands"
 import (
 	"crypto/x509"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/url"
-	"os"
-	"path/filepath"
 	"regexp"
 
-	"code.cloudfoundry.org/commandrunner/linux_command_runner"
-	"code.cloudfoundry.org/grootfs/base_image_puller"
-	unpackerpkg "code.cloudfoundry.org/grootfs/base_image_puller/unpacker"
 	"code.cloudfoundry.org/grootfs/commands/config"
-	"code.cloudfoundry.org/grootfs/fetcher/layer_fetcher"
-	"code.cloudfoundry.org/grootfs/fetcher/layer_fetcher/source"
-	"code.cloudfoundry.org/grootfs/fetcher/tar_fetcher"
 	"code.cloudfoundry.org/grootfs/groot"
-	"code.cloudfoundry.org/grootfs/metrics"
-	storepkg "code.cloudfoundry.org/grootfs/store"
-	"code.cloudfoundry.org/grootfs/store/dependency_manager"
-	"code.cloudfoundry.org/grootfs/store/filesystems/namespaced"
-	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs"
-	"code.cloudfoundry.org/grootfs/store/garbage_collector"
-	"code.cloudfoundry.org/grootfs/store/image_cloner"
-	locksmithpkg "code.cloudfoundry.org/grootfs/store/locksmith"
-	"code.cloudfoundry.org/grootfs/store/manager"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
 	"code.cloudfoundry.org/lager"
 
-	"github.com/containers/image/types"
 	"github.com/docker/distribution/registry/api/errcode"
-	specs "github.com/opencontainers/runtime-spec/specs-go"
 	errorspkg "github.com/pkg/errors"
 	"github.com/urfave/cli"
 )
@@ -70,6 +51,10 @@ var CreateCommand = cli.Command{
 			Name:  "threshold-bytes",
 			Usage: "Disk usage of the store directory at which cleanup should trigger",
 		},
+		cli.Int64Flag{
+			Name:  "target-bytes",
+			Usage: "Disk usage of the store directory that cleanup should bring it down to, evicting least recently used layers first",
+		},
 		cli.BoolFlag{
 			Name:  "with-mount",
 			Usage: "Mount the root filesystem after creation. This may require root privileges.",
@@ -78,6 +63,46 @@ var CreateCommand = cli.Command{
 			Name:  "without-mount",
 			Usage: "Do not mount the root filesystem.",
 		},
+		cli.IntFlag{
+			Name:  "max-layer-depth",
+			Usage: "Flatten the bottom layers of images with more layers than this into a single cached volume (0 disables flattening)",
+		},
+		cli.StringSliceFlag{
+			Name:  "overlay-mount-option",
+			Usage: "Mount the image's overlay with this extra option, replacing the configured ones (can be repeated) <metacopy=on|off | index=on|off | redirect_dir=on|off|follow|nofollow | volatile>",
+		},
+		cli.StringFlag{
+			Name:  "spec-format",
+			Usage: "Output a minimal spec (rootfs, env and mounts) or a full runtime spec with the process and annotations from the image config <minimal | full>",
+		},
+		cli.StringFlag{
+			Name:  "bundle",
+			Usage: "Also write a runc bundle (config.json and rootfs) for the image to this directory. It is removed when the image is deleted.",
+		},
+		cli.StringSliceFlag{
+			Name:  "layer",
+			Usage: "Add a plain or gzipped tar layer on top of the image (can be repeated, layers are stacked in order)",
+		},
+		cli.StringSliceFlag{
+			Name:  "copy",
+			Usage: "Copy a host file into the image (can be repeated) <src:dst[:mode[:uid:gid]]>",
+		},
+		cli.BoolFlag{
+			Name:  "read-only",
+			Usage: "Create the image without a writable layer or a disk quota",
+		},
+		cli.BoolFlag{
+			Name:  "ephemeral",
+			Usage: "Keep the writable layer of the image on a tmpfs instead of the store",
+		},
+		cli.Int64Flag{
+			Name:  "ephemeral-size",
+			Usage: "Size in bytes of the tmpfs of an ephemeral image, which is its disk limit",
+		},
+		cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "Label the image with a key=value pair",
+		},
 		cli.StringFlag{
 			Name:  "username",
 			Usage: "Username to authenticate in image registry",
@@ -106,8 +131,12 @@ var CreateCommand = cli.Command{
 			WithSkipLayerValidation(ctx.Bool("skip-layer-validation"),
 				ctx.IsSet("skip-layer-validation")).
 			WithCleanThresholdBytes(ctx.Int64("threshold-bytes"), ctx.IsSet("threshold-bytes")).
+			WithCleanTargetBytes(ctx.Int64("target-bytes"), ctx.IsSet("target-bytes")).
 			WithClean(ctx.IsSet("with-clean"), ctx.IsSet("without-clean")).
-			WithMount(ctx.IsSet("with-mount"), ctx.IsSet("without-mount"))
+			WithMount(ctx.IsSet("with-mount"), ctx.IsSet("without-mount")).
+			WithMaxLayerDepth(ctx.Int("max-layer-depth"), ctx.IsSet("max-layer-depth")).
+			WithOverlayMountOptions(ctx.StringSlice("overlay-mount-option"), ctx.IsSet("overlay-mount-option")).
+			WithSpecFormat(ctx.String("spec-format"), ctx.IsSet("spec-format"))
 
 		cfg, err := configBuilder.Build()
 		logger.Debug("create-config", lager.Data{"currentConfig": cfg})
@@ -120,7 +149,6 @@ var CreateCommand = cli.Command{
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		storePath := cfg.StorePath
 		id := ctx.Args().Tail()[0]
 		baseImage := ctx.Args().First()
 		baseImageURL, err := url.Parse(baseImage)
@@ -129,197 +157,64 @@ var CreateCommand = cli.Command{
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		fsDriver, err := createFileSystemDriver(cfg)
+		labels, err := groot.ParseLabels(ctx.StringSlice("label"))
 		if err != nil {
+			logger.Error("parsing-labels-failed", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		metricsEmitter := metrics.NewEmitter(logger, cfg.MetronEndpoint)
-		sharedLocksmith := locksmithpkg.NewSharedFileSystem(storePath, metricsEmitter)
-		exclusiveLocksmith := locksmithpkg.NewExclusiveFileSystem(storePath, metricsEmitter)
-		imageCloner := image_cloner.NewImageCloner(fsDriver, storePath)
-
-		storeNamespacer := groot.NewStoreNamespacer(storePath)
-		manager := manager.New(storePath, storeNamespacer, fsDriver, fsDriver, fsDriver)
-		if !manager.IsStoreInitialized(logger) {
-			logger.Error("store-verification-failed", errors.New("store is not initialized"))
-			return cli.NewExitError("Store path is not initialized. Please run init-store.", 1)
+		fileCopies, err := groot.ParseFileCopies(ctx.StringSlice("copy"))
+		if err != nil {
+			logger.Error("parsing-copies-failed", err)
+			return cli.NewExitError(err.Error(), 1)
 		}
 
-		idMappings, err := storeNamespacer.Read()
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
 		if err != nil {
-			logger.Error("reading-namespace-file", err)
+			logger.Error("failed-to-initialise-store", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		runner := linux_command_runner.New()
-		var unpacker base_image_puller.Unpacker
-		unpackerStrategy := unpackerpkg.UnpackStrategy{
-			Name:               cfg.FSDriver,
-			WhiteoutDevicePath: filepath.Join(storePath, overlayxfs.WhiteoutDevice),
+		createSpec := store.DefaultCreateSpec(id, baseImageURL)
+		createSpec.Credentials = grootfs.Credentials{
+			Username: ctx.String("username"),
+			Password: ctx.String("password"),
 		}
-
-		var idMapper unpackerpkg.IDMapper
-		if os.Getuid() == 0 {
-			unpacker, err = unpackerpkg.NewTarUnpacker(unpackerStrategy)
-			if err != nil {
-				return cli.NewExitError(err.Error(), 1)
+		createSpec.Labels = labels
+		createSpec.Bundle = ctx.String("bundle")
+		createSpec.Layers = ctx.StringSlice("layer")
+		createSpec.Copies = fileCopies
+		createSpec.ReadOnly = ctx.Bool("read-only")
+		createSpec.Ephemeral = ctx.Bool("ephemeral")
+		createSpec.EphemeralSizeBytes = ctx.Int64("ephemeral-size")
+		if createSpec.Ephemeral {
+			// Only the quota flags given to this create conflict with it,
+			// not the store's configured defaults.
+			if !ctx.IsSet("disk-limit-size-bytes") {
+				createSpec.DiskLimit = 0
 			}
-		} else {
-			idMapper = unpackerpkg.NewIDMapper(cfg.NewuidmapBin, cfg.NewgidmapBin, runner)
-			unpacker = unpackerpkg.NewNSIdMapperUnpacker(runner, idMapper, unpackerStrategy)
-		}
-
-		dependencyManager := dependency_manager.NewDependencyManager(
-			filepath.Join(storePath, storepkg.MetaDirName, "dependencies"),
-		)
-
-		nsFsDriver := namespaced.New(fsDriver, idMappings, idMapper, runner)
-
-		systemContext := createSystemContext(baseImageURL, cfg.Create, ctx.String("username"), ctx.String("password"))
-
-		fetcher := createFetcher(baseImageURL, systemContext, cfg.Create)
-		defer func() {
-			err := fetcher.Close()
-			if err != nil {
-				logger.Error("closing-fetcher", err)
+			if !ctx.IsSet("exclude-image-from-quota") {
+				createSpec.ExcludeBaseImageFromQuota = false
 			}
-		}()
-
-		baseImagePuller := base_image_puller.NewBaseImagePuller(
-			fetcher,
-			unpacker,
-			nsFsDriver,
-			metricsEmitter,
-			exclusiveLocksmith,
-		)
-
-		gc := garbage_collector.NewGC(nsFsDriver, imageCloner, dependencyManager)
-		sm := storepkg.NewStoreMeasurer(storePath, fsDriver, gc)
-		cleaner := groot.IamCleaner(exclusiveLocksmith, sm, gc, metricsEmitter)
-
-		creator := groot.IamCreator(
-			imageCloner, baseImagePuller, sharedLocksmith,
-			dependencyManager, metricsEmitter, cleaner,
-		)
-
-		createSpec := groot.CreateSpec{
-			ID:                          id,
-			Mount:                       !cfg.Create.WithoutMount,
-			BaseImageURL:                baseImageURL,
-			DiskLimit:                   cfg.Create.DiskLimitSizeBytes,
-			ExcludeBaseImageFromQuota:   cfg.Create.ExcludeImageFromQuota,
-			UIDMappings:                 idMappings.UIDMappings,
-			GIDMappings:                 idMappings.GIDMappings,
-			CleanOnCreate:               cfg.Create.WithClean,
-			CleanOnCreateThresholdBytes: cfg.Clean.ThresholdBytes,
 		}
-		image, err := creator.Create(logger, createSpec)
+		spec, err := store.Create(createSpec)
 		if err != nil {
 			logger.Error("creating", err)
-			humanizedError := tryHumanize(err, createSpec)
+			humanizedError := tryHumanize(err, baseImageURL)
 			return cli.NewExitError(humanizedError, 1)
 		}
 
-		containerSpec := specs.Spec{
-			Root: &specs.Root{
-				Path: image.Rootfs,
-			},
-			Process: &specs.Process{
-				Env: image.Image.Config.Env,
-			},
-			Mounts: []specs.Mount{},
-		}
-
-		for _, mount := range image.Mounts {
-			containerSpec.Mounts = append(containerSpec.Mounts, specs.Mount{
-				Destination: mount.Destination,
-				Type:        mount.Type,
-				Source:      mount.Source,
-				Options:     mount.Options,
-			})
-		}
-
-		jsonBytes, err := json.Marshal(containerSpec)
+		jsonBytes, err := json.Marshal(spec)
 		if err != nil {
 			logger.Error("formatting output", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 		fmt.Println(string(jsonBytes))
 
-		emitMetrics(logger, metricsEmitter, sm)
-
 		return nil
 	},
 }
 
-func emitMetrics(logger lager.Logger, metricsEmitter *metrics.Emitter, sm *storepkg.StoreMeasurer) {
-	usage, err := sm.Usage(logger)
-	if err != nil {
-		logger.Info(fmt.Sprintf("measuring-store: %s", err))
-	}
-	metricsEmitter.TryEmitUsage(logger, "StoreUsage", usage, "bytes")
-
-	unusedVolumesSize, err := sm.UnusedVolumesSize(logger)
-	if err != nil {
-		logger.Info(fmt.Sprintf("getting-unused-layers-size: %s", err))
-	}
-	metricsEmitter.TryEmitUsage(logger, "UnusedLayersSize", unusedVolumesSize, "bytes")
-
-	totalVolumesSize, err := sm.TotalVolumesSize(logger)
-	if err != nil {
-		logger.Info(fmt.Sprintf("getting-total-layers-size: %s", err))
-	}
-	metricsEmitter.TryEmitUsage(logger, "DownloadedLayersSizeInBytes", totalVolumesSize, "bytes")
-
-	commitedQuota, err := sm.CommittedQuota(logger)
-	if err != nil {
-		logger.Info(fmt.Sprintf("getting-commited-quota: %s", err))
-	}
-	metricsEmitter.TryEmitUsage(logger, "CommittedQuotaInBytes", commitedQuota, "bytes")
-}
-
-func createFetcher(baseImageUrl *url.URL, systemContext types.SystemContext, createCfg config.Create) base_image_puller.Fetcher {
-	if baseImageUrl.Scheme == "" {
-		return tar_fetcher.NewTarFetcher(baseImageUrl)
-	}
-
-	skipOCILayerValidation := createCfg.SkipLayerValidation && baseImageUrl.Scheme == "oci"
-	layerSource := source.NewLayerSource(systemContext, skipOCILayerValidation, baseImageUrl)
-	return layer_fetcher.NewLayerFetcher(&layerSource)
-}
-
-func createSystemContext(baseImageURL *url.URL, createConfig config.Create, username, password string) types.SystemContext {
-	scheme := baseImageURL.Scheme
-	switch scheme {
-	case "docker":
-		return types.SystemContext{
-			DockerInsecureSkipTLSVerify: skipTLSValidation(baseImageURL, createConfig.InsecureRegistries),
-			DockerAuthConfig: &types.DockerAuthConfig{
-				Username: username,
-				Password: password,
-			},
-		}
-	case "oci":
-		return types.SystemContext{
-			OCICertPath: createConfig.RemoteLayerClientCertificatesPath,
-		}
-	default:
-		return types.SystemContext{}
-	}
-
-}
-
-func skipTLSValidation(baseImageURL *url.URL, trustedRegistries []string) bool {
-	for _, trustedRegistry := range trustedRegistries {
-		if baseImageURL.Host == trustedRegistry {
-			return true
-		}
-	}
-
-	return false
-}
-
 func containsDockerError(errorsList errcode.Errors, errCode errcode.ErrorCode) bool {
 	for _, err := range errorsList {
 		if e, ok := err.(errcode.Error); ok && e.ErrorCode() == errCode {
@@ -330,9 +225,9 @@ func containsDockerError(errorsList errcode.Errors, errCode errcode.ErrorCode) b
 	return false
 }
 
-func tryHumanizeDockerErrorsList(err errcode.Errors, spec groot.CreateSpec) string {
+func tryHumanizeDockerErrorsList(err errcode.Errors, baseImageURL *url.URL) string {
 	if containsDockerError(err, errcode.ErrorCodeUnauthorized) {
-		return fmt.Sprintf("%s does not exist or you do not have permissions to see it.", spec.BaseImageURL.String())
+		return fmt.Sprintf("%s does not exist or you do not have permissions to see it.", baseImageURL.String())
 	}
 
 	return err.Error()
@@ -353,7 +248,7 @@ func tryParsingErrorMessage(err error) error {
 	return err
 }
 
-func tryHumanize(err error, spec groot.CreateSpec) string {
+func tryHumanize(err error, baseImageURL *url.URL) string {
 	switch e := errorspkg.Cause(err).(type) {
 	case *url.Error:
 		if _, ok := e.Err.(x509.UnknownAuthorityError); ok {
@@ -361,7 +256,7 @@ func tryHumanize(err error, spec groot.CreateSpec) string {
 		}
 
 	case errcode.Errors:
-		return tryHumanizeDockerErrorsList(e, spec)
+		return tryHumanizeDockerErrorsList(e, baseImageURL)
 	}
 
 	return tryParsingErrorMessage(err).Error()
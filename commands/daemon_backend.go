@@ -0,0 +1,155 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"errors"
+	"net/url"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/daemon"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/lager"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// daemonBackend serves daemon requests from a single long-lived store, so the
+// driver graph, configuration and namespace file are only loaded once.
+type daemonBackend struct {
+	store *grootfs.Store
+}
+
+func (b *daemonBackend) Create(logger lager.Logger, request daemon.CreateRequest) (specs.Spec, error) {
+	baseImageURL, err := url.Parse(request.BaseImage)
+	if err != nil {
+		return specs.Spec{}, daemon.InvalidRequest(err)
+	}
+
+	// These name paths on the daemon's host, which callers of the socket
+	// shouldn't get to read from or write to.
+	if request.Bundle != "" || len(request.Layers) > 0 || len(request.Copies) > 0 {
+		return specs.Spec{}, daemon.InvalidRequest(errors.New("invalid argument: bundle, layers and copies are not supported by the daemon"))
+	}
+
+	store := b.store.UsingLogger(logger)
+	createSpec := store.DefaultCreateSpec(request.ID, baseImageURL)
+	createSpec.Credentials = grootfs.Credentials{
+		Username: request.Username,
+		Password: request.Password,
+	}
+	if request.DiskLimitSizeBytes != nil {
+		if *request.DiskLimitSizeBytes < 0 {
+			return specs.Spec{}, daemon.InvalidRequest(errors.New("invalid argument: disk limit cannot be negative"))
+		}
+		createSpec.DiskLimit = *request.DiskLimitSizeBytes
+	}
+	if request.ExcludeImageFromQuota != nil {
+		createSpec.ExcludeBaseImageFromQuota = *request.ExcludeImageFromQuota
+	}
+	if request.Ephemeral {
+		if request.DiskLimitSizeBytes == nil {
+			createSpec.DiskLimit = 0
+		}
+		if request.ExcludeImageFromQuota == nil {
+			createSpec.ExcludeBaseImageFromQuota = false
+		}
+	}
+	if request.Mount != nil {
+		createSpec.Mount = *request.Mount
+	}
+	if request.Clean != nil {
+		createSpec.Clean = *request.Clean
+	}
+	createSpec.Labels = request.Labels
+	createSpec.ReadOnly = request.ReadOnly
+	createSpec.Ephemeral = request.Ephemeral
+	createSpec.EphemeralSizeBytes = request.EphemeralSizeBytes
+	if request.OverlayMountOptions != nil {
+		if err := config.ValidateOverlayMountOptions(request.OverlayMountOptions); err != nil {
+			return specs.Spec{}, daemon.InvalidRequest(err)
+		}
+		createSpec.OverlayMountOptions = request.OverlayMountOptions
+	}
+	switch request.SpecFormat {
+	case "":
+	case config.SpecFormatMinimal, config.SpecFormatFull:
+		createSpec.SpecFormat = request.SpecFormat
+	default:
+		return specs.Spec{}, daemon.InvalidRequest(errors.New("invalid argument: spec format must be minimal or full"))
+	}
+
+	spec, err := store.Create(createSpec)
+	if err != nil {
+		return specs.Spec{}, errors.New(tryHumanize(err, baseImageURL))
+	}
+
+	return spec, nil
+}
+
+func (b *daemonBackend) Delete(logger lager.Logger, idOrPath string) error {
+	err := b.store.UsingLogger(logger).Delete(idOrPath)
+	if grootfs.IsImageNotFound(err) {
+		return daemon.NotFound(err)
+	}
+
+	return err
+}
+
+func (b *daemonBackend) Stats(logger lager.Logger, idOrPath string) (groot.VolumeStats, error) {
+	stats, err := b.store.UsingLogger(logger).Stats(idOrPath)
+	if grootfs.IsImageNotFound(err) {
+		return groot.VolumeStats{}, daemon.NotFound(err)
+	}
+
+	return stats, err
+}
+
+func (b *daemonBackend) List(logger lager.Logger) ([]string, error) {
+	return b.store.UsingLogger(logger).List()
+}
+
+func (b *daemonBackend) Clean(logger lager.Logger, request daemon.CleanRequest) (groot.CleanReport, error) {
+	store := b.store.UsingLogger(logger)
+	cleanSpec := store.DefaultCleanSpec()
+	cleanSpec.DryRun = request.DryRun
+	if request.ThresholdBytes != nil {
+		cleanSpec.ThresholdBytes = *request.ThresholdBytes
+	}
+	if request.TargetBytes != nil {
+		cleanSpec.TargetBytes = *request.TargetBytes
+	}
+
+	if cleanSpec.ThresholdBytes < 0 {
+		return groot.CleanReport{}, daemon.InvalidRequest(errors.New("invalid argument: clean threshold cannot be negative"))
+	}
+	if cleanSpec.TargetBytes < 0 {
+		return groot.CleanReport{}, daemon.InvalidRequest(errors.New("invalid argument: clean target cannot be negative"))
+	}
+	if cleanSpec.ThresholdBytes > 0 && cleanSpec.TargetBytes > cleanSpec.ThresholdBytes {
+		return groot.CleanReport{}, daemon.InvalidRequest(errors.New("invalid argument: clean target cannot be greater than the clean threshold"))
+	}
+
+	return store.Clean(cleanSpec)
+}
+
+func (b *daemonBackend) Pull(logger lager.Logger, request daemon.PullRequest) (daemon.PullResponse, error) {
+	baseImageURL, err := url.Parse(request.BaseImage)
+	if err != nil {
+		return daemon.PullResponse{}, daemon.InvalidRequest(err)
+	}
+
+	chainIDs, err := b.store.UsingLogger(logger).Pull(grootfs.PullSpec{
+		BaseImageURL: baseImageURL,
+		Credentials: grootfs.Credentials{
+			Username: request.Username,
+			Password: request.Password,
+		},
+	})
+	if err != nil {
+		return daemon.PullResponse{}, errors.New(tryHumanize(err, baseImageURL))
+	}
+
+	return daemon.PullResponse{
+		BaseImageURL: baseImageURL.String(),
+		ChainIDs:     chainIDs,
+	}, nil
+}
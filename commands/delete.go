@@ -1,17 +1,14 @@
 package commands // import "code.cloudfoundry.org/grootfs/commands"
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 
 	"code.cloudfoundry.org/grootfs/commands/config"
 	"code.cloudfoundry.org/grootfs/commands/idfinder"
 	"code.cloudfoundry.org/grootfs/groot"
-	"code.cloudfoundry.org/grootfs/metrics"
-	"code.cloudfoundry.org/grootfs/store"
-	"code.cloudfoundry.org/grootfs/store/dependency_manager"
-	"code.cloudfoundry.org/grootfs/store/garbage_collector"
-	"code.cloudfoundry.org/grootfs/store/image_cloner"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
 	"code.cloudfoundry.org/lager"
 	errorspkg "github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -19,17 +16,54 @@ import (
 
 var DeleteCommand = cli.Command{
 	Name:        "delete",
-	Usage:       "delete <id|image path>",
-	Description: "Deletes a container image",
+	Usage:       "delete <id|image path>... | delete --all | delete [--older-than <duration>] [--filter label=<key>[=<value>]]",
+	Description: "Deletes container images, or all the images matching the filters",
+
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "Delete all images matching the filter (label=key or label=key=value)",
+		},
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "Delete all images",
+		},
+		cli.DurationFlag{
+			Name:  "older-than",
+			Usage: "Delete all images created more than this long ago (e.g. 12h)",
+		},
+		cli.BoolFlag{
+			Name:  "with-clean",
+			Usage: "Clean up the layers left unused by the deleted images",
+		},
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "Delete images even when processes still use them, lazily unmounting them",
+		},
+		cli.BoolFlag{
+			Name:  "async",
+			Usage: "Move images to the store trash and return, leaving the removal of their files to the next clean",
+		},
+	},
 
 	Action: func(ctx *cli.Context) error {
 		logger := ctx.App.Metadata["logger"].(lager.Logger)
 		logger = logger.Session("delete")
 
-		if ctx.NArg() != 1 {
+		filters := ctx.StringSlice("filter")
+		selecting := ctx.Bool("all") || ctx.IsSet("older-than") || len(filters) > 0
+		if ctx.NArg() == 0 && !selecting {
 			logger.Error("parsing-command", errorspkg.New("id was not specified"))
 			return cli.NewExitError("id was not specified", 1)
 		}
+		if ctx.NArg() > 0 && selecting {
+			logger.Error("parsing-command", errorspkg.New("ids and selection flags were both specified"))
+			return cli.NewExitError("either ids or --all, --older-than and --filter can be specified, not both", 1)
+		}
+		if ctx.Bool("all") && (ctx.IsSet("older-than") || len(filters) > 0) {
+			logger.Error("parsing-command", errorspkg.New("all and filters were both specified"))
+			return cli.NewExitError("--all can't be used with --older-than or --filter", 1)
+		}
 
 		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
 		cfg, err := configBuilder.Build()
@@ -39,6 +73,15 @@ var DeleteCommand = cli.Command{
 			return cli.NewExitError(err.Error(), 1)
 		}
 
+		// --filter on its own keeps its original per-image output
+		if len(filters) > 0 && !ctx.IsSet("older-than") && !ctx.Bool("with-clean") {
+			return deleteFiltered(logger, cfg, filters, deleteSpec(ctx))
+		}
+
+		if ctx.NArg() > 1 || selecting || ctx.Bool("with-clean") {
+			return deleteImages(ctx, logger, cfg)
+		}
+
 		storePath := cfg.StorePath
 		idOrPath := ctx.Args().First()
 		id, err := idfinder.FindID(storePath, idOrPath)
@@ -48,44 +91,110 @@ var DeleteCommand = cli.Command{
 			return nil
 		}
 
-		fsDriver, err := createFileSystemDriver(cfg)
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
 		if err != nil {
-			logger.Error("failed-to-initialise-filesystem-driver", err)
+			logger.Error("failed-to-initialise-store", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		imageDriver, err := createImageDriver(cfg, fsDriver)
-		if err != nil {
-			logger.Error("failed-to-initialise-image-driver", err)
+		if err := store.DeleteWithSpec(id, deleteSpec(ctx)); err != nil {
+			if grootfs.IsImageNotFound(err) {
+				logger.Debug("id-not-found-skipping", lager.Data{"id": idOrPath, "storePath": storePath, "errorMessage": err.Error()})
+				fmt.Println(err)
+				return nil
+			}
+			logger.Error("deleting-image-failed", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		imageCloner := image_cloner.NewImageCloner(imageDriver, storePath)
-		dependencyManager := dependency_manager.NewDependencyManager(
-			filepath.Join(storePath, store.MetaDirName, "dependencies"),
-		)
+		fmt.Printf("Image %s deleted\n", id)
+		return nil
+	},
+}
 
-		metricsEmitter := metrics.NewEmitter(logger, cfg.MetronEndpoint)
-		deleter := groot.IamDeleter(imageCloner, dependencyManager, metricsEmitter)
+func deleteSpec(ctx *cli.Context) grootfs.DeleteSpec {
+	return grootfs.DeleteSpec{
+		Force: ctx.Bool("force"),
+		Async: ctx.Bool("async"),
+	}
+}
 
-		gc := garbage_collector.NewGC(fsDriver, imageCloner, dependencyManager)
-		sm := store.NewStoreMeasurer(storePath, fsDriver, gc)
+func deleteFiltered(logger lager.Logger, cfg config.Config, filters []string, spec grootfs.DeleteSpec) error {
+	selectors, err := groot.ParseLabelFilters(filters)
+	if err != nil {
+		logger.Error("parsing-filters-failed", err)
+		return cli.NewExitError(err.Error(), 1)
+	}
 
-		defer func() {
-			unusedVolumesSize, err := sm.UnusedVolumesSize(logger)
-			if err != nil {
-				logger.Error("getting-unused-layers-size", err)
-			}
-			metricsEmitter.TryEmitUsage(logger, "UnusedLayersSize", unusedVolumesSize, "bytes")
-		}()
+	store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
+	if err != nil {
+		logger.Error("failed-to-initialise-store", err)
+		return cli.NewExitError(err.Error(), 1)
+	}
 
-		err = deleter.Delete(logger, id)
-		if err != nil {
-			logger.Error("deleting-image-failed", err)
+	images, err := store.List(selectors...)
+	if err != nil {
+		logger.Error("listing-images", err)
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	for _, imagePath := range images {
+		id := filepath.Base(imagePath)
+		if err := store.DeleteWithSpec(id, spec); err != nil {
+			logger.Error("deleting-image-failed", err, lager.Data{"id": id})
 			return cli.NewExitError(err.Error(), 1)
 		}
 
 		fmt.Printf("Image %s deleted\n", id)
-		return nil
-	},
+	}
+
+	return nil
+}
+
+// deleteImages deletes several images at once and prints the result for each
+// of them as JSON.
+func deleteImages(ctx *cli.Context, logger lager.Logger, cfg config.Config) error {
+	selectors, err := groot.ParseLabelFilters(ctx.StringSlice("filter"))
+	if err != nil {
+		logger.Error("parsing-filters-failed", err)
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
+	if err != nil {
+		logger.Error("failed-to-initialise-store", err)
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	results, err := store.DeleteImages(grootfs.BulkDeleteSpec{
+		DeleteSpec:     deleteSpec(ctx),
+		IDs:            ctx.Args(),
+		Selectors:      selectors,
+		OlderThan:      ctx.Duration("older-than"),
+		CollectGarbage: ctx.Bool("with-clean"),
+	})
+	if results != nil {
+		jsonBytes, marshalErr := json.Marshal(results)
+		if marshalErr != nil {
+			logger.Error("formatting-output", marshalErr)
+			return cli.NewExitError(marshalErr.Error(), 1)
+		}
+		fmt.Println(string(jsonBytes))
+	}
+	if err != nil {
+		logger.Error("deleting-images-failed", err)
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Deleted {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return cli.NewExitError(fmt.Sprintf("failed to delete %d of %d images", failed, len(results)), 1)
+	}
+
+	return nil
 }
@@ -6,6 +6,7 @@ import (
 
 	"code.cloudfoundry.org/grootfs/base_image_puller"
 	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
 	"code.cloudfoundry.org/grootfs/store/filesystems"
 	"code.cloudfoundry.org/lager"
 
@@ -32,7 +33,7 @@ var GenerateVolumeSizeMetadata = cli.Command{
 			return err
 		}
 
-		driver, err := createFileSystemDriver(cfg)
+		driver, err := grootfs.NewFileSystemDriver(cfg)
 		if err != nil {
 			return err
 		}
@@ -3,68 +3,22 @@ package commands
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"strconv"
 	"strings"
+	"syscall"
 
-	"code.cloudfoundry.org/commandrunner/linux_command_runner"
-	"code.cloudfoundry.org/grootfs/base_image_puller"
-	unpackerpkg "code.cloudfoundry.org/grootfs/base_image_puller/unpacker"
-	"code.cloudfoundry.org/grootfs/commands/config"
 	"code.cloudfoundry.org/grootfs/groot"
-	"code.cloudfoundry.org/grootfs/store/filesystems/namespaced"
-	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs"
-	"code.cloudfoundry.org/grootfs/store/image_cloner"
-	"code.cloudfoundry.org/lager"
 	"github.com/opencontainers/runc/libcontainer/user"
-	errorspkg "github.com/pkg/errors"
 )
 
-type fileSystemDriver interface {
-	CreateImage(logger lager.Logger, spec image_cloner.ImageDriverSpec) (groot.MountInfo, error)
-	DestroyImage(logger lager.Logger, path string) error
-	FetchStats(logger lager.Logger, path string) (groot.VolumeStats, error)
-	ConfigureStore(logger lager.Logger, storePath string, ownerUID, ownerGID int) error
-	ValidateFileSystem(logger lager.Logger, path string) error
-	InitFilesystem(logger lager.Logger, filesystemPath, storePath string) error
-	DeInitFilesystem(logger lager.Logger, storePath string) error
-	VolumePath(logger lager.Logger, id string) (string, error)
-	Volumes(logger lager.Logger) ([]string, error)
-	VolumeSize(lager.Logger, string) (int64, error)
-	CreateVolume(logger lager.Logger, parentID, id string) (string, error)
-	DestroyVolume(logger lager.Logger, id string) error
-	MoveVolume(logger lager.Logger, from, to string) error
-	WriteVolumeMeta(logger lager.Logger, id string, data base_image_puller.VolumeMeta) error
-	HandleOpaqueWhiteouts(logger lager.Logger, id string, opaqueWhiteouts []string) error
-	Marshal(logger lager.Logger) ([]byte, error)
-}
-
-func createFileSystemDriver(cfg config.Config) (fileSystemDriver, error) {
-	switch cfg.FSDriver {
-	case "overlay-xfs":
-		return overlayxfs.NewDriver(cfg.StorePath, cfg.TardisBin), nil
-	default:
-		return nil, errorspkg.Errorf("filesystem driver not supported: %s", cfg.FSDriver)
-	}
-}
-
-func createImageDriver(cfg config.Config, fsDriver fileSystemDriver) (image_cloner.ImageDriver, error) {
-	if !nsImageDriverRequired(cfg) {
-		return fsDriver, nil
-	}
-
-	storeNamespacer := groot.NewStoreNamespacer(cfg.StorePath)
-	idMappings, err := storeNamespacer.Read()
-	if err != nil {
-		return nil, err
-	}
-
-	runner := linux_command_runner.New()
-	idMapper := unpackerpkg.NewIDMapper(cfg.NewuidmapBin, cfg.NewgidmapBin, runner)
-	return namespaced.New(fsDriver, idMappings, idMapper, runner), nil
-}
+// listenUnix creates the socket with a restrictive umask, so it's never
+// accessible by other users, not even before it's chmoded.
+func listenUnix(path string) (net.Listener, error) {
+	oldUmask := syscall.Umask(0077)
+	defer syscall.Umask(oldUmask)
 
-func nsImageDriverRequired(cfg config.Config) bool {
-	return cfg.FSDriver == "overlay-xfs"
+	return net.Listen("unix", path)
 }
 
 func parseIDMappings(args []string) ([]groot.IDMappingSpec, error) {
@@ -7,6 +7,7 @@ import (
 
 	"code.cloudfoundry.org/grootfs/commands/config"
 	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
 	"code.cloudfoundry.org/grootfs/store/manager"
 	"code.cloudfoundry.org/lager"
 
@@ -69,7 +70,7 @@ var InitStoreCommand = cli.Command{
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		fsDriver, err := createFileSystemDriver(cfg)
+		fsDriver, err := grootfs.NewFileSystemDriver(cfg)
 		if err != nil {
 			logger.Error("failed-to-initialise-filesystem-driver", err)
 			return cli.NewExitError(err.Error(), 1)
@@ -95,11 +96,18 @@ var InitStoreCommand = cli.Command{
 			}
 		}
 
+		if cfg.PreserveDevices && (os.Geteuid() != 0 || len(uidMappings) > 0 || len(gidMappings) > 0) {
+			err := grootfs.ErrPreserveDevicesUnsupported
+			logger.Error("init-store-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
 		namespacer := groot.NewStoreNamespacer(storePath)
 		spec := manager.InitSpec{
-			UIDMappings:    uidMappings,
-			GIDMappings:    gidMappings,
-			StoreSizeBytes: storeSizeBytes,
+			UIDMappings:     uidMappings,
+			GIDMappings:     gidMappings,
+			StoreSizeBytes:  storeSizeBytes,
+			PreserveDevices: cfg.PreserveDevices,
 		}
 
 		manager := manager.New(storePath, namespacer, fsDriver, fsDriver, fsDriver)
@@ -108,6 +116,11 @@ var InitStoreCommand = cli.Command{
 			return cli.NewExitError(errorspkg.Cause(err).Error(), 1)
 		}
 
+		if err := fsDriver.CheckOverlayMountOptions(logger, cfg.Create.OverlayMountOptions); err != nil {
+			logger.Error("checking-overlay-mount-options-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
 		return nil
 	},
 }
@@ -0,0 +1,61 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var InspectCommand = cli.Command{
+	Name:        "inspect",
+	Usage:       "inspect <id|image path>",
+	Description: "Return everything the store knows about an image as JSON",
+
+	Action: func(ctx *cli.Context) error {
+		logger := ctx.App.Metadata["logger"].(lager.Logger)
+		logger = logger.Session("inspect")
+
+		if ctx.NArg() != 1 {
+			logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
+			return cli.NewExitError(fmt.Sprintf("invalid arguments - usage: %s", ctx.Command.Usage), 1)
+		}
+
+		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
+		cfg, err := configBuilder.Build()
+		logger.Debug("inspect-config", lager.Data{"currentConfig": cfg})
+		if err != nil {
+			logger.Error("config-builder-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
+		if err != nil {
+			logger.Error("failed-to-initialise-store", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		idOrPath := ctx.Args().First()
+		inspection, err := store.Inspect(idOrPath)
+		if grootfs.IsImageNotFound(err) {
+			logger.Error("find-id-failed", err, lager.Data{"id": idOrPath, "storePath": cfg.StorePath})
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if err != nil {
+			logger.Error("inspecting-image", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if err := json.NewEncoder(os.Stdout).Encode(inspection); err != nil {
+			logger.Error("encoding-inspection-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		return nil
+	},
+}
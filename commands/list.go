@@ -1,26 +1,43 @@
 package commands // import "code.cloudfoundry.org/grootfs/commands"
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
 
 	"code.cloudfoundry.org/grootfs/commands/config"
 	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
 	"code.cloudfoundry.org/lager"
-	errorspkg "github.com/pkg/errors"
 
 	"github.com/urfave/cli"
 )
 
 var ListCommand = cli.Command{
 	Name:        "list",
-	Usage:       "list",
+	Usage:       "list [--json] [--filter label=<key>[=<value>]]",
 	Description: "Lists images in store",
 
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "Only list images matching the filter (label=key or label=key=value)",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "Print the provenance, mount state and disk usage of each image as JSON",
+		},
+	},
+
 	Action: func(ctx *cli.Context) error {
 		logger := ctx.App.Metadata["logger"].(lager.Logger)
 		logger = logger.Session("list")
 
+		selectors, err := groot.ParseLabelFilters(ctx.StringSlice("filter"))
+		if err != nil {
+			logger.Error("parsing-filters-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
 		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
 		cfg, err := configBuilder.Build()
 		logger.Debug("list-config", lager.Data{"currentConfig": cfg})
@@ -29,20 +46,27 @@ var ListCommand = cli.Command{
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		if _, err := os.Stat(cfg.StorePath); os.IsNotExist(err) {
-			err := errorspkg.Errorf("no store found at %s", cfg.StorePath)
-			logger.Error("store-path-failed", err, nil)
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
+		if err != nil {
+			logger.Error("failed-to-initialise-store", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		lister := groot.IamLister()
-		images, err := lister.List(logger, cfg.StorePath)
+		if ctx.Bool("json") {
+			return listDetails(logger, store, cfg, selectors)
+		}
+
+		images, err := store.List(selectors...)
+		if grootfs.IsStoreNotFound(err) {
+			logger.Error("store-path-failed", err, nil)
+			return cli.NewExitError(err.Error(), 1)
+		}
 		if err != nil {
 			logger.Error("listing-images", err, lager.Data{"storePath": cfg.StorePath})
 			return cli.NewExitError(fmt.Sprintf("Failed to retrieve list of images: %s", err.Error()), 1)
 		}
 
-		if len(images) == 0 {
+		if len(images) == 0 && len(selectors) == 0 {
 			fmt.Println("Store empty")
 		}
 		for _, image := range images {
@@ -52,3 +76,24 @@ var ListCommand = cli.Command{
 		return nil
 	},
 }
+
+func listDetails(logger lager.Logger, store *grootfs.Store, cfg config.Config, selectors []groot.LabelSelector) error {
+	details, err := store.ListDetails(selectors...)
+	if grootfs.IsStoreNotFound(err) {
+		logger.Error("store-path-failed", err, nil)
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if err != nil {
+		logger.Error("listing-image-details", err, lager.Data{"storePath": cfg.StorePath})
+		return cli.NewExitError(fmt.Sprintf("Failed to retrieve list of images: %s", err.Error()), 1)
+	}
+
+	jsonBytes, err := json.Marshal(details)
+	if err != nil {
+		logger.Error("formatting-output", err)
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Println(string(jsonBytes))
+
+	return nil
+}
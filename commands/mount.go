@@ -0,0 +1,88 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/commands/idfinder"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/metrics"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	imageClonerpkg "code.cloudfoundry.org/grootfs/store/image_cloner"
+	locksmithpkg "code.cloudfoundry.org/grootfs/store/locksmith"
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var MountCommand = cli.Command{
+	Name:        "mount",
+	Usage:       "mount [options] <id|image path>",
+	Description: "Mounts the rootfs of an existing image",
+
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "Mount every image that was created with, or later given, a mount",
+		},
+	},
+
+	Action: func(ctx *cli.Context) error {
+		logger := ctx.App.Metadata["logger"].(lager.Logger)
+		logger = logger.Session("mount")
+
+		if ctx.Bool("all") {
+			if ctx.NArg() != 0 {
+				logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
+				return cli.NewExitError("invalid arguments - --all does not take an id or image path", 1)
+			}
+		} else if ctx.NArg() != 1 {
+			logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
+			return cli.NewExitError(fmt.Sprintf("invalid arguments - usage: %s", ctx.Command.Usage), 1)
+		}
+
+		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
+		cfg, err := configBuilder.Build()
+		logger.Debug("mount-config", lager.Data{"currentConfig": cfg})
+		if err != nil {
+			logger.Error("config-builder-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		storePath := cfg.StorePath
+		fsDriver, err := grootfs.NewFileSystemDriver(cfg)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		imageCloner := imageClonerpkg.NewImageCloner(fsDriver, storePath)
+		locksmith := locksmithpkg.NewSharedFileSystem(storePath, metrics.NewEmitter(logger, cfg.MetronEndpoint))
+		mounter := groot.IamMounter(imageCloner, locksmith)
+
+		if ctx.Bool("all") {
+			mounted, err := mounter.MountAll(logger)
+			for _, id := range mounted {
+				fmt.Println(id)
+			}
+
+			if err != nil {
+				logger.Error("mounting-all-images", err)
+				return cli.NewExitError(err.Error(), 1)
+			}
+			return nil
+		}
+
+		idOrPath := ctx.Args().First()
+		id, err := idfinder.FindID(storePath, idOrPath)
+		if err != nil {
+			logger.Error("find-id-failed", err, lager.Data{"id": idOrPath, "storePath": storePath})
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if err := mounter.Mount(logger, id); err != nil {
+			logger.Error("mounting-image", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,90 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/lager"
+
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var PinCommand = cli.Command{
+	Name:        "pin",
+	Usage:       "pin [options] <image>",
+	Description: "Pulls the provided image and keeps its layers from being garbage collected.",
+
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "insecure-registry",
+			Usage: "Whitelist a private registry",
+		},
+		cli.BoolFlag{
+			Name:  "skip-layer-validation",
+			Usage: "Do not validate checksums and sizes of image layers. (Can only be used with oci:/// protocol images.)",
+		},
+		cli.StringFlag{
+			Name:  "username",
+			Usage: "Username to authenticate in image registry",
+		},
+		cli.StringFlag{
+			Name:  "password",
+			Usage: "Password to authenticate in image registry",
+		},
+	},
+
+	Action: func(ctx *cli.Context) error {
+		logger := ctx.App.Metadata["logger"].(lager.Logger)
+		logger = logger.Session("pin")
+
+		if ctx.NArg() != 1 {
+			logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
+			return cli.NewExitError(fmt.Sprintf("invalid arguments - usage: %s", ctx.Command.Usage), 1)
+		}
+
+		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
+		configBuilder.WithInsecureRegistries(ctx.StringSlice("insecure-registry")).
+			WithSkipLayerValidation(ctx.Bool("skip-layer-validation"),
+				ctx.IsSet("skip-layer-validation"))
+
+		cfg, err := configBuilder.Build()
+		logger.Debug("pin-config", lager.Data{"currentConfig": cfg})
+		if err != nil {
+			logger.Error("config-builder-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		baseImageURL, err := url.Parse(ctx.Args().First())
+		if err != nil {
+			logger.Error("base-image-url-parsing-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
+		if err != nil {
+			logger.Error("failed-to-initialise-store", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		pin, err := store.Pin(grootfs.PullSpec{
+			BaseImageURL: baseImageURL,
+			Credentials: grootfs.Credentials{
+				Username: ctx.String("username"),
+				Password: ctx.String("password"),
+			},
+		})
+		if err != nil {
+			logger.Error("pinning", err)
+			humanizedError := tryHumanize(err, baseImageURL)
+			return cli.NewExitError(humanizedError, 1)
+		}
+
+		_ = json.NewEncoder(os.Stdout).Encode(pin)
+		return nil
+	},
+}
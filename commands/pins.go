@@ -0,0 +1,51 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"encoding/json"
+	"os"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var PinsCommand = cli.Command{
+	Name:        "pins",
+	Usage:       "pins",
+	Description: "Lists the pinned images and the layers they keep",
+
+	Action: func(ctx *cli.Context) error {
+		logger := ctx.App.Metadata["logger"].(lager.Logger)
+		logger = logger.Session("pins")
+
+		if ctx.NArg() != 0 {
+			logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
+			return cli.NewExitError("invalid arguments - pins does not take any arguments", 1)
+		}
+
+		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
+		cfg, err := configBuilder.Build()
+		logger.Debug("pins-config", lager.Data{"currentConfig": cfg})
+		if err != nil {
+			logger.Error("config-builder-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
+		if err != nil {
+			logger.Error("failed-to-initialise-store", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		pins, err := store.Pins()
+		if err != nil {
+			logger.Error("listing-pins", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		_ = json.NewEncoder(os.Stdout).Encode(pins)
+		return nil
+	},
+}
@@ -0,0 +1,141 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/daemon"
+	"code.cloudfoundry.org/grootfs/fetcher/cached_fetcher"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var ServeCommand = cli.Command{
+	Name:        "serve",
+	Usage:       "serve --socket <path>",
+	Description: "Serves create, delete, stats, list, clean and pull over HTTP on a unix socket",
+
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "socket",
+			Usage: "Path of the unix socket to listen on",
+		},
+		cli.Int64Flag{
+			Name:  "clean-interval-seconds",
+			Usage: "Clean up unused layers every this many seconds (0 disables scheduled cleans)",
+		},
+		cli.Int64Flag{
+			Name:  "threshold-bytes",
+			Usage: "Disk usage of the store directory at which scheduled cleanup should trigger",
+		},
+		cli.Int64Flag{
+			Name:  "target-bytes",
+			Usage: "Disk usage of the store directory that scheduled cleanup should bring it down to, evicting least recently used layers first",
+		},
+		cli.Int64Flag{
+			Name:  "image-info-cache-ttl-seconds",
+			Usage: "Reuse resolved image manifests and configs for this many seconds (0 disables the cache)",
+		},
+		cli.StringSliceFlag{
+			Name:  "insecure-registry",
+			Usage: "Whitelist a private registry",
+		},
+	},
+
+	Action: func(ctx *cli.Context) error {
+		logger := ctx.App.Metadata["logger"].(lager.Logger)
+		logger = logger.Session("serve")
+
+		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
+		configBuilder.WithInsecureRegistries(ctx.StringSlice("insecure-registry")).
+			WithServeSocketPath(ctx.String("socket"), ctx.IsSet("socket")).
+			WithServeCleanIntervalSeconds(ctx.Int64("clean-interval-seconds"),
+				ctx.IsSet("clean-interval-seconds")).
+			WithServeImageInfoCacheTTLSeconds(ctx.Int64("image-info-cache-ttl-seconds"),
+				ctx.IsSet("image-info-cache-ttl-seconds")).
+			WithCleanThresholdBytes(ctx.Int64("threshold-bytes"), ctx.IsSet("threshold-bytes")).
+			WithCleanTargetBytes(ctx.Int64("target-bytes"), ctx.IsSet("target-bytes"))
+
+		cfg, err := configBuilder.Build()
+		logger.Debug("serve-config", lager.Data{"currentConfig": cfg})
+		if err != nil {
+			logger.Error("config-builder-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		socketPath := cfg.Serve.SocketPath
+		if socketPath == "" {
+			err := errors.New("socket path was not specified")
+			logger.Error("parsing-command", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		storeOptions := []grootfs.Option{grootfs.WithLogger(logger)}
+		if cfg.Serve.ImageInfoCacheTTLSeconds > 0 {
+			ttl := time.Duration(cfg.Serve.ImageInfoCacheTTLSeconds) * time.Second
+			storeOptions = append(storeOptions, grootfs.WithImageInfoCache(cached_fetcher.NewCache(ttl)))
+		}
+
+		store, err := grootfs.NewStore(cfg, storeOptions...)
+		if err != nil {
+			logger.Error("failed-to-initialise-store", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+		backend := &daemonBackend{store: store}
+
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			logger.Error("removing-stale-socket-failed", err)
+			return cli.NewExitError(errorspkg.Wrap(err, "removing stale socket").Error(), 1)
+		}
+
+		listener, err := listenUnix(socketPath)
+		if err != nil {
+			logger.Error("listening-failed", err, lager.Data{"socketPath": socketPath})
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer os.Remove(socketPath)
+
+		if err := os.Chmod(socketPath, 0600); err != nil {
+			logger.Error("chmoding-socket-failed", err)
+			listener.Close()
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		server := daemon.NewServer(logger, backend)
+
+		stop := make(chan struct{})
+		if cfg.Serve.CleanIntervalSeconds > 0 {
+			interval := time.Duration(cfg.Serve.CleanIntervalSeconds) * time.Second
+			go server.ScheduleClean(interval, daemon.CleanRequest{}, stop)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			logger.Info("stopping", lager.Data{"signal": sig.String()})
+			close(stop)
+			listener.Close()
+		}()
+
+		logger.Info("serving", lager.Data{"socketPath": socketPath})
+		if err := server.Serve(listener); err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+			}
+
+			logger.Error("serving-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		return nil
+	},
+}
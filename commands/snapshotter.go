@@ -0,0 +1,101 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/lager"
+	snapshotsapi "github.com/containerd/containerd/api/services/snapshots/v1"
+	"github.com/containerd/containerd/contrib/snapshotservice"
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"google.golang.org/grpc"
+)
+
+var SnapshotterCommand = cli.Command{
+	Name:        "snapshotter",
+	Usage:       "snapshotter --address <path>",
+	Description: "Serves the containerd snapshots API on a unix socket, to be used as a proxy snapshotter plugin",
+
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "address",
+			Usage: "Path of the unix socket to listen on",
+		},
+	},
+
+	Action: func(ctx *cli.Context) error {
+		logger := ctx.App.Metadata["logger"].(lager.Logger)
+		logger = logger.Session("snapshotter")
+
+		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
+		configBuilder.WithSnapshotterAddress(ctx.String("address"), ctx.IsSet("address"))
+
+		cfg, err := configBuilder.Build()
+		logger.Debug("snapshotter-config", lager.Data{"currentConfig": cfg})
+		if err != nil {
+			logger.Error("config-builder-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		address := cfg.Snapshotter.Address
+		if address == "" {
+			err := errors.New("address was not specified")
+			logger.Error("parsing-command", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
+		if err != nil {
+			logger.Error("failed-to-initialise-store", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		sn, err := store.Snapshotter()
+		if err != nil {
+			logger.Error("failed-to-initialise-snapshotter", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+			logger.Error("removing-stale-socket-failed", err)
+			return cli.NewExitError(errorspkg.Wrap(err, "removing stale socket").Error(), 1)
+		}
+
+		listener, err := listenUnix(address)
+		if err != nil {
+			logger.Error("listening-failed", err, lager.Data{"address": address})
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer os.Remove(address)
+
+		if err := os.Chmod(address, 0600); err != nil {
+			logger.Error("chmoding-socket-failed", err)
+			listener.Close()
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		rpc := grpc.NewServer()
+		snapshotsapi.RegisterSnapshotsServer(rpc, snapshotservice.FromSnapshotter(sn))
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			logger.Info("stopping", lager.Data{"signal": sig.String()})
+			rpc.GracefulStop()
+		}()
+
+		logger.Info("serving", lager.Data{"address": address})
+		if err := rpc.Serve(listener); err != nil {
+			logger.Error("serving-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		return nil
+	},
+}
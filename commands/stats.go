@@ -6,9 +6,7 @@ import (
 	"os"
 
 	"code.cloudfoundry.org/grootfs/commands/config"
-	"code.cloudfoundry.org/grootfs/commands/idfinder"
-	"code.cloudfoundry.org/grootfs/groot"
-	imageClonerpkg "code.cloudfoundry.org/grootfs/store/image_cloner"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
 	"code.cloudfoundry.org/lager"
 	errorspkg "github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -19,11 +17,23 @@ var StatsCommand = cli.Command{
 	Usage:       "stats [options] <id|image path>",
 	Description: "Return filesystem stats",
 
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "Return stats for all images in the store, indexed by image id",
+		},
+	},
+
 	Action: func(ctx *cli.Context) error {
 		logger := ctx.App.Metadata["logger"].(lager.Logger)
 		logger = logger.Session("stats")
 
-		if ctx.NArg() != 1 {
+		if ctx.Bool("all") {
+			if ctx.NArg() != 0 {
+				logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
+				return cli.NewExitError("invalid arguments - --all does not take an id or image path", 1)
+			}
+		} else if ctx.NArg() != 1 {
 			logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
 			return cli.NewExitError(fmt.Sprintf("invalid arguments - usage: %s", ctx.Command.Usage), 1)
 		}
@@ -36,22 +46,29 @@ var StatsCommand = cli.Command{
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		storePath := cfg.StorePath
-		idOrPath := ctx.Args().First()
-		id, err := idfinder.FindID(storePath, idOrPath)
+		store, err := grootfs.NewStore(cfg, grootfs.WithLogger(logger))
 		if err != nil {
-			logger.Error("find-id-failed", err, lager.Data{"id": idOrPath, "storePath": storePath})
+			logger.Error("failed-to-initialise-store", err)
 			return cli.NewExitError(err.Error(), 1)
 		}
 
-		fsDriver, err := createFileSystemDriver(cfg)
-		if err != nil {
-			return cli.NewExitError(err.Error(), 1)
+		if ctx.Bool("all") {
+			allStats, err := store.AllStats()
+			if err != nil {
+				logger.Error("fetching-all-stats", err)
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			_ = json.NewEncoder(os.Stdout).Encode(allStats)
+			return nil
 		}
-		imageCloner := imageClonerpkg.NewImageCloner(fsDriver, storePath)
 
-		statser := groot.IamStatser(imageCloner)
-		stats, err := statser.Stats(logger, id)
+		idOrPath := ctx.Args().First()
+		stats, err := store.Stats(idOrPath)
+		if grootfs.IsImageNotFound(err) {
+			logger.Error("find-id-failed", err, lager.Data{"id": idOrPath, "storePath": cfg.StorePath})
+			return cli.NewExitError(err.Error(), 1)
+		}
 		if err != nil {
 			logger.Error("fetching-stats", err)
 			return cli.NewExitError(err.Error(), 1)
@@ -0,0 +1,63 @@
+package commands // import "code.cloudfoundry.org/grootfs/commands"
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/commands/idfinder"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/metrics"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	imageClonerpkg "code.cloudfoundry.org/grootfs/store/image_cloner"
+	locksmithpkg "code.cloudfoundry.org/grootfs/store/locksmith"
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var UnmountCommand = cli.Command{
+	Name:        "unmount",
+	Usage:       "unmount <id|image path>",
+	Description: "Unmounts the rootfs of an existing image",
+
+	Action: func(ctx *cli.Context) error {
+		logger := ctx.App.Metadata["logger"].(lager.Logger)
+		logger = logger.Session("unmount")
+
+		if ctx.NArg() != 1 {
+			logger.Error("parsing-command", errorspkg.New("invalid arguments"), lager.Data{"args": ctx.Args()})
+			return cli.NewExitError(fmt.Sprintf("invalid arguments - usage: %s", ctx.Command.Usage), 1)
+		}
+
+		configBuilder := ctx.App.Metadata["configBuilder"].(*config.Builder)
+		cfg, err := configBuilder.Build()
+		logger.Debug("unmount-config", lager.Data{"currentConfig": cfg})
+		if err != nil {
+			logger.Error("config-builder-failed", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		storePath := cfg.StorePath
+		idOrPath := ctx.Args().First()
+		id, err := idfinder.FindID(storePath, idOrPath)
+		if err != nil {
+			logger.Error("find-id-failed", err, lager.Data{"id": idOrPath, "storePath": storePath})
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		fsDriver, err := grootfs.NewFileSystemDriver(cfg)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		imageCloner := imageClonerpkg.NewImageCloner(fsDriver, storePath)
+		locksmith := locksmithpkg.NewSharedFileSystem(storePath, metrics.NewEmitter(logger, cfg.MetronEndpoint))
+		mounter := groot.IamMounter(imageCloner, locksmith)
+
+		if err := mounter.Unmount(logger, id); err != nil {
+			logger.Error("unmounting-image", err)
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,275 @@
+package daemon // import "code.cloudfoundry.org/grootfs/daemon"
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/lager"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	errorspkg "github.com/pkg/errors"
+)
+
+//go:generate counterfeiter . Backend
+
+type Backend interface {
+	Create(logger lager.Logger, request CreateRequest) (specs.Spec, error)
+	Delete(logger lager.Logger, idOrPath string) error
+	Stats(logger lager.Logger, idOrPath string) (groot.VolumeStats, error)
+	List(logger lager.Logger) ([]string, error)
+	Clean(logger lager.Logger, request CleanRequest) (groot.CleanReport, error)
+	Pull(logger lager.Logger, request PullRequest) (PullResponse, error)
+}
+
+// Optional fields are pointers so that a request only overrides the daemon's
+// configuration for the fields it sets, like the CLI flags do.
+type CreateRequest struct {
+	ID                    string            `json:"id"`
+	BaseImage             string            `json:"base_image"`
+	DiskLimitSizeBytes    *int64            `json:"disk_limit_size_bytes,omitempty"`
+	ExcludeImageFromQuota *bool             `json:"exclude_image_from_quota,omitempty"`
+	Mount                 *bool             `json:"mount,omitempty"`
+	Clean                 *bool             `json:"clean,omitempty"`
+	Labels                map[string]string `json:"labels,omitempty"`
+	SpecFormat            string            `json:"spec_format,omitempty"`
+	Bundle                string            `json:"bundle,omitempty"`
+	Layers                []string          `json:"layers,omitempty"`
+	Copies                []string          `json:"copies,omitempty"`
+	ReadOnly              bool              `json:"read_only,omitempty"`
+	Ephemeral             bool              `json:"ephemeral,omitempty"`
+	EphemeralSizeBytes    int64             `json:"ephemeral_size_bytes,omitempty"`
+	OverlayMountOptions   []string          `json:"overlay_mount_options,omitempty"`
+	Username              string            `json:"username,omitempty"`
+	Password              string            `json:"password,omitempty"`
+}
+
+type CleanRequest struct {
+	ThresholdBytes *int64 `json:"threshold_bytes,omitempty"`
+	TargetBytes    *int64 `json:"target_bytes,omitempty"`
+	DryRun         bool   `json:"dry_run"`
+}
+
+type PullRequest struct {
+	BaseImage string `json:"base_image"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+}
+
+type PullResponse struct {
+	BaseImageURL string   `json:"base_image_url"`
+	ChainIDs     []string `json:"chain_ids"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type notFoundError struct {
+	err error
+}
+
+func (e notFoundError) Error() string {
+	return e.err.Error()
+}
+
+// NotFound marks an error as caused by a missing image, so that it is
+// reported with a 404 status.
+func NotFound(err error) error {
+	return notFoundError{err: err}
+}
+
+type invalidRequestError struct {
+	err error
+}
+
+func (e invalidRequestError) Error() string {
+	return e.err.Error()
+}
+
+// InvalidRequest marks an error as caused by the request itself, so that it
+// is reported with a 400 status.
+func InvalidRequest(err error) error {
+	return invalidRequestError{err: err}
+}
+
+type Server struct {
+	logger  lager.Logger
+	backend Backend
+	mux     *http.ServeMux
+}
+
+func NewServer(logger lager.Logger, backend Backend) *Server {
+	s := &Server{
+		logger:  logger.Session("server"),
+		backend: backend,
+		mux:     http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/images", s.handleImages)
+	s.mux.HandleFunc("/images/", s.handleImage)
+	s.mux.HandleFunc("/clean", s.handleClean)
+	s.mux.HandleFunc("/pull", s.handlePull)
+
+	return s
+}
+
+func (s *Server) Serve(listener net.Listener) error {
+	return http.Serve(listener, s)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ScheduleClean runs a clean with the given request every interval until stop
+// is closed. Failures are logged and the next run goes ahead as scheduled.
+func (s *Server) ScheduleClean(interval time.Duration, request CleanRequest, stop <-chan struct{}) {
+	logger := s.logger.Session("scheduled-clean", lager.Data{"interval": interval.String()})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			report, err := s.backend.Clean(logger, request)
+			if err != nil {
+				logger.Error("cleaning-failed", err)
+				continue
+			}
+			logger.Info("cleaned", lager.Data{"reclaimedBytes": report.ReclaimedBytes, "noop": report.Noop})
+		}
+	}
+}
+
+func (s *Server) handleImages(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		logger := s.logger.Session("list")
+		images, err := s.backend.List(logger)
+		s.respond(logger, w, images, err)
+
+	case "POST":
+		logger := s.logger.Session("create")
+		var request CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			s.respond(logger, w, nil, InvalidRequest(errorspkg.Wrap(err, "decoding request")))
+			return
+		}
+		if request.ID == "" || request.BaseImage == "" {
+			s.respond(logger, w, nil, InvalidRequest(errorspkg.New("id and base_image are required")))
+			return
+		}
+
+		spec, err := s.backend.Create(logger, request)
+		s.respond(logger, w, spec, err)
+
+	default:
+		methodNotAllowed(w, "GET, POST")
+	}
+}
+
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/images/")
+
+	if strings.HasSuffix(path, "/stats") {
+		if r.Method != "GET" {
+			methodNotAllowed(w, "GET")
+			return
+		}
+
+		id := strings.TrimSuffix(path, "/stats")
+		logger := s.logger.Session("stats", lager.Data{"id": id})
+		stats, err := s.backend.Stats(logger, id)
+		s.respond(logger, w, stats, err)
+		return
+	}
+
+	if r.Method != "DELETE" {
+		methodNotAllowed(w, "DELETE")
+		return
+	}
+
+	logger := s.logger.Session("delete", lager.Data{"id": path})
+	if err := s.backend.Delete(logger, path); err != nil {
+		s.respond(logger, w, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleClean(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, "POST")
+		return
+	}
+
+	logger := s.logger.Session("clean")
+	request := CleanRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			s.respond(logger, w, nil, InvalidRequest(errorspkg.Wrap(err, "decoding request")))
+			return
+		}
+	}
+
+	report, err := s.backend.Clean(logger, request)
+	s.respond(logger, w, report, err)
+}
+
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, "POST")
+		return
+	}
+
+	logger := s.logger.Session("pull")
+	var request PullRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.respond(logger, w, nil, InvalidRequest(errorspkg.Wrap(err, "decoding request")))
+		return
+	}
+	if request.BaseImage == "" {
+		s.respond(logger, w, nil, InvalidRequest(errorspkg.New("base_image is required")))
+		return
+	}
+
+	response, err := s.backend.Pull(logger, request)
+	s.respond(logger, w, response, err)
+}
+
+func (s *Server) respond(logger lager.Logger, w http.ResponseWriter, body interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		logger.Error("request-failed", err)
+
+		status := http.StatusInternalServerError
+		switch errorspkg.Cause(err).(type) {
+		case notFoundError:
+			status = http.StatusNotFound
+		case invalidRequestError:
+			status = http.StatusBadRequest
+		}
+
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("encoding-response-failed", err)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
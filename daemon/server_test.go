@@ -0,0 +1,253 @@
+package daemon_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/daemon"
+	"code.cloudfoundry.org/grootfs/daemon/daemonfakes"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/lager/lagertest"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	errorspkg "github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		fakeBackend *daemonfakes.FakeBackend
+		server      *daemon.Server
+		recorder    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		fakeBackend = new(daemonfakes.FakeBackend)
+		server = daemon.NewServer(lagertest.NewTestLogger("daemon"), fakeBackend)
+		recorder = httptest.NewRecorder()
+	})
+
+	request := func(method, path, body string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		server.ServeHTTP(recorder, req)
+	}
+
+	decodeError := func() string {
+		var errorResponse daemon.ErrorResponse
+		Expect(json.NewDecoder(recorder.Body).Decode(&errorResponse)).To(Succeed())
+		return errorResponse.Error
+	}
+
+	Describe("POST /images", func() {
+		BeforeEach(func() {
+			fakeBackend.CreateReturns(specs.Spec{
+				Root: &specs.Root{Path: "/store/images/my-image/rootfs"},
+			}, nil)
+		})
+
+		It("creates the image and returns its runtime spec", func() {
+			request("POST", "/images", `{"id": "my-image", "base_image": "docker:///busybox", "disk_limit_size_bytes": 1024, "mount": false}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			Expect(fakeBackend.CreateCallCount()).To(Equal(1))
+			_, createRequest := fakeBackend.CreateArgsForCall(0)
+			Expect(createRequest.ID).To(Equal("my-image"))
+			Expect(createRequest.BaseImage).To(Equal("docker:///busybox"))
+			Expect(*createRequest.DiskLimitSizeBytes).To(Equal(int64(1024)))
+			Expect(*createRequest.Mount).To(BeFalse())
+			Expect(createRequest.ExcludeImageFromQuota).To(BeNil())
+			Expect(createRequest.Clean).To(BeNil())
+
+			var spec specs.Spec
+			Expect(json.NewDecoder(recorder.Body).Decode(&spec)).To(Succeed())
+			Expect(spec.Root.Path).To(Equal("/store/images/my-image/rootfs"))
+		})
+
+		Context("when the request is malformed", func() {
+			It("returns a bad request", func() {
+				request("POST", "/images", `{"id": `)
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(decodeError()).To(ContainSubstring("decoding request"))
+				Expect(fakeBackend.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the id or base image are missing", func() {
+			It("returns a bad request", func() {
+				request("POST", "/images", `{"id": "my-image"}`)
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+				Expect(decodeError()).To(Equal("id and base_image are required"))
+			})
+		})
+
+		Context("when creating fails", func() {
+			BeforeEach(func() {
+				fakeBackend.CreateReturns(specs.Spec{}, errors.New("failed to create"))
+			})
+
+			It("returns an internal server error", func() {
+				request("POST", "/images", `{"id": "my-image", "base_image": "docker:///busybox"}`)
+				Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+				Expect(decodeError()).To(Equal("failed to create"))
+			})
+		})
+
+		Context("when the backend rejects the request", func() {
+			BeforeEach(func() {
+				fakeBackend.CreateReturns(specs.Spec{}, errorspkg.Wrap(daemon.InvalidRequest(errors.New("invalid base image")), "creating"))
+			})
+
+			It("returns a bad request", func() {
+				request("POST", "/images", `{"id": "my-image", "base_image": "docker:///busybox"}`)
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("GET /images", func() {
+		It("lists the images", func() {
+			fakeBackend.ListReturns([]string{"/store/images/image-1", "/store/images/image-2"}, nil)
+
+			request("GET", "/images", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			var images []string
+			Expect(json.NewDecoder(recorder.Body).Decode(&images)).To(Succeed())
+			Expect(images).To(ConsistOf("/store/images/image-1", "/store/images/image-2"))
+		})
+	})
+
+	Describe("DELETE /images/:id", func() {
+		It("deletes the image", func() {
+			request("DELETE", "/images/my-image", "")
+			Expect(recorder.Code).To(Equal(http.StatusNoContent))
+
+			Expect(fakeBackend.DeleteCallCount()).To(Equal(1))
+			_, id := fakeBackend.DeleteArgsForCall(0)
+			Expect(id).To(Equal("my-image"))
+		})
+
+		Context("when the image doesn't exist", func() {
+			BeforeEach(func() {
+				fakeBackend.DeleteReturns(daemon.NotFound(errors.New("Image `my-image` not found. Skipping delete.")))
+			})
+
+			It("returns not found", func() {
+				request("DELETE", "/images/my-image", "")
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+				Expect(decodeError()).To(ContainSubstring("not found"))
+			})
+		})
+	})
+
+	Describe("GET /images/:id/stats", func() {
+		It("returns the image stats", func() {
+			fakeBackend.StatsReturns(groot.VolumeStats{
+				DiskUsage: groot.DiskUsage{TotalBytesUsed: 2048, ExclusiveBytesUsed: 1024},
+			}, nil)
+
+			request("GET", "/images/my-image/stats", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			_, id := fakeBackend.StatsArgsForCall(0)
+			Expect(id).To(Equal("my-image"))
+
+			var stats groot.VolumeStats
+			Expect(json.NewDecoder(recorder.Body).Decode(&stats)).To(Succeed())
+			Expect(stats.DiskUsage.TotalBytesUsed).To(Equal(int64(2048)))
+		})
+
+		It("rejects other methods", func() {
+			request("POST", "/images/my-image/stats", "")
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	Describe("POST /clean", func() {
+		BeforeEach(func() {
+			fakeBackend.CleanReturns(groot.CleanReport{ReclaimedBytes: 100}, nil)
+		})
+
+		It("cleans the store and returns the report", func() {
+			request("POST", "/clean", `{"threshold_bytes": 2000, "dry_run": true}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			_, cleanRequest := fakeBackend.CleanArgsForCall(0)
+			Expect(*cleanRequest.ThresholdBytes).To(Equal(int64(2000)))
+			Expect(cleanRequest.TargetBytes).To(BeNil())
+			Expect(cleanRequest.DryRun).To(BeTrue())
+
+			var report groot.CleanReport
+			Expect(json.NewDecoder(recorder.Body).Decode(&report)).To(Succeed())
+			Expect(report.ReclaimedBytes).To(Equal(int64(100)))
+		})
+
+		It("accepts an empty body", func() {
+			request("POST", "/clean", "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			_, cleanRequest := fakeBackend.CleanArgsForCall(0)
+			Expect(cleanRequest).To(Equal(daemon.CleanRequest{}))
+		})
+	})
+
+	Describe("POST /pull", func() {
+		It("pulls the image", func() {
+			fakeBackend.PullReturns(daemon.PullResponse{
+				BaseImageURL: "docker:///busybox",
+				ChainIDs:     []string{"chain-1"},
+			}, nil)
+
+			request("POST", "/pull", `{"base_image": "docker:///busybox", "username": "user"}`)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			_, pullRequest := fakeBackend.PullArgsForCall(0)
+			Expect(pullRequest.BaseImage).To(Equal("docker:///busybox"))
+			Expect(pullRequest.Username).To(Equal("user"))
+
+			var response daemon.PullResponse
+			Expect(json.NewDecoder(recorder.Body).Decode(&response)).To(Succeed())
+			Expect(response.ChainIDs).To(Equal([]string{"chain-1"}))
+		})
+
+		Context("when the base image is missing", func() {
+			It("returns a bad request", func() {
+				request("POST", "/pull", `{}`)
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("ScheduleClean", func() {
+		It("cleans the store on every interval until stopped", func() {
+			threshold := int64(1000)
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				server.ScheduleClean(10*time.Millisecond, daemon.CleanRequest{ThresholdBytes: &threshold}, stop)
+			}()
+
+			Eventually(fakeBackend.CleanCallCount).Should(BeNumerically(">=", 2))
+			close(stop)
+			Eventually(done).Should(BeClosed())
+
+			_, cleanRequest := fakeBackend.CleanArgsForCall(0)
+			Expect(*cleanRequest.ThresholdBytes).To(Equal(int64(1000)))
+		})
+
+		It("keeps going when a clean fails", func() {
+			fakeBackend.CleanReturns(groot.CleanReport{}, errors.New("failed to clean"))
+			stop := make(chan struct{})
+			defer close(stop)
+
+			go server.ScheduleClean(10*time.Millisecond, daemon.CleanRequest{}, stop)
+			Eventually(fakeBackend.CleanCallCount).Should(BeNumerically(">=", 2))
+		})
+	})
+})
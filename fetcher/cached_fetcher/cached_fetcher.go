@@ -0,0 +1,92 @@
+package cached_fetcher // import "code.cloudfoundry.org/grootfs/fetcher/cached_fetcher"
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/lager"
+)
+
+type cacheEntry struct {
+	baseImageInfo groot.BaseImageInfo
+	fetchedAt     time.Time
+}
+
+type Cache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *Cache) get(key string) (groot.BaseImageInfo, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return groot.BaseImageInfo{}, false
+	}
+
+	if time.Since(entry.fetchedAt) > c.ttl {
+		delete(c.entries, key)
+		return groot.BaseImageInfo{}, false
+	}
+
+	return entry.baseImageInfo, true
+}
+
+func (c *Cache) put(key string, baseImageInfo groot.BaseImageInfo) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[key] = cacheEntry{
+		baseImageInfo: baseImageInfo,
+		fetchedAt:     time.Now(),
+	}
+}
+
+// CachedFetcher remembers the base image info resolved by the wrapped fetcher,
+// so that repeated requests for the same image don't re-resolve its manifest
+// until the cache entry expires. Blobs are always streamed from the wrapped
+// fetcher.
+type CachedFetcher struct {
+	base_image_puller.Fetcher
+	cache *Cache
+	key   string
+}
+
+func NewCachedFetcher(fetcher base_image_puller.Fetcher, cache *Cache, key string) *CachedFetcher {
+	return &CachedFetcher{
+		Fetcher: fetcher,
+		cache:   cache,
+		key:     key,
+	}
+}
+
+func (f *CachedFetcher) BaseImageInfo(logger lager.Logger) (groot.BaseImageInfo, error) {
+	logger = logger.Session("cached-base-image-info", lager.Data{"key": f.key})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	if baseImageInfo, ok := f.cache.get(f.key); ok {
+		logger.Debug("cache-hit")
+		return baseImageInfo, nil
+	}
+
+	baseImageInfo, err := f.Fetcher.BaseImageInfo(logger)
+	if err != nil {
+		return groot.BaseImageInfo{}, err
+	}
+
+	f.cache.put(f.key, baseImageInfo)
+	return baseImageInfo, nil
+}
@@ -0,0 +1,13 @@
+package cached_fetcher_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCachedFetcher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cached Fetcher Suite")
+}
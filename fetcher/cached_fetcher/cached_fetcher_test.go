@@ -0,0 +1,123 @@
+package cached_fetcher_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/grootfs/fetcher/cached_fetcher"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CachedFetcher", func() {
+	var (
+		fakeFetcher *base_image_pullerfakes.FakeFetcher
+		cache       *cached_fetcher.Cache
+		ttl         time.Duration
+		logger      lager.Logger
+
+		baseImageInfo groot.BaseImageInfo
+	)
+
+	BeforeEach(func() {
+		fakeFetcher = new(base_image_pullerfakes.FakeFetcher)
+		baseImageInfo = groot.BaseImageInfo{
+			LayerInfos: []groot.LayerInfo{{ChainID: "chain-1"}},
+		}
+		fakeFetcher.BaseImageInfoReturns(baseImageInfo, nil)
+
+		ttl = time.Minute
+		logger = lagertest.NewTestLogger("cached-fetcher")
+	})
+
+	JustBeforeEach(func() {
+		cache = cached_fetcher.NewCache(ttl)
+	})
+
+	Describe("BaseImageInfo", func() {
+		It("returns the wrapped fetcher's base image info", func() {
+			fetcher := cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///busybox")
+
+			info, err := fetcher.BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info).To(Equal(baseImageInfo))
+		})
+
+		It("reuses the cached base image info for the same key", func() {
+			_, err := cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///busybox").BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///busybox").BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info).To(Equal(baseImageInfo))
+
+			Expect(fakeFetcher.BaseImageInfoCallCount()).To(Equal(1))
+		})
+
+		It("fetches the base image info again for a different key", func() {
+			_, err := cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///busybox").BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///ubuntu").BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeFetcher.BaseImageInfoCallCount()).To(Equal(2))
+		})
+
+		Context("when the cache entry has expired", func() {
+			BeforeEach(func() {
+				ttl = time.Millisecond
+			})
+
+			It("fetches the base image info again", func() {
+				fetcher := cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///busybox")
+				_, err := fetcher.BaseImageInfo(logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				time.Sleep(10 * time.Millisecond)
+
+				_, err = fetcher.BaseImageInfo(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeFetcher.BaseImageInfoCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when the wrapped fetcher fails", func() {
+			BeforeEach(func() {
+				fakeFetcher.BaseImageInfoReturns(groot.BaseImageInfo{}, errors.New("failed to fetch"))
+			})
+
+			It("returns the error and doesn't cache anything", func() {
+				fetcher := cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///busybox")
+				_, err := fetcher.BaseImageInfo(logger)
+				Expect(err).To(MatchError("failed to fetch"))
+
+				_, err = fetcher.BaseImageInfo(logger)
+				Expect(err).To(HaveOccurred())
+				Expect(fakeFetcher.BaseImageInfoCallCount()).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("StreamBlob", func() {
+		It("streams from the wrapped fetcher", func() {
+			fakeFetcher.StreamBlobReturns(ioutil.NopCloser(strings.NewReader("blob")), 4, nil)
+			fetcher := cached_fetcher.NewCachedFetcher(fakeFetcher, cache, "docker:///busybox")
+
+			stream, size, err := fetcher.StreamBlob(logger, groot.LayerInfo{BlobID: "sha256:blob"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(4)))
+			Expect(ioutil.ReadAll(stream)).To(Equal([]byte("blob")))
+
+			_, layerInfo := fakeFetcher.StreamBlobArgsForCall(0)
+			Expect(layerInfo.BlobID).To(Equal("sha256:blob"))
+		})
+	})
+})
@@ -11,6 +11,7 @@ import (
 	"code.cloudfoundry.org/lager"
 
 	"github.com/containers/image/types"
+	digestpkg "github.com/opencontainers/go-digest"
 	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	errorspkg "github.com/pkg/errors"
 )
@@ -63,6 +64,7 @@ func (f *LayerFetcher) BaseImageInfo(logger lager.Logger) (groot.BaseImageInfo,
 	return groot.BaseImageInfo{
 		LayerInfos: f.createLayerInfos(logger, manifest, config),
 		Config:     *config,
+		Digest:     f.manifestDigest(logger, manifest),
 	}, nil
 }
 
@@ -117,6 +119,21 @@ func (f *LayerFetcher) createLayerInfos(logger lager.Logger, image Manifest, con
 	return layerInfos
 }
 
+// manifestDigest is only recorded as provenance, so failing to compute it
+// doesn't fail the fetch.
+func (f *LayerFetcher) manifestDigest(logger lager.Logger, image Manifest) string {
+	contents, _, err := image.Manifest()
+	if err != nil {
+		logger.Error("fetching-manifest-contents-failed", err)
+		return ""
+	}
+	if len(contents) == 0 {
+		return ""
+	}
+
+	return digestpkg.FromBytes(contents).String()
+}
+
 func (f *LayerFetcher) chainID(diffID string, parentChainID string) string {
 	if diffID != "" {
 		diffID = strings.Split(diffID, ":")[1]
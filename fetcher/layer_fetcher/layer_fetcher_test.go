@@ -147,6 +147,32 @@ var _ = Describe("LayerFetcher", func() {
 
 			Expect(baseImageInfo.Config).To(Equal(expectedConfig))
 		})
+
+		It("returns the digest of the manifest", func() {
+			fakeManifest := new(layer_fetcherfakes.FakeManifest)
+			fakeManifest.OCIConfigReturns(&specsv1.Image{}, nil)
+			fakeManifest.ManifestReturns([]byte(`{"schemaVersion": 2}`), "application/vnd.docker.distribution.manifest.v2+json", nil)
+			fakeSource.ManifestReturns(fakeManifest, nil)
+
+			baseImageInfo, err := fetcher.BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(baseImageInfo.Digest).To(Equal(digestpkg.FromBytes([]byte(`{"schemaVersion": 2}`)).String()))
+		})
+
+		Context("when the manifest contents can't be fetched", func() {
+			It("returns the base image info without a digest", func() {
+				fakeManifest := new(layer_fetcherfakes.FakeManifest)
+				fakeManifest.OCIConfigReturns(&specsv1.Image{}, nil)
+				fakeManifest.ManifestReturns(nil, "", errors.New("no manifest"))
+				fakeSource.ManifestReturns(fakeManifest, nil)
+
+				baseImageInfo, err := fetcher.BaseImageInfo(logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(baseImageInfo.Digest).To(BeEmpty())
+			})
+		})
 	})
 
 	Describe("StreamBlob", func() {
@@ -0,0 +1,223 @@
+package layered_fetcher // import "code.cloudfoundry.org/grootfs/fetcher/layered_fetcher"
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/fetcher/layer_fetcher"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/lager"
+	digestpkg "github.com/opencontainers/go-digest"
+	errorspkg "github.com/pkg/errors"
+)
+
+// LayeredFetcher stacks local tar layers on top of the layers of the wrapped
+// fetcher's base image. Their chain IDs are derived from their uncompressed
+// contents, so they are unpacked, shared and collected like any other layer.
+type LayeredFetcher struct {
+	base_image_puller.Fetcher
+	layerPaths []string
+	layers     map[string]string
+}
+
+func NewLayeredFetcher(fetcher base_image_puller.Fetcher, layerPaths []string) *LayeredFetcher {
+	return &LayeredFetcher{
+		Fetcher:    fetcher,
+		layerPaths: layerPaths,
+		layers:     make(map[string]string),
+	}
+}
+
+func (f *LayeredFetcher) BaseImageInfo(logger lager.Logger) (groot.BaseImageInfo, error) {
+	logger = logger.Session("layered-base-image-info", lager.Data{"layerPaths": f.layerPaths})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	baseImageInfo, err := f.Fetcher.BaseImageInfo(logger)
+	if err != nil {
+		return groot.BaseImageInfo{}, err
+	}
+
+	// The wrapped fetcher may share its base image info through a cache, so
+	// the layers are appended to copies.
+	layerInfos := append([]groot.LayerInfo{}, baseImageInfo.LayerInfos...)
+	config := baseImageInfo.Config
+	config.RootFS.DiffIDs = append([]digestpkg.Digest{}, config.RootFS.DiffIDs...)
+	extraLayerDiffIDs := append([]string{}, baseImageInfo.ExtraLayerDiffIDs...)
+
+	var parentChainID string
+	if len(layerInfos) > 0 {
+		parentChainID = layerInfos[len(layerInfos)-1].ChainID
+	}
+
+	for _, layerPath := range f.layerPaths {
+		diffID, size, err := layerDiffID(layerPath)
+		if err != nil {
+			logger.Error("computing-layer-diff-id-failed", err, lager.Data{"layerPath": layerPath})
+			return groot.BaseImageInfo{}, err
+		}
+
+		chainID := chainID(diffID, parentChainID)
+		layerInfos = append(layerInfos, groot.LayerInfo{
+			BlobID:        layerPath,
+			Size:          size,
+			ChainID:       chainID,
+			DiffID:        diffID,
+			ParentChainID: parentChainID,
+		})
+		layerDigest := digestpkg.NewDigestFromHex("sha256", diffID)
+		config.RootFS.DiffIDs = append(config.RootFS.DiffIDs, layerDigest)
+		extraLayerDiffIDs = append(extraLayerDiffIDs, layerDigest.String())
+		f.layers[chainID] = layerPath
+		parentChainID = chainID
+	}
+
+	baseImageInfo.LayerInfos = layerInfos
+	baseImageInfo.Config = config
+	baseImageInfo.ExtraLayerDiffIDs = extraLayerDiffIDs
+	return baseImageInfo, nil
+}
+
+func (f *LayeredFetcher) StreamBlob(logger lager.Logger, layerInfo groot.LayerInfo) (io.ReadCloser, int64, error) {
+	layerPath, ok := f.layers[layerInfo.ChainID]
+	if !ok {
+		return f.Fetcher.StreamBlob(logger, layerInfo)
+	}
+
+	logger = logger.Session("stream-layer", lager.Data{"layerPath": layerPath})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	// The layer file can change after its diff ID was computed, so it's
+	// copied once and checked against it before anything is unpacked.
+	blobFilePath, err := copyLayer(layerPath, layerInfo.DiffID)
+	if err != nil {
+		logger.Error("copying-layer-failed", err)
+		return nil, 0, err
+	}
+
+	blobReader, err := layer_fetcher.NewBlobReader(blobFilePath)
+	if err != nil {
+		logger.Error("blob-reader-failed", err)
+		os.Remove(blobFilePath)
+		return nil, 0, errorspkg.Wrap(err, "opening stream from temporary blob file")
+	}
+
+	return blobReader, layerInfo.Size, nil
+}
+
+// copyLayer writes the uncompressed layer to a temporary file, which is
+// removed unless its contents match diffID.
+func copyLayer(layerPath, diffID string) (path string, err error) {
+	stream, err := openLayer(layerPath)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	blobTempFile, err := ioutil.TempFile("", "layer-")
+	if err != nil {
+		return "", errorspkg.Wrap(err, "creating temporary blob file")
+	}
+	defer func() {
+		blobTempFile.Close()
+		if err != nil {
+			os.Remove(blobTempFile.Name())
+		}
+	}()
+
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(blobTempFile, hash), stream); err != nil {
+		return "", errorspkg.Wrapf(err, "reading layer `%s`", layerPath)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != diffID {
+		err = errorspkg.Errorf("layer `%s` changed: diffID digest mismatch: expected: %s, actual: %s", layerPath, diffID, actual)
+		return "", err
+	}
+
+	return blobTempFile.Name(), nil
+}
+
+type layerReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *layerReader) Close() error {
+	var closeErr error
+	for _, closer := range r.closers {
+		if err := closer.Close(); err != nil {
+			closeErr = err
+		}
+	}
+
+	return closeErr
+}
+
+// openLayer returns the uncompressed tar stream of a plain or gzipped layer.
+func openLayer(layerPath string) (io.ReadCloser, error) {
+	file, err := os.Open(layerPath)
+	if err != nil {
+		return nil, errorspkg.Wrapf(err, "opening layer `%s`", layerPath)
+	}
+
+	bufferedFile := bufio.NewReader(file)
+	magic, err := bufferedFile.Peek(2)
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, errorspkg.Wrapf(err, "reading layer `%s`", layerPath)
+	}
+
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return &layerReader{Reader: bufferedFile, closers: []io.Closer{file}}, nil
+	}
+
+	gzipReader, err := gzip.NewReader(bufferedFile)
+	if err != nil {
+		file.Close()
+		return nil, errorspkg.Wrapf(err, "decompressing layer `%s`", layerPath)
+	}
+
+	return &layerReader{Reader: gzipReader, closers: []io.Closer{gzipReader, file}}, nil
+}
+
+func layerDiffID(layerPath string) (string, int64, error) {
+	stat, err := os.Stat(layerPath)
+	if err != nil {
+		return "", 0, errorspkg.Wrapf(err, "layer `%s` not found", layerPath)
+	}
+	if stat.IsDir() {
+		return "", 0, errorspkg.Errorf("layer `%s` is a directory, not a tar file", layerPath)
+	}
+
+	stream, err := openLayer(layerPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer stream.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, stream); err != nil {
+		return "", 0, errorspkg.Wrapf(err, "reading layer `%s`", layerPath)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), stat.Size(), nil
+}
+
+// chainID follows the OCI chain ID definition, like the layer fetcher does.
+func chainID(diffID, parentChainID string) string {
+	if parentChainID == "" {
+		return diffID
+	}
+
+	chainIDSha := sha256.Sum256([]byte(fmt.Sprintf("%s %s", parentChainID, diffID)))
+	return hex.EncodeToString(chainIDSha[:])
+}
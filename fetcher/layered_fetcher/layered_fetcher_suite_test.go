@@ -0,0 +1,13 @@
+package layered_fetcher_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLayeredFetcher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Layered Fetcher Suite")
+}
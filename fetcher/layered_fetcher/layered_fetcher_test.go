@@ -0,0 +1,227 @@
+package layered_fetcher_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/grootfs/fetcher/layered_fetcher"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	digestpkg "github.com/opencontainers/go-digest"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var _ = Describe("LayeredFetcher", func() {
+	var (
+		fakeFetcher *base_image_pullerfakes.FakeFetcher
+		logger      lager.Logger
+		tmpDir      string
+
+		layerContents []byte
+		layerDiffID   string
+		layerPath     string
+		fetcher       *layered_fetcher.LayeredFetcher
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "layered-fetcher")
+		Expect(err).NotTo(HaveOccurred())
+
+		layerContents = tarWithFile("foo", "hello")
+		diffIDSha := sha256.Sum256(layerContents)
+		layerDiffID = hex.EncodeToString(diffIDSha[:])
+		layerPath = filepath.Join(tmpDir, "layer.tar")
+		Expect(ioutil.WriteFile(layerPath, layerContents, 0644)).To(Succeed())
+
+		fakeFetcher = new(base_image_pullerfakes.FakeFetcher)
+		fakeFetcher.BaseImageInfoReturns(groot.BaseImageInfo{
+			LayerInfos: []groot.LayerInfo{
+				{BlobID: "sha256:base-blob", ChainID: "base-chain"},
+			},
+			Config: specsv1.Image{
+				RootFS: specsv1.RootFS{DiffIDs: []digestpkg.Digest{"sha256:base-diff"}},
+			},
+			Digest: "sha256:manifest",
+		}, nil)
+
+		logger = lagertest.NewTestLogger("layered-fetcher")
+	})
+
+	JustBeforeEach(func() {
+		fetcher = layered_fetcher.NewLayeredFetcher(fakeFetcher, []string{layerPath})
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("BaseImageInfo", func() {
+		It("appends the layer on top of the base image layers", func() {
+			info, err := fetcher.BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			chainIDSha := sha256.Sum256([]byte(fmt.Sprintf("base-chain %s", layerDiffID)))
+			Expect(info.LayerInfos).To(Equal([]groot.LayerInfo{
+				{BlobID: "sha256:base-blob", ChainID: "base-chain"},
+				{
+					BlobID:        layerPath,
+					ChainID:       hex.EncodeToString(chainIDSha[:]),
+					ParentChainID: "base-chain",
+					DiffID:        layerDiffID,
+					Size:          int64(len(layerContents)),
+				},
+			}))
+			Expect(info.Config.RootFS.DiffIDs).To(Equal([]digestpkg.Digest{
+				"sha256:base-diff", digestpkg.Digest("sha256:" + layerDiffID),
+			}))
+			Expect(info.Digest).To(Equal("sha256:manifest"))
+			Expect(info.ExtraLayerDiffIDs).To(Equal([]string{"sha256:" + layerDiffID}))
+		})
+
+		It("chains multiple layers in order", func() {
+			secondLayerPath := filepath.Join(tmpDir, "second-layer.tar")
+			Expect(ioutil.WriteFile(secondLayerPath, tarWithFile("bar", "world"), 0644)).To(Succeed())
+			fetcher = layered_fetcher.NewLayeredFetcher(fakeFetcher, []string{layerPath, secondLayerPath})
+
+			info, err := fetcher.BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(info.LayerInfos).To(HaveLen(3))
+			Expect(info.LayerInfos[2].BlobID).To(Equal(secondLayerPath))
+			Expect(info.LayerInfos[2].ParentChainID).To(Equal(info.LayerInfos[1].ChainID))
+		})
+
+		It("doesn't modify the wrapped fetcher's base image info", func() {
+			baseLayerInfos := make([]groot.LayerInfo, 1, 4)
+			baseLayerInfos[0] = groot.LayerInfo{ChainID: "base-chain"}
+			fakeFetcher.BaseImageInfoReturns(groot.BaseImageInfo{LayerInfos: baseLayerInfos}, nil)
+
+			_, err := fetcher.BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(baseLayerInfos[:2][1]).To(Equal(groot.LayerInfo{}))
+		})
+
+		Context("when the layer is gzipped", func() {
+			BeforeEach(func() {
+				buffer := bytes.NewBuffer([]byte{})
+				gzipWriter := gzip.NewWriter(buffer)
+				_, err := gzipWriter.Write(layerContents)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(gzipWriter.Close()).To(Succeed())
+				Expect(ioutil.WriteFile(layerPath, buffer.Bytes(), 0644)).To(Succeed())
+			})
+
+			It("derives the diff id from the uncompressed contents", func() {
+				info, err := fetcher.BaseImageInfo(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.LayerInfos[1].DiffID).To(Equal(layerDiffID))
+			})
+		})
+
+		Context("when the layer doesn't exist", func() {
+			BeforeEach(func() {
+				layerPath = filepath.Join(tmpDir, "not-here.tar")
+			})
+
+			It("returns an error", func() {
+				_, err := fetcher.BaseImageInfo(logger)
+				Expect(err).To(MatchError(ContainSubstring("layer `%s` not found", layerPath)))
+			})
+		})
+
+		Context("when the layer is a directory", func() {
+			BeforeEach(func() {
+				layerPath = tmpDir
+			})
+
+			It("returns an error", func() {
+				_, err := fetcher.BaseImageInfo(logger)
+				Expect(err).To(MatchError(ContainSubstring("is a directory")))
+			})
+		})
+
+		Context("when the wrapped fetcher fails", func() {
+			BeforeEach(func() {
+				fakeFetcher.BaseImageInfoReturns(groot.BaseImageInfo{}, errors.New("failed"))
+			})
+
+			It("returns the error", func() {
+				_, err := fetcher.BaseImageInfo(logger)
+				Expect(err).To(MatchError("failed"))
+			})
+		})
+	})
+
+	Describe("StreamBlob", func() {
+		var info groot.BaseImageInfo
+
+		JustBeforeEach(func() {
+			var err error
+			info, err = fetcher.BaseImageInfo(logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("streams the layer tar", func() {
+			stream, size, err := fetcher.StreamBlob(logger, info.LayerInfos[1])
+			Expect(err).NotTo(HaveOccurred())
+			defer stream.Close()
+
+			contents, err := ioutil.ReadAll(stream)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(layerContents))
+			Expect(size).To(Equal(int64(len(layerContents))))
+			Expect(fakeFetcher.StreamBlobCallCount()).To(Equal(0))
+		})
+
+		It("streams the base image layers from the wrapped fetcher", func() {
+			fakeFetcher.StreamBlobReturns(ioutil.NopCloser(strings.NewReader("base")), 4, nil)
+
+			stream, _, err := fetcher.StreamBlob(logger, info.LayerInfos[0])
+			Expect(err).NotTo(HaveOccurred())
+			contents, err := ioutil.ReadAll(stream)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("base"))
+
+			Expect(fakeFetcher.StreamBlobCallCount()).To(Equal(1))
+			_, layerInfo := fakeFetcher.StreamBlobArgsForCall(0)
+			Expect(layerInfo).To(Equal(info.LayerInfos[0]))
+		})
+
+		Context("when the layer changed after its diff id was computed", func() {
+			JustBeforeEach(func() {
+				Expect(ioutil.WriteFile(layerPath, tarWithFile("foo", "changed"), 0644)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, _, err := fetcher.StreamBlob(logger, info.LayerInfos[1])
+				Expect(err).To(MatchError(ContainSubstring("diffID digest mismatch")))
+			})
+		})
+	})
+})
+
+func tarWithFile(name, contents string) []byte {
+	buffer := bytes.NewBuffer([]byte{})
+	tarWriter := tar.NewWriter(buffer)
+	Expect(tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents))})).To(Succeed())
+	_, err := tarWriter.Write([]byte(contents))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(tarWriter.Close()).To(Succeed())
+
+	return buffer.Bytes()
+}
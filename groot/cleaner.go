@@ -9,7 +9,23 @@ import (
 
 //go:generate counterfeiter . Cleaner
 type Cleaner interface {
-	Clean(logger lager.Logger, cacheSize int64) (bool, error)
+	Clean(logger lager.Logger, threshold, target int64) (CleanReport, error)
+	DryRun(logger lager.Logger, threshold, target int64) (CleanReport, error)
+}
+
+type CleanReport struct {
+	Noop           bool           `json:"noop"`
+	DryRun         bool           `json:"dry_run"`
+	Volumes        []VolumeReport `json:"volumes"`
+	ReclaimedBytes int64          `json:"reclaimed_bytes"`
+	Measured       bool           `json:"measured"`
+	UsageBefore    int64          `json:"usage_before_bytes"`
+	UsageAfter     int64          `json:"usage_after_bytes"`
+}
+
+type VolumeReport struct {
+	ID   string `json:"id"`
+	Size int64  `json:"size_bytes"`
 }
 
 type cleaner struct {
@@ -30,43 +46,107 @@ func IamCleaner(locksmith Locksmith, sm StoreMeasurer,
 	}
 }
 
-func (c *cleaner) Clean(logger lager.Logger, threshold int64) (bool, error) {
-	logger = logger.Session("groot-cleaning")
+func (c *cleaner) Clean(logger lager.Logger, threshold, target int64) (CleanReport, error) {
+	logger = logger.Session("groot-cleaning", lager.Data{"threshold": threshold, "target": target})
 	logger.Info("starting")
 
 	defer c.metricsEmitter.TryEmitDurationFrom(logger, MetricImageCleanTime, time.Now())
 	defer logger.Info("ending")
 
-	if threshold > 0 {
-		committedQuota, err := c.storeMeasurer.CommittedQuota(logger)
-		if err != nil {
-			return false, errorspkg.Wrap(err, "failed to calculate committed quota")
-		}
-		totalVolumesSize, err := c.storeMeasurer.TotalVolumesSize(logger)
+	return c.clean(logger, threshold, target, false)
+}
+
+func (c *cleaner) DryRun(logger lager.Logger, threshold, target int64) (CleanReport, error) {
+	logger = logger.Session("groot-cleaning-dry-run", lager.Data{"threshold": threshold, "target": target})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	return c.clean(logger, threshold, target, true)
+}
+
+func (c *cleaner) clean(logger lager.Logger, threshold, target int64, dryRun bool) (CleanReport, error) {
+	report := CleanReport{DryRun: dryRun, Volumes: []VolumeReport{}}
+
+	if threshold < 0 {
+		report.Noop = true
+		return report, errorspkg.New("Threshold must be greater than 0")
+	}
+
+	if target < 0 {
+		report.Noop = true
+		return report, errorspkg.New("Target must be greater than 0")
+	}
+
+	// Measuring the store walks every volume and image, so it is only done
+	// when the usage is needed to decide what to collect or to report on it.
+	var usage int64
+	if threshold > 0 || target > 0 || dryRun {
+		var err error
+		usage, err = c.storeUsage(logger)
 		if err != nil {
-			return false, errorspkg.Wrap(err, "failed to calculate total volumes size")
+			return report, err
 		}
-		if (committedQuota + totalVolumesSize) < threshold {
-			return true, nil
-		}
-	} else if threshold < 0 {
-		return true, errorspkg.New("Threshold must be greater than 0")
+		report.Measured = true
+		report.UsageBefore = usage
+		report.UsageAfter = usage
+	}
+
+	if threshold > 0 && usage < threshold {
+		report.Noop = true
+		return report, nil
+	}
+
+	if dryRun {
+		c.reportVolumes(logger, &report, c.collectableVolumes(logger, usage, target))
+		return report, nil
+	}
+
+	if err := c.collectGarbage(logger, &report, usage, target); err != nil {
+		return report, err
+	}
+
+	if !report.Measured {
+		return report, nil
+	}
+
+	if usageAfter, err := c.storeUsage(logger); err != nil {
+		logger.Error("measuring-usage-after-clean-failed", err)
+	} else {
+		report.UsageAfter = usageAfter
 	}
 
-	return false, c.collectGarbage(logger)
+	return report, nil
 }
 
-func (c *cleaner) collectGarbage(logger lager.Logger) error {
-	lockFile, err := c.locksmith.Lock(GlobalLockKey)
+func (c *cleaner) storeUsage(logger lager.Logger) (int64, error) {
+	committedQuota, err := c.storeMeasurer.CommittedQuota(logger)
 	if err != nil {
-		return errorspkg.Wrap(err, "garbage collector acquiring lock")
+		return 0, errorspkg.Wrap(err, "failed to calculate committed quota")
 	}
 
-	unusedVolumes, err := c.garbageCollector.UnusedVolumes(logger)
+	totalVolumesSize, err := c.storeMeasurer.TotalVolumesSize(logger)
 	if err != nil {
-		logger.Error("finding-unused-failed", err)
+		return 0, errorspkg.Wrap(err, "failed to calculate total volumes size")
+	}
+
+	// Images deleted asynchronously use their space until they are reaped.
+	trashSize, err := c.storeMeasurer.TrashSize(logger)
+	if err != nil {
+		return 0, errorspkg.Wrap(err, "failed to calculate trash size")
+	}
+
+	return committedQuota + totalVolumesSize + trashSize, nil
+}
+
+func (c *cleaner) collectGarbage(logger lager.Logger, report *CleanReport, usage, target int64) error {
+	lockFile, err := c.locksmith.Lock(GlobalLockKey)
+	if err != nil {
+		return errorspkg.Wrap(err, "garbage collector acquiring lock")
 	}
 
+	unusedVolumes := c.collectableVolumes(logger, usage, target)
+	c.reportVolumes(logger, report, unusedVolumes)
+
 	if err := c.garbageCollector.MarkUnused(logger, unusedVolumes); err != nil {
 		logger.Error("marking-unused-failed", err)
 	}
@@ -77,3 +157,37 @@ func (c *cleaner) collectGarbage(logger lager.Logger) error {
 
 	return c.garbageCollector.Collect(logger)
 }
+
+func (c *cleaner) collectableVolumes(logger lager.Logger, usage, target int64) []string {
+	unusedVolumes, err := c.garbageCollector.UnusedVolumes(logger)
+	if err != nil {
+		logger.Error("finding-unused-failed", err)
+	}
+
+	// Without a target every unused volume is collected, otherwise only the
+	// least recently used ones needed to bring usage down to the target.
+	if target > 0 {
+		unusedVolumes, err = c.garbageCollector.LeastRecentlyUsed(logger, unusedVolumes, usage-target)
+		if err != nil {
+			logger.Error("selecting-least-recently-used-failed", err)
+		}
+	}
+
+	return unusedVolumes
+}
+
+func (c *cleaner) reportVolumes(logger lager.Logger, report *CleanReport, volumeIDs []string) {
+	for _, volumeID := range volumeIDs {
+		size, err := c.storeMeasurer.VolumeSize(logger, volumeID)
+		if err != nil {
+			logger.Error("fetching-volume-size-failed", err, lager.Data{"volumeID": volumeID})
+		}
+
+		report.Volumes = append(report.Volumes, VolumeReport{ID: volumeID, Size: size})
+		report.ReclaimedBytes += size
+	}
+
+	if report.Measured {
+		report.UsageAfter = report.UsageBefore - report.ReclaimedBytes
+	}
+}
@@ -49,19 +49,19 @@ var _ = Describe("Cleaner", func() {
 
 	Describe("Clean", func() {
 		It("calls the garbage collector to gather a list of unused volumes", func() {
-			_, err := cleaner.Clean(logger, 0)
+			_, err := cleaner.Clean(logger, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeGarbageCollector.UnusedVolumesCallCount()).To(Equal(1))
 		})
 
 		It("calls the garbage collector to mark unused volumes", func() {
-			_, err := cleaner.Clean(logger, 0)
+			_, err := cleaner.Clean(logger, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeGarbageCollector.MarkUnusedCallCount()).To(Equal(1))
 		})
 
 		It("calls the garbage collector to collect", func() {
-			_, err := cleaner.Clean(logger, 0)
+			_, err := cleaner.Clean(logger, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeGarbageCollector.CollectCallCount()).To(Equal(1))
 		})
@@ -72,13 +72,13 @@ var _ = Describe("Cleaner", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := cleaner.Clean(logger, 0)
+				_, err := cleaner.Clean(logger, 0, 0)
 				Expect(err).To(MatchError(ContainSubstring("failed to collect unused bits")))
 			})
 		})
 
 		It("emits metrics for clean duration", func() {
-			_, err := cleaner.Clean(logger, 0)
+			_, err := cleaner.Clean(logger, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeMetricsEmitter.TryEmitDurationFromCallCount()).To(Equal(1))
@@ -88,7 +88,7 @@ var _ = Describe("Cleaner", func() {
 		})
 
 		It("acquires the global lock", func() {
-			_, err := cleaner.Clean(logger, 0)
+			_, err := cleaner.Clean(logger, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeLocksmith.LockCallCount()).To(Equal(1))
@@ -96,7 +96,7 @@ var _ = Describe("Cleaner", func() {
 		})
 
 		It("releases the global lock", func() {
-			_, err := cleaner.Clean(logger, 0)
+			_, err := cleaner.Clean(logger, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
@@ -120,7 +120,7 @@ var _ = Describe("Cleaner", func() {
 				return nil
 			}
 
-			_, err := cleaner.Clean(logger, 0)
+			_, err := cleaner.Clean(logger, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(markTime.UnixNano()).To(BeNumerically("<", unLockTime.UnixNano()))
@@ -133,13 +133,13 @@ var _ = Describe("Cleaner", func() {
 			})
 
 			It("still collects the garbage", func() {
-				_, err := cleaner.Clean(logger, 0)
+				_, err := cleaner.Clean(logger, 0, 0)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(fakeGarbageCollector.CollectCallCount()).To(Equal(1))
 			})
 
 			It("releases the global lock", func() {
-				_, err := cleaner.Clean(logger, 0)
+				_, err := cleaner.Clean(logger, 0, 0)
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
@@ -152,12 +152,12 @@ var _ = Describe("Cleaner", func() {
 			})
 
 			It("returns the error", func() {
-				_, err := cleaner.Clean(logger, 0)
+				_, err := cleaner.Clean(logger, 0, 0)
 				Expect(err).To(MatchError(ContainSubstring("failed to acquire lock")))
 			})
 
 			It("does not collect the garbage", func() {
-				_, err := cleaner.Clean(logger, 0)
+				_, err := cleaner.Clean(logger, 0, 0)
 				Expect(err).To(HaveOccurred())
 				Expect(fakeGarbageCollector.CollectCallCount()).To(Equal(0))
 			})
@@ -178,21 +178,21 @@ var _ = Describe("Cleaner", func() {
 				})
 
 				It("does not remove anything", func() {
-					_, err := cleaner.Clean(logger, threshold)
+					_, err := cleaner.Clean(logger, threshold, 0)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(fakeGarbageCollector.CollectCallCount()).To(Equal(0))
 				})
 
 				It("does not acquire the lock", func() {
-					_, err := cleaner.Clean(logger, threshold)
+					_, err := cleaner.Clean(logger, threshold, 0)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(fakeLocksmith.LockCallCount()).To(Equal(0))
 				})
 
 				It("sets noop to `true`", func() {
-					noop, err := cleaner.Clean(logger, threshold)
+					report, err := cleaner.Clean(logger, threshold, 0)
 					Expect(err).NotTo(HaveOccurred())
-					Expect(noop).To(BeTrue())
+					Expect(report.Noop).To(BeTrue())
 				})
 			})
 
@@ -203,20 +203,35 @@ var _ = Describe("Cleaner", func() {
 				})
 
 				It("calls the garbage collector", func() {
-					_, err := cleaner.Clean(logger, threshold)
+					_, err := cleaner.Clean(logger, threshold, 0)
 					Expect(err).NotTo(HaveOccurred())
 					Expect(fakeGarbageCollector.CollectCallCount()).To(Equal(1))
 				})
 			})
 
+			Context("when the trash takes the usage over the threshold", func() {
+				BeforeEach(func() {
+					fakeStoreMeasurer.TotalVolumesSizeReturns(999997, nil)
+					fakeStoreMeasurer.CommittedQuotaReturns(2, nil)
+					fakeStoreMeasurer.TrashSizeReturns(1, nil)
+				})
+
+				It("calls the garbage collector", func() {
+					report, err := cleaner.Clean(logger, threshold, 0)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.UsageBefore).To(Equal(int64(1000000)))
+					Expect(fakeGarbageCollector.CollectCallCount()).To(Equal(1))
+				})
+			})
+
 			Context("when the threshold is negative", func() {
 				BeforeEach(func() {
 					threshold = -120
 				})
 
 				It("indicates a no-op and returns an error", func() {
-					noop, err := cleaner.Clean(logger, threshold)
-					Expect(noop).To(BeTrue())
+					report, err := cleaner.Clean(logger, threshold, 0)
+					Expect(report.Noop).To(BeTrue())
 					Expect(err).To(MatchError("Threshold must be greater than 0"))
 				})
 			})
@@ -227,7 +242,7 @@ var _ = Describe("Cleaner", func() {
 				})
 
 				It("returns a wrapped error", func() {
-					_, err := cleaner.Clean(logger, threshold)
+					_, err := cleaner.Clean(logger, threshold, 0)
 					Expect(err).To(MatchError(ContainSubstring("failed to calculate committed quota")))
 				})
 			})
@@ -238,10 +253,262 @@ var _ = Describe("Cleaner", func() {
 				})
 
 				It("returns a wrapped error", func() {
-					_, err := cleaner.Clean(logger, threshold)
+					_, err := cleaner.Clean(logger, threshold, 0)
 					Expect(err).To(MatchError(ContainSubstring("failed to calculate total volumes size")))
 				})
 			})
+
+			Context("when getting the trash size fails", func() {
+				BeforeEach(func() {
+					fakeStoreMeasurer.TrashSizeReturns(0, errors.New("explosion"))
+				})
+
+				It("returns a wrapped error", func() {
+					_, err := cleaner.Clean(logger, threshold, 0)
+					Expect(err).To(MatchError(ContainSubstring("failed to calculate trash size")))
+				})
+			})
+		})
+
+		Context("when a target is provided", func() {
+			var threshold, target int64
+
+			BeforeEach(func() {
+				threshold = 1000000
+				target = 600000
+
+				fakeStoreMeasurer.TotalVolumesSizeReturns(999999, nil)
+				fakeStoreMeasurer.CommittedQuotaReturns(2, nil)
+				fakeGarbageCollector.UnusedVolumesReturns([]string{"old-volume", "new-volume"}, nil)
+				fakeGarbageCollector.LeastRecentlyUsedReturns([]string{"old-volume"}, nil)
+			})
+
+			It("asks for enough least recently used volumes to reach the target", func() {
+				_, err := cleaner.Clean(logger, threshold, target)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGarbageCollector.LeastRecentlyUsedCallCount()).To(Equal(1))
+				_, volumes, bytesToFree := fakeGarbageCollector.LeastRecentlyUsedArgsForCall(0)
+				Expect(volumes).To(Equal([]string{"old-volume", "new-volume"}))
+				Expect(bytesToFree).To(BeEquivalentTo(400001))
+			})
+
+			It("only marks the least recently used volumes", func() {
+				_, err := cleaner.Clean(logger, threshold, target)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGarbageCollector.MarkUnusedCallCount()).To(Equal(1))
+				_, volumes := fakeGarbageCollector.MarkUnusedArgsForCall(0)
+				Expect(volumes).To(Equal([]string{"old-volume"}))
+			})
+
+			Context("when the threshold is not reached", func() {
+				BeforeEach(func() {
+					fakeStoreMeasurer.TotalVolumesSizeReturns(700000, nil)
+				})
+
+				It("does not remove anything", func() {
+					report, err := cleaner.Clean(logger, threshold, target)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Noop).To(BeTrue())
+					Expect(fakeGarbageCollector.MarkUnusedCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when no threshold is given", func() {
+				It("still evicts down to the target", func() {
+					_, err := cleaner.Clean(logger, 0, target)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeGarbageCollector.LeastRecentlyUsedCallCount()).To(Equal(1))
+					_, _, bytesToFree := fakeGarbageCollector.LeastRecentlyUsedArgsForCall(0)
+					Expect(bytesToFree).To(BeEquivalentTo(400001))
+				})
+			})
+
+			Context("when selecting the least recently used volumes fails", func() {
+				BeforeEach(func() {
+					fakeGarbageCollector.LeastRecentlyUsedReturns(nil, errors.New("failed to select"))
+				})
+
+				It("doesn't mark any volume", func() {
+					_, err := cleaner.Clean(logger, threshold, target)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeGarbageCollector.MarkUnusedCallCount()).To(Equal(1))
+					_, volumes := fakeGarbageCollector.MarkUnusedArgsForCall(0)
+					Expect(volumes).To(BeEmpty())
+				})
+			})
+
+			Context("when the target is negative", func() {
+				It("indicates a no-op and returns an error", func() {
+					report, err := cleaner.Clean(logger, threshold, -1)
+					Expect(report.Noop).To(BeTrue())
+					Expect(err).To(MatchError("Target must be greater than 0"))
+				})
+			})
+		})
+
+		Context("when no target is provided", func() {
+			It("marks every unused volume", func() {
+				fakeGarbageCollector.UnusedVolumesReturns([]string{"old-volume", "new-volume"}, nil)
+
+				_, err := cleaner.Clean(logger, 0, 0)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeGarbageCollector.LeastRecentlyUsedCallCount()).To(Equal(0))
+				_, volumes := fakeGarbageCollector.MarkUnusedArgsForCall(0)
+				Expect(volumes).To(Equal([]string{"old-volume", "new-volume"}))
+			})
+		})
+
+		Describe("report", func() {
+			BeforeEach(func() {
+				fakeStoreMeasurer.CommittedQuotaReturns(100, nil)
+				fakeStoreMeasurer.TotalVolumesSizeReturnsOnCall(0, 900, nil)
+				fakeStoreMeasurer.TotalVolumesSizeReturnsOnCall(1, 600, nil)
+				fakeGarbageCollector.UnusedVolumesReturns([]string{"volume-a", "volume-b"}, nil)
+				fakeStoreMeasurer.VolumeSizeStub = func(_ lager.Logger, id string) (int64, error) {
+					return map[string]int64{"volume-a": 100, "volume-b": 200}[id], nil
+				}
+			})
+
+			It("lists the collected volumes and their sizes", func() {
+				report, err := cleaner.Clean(logger, 0, 0)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Volumes).To(Equal([]groot.VolumeReport{
+					{ID: "volume-a", Size: 100},
+					{ID: "volume-b", Size: 200},
+				}))
+				Expect(report.ReclaimedBytes).To(BeEquivalentTo(300))
+			})
+
+			It("reports the usage before and after cleaning", func() {
+				report, err := cleaner.Clean(logger, 500, 0)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.DryRun).To(BeFalse())
+				Expect(report.Noop).To(BeFalse())
+				Expect(report.Measured).To(BeTrue())
+				Expect(report.UsageBefore).To(BeEquivalentTo(1000))
+				Expect(report.UsageAfter).To(BeEquivalentTo(700))
+			})
+
+			Context("when measuring the usage after cleaning fails", func() {
+				BeforeEach(func() {
+					fakeStoreMeasurer.TotalVolumesSizeReturnsOnCall(1, 0, errors.New("failed to measure"))
+				})
+
+				It("estimates it from the reclaimed bytes", func() {
+					report, err := cleaner.Clean(logger, 500, 0)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.UsageAfter).To(BeEquivalentTo(700))
+				})
+			})
+
+			Context("when neither a threshold nor a target is provided", func() {
+				It("doesn't measure the store", func() {
+					report, err := cleaner.Clean(logger, 0, 0)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeStoreMeasurer.CommittedQuotaCallCount()).To(Equal(0))
+					Expect(fakeStoreMeasurer.TotalVolumesSizeCallCount()).To(Equal(0))
+					Expect(report.Measured).To(BeFalse())
+					Expect(report.UsageBefore).To(BeZero())
+					Expect(report.UsageAfter).To(BeZero())
+				})
+			})
+
+			Context("when fetching a volume size fails", func() {
+				BeforeEach(func() {
+					fakeStoreMeasurer.VolumeSizeStub = func(_ lager.Logger, id string) (int64, error) {
+						if id == "volume-a" {
+							return 0, errors.New("failed to get size")
+						}
+						return 200, nil
+					}
+				})
+
+				It("still reports the volume", func() {
+					report, err := cleaner.Clean(logger, 0, 0)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(report.Volumes).To(Equal([]groot.VolumeReport{
+						{ID: "volume-a", Size: 0},
+						{ID: "volume-b", Size: 200},
+					}))
+				})
+			})
+		})
+	})
+
+	Describe("DryRun", func() {
+		BeforeEach(func() {
+			fakeStoreMeasurer.CommittedQuotaReturns(100, nil)
+			fakeStoreMeasurer.TotalVolumesSizeReturns(900, nil)
+			fakeGarbageCollector.UnusedVolumesReturns([]string{"volume-a", "volume-b"}, nil)
+			fakeStoreMeasurer.VolumeSizeStub = func(_ lager.Logger, id string) (int64, error) {
+				return map[string]int64{"volume-a": 100, "volume-b": 200}[id], nil
+			}
+		})
+
+		It("reports the volumes that would be collected", func() {
+			report, err := cleaner.DryRun(logger, 0, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.DryRun).To(BeTrue())
+			Expect(report.Volumes).To(Equal([]groot.VolumeReport{
+				{ID: "volume-a", Size: 100},
+				{ID: "volume-b", Size: 200},
+			}))
+			Expect(report.ReclaimedBytes).To(BeEquivalentTo(300))
+			Expect(report.Measured).To(BeTrue())
+			Expect(report.UsageBefore).To(BeEquivalentTo(1000))
+			Expect(report.UsageAfter).To(BeEquivalentTo(700))
+		})
+
+		It("doesn't mark or collect anything", func() {
+			_, err := cleaner.DryRun(logger, 0, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeGarbageCollector.MarkUnusedCallCount()).To(Equal(0))
+			Expect(fakeGarbageCollector.CollectCallCount()).To(Equal(0))
+		})
+
+		It("doesn't acquire the global lock", func() {
+			_, err := cleaner.DryRun(logger, 0, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeLocksmith.LockCallCount()).To(Equal(0))
+		})
+
+		Context("when a target is provided", func() {
+			BeforeEach(func() {
+				fakeGarbageCollector.LeastRecentlyUsedReturns([]string{"volume-b"}, nil)
+			})
+
+			It("only reports the least recently used volumes", func() {
+				report, err := cleaner.DryRun(logger, 0, 850)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, bytesToFree := fakeGarbageCollector.LeastRecentlyUsedArgsForCall(0)
+				Expect(bytesToFree).To(BeEquivalentTo(150))
+				Expect(report.Volumes).To(Equal([]groot.VolumeReport{{ID: "volume-b", Size: 200}}))
+				Expect(report.UsageAfter).To(BeEquivalentTo(800))
+			})
+		})
+
+		Context("when the threshold is not reached", func() {
+			It("reports a no-op", func() {
+				report, err := cleaner.DryRun(logger, 5000, 0)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Noop).To(BeTrue())
+				Expect(report.Volumes).To(BeEmpty())
+				Expect(report.UsageAfter).To(BeEquivalentTo(1000))
+			})
 		})
 	})
 })
@@ -11,7 +11,8 @@ import (
 	errorspkg "github.com/pkg/errors"
 )
 
-const ImageReferenceFormat = "image:%s"
+const ImageReferencePrefix = "image:"
+const ImageReferenceFormat = ImageReferencePrefix + "%s"
 
 type CreateSpec struct {
 	ID                          string
@@ -21,8 +22,17 @@ type CreateSpec struct {
 	ExcludeBaseImageFromQuota   bool
 	CleanOnCreate               bool
 	CleanOnCreateThresholdBytes int64
+	CleanOnCreateTargetBytes    int64
+	MaxLayerDepth               int
 	UIDMappings                 []IDMappingSpec
 	GIDMappings                 []IDMappingSpec
+	Labels                      map[string]string
+	ReadOnly                    bool
+	// EphemeralSizeBytes, when set, is the size of the tmpfs holding the
+	// writable layer of the image, instead of the store.
+	EphemeralSizeBytes int64
+	// OverlayMountOptions are extra options to mount the image's overlay with.
+	OverlayMountOptions []string
 }
 
 type Creator struct {
@@ -32,12 +42,15 @@ type Creator struct {
 	locksmith         Locksmith
 	dependencyManager DependencyManager
 	metricsEmitter    MetricsEmitter
+	rootFSConfigurer  RootFSConfigurer
 }
 
+// IamCreator takes an optional rootFSConfigurer, which is given the upper
+// directory of each new image before it is returned.
 func IamCreator(
 	imageCloner ImageCloner, baseImagePuller BaseImagePuller,
 	locksmith Locksmith, dependencyManager DependencyManager,
-	metricsEmitter MetricsEmitter, cleaner Cleaner) *Creator {
+	metricsEmitter MetricsEmitter, cleaner Cleaner, rootFSConfigurer RootFSConfigurer) *Creator {
 	return &Creator{
 		imageCloner:       imageCloner,
 		baseImagePuller:   baseImagePuller,
@@ -45,6 +58,7 @@ func IamCreator(
 		dependencyManager: dependencyManager,
 		metricsEmitter:    metricsEmitter,
 		cleaner:           cleaner,
+		rootFSConfigurer:  rootFSConfigurer,
 	}
 }
 
@@ -67,7 +81,7 @@ func (c *Creator) Create(logger lager.Logger, spec CreateSpec) (info ImageInfo,
 		return ImageInfo{}, errorspkg.Errorf("image for id `%s` already exists", spec.ID)
 	}
 
-	ownerUid, ownerGid := c.parseOwner(spec.UIDMappings, spec.GIDMappings)
+	ownerUid, ownerGid := parseOwner(spec.UIDMappings, spec.GIDMappings)
 	baseImageSpec := BaseImageSpec{
 		DiskLimit:                 spec.DiskLimit,
 		ExcludeBaseImageFromQuota: spec.ExcludeBaseImageFromQuota,
@@ -92,7 +106,7 @@ func (c *Creator) Create(logger lager.Logger, spec CreateSpec) (info ImageInfo,
 			logger.Error("failed-to-unlock", err)
 		}
 		if spec.CleanOnCreate {
-			if _, err = c.cleaner.Clean(logger, spec.CleanOnCreateThresholdBytes); err != nil {
+			if _, err = c.cleaner.Clean(logger, spec.CleanOnCreateThresholdBytes, spec.CleanOnCreateTargetBytes); err != nil {
 				createErr = errorspkg.Wrap(err, "failed-to-cleanup-store")
 			}
 		}
@@ -108,9 +122,19 @@ func (c *Creator) Create(logger lager.Logger, spec CreateSpec) (info ImageInfo,
 		DiskLimit:                 spec.DiskLimit,
 		ExcludeBaseImageFromQuota: spec.ExcludeBaseImageFromQuota,
 		BaseVolumeIDs:             baseImageChainIDs,
+		MaxLayerDepth:             spec.MaxLayerDepth,
 		BaseImage:                 baseImageInfo.Config,
 		OwnerUID:                  ownerUid,
 		OwnerGID:                  ownerGid,
+		Labels:                    spec.Labels,
+		BaseImageDigest:           baseImageInfo.Digest,
+		ExtraLayerDiffIDs:         baseImageInfo.ExtraLayerDiffIDs,
+		ReadOnly:                  spec.ReadOnly,
+		EphemeralSizeBytes:        spec.EphemeralSizeBytes,
+		OverlayMountOptions:       spec.OverlayMountOptions,
+	}
+	if spec.BaseImageURL != nil {
+		imageSpec.BaseImageURL = spec.BaseImageURL.String()
 	}
 
 	image, err := c.imageCloner.Create(logger, imageSpec)
@@ -118,8 +142,30 @@ func (c *Creator) Create(logger lager.Logger, spec CreateSpec) (info ImageInfo,
 		return ImageInfo{}, errorspkg.Wrap(err, "making image")
 	}
 
+	if c.rootFSConfigurer != nil {
+		if err := c.rootFSConfigurer.Configure(image.UpperDir, &image.Image); err != nil {
+			if destroyErr := c.imageCloner.Destroy(logger, spec.ID); destroyErr != nil {
+				logger.Error("failed-to-destroy-image", destroyErr)
+			}
+
+			return ImageInfo{}, errorspkg.Wrap(err, "configuring rootfs")
+		}
+	}
+
+	// Flattening may have replaced some of the base volumes. The flattened
+	// volumes are registered along with the original ones, which are kept so
+	// that the next image from the same base doesn't pull them again.
+	volumeIDs := append([]string{}, baseImageChainIDs...)
+	for _, id := range image.BaseVolumeIDs {
+		if !containsString(volumeIDs, id) {
+			volumeIDs = append(volumeIDs, id)
+		}
+	}
+
 	imageRefName := fmt.Sprintf(ImageReferenceFormat, spec.ID)
-	if err := c.dependencyManager.Register(imageRefName, baseImageChainIDs); err != nil {
+	// The image labels are kept with its dependencies, so clean can tell which
+	// volumes labelled images are using.
+	if err := c.dependencyManager.RegisterWithMetadata(imageRefName, volumeIDs, spec.Labels); err != nil {
 		if destroyErr := c.imageCloner.Destroy(logger, spec.ID); destroyErr != nil {
 			logger.Error("failed-to-destroy-image", destroyErr)
 		}
@@ -127,6 +173,10 @@ func (c *Creator) Create(logger lager.Logger, spec CreateSpec) (info ImageInfo,
 		return ImageInfo{}, err
 	}
 
+	if err := c.baseImagePuller.TouchVolumes(logger, volumeIDs); err != nil {
+		logger.Error("failed-to-record-volume-use", err)
+	}
+
 	return image, nil
 }
 
@@ -138,7 +188,7 @@ func chainIDs(layerInfos []LayerInfo) []string {
 	return chainIDs
 }
 
-func (c *Creator) parseOwner(uidMappings, gidMappings []IDMappingSpec) (int, int) {
+func parseOwner(uidMappings, gidMappings []IDMappingSpec) (int, int) {
 	uid := os.Getuid()
 	gid := os.Getgid()
 
@@ -158,3 +208,12 @@ func (c *Creator) parseOwner(uidMappings, gidMappings []IDMappingSpec) (int, int
 
 	return uid, gid
 }
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
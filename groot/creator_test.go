@@ -67,6 +67,7 @@ var _ = Describe("Creator", func() {
 			Config: specsv1.Image{
 				Author: "Groot",
 			},
+			Digest: "sha256:base-image-digest",
 		}
 
 		pullError = nil
@@ -74,7 +75,7 @@ var _ = Describe("Creator", func() {
 		creator = groot.IamCreator(
 			fakeImageCloner, fakeBaseImagePuller, fakeLocksmith,
 			fakeDependencyManager, fakeMetricsEmitter,
-			fakeCleaner)
+			fakeCleaner, nil)
 	})
 
 	JustBeforeEach(func() {
@@ -103,16 +104,18 @@ var _ = Describe("Creator", func() {
 					BaseImageURL:                baseImageUrl,
 					CleanOnCreate:               true,
 					CleanOnCreateThresholdBytes: int64(250000),
+					CleanOnCreateTargetBytes:    int64(150000),
 				})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(fakeCleaner.CleanCallCount()).To(Equal(1))
-				_, cacheSize := fakeCleaner.CleanArgsForCall(0)
+				_, cacheSize, target := fakeCleaner.CleanArgsForCall(0)
 				Expect(cacheSize).To(Equal(int64(250000)))
+				Expect(target).To(Equal(int64(150000)))
 			})
 
 			Context("and fails to clean up", func() {
 				BeforeEach(func() {
-					fakeCleaner.CleanReturns(true, errors.New("failed to clean up store"))
+					fakeCleaner.CleanReturns(groot.CleanReport{}, errors.New("failed to clean up store"))
 				})
 
 				It("returns an error", func() {
@@ -164,11 +167,64 @@ var _ = Describe("Creator", func() {
 				BaseImage: specsv1.Image{
 					Author: "Groot",
 				},
-				OwnerUID: 50,
-				OwnerGID: 60,
+				OwnerUID:        50,
+				OwnerGID:        60,
+				BaseImageDigest: "sha256:base-image-digest",
 			}))
 		})
 
+		It("passes the diff ids of the extra layers to the image cloner", func() {
+			baseImageInfo.ExtraLayerDiffIDs = []string{"sha256:extra-diff"}
+			fakeBaseImagePuller.FetchBaseImageInfoReturns(baseImageInfo, nil)
+
+			_, err := creator.Create(logger, groot.CreateSpec{
+				ID:           "some-id",
+				BaseImageURL: baseImageUrl,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, createImagerSpec := fakeImageCloner.CreateArgsForCall(0)
+			Expect(createImagerSpec.ExtraLayerDiffIDs).To(Equal([]string{"sha256:extra-diff"}))
+		})
+
+		It("passes the read-only flag to the image cloner", func() {
+			_, err := creator.Create(logger, groot.CreateSpec{
+				ID:           "some-id",
+				BaseImageURL: baseImageUrl,
+				ReadOnly:     true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, createImagerSpec := fakeImageCloner.CreateArgsForCall(0)
+			Expect(createImagerSpec.ReadOnly).To(BeTrue())
+		})
+
+		It("passes the ephemeral size to the image cloner", func() {
+			_, err := creator.Create(logger, groot.CreateSpec{
+				ID:                 "some-id",
+				BaseImageURL:       baseImageUrl,
+				EphemeralSizeBytes: 1024,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, createImagerSpec := fakeImageCloner.CreateArgsForCall(0)
+			Expect(createImagerSpec.EphemeralSizeBytes).To(Equal(int64(1024)))
+		})
+
+		It("passes the base image URL to the image cloner", func() {
+			imageURL, err := url.Parse("docker:///ubuntu:latest")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = creator.Create(logger, groot.CreateSpec{
+				ID:           "some-id",
+				BaseImageURL: imageURL,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, createImagerSpec := fakeImageCloner.CreateArgsForCall(0)
+			Expect(createImagerSpec.BaseImageURL).To(Equal("docker:///ubuntu:latest"))
+		})
+
 		It("releases the global lock", func() {
 			_, err := creator.Create(logger, groot.CreateSpec{
 				BaseImageURL: baseImageUrl,
@@ -376,7 +432,7 @@ var _ = Describe("Creator", func() {
 
 		Context("when registering dependencies fails", func() {
 			BeforeEach(func() {
-				fakeDependencyManager.RegisterReturns(errors.New("failed to register dependencies"))
+				fakeDependencyManager.RegisterWithMetadataReturns(errors.New("failed to register dependencies"))
 			})
 
 			It("returns an errors", func() {
@@ -416,11 +472,181 @@ var _ = Describe("Creator", func() {
 					BaseImage: specsv1.Image{
 						Author: "Groot",
 					},
-					OwnerUID:  os.Getuid(),
-					OwnerGID:  os.Getgid(),
-					DiskLimit: int64(1024),
+					OwnerUID:        os.Getuid(),
+					OwnerGID:        os.Getgid(),
+					DiskLimit:       int64(1024),
+					BaseImageDigest: "sha256:base-image-digest",
 				}))
 			})
 		})
+
+		Context("when max layer depth is given", func() {
+			It("passes the max layer depth to the imageCloner", func() {
+				_, err := creator.Create(logger, groot.CreateSpec{
+					ID:            "some-id",
+					MaxLayerDepth: 4,
+					BaseImageURL:  baseImageUrl,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeImageCloner.CreateCallCount()).To(Equal(1))
+				_, createImagerSpec := fakeImageCloner.CreateArgsForCall(0)
+				Expect(createImagerSpec.MaxLayerDepth).To(Equal(4))
+			})
+		})
+
+		It("registers the base volumes as dependencies", func() {
+			_, err := creator.Create(logger, groot.CreateSpec{
+				ID:           "my-image",
+				BaseImageURL: baseImageUrl,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(1))
+			id, chainIDs, _ := fakeDependencyManager.RegisterWithMetadataArgsForCall(0)
+			Expect(id).To(Equal("image:my-image"))
+			Expect(chainIDs).To(Equal([]string{"id-1", "id-2"}))
+		})
+
+		It("records the registered volumes as used", func() {
+			_, err := creator.Create(logger, groot.CreateSpec{
+				ID:           "my-image",
+				BaseImageURL: baseImageUrl,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeBaseImagePuller.TouchVolumesCallCount()).To(Equal(1))
+			_, volumeIDs := fakeBaseImagePuller.TouchVolumesArgsForCall(0)
+			Expect(volumeIDs).To(Equal([]string{"id-1", "id-2"}))
+		})
+
+		Context("when labels are given", func() {
+			var labels map[string]string
+
+			BeforeEach(func() {
+				labels = map[string]string{"tenant": "acme"}
+			})
+
+			It("passes them to the image cloner", func() {
+				_, err := creator.Create(logger, groot.CreateSpec{
+					ID:           "my-image",
+					BaseImageURL: baseImageUrl,
+					Labels:       labels,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, createImagerSpec := fakeImageCloner.CreateArgsForCall(0)
+				Expect(createImagerSpec.Labels).To(Equal(labels))
+			})
+
+			It("registers them with the image dependencies", func() {
+				_, err := creator.Create(logger, groot.CreateSpec{
+					ID:           "my-image",
+					BaseImageURL: baseImageUrl,
+					Labels:       labels,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(1))
+				id, chainIDs, metadata := fakeDependencyManager.RegisterWithMetadataArgsForCall(0)
+				Expect(id).To(Equal("image:my-image"))
+				Expect(chainIDs).To(Equal([]string{"id-1", "id-2"}))
+				Expect(metadata).To(Equal(labels))
+			})
+		})
+
+		Context("when recording the volume use fails", func() {
+			BeforeEach(func() {
+				fakeBaseImagePuller.TouchVolumesReturns(errors.New("failed to touch"))
+			})
+
+			It("doesn't fail", func() {
+				_, err := creator.Create(logger, groot.CreateSpec{
+					ID:           "my-image",
+					BaseImageURL: baseImageUrl,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when the image cloner flattens the base volumes", func() {
+			BeforeEach(func() {
+				fakeImageCloner.CreateReturns(groot.ImageInfo{
+					Path:          "/path/to/images/123",
+					Rootfs:        "/path/to/images/123/rootfs",
+					BaseVolumeIDs: []string{"flat-id-1", "id-2"},
+				}, nil)
+			})
+
+			It("registers the flattened volumes along with the original ones", func() {
+				_, err := creator.Create(logger, groot.CreateSpec{
+					ID:            "my-image",
+					MaxLayerDepth: 2,
+					BaseImageURL:  baseImageUrl,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(1))
+				_, chainIDs, _ := fakeDependencyManager.RegisterWithMetadataArgsForCall(0)
+				Expect(chainIDs).To(Equal([]string{"id-1", "id-2", "flat-id-1"}))
+			})
+		})
+
+		Context("when a rootfs configurer is given", func() {
+			var fakeRootFSConfigurer *grootfakes.FakeRootFSConfigurer
+
+			BeforeEach(func() {
+				fakeRootFSConfigurer = new(grootfakes.FakeRootFSConfigurer)
+				fakeImageCloner.CreateReturns(groot.ImageInfo{
+					Path:     "/path/to/images/123",
+					Rootfs:   "/path/to/images/123/rootfs",
+					UpperDir: "/path/to/images/123/diff",
+					Image:    specsv1.Image{Author: "Groot"},
+				}, nil)
+
+				creator = groot.IamCreator(
+					fakeImageCloner, fakeBaseImagePuller, fakeLocksmith,
+					fakeDependencyManager, fakeMetricsEmitter,
+					fakeCleaner, fakeRootFSConfigurer)
+			})
+
+			It("configures the upper directory of the image", func() {
+				_, err := creator.Create(logger, groot.CreateSpec{
+					ID:           "my-image",
+					BaseImageURL: baseImageUrl,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeRootFSConfigurer.ConfigureCallCount()).To(Equal(1))
+				rootFSPath, baseImage := fakeRootFSConfigurer.ConfigureArgsForCall(0)
+				Expect(rootFSPath).To(Equal("/path/to/images/123/diff"))
+				Expect(baseImage.Author).To(Equal("Groot"))
+			})
+
+			Context("when configuring fails", func() {
+				BeforeEach(func() {
+					fakeRootFSConfigurer.ConfigureReturns(errors.New("failed to configure"))
+				})
+
+				It("returns the error", func() {
+					_, err := creator.Create(logger, groot.CreateSpec{
+						ID:           "my-image",
+						BaseImageURL: baseImageUrl,
+					})
+					Expect(err).To(MatchError("configuring rootfs: failed to configure"))
+				})
+
+				It("destroys the image without registering it", func() {
+					_, err := creator.Create(logger, groot.CreateSpec{
+						ID:           "my-image",
+						BaseImageURL: baseImageUrl,
+					})
+					Expect(err).To(HaveOccurred())
+
+					Expect(fakeImageCloner.DestroyCallCount()).To(Equal(1))
+					Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(0))
+				})
+			})
+		})
 	})
 })
@@ -10,6 +10,12 @@ import (
 	"code.cloudfoundry.org/lager"
 )
 
+// DeletedImageReferencePrefix prefixes the dependencies kept for labelled
+// images once they're deleted, so that clean can still retain their volumes by
+// label. Each deletion gets its own reference, as an ID can be reused.
+const DeletedImageReferencePrefix = "deleted-image:"
+const DeletedImageReferenceFormat = DeletedImageReferencePrefix + "%s@%d"
+
 type Deleter struct {
 	imageCloner       ImageCloner
 	dependencyManager DependencyManager
@@ -35,8 +41,7 @@ func (d *Deleter) Delete(logger lager.Logger, id string) error {
 		return err
 	}
 
-	imageRefName := fmt.Sprintf(ImageReferenceFormat, id)
-	if err := d.dependencyManager.Deregister(imageRefName); err != nil {
+	if err := DeregisterImage(d.dependencyManager, id); err != nil {
 		if !os.IsNotExist(errors.Cause(err)) {
 			logger.Error("failed-to-deregister-dependencies", err)
 			return err
@@ -45,3 +50,24 @@ func (d *Deleter) Delete(logger lager.Logger, id string) error {
 
 	return nil
 }
+
+// DeregisterImage removes the dependencies of a deleted image. The volumes of
+// an image with labels stay registered under a deleted image reference with
+// those labels.
+func DeregisterImage(dependencyManager DependencyManager, id string) error {
+	imageRefName := fmt.Sprintf(ImageReferenceFormat, id)
+	labels, err := dependencyManager.Metadata(imageRefName)
+	if err == nil && len(labels) > 0 {
+		chainIDs, err := dependencyManager.Dependencies(imageRefName)
+		if err != nil {
+			return errors.Wrap(err, "reading the dependencies of the deleted image")
+		}
+
+		deletedRefName := fmt.Sprintf(DeletedImageReferenceFormat, id, time.Now().UnixNano())
+		if err := dependencyManager.RegisterWithMetadata(deletedRefName, chainIDs, labels); err != nil {
+			return errors.Wrap(err, "recording the labels of the deleted image")
+		}
+	}
+
+	return dependencyManager.Deregister(imageRefName)
+}
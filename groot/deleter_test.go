@@ -42,6 +42,38 @@ var _ = Describe("Deleter", func() {
 		It("deregisters image dependencies", func() {
 			Expect(deleter.Delete(logger, "some-id")).To(Succeed())
 			Expect(fakeDependencyManager.DeregisterCallCount()).To(Equal(1))
+			Expect(fakeDependencyManager.DeregisterArgsForCall(0)).To(Equal("image:some-id"))
+			Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(0))
+		})
+
+		Context("when the image has labels", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.MetadataReturns(map[string]string{"tenant": "acme"}, nil)
+				fakeDependencyManager.DependenciesReturns([]string{"vol-1", "vol-2"}, nil)
+			})
+
+			It("keeps its volumes registered under a deleted image reference with its labels", func() {
+				Expect(deleter.Delete(logger, "some-id")).To(Succeed())
+
+				Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(1))
+				refName, chainIDs, labels := fakeDependencyManager.RegisterWithMetadataArgsForCall(0)
+				Expect(refName).To(HavePrefix(groot.DeletedImageReferencePrefix + "some-id@"))
+				Expect(chainIDs).To(Equal([]string{"vol-1", "vol-2"}))
+				Expect(labels).To(Equal(map[string]string{"tenant": "acme"}))
+
+				Expect(fakeDependencyManager.DeregisterArgsForCall(0)).To(Equal("image:some-id"))
+			})
+
+			Context("when recording them fails", func() {
+				BeforeEach(func() {
+					fakeDependencyManager.RegisterWithMetadataReturns(errors.New("disk full"))
+				})
+
+				It("returns an error and keeps the image dependencies", func() {
+					Expect(deleter.Delete(logger, "some-id")).To(MatchError(ContainSubstring("disk full")))
+					Expect(fakeDependencyManager.DeregisterCallCount()).To(Equal(0))
+				})
+			})
 		})
 
 		Context("when destroying a image fails", func() {
@@ -0,0 +1,80 @@
+package groot
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	errorspkg "github.com/pkg/errors"
+)
+
+// FileCopy is a host file written into a new image, as given to `create
+// --copy src:dst[:mode[:uid:gid]]`. UID and GID are ids inside the image,
+// and the mode defaults to the mode of the source file.
+type FileCopy struct {
+	Source      string
+	Destination string
+	Mode        os.FileMode
+	HasMode     bool
+	UID         int
+	GID         int
+}
+
+func ParseFileCopy(copySpec string) (FileCopy, error) {
+	invalid := func(reason string) error {
+		return errorspkg.Errorf("invalid copy `%s`: %s", copySpec, reason)
+	}
+
+	parts := strings.Split(copySpec, ":")
+	if len(parts) < 2 || len(parts) == 4 || len(parts) > 5 {
+		return FileCopy{}, invalid("expected src:dst[:mode[:uid:gid]]")
+	}
+	if parts[0] == "" || parts[1] == "" {
+		return FileCopy{}, invalid("source and destination can't be empty")
+	}
+	if !filepath.IsAbs(parts[1]) {
+		return FileCopy{}, invalid("destination must be an absolute path")
+	}
+
+	fileCopy := FileCopy{Source: parts[0], Destination: filepath.Clean(parts[1])}
+	if fileCopy.Destination == "/" {
+		return FileCopy{}, invalid("destination must be a file")
+	}
+
+	if len(parts) >= 3 {
+		mode, err := strconv.ParseUint(parts[2], 8, 32)
+		if err != nil || mode > 07777 {
+			return FileCopy{}, invalid("mode must be octal, e.g. 0644")
+		}
+		fileCopy.Mode = os.FileMode(mode)
+		fileCopy.HasMode = true
+	}
+
+	if len(parts) == 5 {
+		uid, err := strconv.ParseUint(parts[3], 10, 32)
+		if err != nil {
+			return FileCopy{}, invalid("uid must be a number")
+		}
+		gid, err := strconv.ParseUint(parts[4], 10, 32)
+		if err != nil {
+			return FileCopy{}, invalid("gid must be a number")
+		}
+		fileCopy.UID, fileCopy.GID = int(uid), int(gid)
+	}
+
+	return fileCopy, nil
+}
+
+func ParseFileCopies(copySpecs []string) ([]FileCopy, error) {
+	fileCopies := []FileCopy{}
+	for _, copySpec := range copySpecs {
+		fileCopy, err := ParseFileCopy(copySpec)
+		if err != nil {
+			return nil, err
+		}
+		fileCopies = append(fileCopies, fileCopy)
+	}
+
+	return fileCopies, nil
+}
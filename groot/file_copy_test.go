@@ -0,0 +1,86 @@
+package groot_test
+
+import (
+	"os"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileCopy", func() {
+	Describe("ParseFileCopy", func() {
+		It("parses the source and destination", func() {
+			fileCopy, err := groot.ParseFileCopy("/etc/hosts:/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileCopy).To(Equal(groot.FileCopy{Source: "/etc/hosts", Destination: "/etc/hosts"}))
+		})
+
+		It("parses an octal mode", func() {
+			fileCopy, err := groot.ParseFileCopy("certs.pem:/etc/ssl/certs.pem:0600")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileCopy.Mode).To(Equal(os.FileMode(0600)))
+			Expect(fileCopy.HasMode).To(BeTrue())
+		})
+
+		It("parses the owner", func() {
+			fileCopy, err := groot.ParseFileCopy("app.conf:/app/app.conf:0644:1000:1001")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileCopy.UID).To(Equal(1000))
+			Expect(fileCopy.GID).To(Equal(1001))
+		})
+
+		It("cleans the destination", func() {
+			fileCopy, err := groot.ParseFileCopy("hosts:/etc/../etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileCopy.Destination).To(Equal("/etc/hosts"))
+		})
+
+		expectInvalid := func(copySpec, reason string) {
+			_, err := groot.ParseFileCopy(copySpec)
+			ExpectWithOffset(1, err).To(MatchError(ContainSubstring(reason)))
+		}
+
+		It("rejects specs without a destination", func() {
+			expectInvalid("/etc/hosts", "expected src:dst[:mode[:uid:gid]]")
+			expectInvalid("/etc/hosts:", "source and destination can't be empty")
+		})
+
+		It("rejects relative destinations", func() {
+			expectInvalid("/etc/hosts:etc/hosts", "destination must be an absolute path")
+		})
+
+		It("rejects the root as destination", func() {
+			expectInvalid("/etc/hosts:/", "destination must be a file")
+		})
+
+		It("rejects invalid modes", func() {
+			expectInvalid("/etc/hosts:/etc/hosts:rw", "mode must be octal")
+			expectInvalid("/etc/hosts:/etc/hosts:0999", "mode must be octal")
+		})
+
+		It("rejects a uid without a gid", func() {
+			expectInvalid("/etc/hosts:/etc/hosts:0644:1000", "expected src:dst[:mode[:uid:gid]]")
+		})
+
+		It("rejects invalid owners", func() {
+			expectInvalid("/etc/hosts:/etc/hosts:0644:root:0", "uid must be a number")
+			expectInvalid("/etc/hosts:/etc/hosts:0644:0:root", "gid must be a number")
+		})
+	})
+
+	Describe("ParseFileCopies", func() {
+		It("parses all the copies", func() {
+			fileCopies, err := groot.ParseFileCopies([]string{"a:/a", "b:/b"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileCopies).To(HaveLen(2))
+		})
+
+		Context("when a copy is invalid", func() {
+			It("returns an error", func() {
+				_, err := groot.ParseFileCopies([]string{"a:/a", "b"})
+				Expect(err).To(MatchError(ContainSubstring("invalid copy `b`")))
+			})
+		})
+	})
+})
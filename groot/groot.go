@@ -29,10 +29,13 @@ const (
 //go:generate counterfeiter . MetricsEmitter
 
 type ImageInfo struct {
-	Rootfs string        `json:"rootfs"`
-	Image  specsv1.Image `json:"image,omitempty"`
-	Mounts []MountInfo   `json:"mounts,omitempty"`
-	Path   string        `json:"-"`
+	Rootfs        string        `json:"rootfs"`
+	Image         specsv1.Image `json:"image,omitempty"`
+	Mounts        []MountInfo   `json:"mounts,omitempty"`
+	Path          string        `json:"-"`
+	BaseVolumeIDs []string      `json:"-"`
+	UpperDir      string        `json:"-"`
+	ReadOnly      bool          `json:"-"`
 }
 
 type MountInfo struct {
@@ -76,11 +79,16 @@ type LayerInfo struct {
 type BaseImageInfo struct {
 	LayerInfos []LayerInfo
 	Config     specsv1.Image
+	Digest     string
+	// ExtraLayerDiffIDs are the diff IDs of the local layers stacked on top of
+	// the base image, which its Digest doesn't cover.
+	ExtraLayerDiffIDs []string
 }
 
 type BaseImagePuller interface {
 	FetchBaseImageInfo(logger lager.Logger) (BaseImageInfo, error)
 	Pull(logger lager.Logger, imageInfo BaseImageInfo, spec BaseImageSpec) error
+	TouchVolumes(logger lager.Logger, volumeIDs []string) error
 }
 
 type ImageSpec struct {
@@ -89,9 +97,17 @@ type ImageSpec struct {
 	DiskLimit                 int64
 	ExcludeBaseImageFromQuota bool
 	BaseVolumeIDs             []string
+	MaxLayerDepth             int
 	BaseImage                 specsv1.Image
 	OwnerUID                  int
 	OwnerGID                  int
+	Labels                    map[string]string
+	BaseImageURL              string
+	BaseImageDigest           string
+	ExtraLayerDiffIDs         []string
+	ReadOnly                  bool
+	EphemeralSizeBytes        int64
+	OverlayMountOptions       []string
 }
 
 type ImageCloner interface {
@@ -99,6 +115,12 @@ type ImageCloner interface {
 	Create(logger lager.Logger, spec ImageSpec) (ImageInfo, error)
 	Destroy(logger lager.Logger, id string) error
 	Stats(logger lager.Logger, id string) (VolumeStats, error)
+	AllStats(logger lager.Logger) (map[string]VolumeStats, error)
+	Mount(logger lager.Logger, id string) error
+	Unmount(logger lager.Logger, id string) error
+	MountAll(logger lager.Logger) ([]string, error)
+	Details(logger lager.Logger, id string) (ImageDetails, error)
+	Inspect(logger lager.Logger, id string) (ImageInspection, error)
 }
 
 type RootFSConfigurer interface {
@@ -107,18 +129,25 @@ type RootFSConfigurer interface {
 
 type DependencyManager interface {
 	Register(id string, chainIDs []string) error
+	RegisterWithMetadata(id string, chainIDs []string, metadata map[string]string) error
 	Deregister(id string) error
+	Dependencies(id string) ([]string, error)
+	Metadata(id string) (map[string]string, error)
+	List(prefix string) ([]string, error)
 }
 
 type GarbageCollector interface {
 	UnusedVolumes(logger lager.Logger) ([]string, error)
 	MarkUnused(logger lager.Logger, unusedVolumes []string) error
+	LeastRecentlyUsed(logger lager.Logger, volumeIDs []string, bytesToFree int64) ([]string, error)
 	Collect(logger lager.Logger) error
 }
 
 type StoreMeasurer interface {
 	CommittedQuota(logger lager.Logger) (int64, error)
 	TotalVolumesSize(logger lager.Logger) (int64, error)
+	TrashSize(logger lager.Logger) (int64, error)
+	VolumeSize(logger lager.Logger, id string) (int64, error)
 }
 
 type Locksmith interface {
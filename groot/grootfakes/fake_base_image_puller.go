@@ -35,6 +35,18 @@ type FakeBaseImagePuller struct {
 	pullReturnsOnCall map[int]struct {
 		result1 error
 	}
+	TouchVolumesStub        func(logger lager.Logger, volumeIDs []string) error
+	touchVolumesMutex       sync.RWMutex
+	touchVolumesArgsForCall []struct {
+		logger    lager.Logger
+		volumeIDs []string
+	}
+	touchVolumesReturns struct {
+		result1 error
+	}
+	touchVolumesReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -140,6 +152,60 @@ func (fake *FakeBaseImagePuller) PullReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeBaseImagePuller) TouchVolumes(logger lager.Logger, volumeIDs []string) error {
+	var volumeIDsCopy []string
+	if volumeIDs != nil {
+		volumeIDsCopy = make([]string, len(volumeIDs))
+		copy(volumeIDsCopy, volumeIDs)
+	}
+	fake.touchVolumesMutex.Lock()
+	ret, specificReturn := fake.touchVolumesReturnsOnCall[len(fake.touchVolumesArgsForCall)]
+	fake.touchVolumesArgsForCall = append(fake.touchVolumesArgsForCall, struct {
+		logger    lager.Logger
+		volumeIDs []string
+	}{logger, volumeIDsCopy})
+	fake.recordInvocation("TouchVolumes", []interface{}{logger, volumeIDsCopy})
+	fake.touchVolumesMutex.Unlock()
+	if fake.TouchVolumesStub != nil {
+		return fake.TouchVolumesStub(logger, volumeIDs)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.touchVolumesReturns.result1
+}
+
+func (fake *FakeBaseImagePuller) TouchVolumesCallCount() int {
+	fake.touchVolumesMutex.RLock()
+	defer fake.touchVolumesMutex.RUnlock()
+	return len(fake.touchVolumesArgsForCall)
+}
+
+func (fake *FakeBaseImagePuller) TouchVolumesArgsForCall(i int) (lager.Logger, []string) {
+	fake.touchVolumesMutex.RLock()
+	defer fake.touchVolumesMutex.RUnlock()
+	return fake.touchVolumesArgsForCall[i].logger, fake.touchVolumesArgsForCall[i].volumeIDs
+}
+
+func (fake *FakeBaseImagePuller) TouchVolumesReturns(result1 error) {
+	fake.TouchVolumesStub = nil
+	fake.touchVolumesReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeBaseImagePuller) TouchVolumesReturnsOnCall(i int, result1 error) {
+	fake.TouchVolumesStub = nil
+	if fake.touchVolumesReturnsOnCall == nil {
+		fake.touchVolumesReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.touchVolumesReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeBaseImagePuller) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -147,6 +213,8 @@ func (fake *FakeBaseImagePuller) Invocations() map[string][][]interface{} {
 	defer fake.fetchBaseImageInfoMutex.RUnlock()
 	fake.pullMutex.RLock()
 	defer fake.pullMutex.RUnlock()
+	fake.touchVolumesMutex.RLock()
+	defer fake.touchVolumesMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
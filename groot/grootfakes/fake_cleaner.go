@@ -9,35 +9,52 @@ import (
 )
 
 type FakeCleaner struct {
-	CleanStub        func(logger lager.Logger, cacheSize int64) (bool, error)
+	CleanStub        func(logger lager.Logger, threshold, target int64) (groot.CleanReport, error)
 	cleanMutex       sync.RWMutex
 	cleanArgsForCall []struct {
 		logger    lager.Logger
-		cacheSize int64
+		threshold int64
+		target    int64
 	}
 	cleanReturns struct {
-		result1 bool
+		result1 groot.CleanReport
 		result2 error
 	}
 	cleanReturnsOnCall map[int]struct {
-		result1 bool
+		result1 groot.CleanReport
+		result2 error
+	}
+	DryRunStub        func(logger lager.Logger, threshold, target int64) (groot.CleanReport, error)
+	dryRunMutex       sync.RWMutex
+	dryRunArgsForCall []struct {
+		logger    lager.Logger
+		threshold int64
+		target    int64
+	}
+	dryRunReturns struct {
+		result1 groot.CleanReport
+		result2 error
+	}
+	dryRunReturnsOnCall map[int]struct {
+		result1 groot.CleanReport
 		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeCleaner) Clean(logger lager.Logger, cacheSize int64) (bool, error) {
+func (fake *FakeCleaner) Clean(logger lager.Logger, threshold int64, target int64) (groot.CleanReport, error) {
 	fake.cleanMutex.Lock()
 	ret, specificReturn := fake.cleanReturnsOnCall[len(fake.cleanArgsForCall)]
 	fake.cleanArgsForCall = append(fake.cleanArgsForCall, struct {
 		logger    lager.Logger
-		cacheSize int64
-	}{logger, cacheSize})
-	fake.recordInvocation("Clean", []interface{}{logger, cacheSize})
+		threshold int64
+		target    int64
+	}{logger, threshold, target})
+	fake.recordInvocation("Clean", []interface{}{logger, threshold, target})
 	fake.cleanMutex.Unlock()
 	if fake.CleanStub != nil {
-		return fake.CleanStub(logger, cacheSize)
+		return fake.CleanStub(logger, threshold, target)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -51,30 +68,83 @@ func (fake *FakeCleaner) CleanCallCount() int {
 	return len(fake.cleanArgsForCall)
 }
 
-func (fake *FakeCleaner) CleanArgsForCall(i int) (lager.Logger, int64) {
+func (fake *FakeCleaner) CleanArgsForCall(i int) (lager.Logger, int64, int64) {
 	fake.cleanMutex.RLock()
 	defer fake.cleanMutex.RUnlock()
-	return fake.cleanArgsForCall[i].logger, fake.cleanArgsForCall[i].cacheSize
+	return fake.cleanArgsForCall[i].logger, fake.cleanArgsForCall[i].threshold, fake.cleanArgsForCall[i].target
 }
 
-func (fake *FakeCleaner) CleanReturns(result1 bool, result2 error) {
+func (fake *FakeCleaner) CleanReturns(result1 groot.CleanReport, result2 error) {
 	fake.CleanStub = nil
 	fake.cleanReturns = struct {
-		result1 bool
+		result1 groot.CleanReport
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeCleaner) CleanReturnsOnCall(i int, result1 bool, result2 error) {
+func (fake *FakeCleaner) CleanReturnsOnCall(i int, result1 groot.CleanReport, result2 error) {
 	fake.CleanStub = nil
 	if fake.cleanReturnsOnCall == nil {
 		fake.cleanReturnsOnCall = make(map[int]struct {
-			result1 bool
+			result1 groot.CleanReport
 			result2 error
 		})
 	}
 	fake.cleanReturnsOnCall[i] = struct {
-		result1 bool
+		result1 groot.CleanReport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCleaner) DryRun(logger lager.Logger, threshold int64, target int64) (groot.CleanReport, error) {
+	fake.dryRunMutex.Lock()
+	ret, specificReturn := fake.dryRunReturnsOnCall[len(fake.dryRunArgsForCall)]
+	fake.dryRunArgsForCall = append(fake.dryRunArgsForCall, struct {
+		logger    lager.Logger
+		threshold int64
+		target    int64
+	}{logger, threshold, target})
+	fake.recordInvocation("DryRun", []interface{}{logger, threshold, target})
+	fake.dryRunMutex.Unlock()
+	if fake.DryRunStub != nil {
+		return fake.DryRunStub(logger, threshold, target)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.dryRunReturns.result1, fake.dryRunReturns.result2
+}
+
+func (fake *FakeCleaner) DryRunCallCount() int {
+	fake.dryRunMutex.RLock()
+	defer fake.dryRunMutex.RUnlock()
+	return len(fake.dryRunArgsForCall)
+}
+
+func (fake *FakeCleaner) DryRunArgsForCall(i int) (lager.Logger, int64, int64) {
+	fake.dryRunMutex.RLock()
+	defer fake.dryRunMutex.RUnlock()
+	return fake.dryRunArgsForCall[i].logger, fake.dryRunArgsForCall[i].threshold, fake.dryRunArgsForCall[i].target
+}
+
+func (fake *FakeCleaner) DryRunReturns(result1 groot.CleanReport, result2 error) {
+	fake.DryRunStub = nil
+	fake.dryRunReturns = struct {
+		result1 groot.CleanReport
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCleaner) DryRunReturnsOnCall(i int, result1 groot.CleanReport, result2 error) {
+	fake.DryRunStub = nil
+	if fake.dryRunReturnsOnCall == nil {
+		fake.dryRunReturnsOnCall = make(map[int]struct {
+			result1 groot.CleanReport
+			result2 error
+		})
+	}
+	fake.dryRunReturnsOnCall[i] = struct {
+		result1 groot.CleanReport
 		result2 error
 	}{result1, result2}
 }
@@ -84,6 +154,8 @@ func (fake *FakeCleaner) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.cleanMutex.RLock()
 	defer fake.cleanMutex.RUnlock()
+	fake.dryRunMutex.RLock()
+	defer fake.dryRunMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -20,6 +20,19 @@ type FakeDependencyManager struct {
 	registerReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RegisterWithMetadataStub        func(id string, chainIDs []string, metadata map[string]string) error
+	registerWithMetadataMutex       sync.RWMutex
+	registerWithMetadataArgsForCall []struct {
+		id       string
+		chainIDs []string
+		metadata map[string]string
+	}
+	registerWithMetadataReturns struct {
+		result1 error
+	}
+	registerWithMetadataReturnsOnCall map[int]struct {
+		result1 error
+	}
 	DeregisterStub        func(id string) error
 	deregisterMutex       sync.RWMutex
 	deregisterArgsForCall []struct {
@@ -31,6 +44,45 @@ type FakeDependencyManager struct {
 	deregisterReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DependenciesStub        func(id string) ([]string, error)
+	dependenciesMutex       sync.RWMutex
+	dependenciesArgsForCall []struct {
+		id string
+	}
+	dependenciesReturns struct {
+		result1 []string
+		result2 error
+	}
+	dependenciesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	MetadataStub        func(id string) (map[string]string, error)
+	metadataMutex       sync.RWMutex
+	metadataArgsForCall []struct {
+		id string
+	}
+	metadataReturns struct {
+		result1 map[string]string
+		result2 error
+	}
+	metadataReturnsOnCall map[int]struct {
+		result1 map[string]string
+		result2 error
+	}
+	ListStub        func(prefix string) ([]string, error)
+	listMutex       sync.RWMutex
+	listArgsForCall []struct {
+		prefix string
+	}
+	listReturns struct {
+		result1 []string
+		result2 error
+	}
+	listReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -89,6 +141,61 @@ func (fake *FakeDependencyManager) RegisterReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeDependencyManager) RegisterWithMetadata(id string, chainIDs []string, metadata map[string]string) error {
+	var chainIDsCopy []string
+	if chainIDs != nil {
+		chainIDsCopy = make([]string, len(chainIDs))
+		copy(chainIDsCopy, chainIDs)
+	}
+	fake.registerWithMetadataMutex.Lock()
+	ret, specificReturn := fake.registerWithMetadataReturnsOnCall[len(fake.registerWithMetadataArgsForCall)]
+	fake.registerWithMetadataArgsForCall = append(fake.registerWithMetadataArgsForCall, struct {
+		id       string
+		chainIDs []string
+		metadata map[string]string
+	}{id, chainIDsCopy, metadata})
+	fake.recordInvocation("RegisterWithMetadata", []interface{}{id, chainIDsCopy, metadata})
+	fake.registerWithMetadataMutex.Unlock()
+	if fake.RegisterWithMetadataStub != nil {
+		return fake.RegisterWithMetadataStub(id, chainIDs, metadata)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.registerWithMetadataReturns.result1
+}
+
+func (fake *FakeDependencyManager) RegisterWithMetadataCallCount() int {
+	fake.registerWithMetadataMutex.RLock()
+	defer fake.registerWithMetadataMutex.RUnlock()
+	return len(fake.registerWithMetadataArgsForCall)
+}
+
+func (fake *FakeDependencyManager) RegisterWithMetadataArgsForCall(i int) (string, []string, map[string]string) {
+	fake.registerWithMetadataMutex.RLock()
+	defer fake.registerWithMetadataMutex.RUnlock()
+	return fake.registerWithMetadataArgsForCall[i].id, fake.registerWithMetadataArgsForCall[i].chainIDs, fake.registerWithMetadataArgsForCall[i].metadata
+}
+
+func (fake *FakeDependencyManager) RegisterWithMetadataReturns(result1 error) {
+	fake.RegisterWithMetadataStub = nil
+	fake.registerWithMetadataReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDependencyManager) RegisterWithMetadataReturnsOnCall(i int, result1 error) {
+	fake.RegisterWithMetadataStub = nil
+	if fake.registerWithMetadataReturnsOnCall == nil {
+		fake.registerWithMetadataReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.registerWithMetadataReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeDependencyManager) Deregister(id string) error {
 	fake.deregisterMutex.Lock()
 	ret, specificReturn := fake.deregisterReturnsOnCall[len(fake.deregisterArgsForCall)]
@@ -137,13 +244,174 @@ func (fake *FakeDependencyManager) DeregisterReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
+func (fake *FakeDependencyManager) Dependencies(id string) ([]string, error) {
+	fake.dependenciesMutex.Lock()
+	ret, specificReturn := fake.dependenciesReturnsOnCall[len(fake.dependenciesArgsForCall)]
+	fake.dependenciesArgsForCall = append(fake.dependenciesArgsForCall, struct {
+		id string
+	}{id})
+	fake.recordInvocation("Dependencies", []interface{}{id})
+	fake.dependenciesMutex.Unlock()
+	if fake.DependenciesStub != nil {
+		return fake.DependenciesStub(id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.dependenciesReturns.result1, fake.dependenciesReturns.result2
+}
+
+func (fake *FakeDependencyManager) DependenciesCallCount() int {
+	fake.dependenciesMutex.RLock()
+	defer fake.dependenciesMutex.RUnlock()
+	return len(fake.dependenciesArgsForCall)
+}
+
+func (fake *FakeDependencyManager) DependenciesArgsForCall(i int) string {
+	fake.dependenciesMutex.RLock()
+	defer fake.dependenciesMutex.RUnlock()
+	return fake.dependenciesArgsForCall[i].id
+}
+
+func (fake *FakeDependencyManager) DependenciesReturns(result1 []string, result2 error) {
+	fake.DependenciesStub = nil
+	fake.dependenciesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) DependenciesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.DependenciesStub = nil
+	if fake.dependenciesReturnsOnCall == nil {
+		fake.dependenciesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.dependenciesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) Metadata(id string) (map[string]string, error) {
+	fake.metadataMutex.Lock()
+	ret, specificReturn := fake.metadataReturnsOnCall[len(fake.metadataArgsForCall)]
+	fake.metadataArgsForCall = append(fake.metadataArgsForCall, struct {
+		id string
+	}{id})
+	fake.recordInvocation("Metadata", []interface{}{id})
+	fake.metadataMutex.Unlock()
+	if fake.MetadataStub != nil {
+		return fake.MetadataStub(id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.metadataReturns.result1, fake.metadataReturns.result2
+}
+
+func (fake *FakeDependencyManager) MetadataCallCount() int {
+	fake.metadataMutex.RLock()
+	defer fake.metadataMutex.RUnlock()
+	return len(fake.metadataArgsForCall)
+}
+
+func (fake *FakeDependencyManager) MetadataArgsForCall(i int) string {
+	fake.metadataMutex.RLock()
+	defer fake.metadataMutex.RUnlock()
+	return fake.metadataArgsForCall[i].id
+}
+
+func (fake *FakeDependencyManager) MetadataReturns(result1 map[string]string, result2 error) {
+	fake.MetadataStub = nil
+	fake.metadataReturns = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) MetadataReturnsOnCall(i int, result1 map[string]string, result2 error) {
+	fake.MetadataStub = nil
+	if fake.metadataReturnsOnCall == nil {
+		fake.metadataReturnsOnCall = make(map[int]struct {
+			result1 map[string]string
+			result2 error
+		})
+	}
+	fake.metadataReturnsOnCall[i] = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) List(prefix string) ([]string, error) {
+	fake.listMutex.Lock()
+	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
+	fake.listArgsForCall = append(fake.listArgsForCall, struct {
+		prefix string
+	}{prefix})
+	fake.recordInvocation("List", []interface{}{prefix})
+	fake.listMutex.Unlock()
+	if fake.ListStub != nil {
+		return fake.ListStub(prefix)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.listReturns.result1, fake.listReturns.result2
+}
+
+func (fake *FakeDependencyManager) ListCallCount() int {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return len(fake.listArgsForCall)
+}
+
+func (fake *FakeDependencyManager) ListArgsForCall(i int) string {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return fake.listArgsForCall[i].prefix
+}
+
+func (fake *FakeDependencyManager) ListReturns(result1 []string, result2 error) {
+	fake.ListStub = nil
+	fake.listReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) ListReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.ListStub = nil
+	if fake.listReturnsOnCall == nil {
+		fake.listReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.listReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeDependencyManager) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.registerMutex.RLock()
 	defer fake.registerMutex.RUnlock()
+	fake.registerWithMetadataMutex.RLock()
+	defer fake.registerWithMetadataMutex.RUnlock()
 	fake.deregisterMutex.RLock()
 	defer fake.deregisterMutex.RUnlock()
+	fake.dependenciesMutex.RLock()
+	defer fake.dependenciesMutex.RUnlock()
+	fake.metadataMutex.RLock()
+	defer fake.metadataMutex.RUnlock()
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
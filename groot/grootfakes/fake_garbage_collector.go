@@ -34,6 +34,21 @@ type FakeGarbageCollector struct {
 	markUnusedReturnsOnCall map[int]struct {
 		result1 error
 	}
+	LeastRecentlyUsedStub        func(logger lager.Logger, volumeIDs []string, bytesToFree int64) ([]string, error)
+	leastRecentlyUsedMutex       sync.RWMutex
+	leastRecentlyUsedArgsForCall []struct {
+		logger      lager.Logger
+		volumeIDs   []string
+		bytesToFree int64
+	}
+	leastRecentlyUsedReturns struct {
+		result1 []string
+		result2 error
+	}
+	leastRecentlyUsedReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	CollectStub        func(logger lager.Logger) error
 	collectMutex       sync.RWMutex
 	collectArgsForCall []struct {
@@ -154,6 +169,64 @@ func (fake *FakeGarbageCollector) MarkUnusedReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
+func (fake *FakeGarbageCollector) LeastRecentlyUsed(logger lager.Logger, volumeIDs []string, bytesToFree int64) ([]string, error) {
+	var volumeIDsCopy []string
+	if volumeIDs != nil {
+		volumeIDsCopy = make([]string, len(volumeIDs))
+		copy(volumeIDsCopy, volumeIDs)
+	}
+	fake.leastRecentlyUsedMutex.Lock()
+	ret, specificReturn := fake.leastRecentlyUsedReturnsOnCall[len(fake.leastRecentlyUsedArgsForCall)]
+	fake.leastRecentlyUsedArgsForCall = append(fake.leastRecentlyUsedArgsForCall, struct {
+		logger      lager.Logger
+		volumeIDs   []string
+		bytesToFree int64
+	}{logger, volumeIDsCopy, bytesToFree})
+	fake.recordInvocation("LeastRecentlyUsed", []interface{}{logger, volumeIDsCopy, bytesToFree})
+	fake.leastRecentlyUsedMutex.Unlock()
+	if fake.LeastRecentlyUsedStub != nil {
+		return fake.LeastRecentlyUsedStub(logger, volumeIDs, bytesToFree)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.leastRecentlyUsedReturns.result1, fake.leastRecentlyUsedReturns.result2
+}
+
+func (fake *FakeGarbageCollector) LeastRecentlyUsedCallCount() int {
+	fake.leastRecentlyUsedMutex.RLock()
+	defer fake.leastRecentlyUsedMutex.RUnlock()
+	return len(fake.leastRecentlyUsedArgsForCall)
+}
+
+func (fake *FakeGarbageCollector) LeastRecentlyUsedArgsForCall(i int) (lager.Logger, []string, int64) {
+	fake.leastRecentlyUsedMutex.RLock()
+	defer fake.leastRecentlyUsedMutex.RUnlock()
+	return fake.leastRecentlyUsedArgsForCall[i].logger, fake.leastRecentlyUsedArgsForCall[i].volumeIDs, fake.leastRecentlyUsedArgsForCall[i].bytesToFree
+}
+
+func (fake *FakeGarbageCollector) LeastRecentlyUsedReturns(result1 []string, result2 error) {
+	fake.LeastRecentlyUsedStub = nil
+	fake.leastRecentlyUsedReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGarbageCollector) LeastRecentlyUsedReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.LeastRecentlyUsedStub = nil
+	if fake.leastRecentlyUsedReturnsOnCall == nil {
+		fake.leastRecentlyUsedReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.leastRecentlyUsedReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGarbageCollector) Collect(logger lager.Logger) error {
 	fake.collectMutex.Lock()
 	ret, specificReturn := fake.collectReturnsOnCall[len(fake.collectArgsForCall)]
@@ -209,6 +282,8 @@ func (fake *FakeGarbageCollector) Invocations() map[string][][]interface{} {
 	defer fake.unusedVolumesMutex.RUnlock()
 	fake.markUnusedMutex.RLock()
 	defer fake.markUnusedMutex.RUnlock()
+	fake.leastRecentlyUsedMutex.RLock()
+	defer fake.leastRecentlyUsedMutex.RUnlock()
 	fake.collectMutex.RLock()
 	defer fake.collectMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -62,6 +62,84 @@ type FakeImageCloner struct {
 		result1 groot.VolumeStats
 		result2 error
 	}
+	AllStatsStub        func(logger lager.Logger) (map[string]groot.VolumeStats, error)
+	allStatsMutex       sync.RWMutex
+	allStatsArgsForCall []struct {
+		logger lager.Logger
+	}
+	allStatsReturns struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}
+	allStatsReturnsOnCall map[int]struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}
+	MountStub        func(logger lager.Logger, id string) error
+	mountMutex       sync.RWMutex
+	mountArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	mountReturns struct {
+		result1 error
+	}
+	mountReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UnmountStub        func(logger lager.Logger, id string) error
+	unmountMutex       sync.RWMutex
+	unmountArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	unmountReturns struct {
+		result1 error
+	}
+	unmountReturnsOnCall map[int]struct {
+		result1 error
+	}
+	MountAllStub        func(logger lager.Logger) ([]string, error)
+	mountAllMutex       sync.RWMutex
+	mountAllArgsForCall []struct {
+		logger lager.Logger
+	}
+	mountAllReturns struct {
+		result1 []string
+		result2 error
+	}
+	mountAllReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	DetailsStub        func(logger lager.Logger, id string) (groot.ImageDetails, error)
+	detailsMutex       sync.RWMutex
+	detailsArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	detailsReturns struct {
+		result1 groot.ImageDetails
+		result2 error
+	}
+	detailsReturnsOnCall map[int]struct {
+		result1 groot.ImageDetails
+		result2 error
+	}
+	InspectStub        func(logger lager.Logger, id string) (groot.ImageInspection, error)
+	inspectMutex       sync.RWMutex
+	inspectArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	inspectReturns struct {
+		result1 groot.ImageInspection
+		result2 error
+	}
+	inspectReturnsOnCall map[int]struct {
+		result1 groot.ImageInspection
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -270,6 +348,310 @@ func (fake *FakeImageCloner) StatsReturnsOnCall(i int, result1 groot.VolumeStats
 	}{result1, result2}
 }
 
+func (fake *FakeImageCloner) AllStats(logger lager.Logger) (map[string]groot.VolumeStats, error) {
+	fake.allStatsMutex.Lock()
+	ret, specificReturn := fake.allStatsReturnsOnCall[len(fake.allStatsArgsForCall)]
+	fake.allStatsArgsForCall = append(fake.allStatsArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("AllStats", []interface{}{logger})
+	fake.allStatsMutex.Unlock()
+	if fake.AllStatsStub != nil {
+		return fake.AllStatsStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.allStatsReturns.result1, fake.allStatsReturns.result2
+}
+
+func (fake *FakeImageCloner) AllStatsCallCount() int {
+	fake.allStatsMutex.RLock()
+	defer fake.allStatsMutex.RUnlock()
+	return len(fake.allStatsArgsForCall)
+}
+
+func (fake *FakeImageCloner) AllStatsArgsForCall(i int) lager.Logger {
+	fake.allStatsMutex.RLock()
+	defer fake.allStatsMutex.RUnlock()
+	return fake.allStatsArgsForCall[i].logger
+}
+
+func (fake *FakeImageCloner) AllStatsReturns(result1 map[string]groot.VolumeStats, result2 error) {
+	fake.AllStatsStub = nil
+	fake.allStatsReturns = struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageCloner) AllStatsReturnsOnCall(i int, result1 map[string]groot.VolumeStats, result2 error) {
+	fake.AllStatsStub = nil
+	if fake.allStatsReturnsOnCall == nil {
+		fake.allStatsReturnsOnCall = make(map[int]struct {
+			result1 map[string]groot.VolumeStats
+			result2 error
+		})
+	}
+	fake.allStatsReturnsOnCall[i] = struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageCloner) Mount(logger lager.Logger, id string) error {
+	fake.mountMutex.Lock()
+	ret, specificReturn := fake.mountReturnsOnCall[len(fake.mountArgsForCall)]
+	fake.mountArgsForCall = append(fake.mountArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("Mount", []interface{}{logger, id})
+	fake.mountMutex.Unlock()
+	if fake.MountStub != nil {
+		return fake.MountStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.mountReturns.result1
+}
+
+func (fake *FakeImageCloner) MountCallCount() int {
+	fake.mountMutex.RLock()
+	defer fake.mountMutex.RUnlock()
+	return len(fake.mountArgsForCall)
+}
+
+func (fake *FakeImageCloner) MountArgsForCall(i int) (lager.Logger, string) {
+	fake.mountMutex.RLock()
+	defer fake.mountMutex.RUnlock()
+	return fake.mountArgsForCall[i].logger, fake.mountArgsForCall[i].id
+}
+
+func (fake *FakeImageCloner) MountReturns(result1 error) {
+	fake.MountStub = nil
+	fake.mountReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageCloner) MountReturnsOnCall(i int, result1 error) {
+	fake.MountStub = nil
+	if fake.mountReturnsOnCall == nil {
+		fake.mountReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.mountReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageCloner) Unmount(logger lager.Logger, id string) error {
+	fake.unmountMutex.Lock()
+	ret, specificReturn := fake.unmountReturnsOnCall[len(fake.unmountArgsForCall)]
+	fake.unmountArgsForCall = append(fake.unmountArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("Unmount", []interface{}{logger, id})
+	fake.unmountMutex.Unlock()
+	if fake.UnmountStub != nil {
+		return fake.UnmountStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.unmountReturns.result1
+}
+
+func (fake *FakeImageCloner) UnmountCallCount() int {
+	fake.unmountMutex.RLock()
+	defer fake.unmountMutex.RUnlock()
+	return len(fake.unmountArgsForCall)
+}
+
+func (fake *FakeImageCloner) UnmountArgsForCall(i int) (lager.Logger, string) {
+	fake.unmountMutex.RLock()
+	defer fake.unmountMutex.RUnlock()
+	return fake.unmountArgsForCall[i].logger, fake.unmountArgsForCall[i].id
+}
+
+func (fake *FakeImageCloner) UnmountReturns(result1 error) {
+	fake.UnmountStub = nil
+	fake.unmountReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageCloner) UnmountReturnsOnCall(i int, result1 error) {
+	fake.UnmountStub = nil
+	if fake.unmountReturnsOnCall == nil {
+		fake.unmountReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.unmountReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageCloner) MountAll(logger lager.Logger) ([]string, error) {
+	fake.mountAllMutex.Lock()
+	ret, specificReturn := fake.mountAllReturnsOnCall[len(fake.mountAllArgsForCall)]
+	fake.mountAllArgsForCall = append(fake.mountAllArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("MountAll", []interface{}{logger})
+	fake.mountAllMutex.Unlock()
+	if fake.MountAllStub != nil {
+		return fake.MountAllStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.mountAllReturns.result1, fake.mountAllReturns.result2
+}
+
+func (fake *FakeImageCloner) MountAllCallCount() int {
+	fake.mountAllMutex.RLock()
+	defer fake.mountAllMutex.RUnlock()
+	return len(fake.mountAllArgsForCall)
+}
+
+func (fake *FakeImageCloner) MountAllArgsForCall(i int) lager.Logger {
+	fake.mountAllMutex.RLock()
+	defer fake.mountAllMutex.RUnlock()
+	return fake.mountAllArgsForCall[i].logger
+}
+
+func (fake *FakeImageCloner) MountAllReturns(result1 []string, result2 error) {
+	fake.MountAllStub = nil
+	fake.mountAllReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageCloner) MountAllReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.MountAllStub = nil
+	if fake.mountAllReturnsOnCall == nil {
+		fake.mountAllReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.mountAllReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageCloner) Details(logger lager.Logger, id string) (groot.ImageDetails, error) {
+	fake.detailsMutex.Lock()
+	ret, specificReturn := fake.detailsReturnsOnCall[len(fake.detailsArgsForCall)]
+	fake.detailsArgsForCall = append(fake.detailsArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("Details", []interface{}{logger, id})
+	fake.detailsMutex.Unlock()
+	if fake.DetailsStub != nil {
+		return fake.DetailsStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.detailsReturns.result1, fake.detailsReturns.result2
+}
+
+func (fake *FakeImageCloner) DetailsCallCount() int {
+	fake.detailsMutex.RLock()
+	defer fake.detailsMutex.RUnlock()
+	return len(fake.detailsArgsForCall)
+}
+
+func (fake *FakeImageCloner) DetailsArgsForCall(i int) (lager.Logger, string) {
+	fake.detailsMutex.RLock()
+	defer fake.detailsMutex.RUnlock()
+	return fake.detailsArgsForCall[i].logger, fake.detailsArgsForCall[i].id
+}
+
+func (fake *FakeImageCloner) DetailsReturns(result1 groot.ImageDetails, result2 error) {
+	fake.DetailsStub = nil
+	fake.detailsReturns = struct {
+		result1 groot.ImageDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageCloner) DetailsReturnsOnCall(i int, result1 groot.ImageDetails, result2 error) {
+	fake.DetailsStub = nil
+	if fake.detailsReturnsOnCall == nil {
+		fake.detailsReturnsOnCall = make(map[int]struct {
+			result1 groot.ImageDetails
+			result2 error
+		})
+	}
+	fake.detailsReturnsOnCall[i] = struct {
+		result1 groot.ImageDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageCloner) Inspect(logger lager.Logger, id string) (groot.ImageInspection, error) {
+	fake.inspectMutex.Lock()
+	ret, specificReturn := fake.inspectReturnsOnCall[len(fake.inspectArgsForCall)]
+	fake.inspectArgsForCall = append(fake.inspectArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("Inspect", []interface{}{logger, id})
+	fake.inspectMutex.Unlock()
+	if fake.InspectStub != nil {
+		return fake.InspectStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.inspectReturns.result1, fake.inspectReturns.result2
+}
+
+func (fake *FakeImageCloner) InspectCallCount() int {
+	fake.inspectMutex.RLock()
+	defer fake.inspectMutex.RUnlock()
+	return len(fake.inspectArgsForCall)
+}
+
+func (fake *FakeImageCloner) InspectArgsForCall(i int) (lager.Logger, string) {
+	fake.inspectMutex.RLock()
+	defer fake.inspectMutex.RUnlock()
+	return fake.inspectArgsForCall[i].logger, fake.inspectArgsForCall[i].id
+}
+
+func (fake *FakeImageCloner) InspectReturns(result1 groot.ImageInspection, result2 error) {
+	fake.InspectStub = nil
+	fake.inspectReturns = struct {
+		result1 groot.ImageInspection
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageCloner) InspectReturnsOnCall(i int, result1 groot.ImageInspection, result2 error) {
+	fake.InspectStub = nil
+	if fake.inspectReturnsOnCall == nil {
+		fake.inspectReturnsOnCall = make(map[int]struct {
+			result1 groot.ImageInspection
+			result2 error
+		})
+	}
+	fake.inspectReturnsOnCall[i] = struct {
+		result1 groot.ImageInspection
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeImageCloner) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -281,6 +663,18 @@ func (fake *FakeImageCloner) Invocations() map[string][][]interface{} {
 	defer fake.destroyMutex.RUnlock()
 	fake.statsMutex.RLock()
 	defer fake.statsMutex.RUnlock()
+	fake.allStatsMutex.RLock()
+	defer fake.allStatsMutex.RUnlock()
+	fake.mountMutex.RLock()
+	defer fake.mountMutex.RUnlock()
+	fake.unmountMutex.RLock()
+	defer fake.unmountMutex.RUnlock()
+	fake.mountAllMutex.RLock()
+	defer fake.mountAllMutex.RUnlock()
+	fake.detailsMutex.RLock()
+	defer fake.detailsMutex.RUnlock()
+	fake.inspectMutex.RLock()
+	defer fake.inspectMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
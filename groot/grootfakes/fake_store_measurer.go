@@ -35,6 +35,33 @@ type FakeStoreMeasurer struct {
 		result1 int64
 		result2 error
 	}
+	TrashSizeStub        func(logger lager.Logger) (int64, error)
+	trashSizeMutex       sync.RWMutex
+	trashSizeArgsForCall []struct {
+		logger lager.Logger
+	}
+	trashSizeReturns struct {
+		result1 int64
+		result2 error
+	}
+	trashSizeReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
+	VolumeSizeStub        func(logger lager.Logger, id string) (int64, error)
+	volumeSizeMutex       sync.RWMutex
+	volumeSizeArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	volumeSizeReturns struct {
+		result1 int64
+		result2 error
+	}
+	volumeSizeReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -141,6 +168,109 @@ func (fake *FakeStoreMeasurer) TotalVolumesSizeReturnsOnCall(i int, result1 int6
 	}{result1, result2}
 }
 
+func (fake *FakeStoreMeasurer) TrashSize(logger lager.Logger) (int64, error) {
+	fake.trashSizeMutex.Lock()
+	ret, specificReturn := fake.trashSizeReturnsOnCall[len(fake.trashSizeArgsForCall)]
+	fake.trashSizeArgsForCall = append(fake.trashSizeArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("TrashSize", []interface{}{logger})
+	fake.trashSizeMutex.Unlock()
+	if fake.TrashSizeStub != nil {
+		return fake.TrashSizeStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.trashSizeReturns.result1, fake.trashSizeReturns.result2
+}
+
+func (fake *FakeStoreMeasurer) TrashSizeCallCount() int {
+	fake.trashSizeMutex.RLock()
+	defer fake.trashSizeMutex.RUnlock()
+	return len(fake.trashSizeArgsForCall)
+}
+
+func (fake *FakeStoreMeasurer) TrashSizeArgsForCall(i int) lager.Logger {
+	fake.trashSizeMutex.RLock()
+	defer fake.trashSizeMutex.RUnlock()
+	return fake.trashSizeArgsForCall[i].logger
+}
+
+func (fake *FakeStoreMeasurer) TrashSizeReturns(result1 int64, result2 error) {
+	fake.TrashSizeStub = nil
+	fake.trashSizeReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStoreMeasurer) TrashSizeReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.TrashSizeStub = nil
+	if fake.trashSizeReturnsOnCall == nil {
+		fake.trashSizeReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.trashSizeReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStoreMeasurer) VolumeSize(logger lager.Logger, id string) (int64, error) {
+	fake.volumeSizeMutex.Lock()
+	ret, specificReturn := fake.volumeSizeReturnsOnCall[len(fake.volumeSizeArgsForCall)]
+	fake.volumeSizeArgsForCall = append(fake.volumeSizeArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("VolumeSize", []interface{}{logger, id})
+	fake.volumeSizeMutex.Unlock()
+	if fake.VolumeSizeStub != nil {
+		return fake.VolumeSizeStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.volumeSizeReturns.result1, fake.volumeSizeReturns.result2
+}
+
+func (fake *FakeStoreMeasurer) VolumeSizeCallCount() int {
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	return len(fake.volumeSizeArgsForCall)
+}
+
+func (fake *FakeStoreMeasurer) VolumeSizeArgsForCall(i int) (lager.Logger, string) {
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	return fake.volumeSizeArgsForCall[i].logger, fake.volumeSizeArgsForCall[i].id
+}
+
+func (fake *FakeStoreMeasurer) VolumeSizeReturns(result1 int64, result2 error) {
+	fake.VolumeSizeStub = nil
+	fake.volumeSizeReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStoreMeasurer) VolumeSizeReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.VolumeSizeStub = nil
+	if fake.volumeSizeReturnsOnCall == nil {
+		fake.volumeSizeReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.volumeSizeReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeStoreMeasurer) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -148,6 +278,10 @@ func (fake *FakeStoreMeasurer) Invocations() map[string][][]interface{} {
 	defer fake.committedQuotaMutex.RUnlock()
 	fake.totalVolumesSizeMutex.RLock()
 	defer fake.totalVolumesSizeMutex.RUnlock()
+	fake.trashSizeMutex.RLock()
+	defer fake.trashSizeMutex.RUnlock()
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,113 @@
+package groot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	errorspkg "github.com/pkg/errors"
+)
+
+const (
+	ImageMetadataFilename   = "image_metadata.json"
+	BaseImageConfigFilename = "base_image_config.json"
+)
+
+// ImageMetadata is the provenance of an image, recorded when it is created.
+type ImageMetadata struct {
+	ID                 string    `json:"id"`
+	Created            time.Time `json:"created"`
+	BaseImageURL       string    `json:"base_image_url"`
+	BaseImageDigest    string    `json:"base_image_digest"`
+	ExtraLayerDiffIDs  []string  `json:"extra_layer_diff_ids,omitempty"`
+	ChainIDs           []string  `json:"chain_ids"`
+	DiskLimit          int64     `json:"disk_limit"`
+	ExclusiveDiskLimit bool      `json:"exclusive_disk_limit"`
+	ReadOnly           bool      `json:"read_only,omitempty"`
+	EphemeralSizeBytes int64     `json:"ephemeral_size_bytes,omitempty"`
+}
+
+// ImageDetails is what `list --json` reports for each image.
+type ImageDetails struct {
+	ImageMetadata
+	Path      string            `json:"path"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Mounted   bool              `json:"mounted"`
+	DiskUsage DiskUsage         `json:"disk_usage"`
+}
+
+func WriteImageMetadata(imagePath string, metadata ImageMetadata) error {
+	contents, err := json.Marshal(metadata)
+	if err != nil {
+		return errorspkg.Wrap(err, "encoding image metadata")
+	}
+
+	metadataPath := filepath.Join(imagePath, ImageMetadataFilename)
+	if err := ioutil.WriteFile(metadataPath, contents, 0644); err != nil {
+		return errorspkg.Wrapf(err, "writing image metadata %s", metadataPath)
+	}
+
+	return nil
+}
+
+// ReadImageMetadata falls back to the ID and the modification time of the
+// image directory for images created before metadata was recorded.
+func ReadImageMetadata(imagePath string) (ImageMetadata, error) {
+	metadataPath := filepath.Join(imagePath, ImageMetadataFilename)
+	contents, err := ioutil.ReadFile(metadataPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return ImageMetadata{}, errorspkg.Wrapf(err, "reading image metadata %s", metadataPath)
+		}
+
+		stat, err := os.Stat(imagePath)
+		if err != nil {
+			return ImageMetadata{}, errorspkg.Wrapf(err, "reading image metadata %s", metadataPath)
+		}
+		return ImageMetadata{ID: filepath.Base(imagePath), Created: stat.ModTime()}, nil
+	}
+
+	var metadata ImageMetadata
+	if err := json.Unmarshal(contents, &metadata); err != nil {
+		return ImageMetadata{}, errorspkg.Wrapf(err, "parsing image metadata %s", metadataPath)
+	}
+
+	return metadata, nil
+}
+
+func WriteBaseImageConfig(imagePath string, config specsv1.Image) error {
+	contents, err := json.Marshal(config)
+	if err != nil {
+		return errorspkg.Wrap(err, "encoding base image config")
+	}
+
+	configPath := filepath.Join(imagePath, BaseImageConfigFilename)
+	if err := ioutil.WriteFile(configPath, contents, 0644); err != nil {
+		return errorspkg.Wrapf(err, "writing base image config %s", configPath)
+	}
+
+	return nil
+}
+
+// ReadBaseImageConfig returns an empty config for images created before the
+// config was recorded.
+func ReadBaseImageConfig(imagePath string) (specsv1.Image, error) {
+	configPath := filepath.Join(imagePath, BaseImageConfigFilename)
+	contents, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return specsv1.Image{}, nil
+		}
+		return specsv1.Image{}, errorspkg.Wrapf(err, "reading base image config %s", configPath)
+	}
+
+	var config specsv1.Image
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return specsv1.Image{}, errorspkg.Wrapf(err, "parsing base image config %s", configPath)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,52 @@
+package groot
+
+import (
+	"code.cloudfoundry.org/lager"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type VolumeDetails struct {
+	ID        string `json:"id"`
+	SizeBytes int64  `json:"size_bytes"`
+	Missing   bool   `json:"missing,omitempty"`
+}
+
+// ImageFilesystemDetails is what the filesystem driver knows about an image.
+// Mount is left empty when a base volume is missing.
+type ImageFilesystemDetails struct {
+	Volumes    []VolumeDetails `json:"volumes"`
+	Mount      MountInfo       `json:"mount"`
+	ProjectID  uint32          `json:"project_id"`
+	QuotaBytes int64           `json:"quota_bytes"`
+}
+
+type ImageInspection struct {
+	ImageDetails
+	ImageFilesystemDetails
+	BaseImage   specsv1.Image `json:"base_image"`
+	BindVolumes []MountInfo   `json:"bind_volumes"`
+}
+
+type Inspector struct {
+	imageCloner ImageCloner
+}
+
+func IamInspector(imageCloner ImageCloner) *Inspector {
+	return &Inspector{
+		imageCloner: imageCloner,
+	}
+}
+
+func (i *Inspector) Inspect(logger lager.Logger, id string) (ImageInspection, error) {
+	logger = logger.Session("groot-inspecting", lager.Data{"imageID": id})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	inspection, err := i.imageCloner.Inspect(logger, id)
+	if err != nil {
+		logger.Error("inspecting-image", err, lager.Data{"id": id})
+		return ImageInspection{}, err
+	}
+
+	return inspection, nil
+}
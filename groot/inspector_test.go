@@ -0,0 +1,60 @@
+package groot_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspector", func() {
+	var (
+		fakeImageCloner *grootfakes.FakeImageCloner
+		inspector       *groot.Inspector
+		logger          lager.Logger
+	)
+
+	BeforeEach(func() {
+		fakeImageCloner = new(grootfakes.FakeImageCloner)
+		inspector = groot.IamInspector(fakeImageCloner)
+		logger = lagertest.NewTestLogger("inspector")
+	})
+
+	Describe("Inspect", func() {
+		It("returns the inspection from the imageCloner", func() {
+			inspection := groot.ImageInspection{
+				ImageDetails: groot.ImageDetails{
+					ImageMetadata: groot.ImageMetadata{ID: "some-id"},
+					Mounted:       true,
+				},
+				ImageFilesystemDetails: groot.ImageFilesystemDetails{
+					Volumes:   []groot.VolumeDetails{{ID: "volume-1", SizeBytes: 1024}},
+					ProjectID: 10,
+				},
+			}
+			fakeImageCloner.InspectReturns(inspection, nil)
+
+			returnedInspection, err := inspector.Inspect(logger, "some-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(returnedInspection).To(Equal(inspection))
+
+			Expect(fakeImageCloner.InspectCallCount()).To(Equal(1))
+			_, id := fakeImageCloner.InspectArgsForCall(0)
+			Expect(id).To(Equal("some-id"))
+		})
+
+		Context("when imageCloner fails", func() {
+			It("returns an error", func() {
+				fakeImageCloner.InspectReturns(groot.ImageInspection{}, errors.New("sorry"))
+
+				_, err := inspector.Inspect(logger, "some-id")
+				Expect(err).To(MatchError(ContainSubstring("sorry")))
+			})
+		})
+	})
+})
@@ -0,0 +1,146 @@
+package groot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	errorspkg "github.com/pkg/errors"
+)
+
+const (
+	ImageLabelsFilename = "labels.json"
+	labelFilterPrefix   = "label="
+)
+
+// LabelSelector matches label sets that have Key, and Value too when
+// HasValue is set.
+type LabelSelector struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// ParseLabels parses `key=value` pairs as given to `create --label`.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errorspkg.Errorf("invalid label `%s`: expected key=value", pair)
+		}
+		labels[parts[0]] = parts[1]
+	}
+
+	return labels, nil
+}
+
+// ParseLabelSelector parses `key` or `key=value`.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	parts := strings.SplitN(selector, "=", 2)
+	if parts[0] == "" {
+		return LabelSelector{}, errorspkg.Errorf("invalid label selector `%s`: expected key or key=value", selector)
+	}
+
+	if len(parts) == 1 {
+		return LabelSelector{Key: parts[0]}, nil
+	}
+	return LabelSelector{Key: parts[0], Value: parts[1], HasValue: true}, nil
+}
+
+// ParseLabelFilter parses `label=key` or `label=key=value`, as given to
+// `list --filter` and `delete --filter`.
+func ParseLabelFilter(filter string) (LabelSelector, error) {
+	if !strings.HasPrefix(filter, labelFilterPrefix) {
+		return LabelSelector{}, errorspkg.Errorf("invalid filter `%s`: only label=key[=value] is supported", filter)
+	}
+
+	return ParseLabelSelector(strings.TrimPrefix(filter, labelFilterPrefix))
+}
+
+func ParseLabelFilters(filters []string) ([]LabelSelector, error) {
+	selectors := []LabelSelector{}
+	for _, filter := range filters {
+		selector, err := ParseLabelFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+
+	return selectors, nil
+}
+
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	value, ok := labels[s.Key]
+	if !ok {
+		return false
+	}
+
+	return !s.HasValue || value == s.Value
+}
+
+func (s LabelSelector) String() string {
+	if !s.HasValue {
+		return s.Key
+	}
+	return s.Key + "=" + s.Value
+}
+
+// MatchesAllLabels is true when every selector matches, including when there
+// are none.
+func MatchesAllLabels(selectors []LabelSelector, labels map[string]string) bool {
+	for _, selector := range selectors {
+		if !selector.Matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchesAnyLabel is false when there are no selectors.
+func MatchesAnyLabel(selectors []LabelSelector, labels map[string]string) bool {
+	for _, selector := range selectors {
+		if selector.Matches(labels) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func WriteImageLabels(imagePath string, labels map[string]string) error {
+	contents, err := json.Marshal(labels)
+	if err != nil {
+		return errorspkg.Wrap(err, "encoding image labels")
+	}
+
+	labelsPath := filepath.Join(imagePath, ImageLabelsFilename)
+	if err := ioutil.WriteFile(labelsPath, contents, 0644); err != nil {
+		return errorspkg.Wrapf(err, "writing image labels %s", labelsPath)
+	}
+
+	return nil
+}
+
+// ReadImageLabels returns no labels for images created without any.
+func ReadImageLabels(imagePath string) (map[string]string, error) {
+	labelsPath := filepath.Join(imagePath, ImageLabelsFilename)
+	contents, err := ioutil.ReadFile(labelsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, errorspkg.Wrapf(err, "reading image labels %s", labelsPath)
+	}
+
+	labels := map[string]string{}
+	if err := json.Unmarshal(contents, &labels); err != nil {
+		return nil, errorspkg.Wrapf(err, "parsing image labels %s", labelsPath)
+	}
+
+	return labels, nil
+}
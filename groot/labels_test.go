@@ -0,0 +1,108 @@
+package groot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Labels", func() {
+	Describe("ParseLabels", func() {
+		It("parses key=value pairs", func() {
+			labels, err := groot.ParseLabels([]string{"tenant=acme", "empty=", "url=a=b"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(Equal(map[string]string{"tenant": "acme", "empty": "", "url": "a=b"}))
+		})
+
+		It("returns an error when the value is missing", func() {
+			_, err := groot.ParseLabels([]string{"tenant"})
+			Expect(err).To(MatchError(ContainSubstring("invalid label `tenant`")))
+		})
+
+		It("returns an error when the key is empty", func() {
+			_, err := groot.ParseLabels([]string{"=acme"})
+			Expect(err).To(MatchError(ContainSubstring("invalid label `=acme`")))
+		})
+	})
+
+	Describe("ParseLabelFilter", func() {
+		It("parses label=key", func() {
+			selector, err := groot.ParseLabelFilter("label=tenant")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selector).To(Equal(groot.LabelSelector{Key: "tenant"}))
+		})
+
+		It("parses label=key=value", func() {
+			selector, err := groot.ParseLabelFilter("label=tenant=acme")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selector).To(Equal(groot.LabelSelector{Key: "tenant", Value: "acme", HasValue: true}))
+		})
+
+		It("returns an error for other filters", func() {
+			_, err := groot.ParseLabelFilter("name=foo")
+			Expect(err).To(MatchError(ContainSubstring("only label=key[=value] is supported")))
+		})
+
+		It("returns an error when the key is empty", func() {
+			_, err := groot.ParseLabelFilter("label==acme")
+			Expect(err).To(MatchError(ContainSubstring("invalid label selector")))
+		})
+	})
+
+	Describe("LabelSelector", func() {
+		labels := map[string]string{"tenant": "acme"}
+
+		It("matches on the key alone when it has no value", func() {
+			Expect(groot.LabelSelector{Key: "tenant"}.Matches(labels)).To(BeTrue())
+			Expect(groot.LabelSelector{Key: "tier"}.Matches(labels)).To(BeFalse())
+		})
+
+		It("matches on the value when it has one", func() {
+			Expect(groot.LabelSelector{Key: "tenant", Value: "acme", HasValue: true}.Matches(labels)).To(BeTrue())
+			Expect(groot.LabelSelector{Key: "tenant", Value: "", HasValue: true}.Matches(labels)).To(BeFalse())
+		})
+
+		It("requires every selector to match in MatchesAllLabels", func() {
+			Expect(groot.MatchesAllLabels(nil, labels)).To(BeTrue())
+			Expect(groot.MatchesAllLabels([]groot.LabelSelector{{Key: "tenant"}, {Key: "tier"}}, labels)).To(BeFalse())
+		})
+
+		It("requires one selector to match in MatchesAnyLabel", func() {
+			Expect(groot.MatchesAnyLabel(nil, labels)).To(BeFalse())
+			Expect(groot.MatchesAnyLabel([]groot.LabelSelector{{Key: "tenant"}, {Key: "tier"}}, labels)).To(BeTrue())
+		})
+	})
+
+	Describe("image labels", func() {
+		var imagePath string
+
+		BeforeEach(func() {
+			var err error
+			imagePath, err = ioutil.TempDir("", "image")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(imagePath)).To(Succeed())
+		})
+
+		It("reads back the written labels", func() {
+			Expect(groot.WriteImageLabels(imagePath, map[string]string{"tenant": "acme"})).To(Succeed())
+			Expect(filepath.Join(imagePath, groot.ImageLabelsFilename)).To(BeAnExistingFile())
+
+			labels, err := groot.ReadImageLabels(imagePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(Equal(map[string]string{"tenant": "acme"}))
+		})
+
+		It("reads no labels when the image has none", func() {
+			labels, err := groot.ReadImageLabels(imagePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(BeEmpty())
+		})
+	})
+})
@@ -10,14 +10,18 @@ import (
 )
 
 type Lister struct {
+	imageCloner ImageCloner
 }
 
-func IamLister() *Lister {
-	return &Lister{}
+func IamLister(imageCloner ImageCloner) *Lister {
+	return &Lister{
+		imageCloner: imageCloner,
+	}
 }
 
-func (l *Lister) List(logger lager.Logger, storePath string) ([]string, error) {
-	logger = logger.Session("groot-listing", lager.Data{"storePath": storePath})
+// List returns the paths of the images that match all the selectors.
+func (l *Lister) List(logger lager.Logger, storePath string, selectors ...LabelSelector) ([]string, error) {
+	logger = logger.Session("groot-listing", lager.Data{"storePath": storePath, "selectors": selectors})
 	logger.Info("starting")
 	defer logger.Info("ending")
 
@@ -26,10 +30,46 @@ func (l *Lister) List(logger lager.Logger, storePath string) ([]string, error) {
 		return nil, errorspkg.Wrap(err, "failed to list store path")
 	}
 
+	if len(selectors) > 0 {
+		if imagePaths, err = l.filter(logger, imagePaths, selectors); err != nil {
+			return nil, err
+		}
+	}
+
 	logger.Debug("list-images", lager.Data{"imagePaths": imagePaths})
 	return imagePaths, nil
 }
 
+// ListDetails returns the details of the images that match all the selectors.
+func (l *Lister) ListDetails(logger lager.Logger, storePath string, selectors ...LabelSelector) ([]ImageDetails, error) {
+	imagePaths, err := l.List(logger, storePath, selectors...)
+	if err != nil {
+		return nil, err
+	}
+
+	logger = logger.Session("groot-listing-details")
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	details := []ImageDetails{}
+	for _, imagePath := range imagePaths {
+		id := filepath.Base(imagePath)
+		imageDetails, err := l.imageCloner.Details(logger, id)
+		if err != nil {
+			// The image may have been deleted since the images were listed.
+			if exists, existsErr := l.imageCloner.Exists(id); existsErr == nil && !exists {
+				continue
+			}
+			logger.Error("fetching-image-details-failed", err, lager.Data{"id": id})
+			return nil, errorspkg.Wrapf(err, "fetching details of image `%s`", id)
+		}
+
+		details = append(details, imageDetails)
+	}
+
+	return details, nil
+}
+
 func (l *Lister) listDirs(path string) ([]string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -49,3 +89,24 @@ func (l *Lister) listDirs(path string) ([]string, error) {
 
 	return names, nil
 }
+
+func (l *Lister) filter(logger lager.Logger, imagePaths []string, selectors []LabelSelector) ([]string, error) {
+	matching := []string{}
+	for _, imagePath := range imagePaths {
+		labels, err := ReadImageLabels(imagePath)
+		if err != nil {
+			// The image may have been deleted since the images were listed.
+			if _, statErr := os.Stat(imagePath); os.IsNotExist(statErr) {
+				continue
+			}
+			logger.Error("reading-image-labels-failed", err, lager.Data{"imagePath": imagePath})
+			return nil, err
+		}
+
+		if MatchesAllLabels(selectors, labels) {
+			matching = append(matching, imagePath)
+		}
+	}
+
+	return matching, nil
+}
@@ -1,11 +1,14 @@
 package groot_test
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,6 +19,8 @@ var _ = Describe("Lister", func() {
 		storePath string
 		logger    *lagertest.TestLogger
 		lister    *groot.Lister
+
+		fakeImageCloner *grootfakes.FakeImageCloner
 	)
 
 	BeforeEach(func() {
@@ -27,7 +32,8 @@ var _ = Describe("Lister", func() {
 		Expect(os.MkdirAll(filepath.Join(storePath, "images", "image-1", "too-far"), 0755)).To(Succeed())
 		logger = lagertest.NewTestLogger("iam-lister")
 
-		lister = groot.IamLister()
+		fakeImageCloner = new(grootfakes.FakeImageCloner)
+		lister = groot.IamLister(fakeImageCloner)
 	})
 
 	AfterEach(func() {
@@ -44,6 +50,31 @@ var _ = Describe("Lister", func() {
 			Expect(paths).To(ContainElement(filepath.Join(storePath, "images", "image-1")))
 		})
 
+		Context("when label selectors are given", func() {
+			BeforeEach(func() {
+				Expect(groot.WriteImageLabels(filepath.Join(storePath, "images", "image-0"), map[string]string{"tenant": "acme", "tier": "web"})).To(Succeed())
+				Expect(groot.WriteImageLabels(filepath.Join(storePath, "images", "image-1"), map[string]string{"tenant": "other"})).To(Succeed())
+			})
+
+			It("lists only the images matching all of them", func() {
+				paths, err := lister.List(logger, storePath,
+					groot.LabelSelector{Key: "tenant", Value: "acme", HasValue: true},
+					groot.LabelSelector{Key: "tier"},
+				)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).To(ConsistOf(filepath.Join(storePath, "images", "image-0")))
+			})
+
+			It("doesn't list images without labels", func() {
+				Expect(os.MkdirAll(filepath.Join(storePath, "images", "image-2"), 0755)).To(Succeed())
+
+				paths, err := lister.List(logger, storePath, groot.LabelSelector{Key: "tenant"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).To(HaveLen(2))
+				Expect(paths).NotTo(ContainElement(filepath.Join(storePath, "images", "image-2")))
+			})
+		})
+
 		Context("when fails to list store path", func() {
 			It("returns an error", func() {
 				paths, err := lister.List(logger, "invalid-store-path")
@@ -52,4 +83,62 @@ var _ = Describe("Lister", func() {
 			})
 		})
 	})
+
+	Describe("ListDetails", func() {
+		BeforeEach(func() {
+			fakeImageCloner.DetailsStub = func(_ lager.Logger, id string) (groot.ImageDetails, error) {
+				return groot.ImageDetails{
+					ImageMetadata: groot.ImageMetadata{ID: id, BaseImageURL: "docker:///ubuntu"},
+					Path:          filepath.Join(storePath, "images", id),
+					Mounted:       true,
+				}, nil
+			}
+		})
+
+		It("returns the details of each image", func() {
+			details, err := lister.ListDetails(logger, storePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(details).To(HaveLen(2))
+			Expect(fakeImageCloner.DetailsCallCount()).To(Equal(2))
+			ids := []string{details[0].ID, details[1].ID}
+			Expect(ids).To(ConsistOf("image-0", "image-1"))
+			Expect(details[0].BaseImageURL).To(Equal("docker:///ubuntu"))
+			Expect(details[0].Mounted).To(BeTrue())
+		})
+
+		It("only returns the images matching the selectors", func() {
+			Expect(groot.WriteImageLabels(filepath.Join(storePath, "images", "image-1"), map[string]string{"tenant": "acme"})).To(Succeed())
+
+			details, err := lister.ListDetails(logger, storePath, groot.LabelSelector{Key: "tenant"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(details).To(HaveLen(1))
+			Expect(details[0].ID).To(Equal("image-1"))
+		})
+
+		Context("when fetching the details of an image fails", func() {
+			BeforeEach(func() {
+				fakeImageCloner.DetailsReturns(groot.ImageDetails{}, errors.New("stats failed"))
+				fakeImageCloner.ExistsReturns(true, nil)
+			})
+
+			It("returns an error", func() {
+				_, err := lister.ListDetails(logger, storePath)
+				Expect(err).To(MatchError(ContainSubstring("stats failed")))
+			})
+
+			Context("because the image was deleted", func() {
+				BeforeEach(func() {
+					fakeImageCloner.ExistsReturns(false, nil)
+				})
+
+				It("skips the image", func() {
+					details, err := lister.ListDetails(logger, storePath)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(details).To(BeEmpty())
+				})
+			})
+		})
+	})
 })
@@ -0,0 +1,83 @@
+package groot
+
+import (
+	"os"
+
+	"code.cloudfoundry.org/lager"
+)
+
+type Mounter struct {
+	imageCloner ImageCloner
+	locksmith   Locksmith
+}
+
+func IamMounter(imageCloner ImageCloner, locksmith Locksmith) *Mounter {
+	return &Mounter{
+		imageCloner: imageCloner,
+		locksmith:   locksmith,
+	}
+}
+
+func (m *Mounter) Mount(logger lager.Logger, id string) error {
+	logger = logger.Session("groot-mounting", lager.Data{"imageID": id})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	lockFile, err := m.locksmith.Lock(GlobalLockKey)
+	if err != nil {
+		return err
+	}
+	defer m.unlock(logger, lockFile)
+
+	if err := m.imageCloner.Mount(logger, id); err != nil {
+		logger.Error("mounting-image", err, lager.Data{"id": id})
+		return err
+	}
+
+	return nil
+}
+
+func (m *Mounter) Unmount(logger lager.Logger, id string) error {
+	logger = logger.Session("groot-unmounting", lager.Data{"imageID": id})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	lockFile, err := m.locksmith.Lock(GlobalLockKey)
+	if err != nil {
+		return err
+	}
+	defer m.unlock(logger, lockFile)
+
+	if err := m.imageCloner.Unmount(logger, id); err != nil {
+		logger.Error("unmounting-image", err, lager.Data{"id": id})
+		return err
+	}
+
+	return nil
+}
+
+func (m *Mounter) MountAll(logger lager.Logger) ([]string, error) {
+	logger = logger.Session("groot-mounting-all")
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	lockFile, err := m.locksmith.Lock(GlobalLockKey)
+	if err != nil {
+		return nil, err
+	}
+	defer m.unlock(logger, lockFile)
+
+	mounted, err := m.imageCloner.MountAll(logger)
+	if err != nil {
+		logger.Error("mounting-all-images", err)
+		return mounted, err
+	}
+
+	return mounted, nil
+}
+
+func (m *Mounter) unlock(logger lager.Logger, lockFile *os.File) {
+	if err := m.locksmith.Unlock(lockFile); err != nil {
+		logger.Error("failed-to-unlock", err)
+	}
+}
@@ -0,0 +1,134 @@
+package groot_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mounter", func() {
+	var (
+		fakeImageCloner *grootfakes.FakeImageCloner
+		fakeLocksmith   *grootfakes.FakeLocksmith
+		mounter         *groot.Mounter
+		logger          lager.Logger
+	)
+
+	BeforeEach(func() {
+		fakeImageCloner = new(grootfakes.FakeImageCloner)
+		fakeLocksmith = new(grootfakes.FakeLocksmith)
+		mounter = groot.IamMounter(fakeImageCloner, fakeLocksmith)
+		logger = lagertest.NewTestLogger("mounter")
+	})
+
+	Describe("Mount", func() {
+		It("asks the imageCloner to mount the image", func() {
+			Expect(mounter.Mount(logger, "some-id")).To(Succeed())
+
+			Expect(fakeImageCloner.MountCallCount()).To(Equal(1))
+			_, id := fakeImageCloner.MountArgsForCall(0)
+			Expect(id).To(Equal("some-id"))
+		})
+
+		It("holds the global lock while mounting", func() {
+			fakeImageCloner.MountStub = func(lager.Logger, string) error {
+				Expect(fakeLocksmith.LockCallCount()).To(Equal(1))
+				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(0))
+				return nil
+			}
+
+			Expect(mounter.Mount(logger, "some-id")).To(Succeed())
+			Expect(fakeLocksmith.LockArgsForCall(0)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
+		})
+
+		Context("when acquiring the lock fails", func() {
+			It("doesn't mount the image", func() {
+				fakeLocksmith.LockReturns(nil, errors.New("failed to lock"))
+
+				Expect(mounter.Mount(logger, "some-id")).To(MatchError(ContainSubstring("failed to lock")))
+				Expect(fakeImageCloner.MountCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when imageCloner fails", func() {
+			It("returns an error", func() {
+				fakeImageCloner.MountReturns(errors.New("sorry"))
+
+				err := mounter.Mount(logger, "some-id")
+				Expect(err).To(MatchError(ContainSubstring("sorry")))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("asks the imageCloner to unmount the image", func() {
+			Expect(mounter.Unmount(logger, "some-id")).To(Succeed())
+
+			Expect(fakeImageCloner.UnmountCallCount()).To(Equal(1))
+			_, id := fakeImageCloner.UnmountArgsForCall(0)
+			Expect(id).To(Equal("some-id"))
+		})
+
+		It("holds the global lock while unmounting", func() {
+			fakeImageCloner.UnmountStub = func(lager.Logger, string) error {
+				Expect(fakeLocksmith.LockCallCount()).To(Equal(1))
+				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(0))
+				return nil
+			}
+
+			Expect(mounter.Unmount(logger, "some-id")).To(Succeed())
+			Expect(fakeLocksmith.LockArgsForCall(0)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
+		})
+
+		Context("when imageCloner fails", func() {
+			It("returns an error", func() {
+				fakeImageCloner.UnmountReturns(errors.New("sorry"))
+
+				err := mounter.Unmount(logger, "some-id")
+				Expect(err).To(MatchError(ContainSubstring("sorry")))
+			})
+		})
+	})
+
+	Describe("MountAll", func() {
+		It("returns the images mounted by the imageCloner", func() {
+			fakeImageCloner.MountAllReturns([]string{"image-1", "image-2"}, nil)
+
+			mounted, err := mounter.MountAll(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeImageCloner.MountAllCallCount()).To(Equal(1))
+			Expect(mounted).To(ConsistOf("image-1", "image-2"))
+		})
+
+		It("holds the global lock while mounting", func() {
+			fakeImageCloner.MountAllStub = func(lager.Logger) ([]string, error) {
+				Expect(fakeLocksmith.LockCallCount()).To(Equal(1))
+				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(0))
+				return nil, nil
+			}
+
+			_, err := mounter.MountAll(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeLocksmith.LockArgsForCall(0)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
+		})
+
+		Context("when imageCloner fails", func() {
+			It("returns the error along with the images it could mount", func() {
+				fakeImageCloner.MountAllReturns([]string{"image-1"}, errors.New("sorry"))
+
+				mounted, err := mounter.MountAll(logger)
+				Expect(err).To(MatchError(ContainSubstring("sorry")))
+				Expect(mounted).To(ConsistOf("image-1"))
+			})
+		})
+	})
+})
@@ -0,0 +1,187 @@
+package groot
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+)
+
+const PinReferencePrefix = "pin:"
+const PinReferenceFormat = PinReferencePrefix + "%s"
+
+type PinSpec struct {
+	BaseImageURL *url.URL
+	UIDMappings  []IDMappingSpec
+	GIDMappings  []IDMappingSpec
+}
+
+type Pin struct {
+	Digest       string   `json:"digest"`
+	BaseImageURL string   `json:"base_image_url"`
+	ChainIDs     []string `json:"chain_ids"`
+}
+
+const pinBaseImageURLKey = "base_image_url"
+
+type Pinner struct {
+	baseImagePuller   BaseImagePuller
+	locksmith         Locksmith
+	dependencyManager DependencyManager
+}
+
+func IamPinner(baseImagePuller BaseImagePuller, locksmith Locksmith, dependencyManager DependencyManager) *Pinner {
+	return &Pinner{
+		baseImagePuller:   baseImagePuller,
+		locksmith:         locksmith,
+		dependencyManager: dependencyManager,
+	}
+}
+
+func (p *Pinner) Pin(logger lager.Logger, spec PinSpec) (Pin, error) {
+	logger = logger.Session("groot-pinning", lager.Data{"spec": spec})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	ownerUid, ownerGid := parseOwner(spec.UIDMappings, spec.GIDMappings)
+	baseImageSpec := BaseImageSpec{
+		UIDMappings: spec.UIDMappings,
+		GIDMappings: spec.GIDMappings,
+		OwnerUID:    ownerUid,
+		OwnerGID:    ownerGid,
+	}
+
+	baseImageInfo, err := p.baseImagePuller.FetchBaseImageInfo(logger)
+	if err != nil {
+		return Pin{}, err
+	}
+	baseImageChainIDs := chainIDs(baseImageInfo.LayerInfos)
+
+	lockFile, err := p.locksmith.Lock(GlobalLockKey)
+	if err != nil {
+		return Pin{}, err
+	}
+	defer func() {
+		if err := p.locksmith.Unlock(lockFile); err != nil {
+			logger.Error("failed-to-unlock", err)
+		}
+	}()
+
+	if err := p.baseImagePuller.Pull(logger, baseImageInfo, baseImageSpec); err != nil {
+		return Pin{}, errorspkg.Wrap(err, "pulling the image")
+	}
+
+	// Pins are keyed by what they keep, so that a tag moving to another image
+	// doesn't replace the pin of the image it pointed to. Tarballs have no
+	// manifest, so their top layer identifies them instead.
+	digest := baseImageInfo.Digest
+	if digest == "" && len(baseImageChainIDs) > 0 {
+		digest = baseImageChainIDs[len(baseImageChainIDs)-1]
+	}
+
+	pinRefName := fmt.Sprintf(PinReferenceFormat, digest)
+	metadata := map[string]string{pinBaseImageURLKey: spec.BaseImageURL.String()}
+	if err := p.dependencyManager.RegisterWithMetadata(pinRefName, baseImageChainIDs, metadata); err != nil {
+		return Pin{}, errorspkg.Wrap(err, "registering pin")
+	}
+
+	if err := p.baseImagePuller.TouchVolumes(logger, baseImageChainIDs); err != nil {
+		logger.Error("failed-to-record-volume-use", err)
+	}
+
+	return Pin{
+		Digest:       digest,
+		BaseImageURL: spec.BaseImageURL.String(),
+		ChainIDs:     baseImageChainIDs,
+	}, nil
+}
+
+type Unpinner struct {
+	dependencyManager DependencyManager
+}
+
+func IamUnpinner(dependencyManager DependencyManager) *Unpinner {
+	return &Unpinner{
+		dependencyManager: dependencyManager,
+	}
+}
+
+// Unpin removes the pin with the given digest, or every pin that was made
+// from the given base image URL.
+func (u *Unpinner) Unpin(logger lager.Logger, ref string) error {
+	logger = logger.Session("groot-unpinning", lager.Data{"ref": ref})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	pins, err := IamPinLister(u.dependencyManager).Pins(logger)
+	if err != nil {
+		return err
+	}
+
+	unpinned := false
+	for _, pin := range pins {
+		if pin.Digest != ref && pin.BaseImageURL != ref {
+			continue
+		}
+
+		pinRefName := fmt.Sprintf(PinReferenceFormat, pin.Digest)
+		if err := u.dependencyManager.Deregister(pinRefName); err != nil {
+			if os.IsNotExist(errorspkg.Cause(err)) {
+				continue
+			}
+			return errorspkg.Wrap(err, "deregistering pin")
+		}
+		unpinned = true
+	}
+
+	if !unpinned {
+		return errorspkg.Errorf("base image `%s` is not pinned", ref)
+	}
+
+	return nil
+}
+
+type PinLister struct {
+	dependencyManager DependencyManager
+}
+
+func IamPinLister(dependencyManager DependencyManager) *PinLister {
+	return &PinLister{
+		dependencyManager: dependencyManager,
+	}
+}
+
+func (l *PinLister) Pins(logger lager.Logger) ([]Pin, error) {
+	logger = logger.Session("groot-listing-pins")
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	pinRefNames, err := l.dependencyManager.List(PinReferencePrefix)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "listing pins")
+	}
+
+	pins := []Pin{}
+	for _, pinRefName := range pinRefNames {
+		chainIDs, err := l.dependencyManager.Dependencies(pinRefName)
+		if err != nil {
+			return nil, errorspkg.Wrapf(err, "reading pin `%s`", pinRefName)
+		}
+
+		metadata, err := l.dependencyManager.Metadata(pinRefName)
+		if err != nil {
+			return nil, errorspkg.Wrapf(err, "reading pin `%s`", pinRefName)
+		}
+
+		pins = append(pins, Pin{
+			Digest:       strings.TrimPrefix(pinRefName, PinReferencePrefix),
+			BaseImageURL: metadata[pinBaseImageURLKey],
+			ChainIDs:     chainIDs,
+		})
+	}
+
+	return pins, nil
+}
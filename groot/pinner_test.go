@@ -0,0 +1,329 @@
+package groot_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pinner", func() {
+	var (
+		fakeBaseImagePuller   *grootfakes.FakeBaseImagePuller
+		fakeLocksmith         *grootfakes.FakeLocksmith
+		fakeDependencyManager *grootfakes.FakeDependencyManager
+		lockFile              *os.File
+		baseImageURL          *url.URL
+
+		pinner *groot.Pinner
+		logger lager.Logger
+	)
+
+	BeforeEach(func() {
+		fakeBaseImagePuller = new(grootfakes.FakeBaseImagePuller)
+		fakeLocksmith = new(grootfakes.FakeLocksmith)
+		fakeDependencyManager = new(grootfakes.FakeDependencyManager)
+
+		var err error
+		lockFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+		fakeLocksmith.LockReturns(lockFile, nil)
+
+		baseImageURL, err = url.Parse("docker:///cflinuxfs2")
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeBaseImagePuller.FetchBaseImageInfoReturns(groot.BaseImageInfo{
+			Digest: "sha256:manifest",
+			LayerInfos: []groot.LayerInfo{
+				{ChainID: "id-1"},
+				{ChainID: "id-2"},
+			},
+		}, nil)
+
+		pinner = groot.IamPinner(fakeBaseImagePuller, fakeLocksmith, fakeDependencyManager)
+		logger = lagertest.NewTestLogger("pinner")
+	})
+
+	AfterEach(func() {
+		Expect(os.Remove(lockFile.Name())).To(Succeed())
+	})
+
+	Describe("Pin", func() {
+		It("pulls the image", func() {
+			uidMappings := []groot.IDMappingSpec{{HostID: 50, NamespaceID: 0, Size: 1}}
+			gidMappings := []groot.IDMappingSpec{{HostID: 60, NamespaceID: 0, Size: 1}}
+			_, err := pinner.Pin(logger, groot.PinSpec{
+				BaseImageURL: baseImageURL,
+				UIDMappings:  uidMappings,
+				GIDMappings:  gidMappings,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeBaseImagePuller.PullCallCount()).To(Equal(1))
+			_, baseImageInfo, baseImageSpec := fakeBaseImagePuller.PullArgsForCall(0)
+			Expect(baseImageInfo.LayerInfos).To(HaveLen(2))
+			Expect(baseImageSpec.OwnerUID).To(Equal(50))
+			Expect(baseImageSpec.OwnerGID).To(Equal(60))
+		})
+
+		It("registers the pin by digest with the image's chain ids", func() {
+			pin, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(1))
+			id, chainIDs, metadata := fakeDependencyManager.RegisterWithMetadataArgsForCall(0)
+			Expect(id).To(Equal("pin:sha256:manifest"))
+			Expect(chainIDs).To(Equal([]string{"id-1", "id-2"}))
+			Expect(metadata).To(Equal(map[string]string{"base_image_url": "docker:///cflinuxfs2"}))
+
+			Expect(pin).To(Equal(groot.Pin{
+				Digest:       "sha256:manifest",
+				BaseImageURL: "docker:///cflinuxfs2",
+				ChainIDs:     []string{"id-1", "id-2"},
+			}))
+		})
+
+		Context("when the image has no manifest digest", func() {
+			BeforeEach(func() {
+				fakeBaseImagePuller.FetchBaseImageInfoReturns(groot.BaseImageInfo{
+					LayerInfos: []groot.LayerInfo{
+						{ChainID: "id-1"},
+						{ChainID: "id-2"},
+					},
+				}, nil)
+			})
+
+			It("keys the pin by the top layer's chain id", func() {
+				pin, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+				Expect(err).NotTo(HaveOccurred())
+
+				id, _, _ := fakeDependencyManager.RegisterWithMetadataArgsForCall(0)
+				Expect(id).To(Equal("pin:id-2"))
+				Expect(pin.Digest).To(Equal("id-2"))
+			})
+		})
+
+		It("records the pinned volumes as used", func() {
+			_, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeBaseImagePuller.TouchVolumesCallCount()).To(Equal(1))
+			_, volumeIDs := fakeBaseImagePuller.TouchVolumesArgsForCall(0)
+			Expect(volumeIDs).To(Equal([]string{"id-1", "id-2"}))
+		})
+
+		It("holds the global lock while pulling and registering", func() {
+			_, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeLocksmith.LockCallCount()).To(Equal(1))
+			Expect(fakeLocksmith.LockArgsForCall(0)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
+			Expect(fakeLocksmith.UnlockArgsForCall(0)).To(Equal(lockFile))
+		})
+
+		Context("when fetching the image info fails", func() {
+			BeforeEach(func() {
+				fakeBaseImagePuller.FetchBaseImageInfoReturns(groot.BaseImageInfo{}, errors.New("failed to fetch"))
+			})
+
+			It("returns the error", func() {
+				_, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+				Expect(err).To(MatchError("failed to fetch"))
+				Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when pulling the image fails", func() {
+			BeforeEach(func() {
+				fakeBaseImagePuller.PullReturns(errors.New("failed to pull"))
+			})
+
+			It("doesn't register the pin", func() {
+				_, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+				Expect(err).To(MatchError(ContainSubstring("failed to pull")))
+				Expect(fakeDependencyManager.RegisterWithMetadataCallCount()).To(Equal(0))
+			})
+
+			It("releases the global lock", func() {
+				_, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+				Expect(err).To(HaveOccurred())
+				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when registering the pin fails", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.RegisterWithMetadataReturns(errors.New("failed to register"))
+			})
+
+			It("returns an error", func() {
+				_, err := pinner.Pin(logger, groot.PinSpec{BaseImageURL: baseImageURL})
+				Expect(err).To(MatchError(ContainSubstring("failed to register")))
+			})
+		})
+	})
+})
+
+var _ = Describe("Unpinner", func() {
+	var (
+		fakeDependencyManager *grootfakes.FakeDependencyManager
+		unpinner              *groot.Unpinner
+		logger                lager.Logger
+	)
+
+	BeforeEach(func() {
+		fakeDependencyManager = new(grootfakes.FakeDependencyManager)
+		fakeDependencyManager.ListReturns([]string{"pin:sha256:one", "pin:sha256:two", "pin:sha256:three"}, nil)
+		fakeDependencyManager.MetadataStub = func(id string) (map[string]string, error) {
+			return map[string]map[string]string{
+				"pin:sha256:one":   {"base_image_url": "docker:///cflinuxfs2"},
+				"pin:sha256:two":   {"base_image_url": "docker:///cflinuxfs2"},
+				"pin:sha256:three": {"base_image_url": "docker:///busybox"},
+			}[id], nil
+		}
+
+		unpinner = groot.IamUnpinner(fakeDependencyManager)
+		logger = lagertest.NewTestLogger("unpinner")
+	})
+
+	Describe("Unpin", func() {
+		It("deregisters the pin with the given digest", func() {
+			Expect(unpinner.Unpin(logger, "sha256:three")).To(Succeed())
+
+			Expect(fakeDependencyManager.DeregisterCallCount()).To(Equal(1))
+			Expect(fakeDependencyManager.DeregisterArgsForCall(0)).To(Equal("pin:sha256:three"))
+		})
+
+		It("deregisters every pin made from the given url", func() {
+			Expect(unpinner.Unpin(logger, "docker:///cflinuxfs2")).To(Succeed())
+
+			Expect(fakeDependencyManager.DeregisterCallCount()).To(Equal(2))
+			Expect(fakeDependencyManager.DeregisterArgsForCall(0)).To(Equal("pin:sha256:one"))
+			Expect(fakeDependencyManager.DeregisterArgsForCall(1)).To(Equal("pin:sha256:two"))
+		})
+
+		Context("when the image is not pinned", func() {
+			It("returns an error", func() {
+				err := unpinner.Unpin(logger, "docker:///ubuntu")
+				Expect(err).To(MatchError("base image `docker:///ubuntu` is not pinned"))
+				Expect(fakeDependencyManager.DeregisterCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the pin is removed concurrently", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.DeregisterReturns(os.ErrNotExist)
+			})
+
+			It("returns an error", func() {
+				err := unpinner.Unpin(logger, "sha256:three")
+				Expect(err).To(MatchError("base image `sha256:three` is not pinned"))
+			})
+		})
+
+		Context("when listing the pins fails", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.ListReturns(nil, errors.New("failed to list"))
+			})
+
+			It("returns an error", func() {
+				err := unpinner.Unpin(logger, "sha256:three")
+				Expect(err).To(MatchError(ContainSubstring("failed to list")))
+			})
+		})
+
+		Context("when deregistering fails", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.DeregisterReturns(errors.New("failed to deregister"))
+			})
+
+			It("returns an error", func() {
+				err := unpinner.Unpin(logger, "sha256:three")
+				Expect(err).To(MatchError(ContainSubstring("failed to deregister")))
+			})
+		})
+	})
+})
+
+var _ = Describe("PinLister", func() {
+	var (
+		fakeDependencyManager *grootfakes.FakeDependencyManager
+		pinLister             *groot.PinLister
+		logger                lager.Logger
+	)
+
+	BeforeEach(func() {
+		fakeDependencyManager = new(grootfakes.FakeDependencyManager)
+		fakeDependencyManager.ListReturns([]string{"pin:sha256:one", "pin:sha256:two"}, nil)
+		fakeDependencyManager.DependenciesStub = func(id string) ([]string, error) {
+			return map[string][]string{
+				"pin:sha256:one": []string{"id-1", "id-2"},
+				"pin:sha256:two": []string{"id-3"},
+			}[id], nil
+		}
+		fakeDependencyManager.MetadataStub = func(id string) (map[string]string, error) {
+			return map[string]map[string]string{
+				"pin:sha256:one": {"base_image_url": "docker:///cflinuxfs2"},
+				"pin:sha256:two": {"base_image_url": "oci:///path/to/image"},
+			}[id], nil
+		}
+
+		pinLister = groot.IamPinLister(fakeDependencyManager)
+		logger = lagertest.NewTestLogger("pin-lister")
+	})
+
+	Describe("Pins", func() {
+		It("lists the pinned images", func() {
+			pins, err := pinLister.Pins(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeDependencyManager.ListArgsForCall(0)).To(Equal("pin:"))
+			Expect(pins).To(Equal([]groot.Pin{
+				{Digest: "sha256:one", BaseImageURL: "docker:///cflinuxfs2", ChainIDs: []string{"id-1", "id-2"}},
+				{Digest: "sha256:two", BaseImageURL: "oci:///path/to/image", ChainIDs: []string{"id-3"}},
+			}))
+		})
+
+		Context("when listing fails", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.ListReturns(nil, errors.New("failed to list"))
+			})
+
+			It("returns an error", func() {
+				_, err := pinLister.Pins(logger)
+				Expect(err).To(MatchError(ContainSubstring("failed to list")))
+			})
+		})
+
+		Context("when reading a pin fails", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.DependenciesReturns(nil, errors.New("failed to read"))
+			})
+
+			It("returns an error", func() {
+				_, err := pinLister.Pins(logger)
+				Expect(err).To(MatchError(ContainSubstring("failed to read")))
+			})
+		})
+
+		Context("when reading a pin's metadata fails", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.MetadataReturns(nil, errors.New("failed to read metadata"))
+			})
+
+			It("returns an error", func() {
+				_, err := pinLister.Pins(logger)
+				Expect(err).To(MatchError(ContainSubstring("failed to read metadata")))
+			})
+		})
+	})
+})
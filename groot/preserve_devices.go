@@ -0,0 +1,26 @@
+package groot
+
+import (
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/store"
+	errorspkg "github.com/pkg/errors"
+)
+
+const PreserveDevicesFilename = "preserve_devices"
+
+// DevicesPreserved tells whether the store was initialized to keep the device
+// nodes of the layers it unpacks. Volumes are shared by every image, so the
+// setting is fixed for the lifetime of the store.
+func DevicesPreserved(storePath string) (bool, error) {
+	_, err := os.Stat(filepath.Join(storePath, store.MetaDirName, PreserveDevicesFilename))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return false, errorspkg.Wrap(err, "reading preserve devices file")
+}
@@ -0,0 +1,69 @@
+package groot
+
+import (
+	"net/url"
+
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+)
+
+type PullSpec struct {
+	BaseImageURL *url.URL
+	UIDMappings  []IDMappingSpec
+	GIDMappings  []IDMappingSpec
+}
+
+type Puller struct {
+	baseImagePuller BaseImagePuller
+	locksmith       Locksmith
+}
+
+func IamPuller(baseImagePuller BaseImagePuller, locksmith Locksmith) *Puller {
+	return &Puller{
+		baseImagePuller: baseImagePuller,
+		locksmith:       locksmith,
+	}
+}
+
+// Pull brings the layers of a base image into the store without creating an
+// image from them. The layers can be collected by the next clean unless an
+// image or a pin starts using them.
+func (p *Puller) Pull(logger lager.Logger, spec PullSpec) ([]string, error) {
+	logger = logger.Session("groot-pulling", lager.Data{"spec": spec})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	ownerUid, ownerGid := parseOwner(spec.UIDMappings, spec.GIDMappings)
+	baseImageSpec := BaseImageSpec{
+		UIDMappings: spec.UIDMappings,
+		GIDMappings: spec.GIDMappings,
+		OwnerUID:    ownerUid,
+		OwnerGID:    ownerGid,
+	}
+
+	baseImageInfo, err := p.baseImagePuller.FetchBaseImageInfo(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	lockFile, err := p.locksmith.Lock(GlobalLockKey)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := p.locksmith.Unlock(lockFile); err != nil {
+			logger.Error("failed-to-unlock", err)
+		}
+	}()
+
+	if err := p.baseImagePuller.Pull(logger, baseImageInfo, baseImageSpec); err != nil {
+		return nil, errorspkg.Wrap(err, "pulling the image")
+	}
+
+	baseImageChainIDs := chainIDs(baseImageInfo.LayerInfos)
+	if err := p.baseImagePuller.TouchVolumes(logger, baseImageChainIDs); err != nil {
+		logger.Error("failed-to-record-volume-use", err)
+	}
+
+	return baseImageChainIDs, nil
+}
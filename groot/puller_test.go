@@ -0,0 +1,115 @@
+package groot_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Puller", func() {
+	var (
+		fakeBaseImagePuller *grootfakes.FakeBaseImagePuller
+		fakeLocksmith       *grootfakes.FakeLocksmith
+		lockFile            *os.File
+		baseImageURL        *url.URL
+
+		puller *groot.Puller
+		logger lager.Logger
+	)
+
+	BeforeEach(func() {
+		fakeBaseImagePuller = new(grootfakes.FakeBaseImagePuller)
+		fakeLocksmith = new(grootfakes.FakeLocksmith)
+
+		var err error
+		lockFile, err = ioutil.TempFile("", "")
+		Expect(err).NotTo(HaveOccurred())
+		fakeLocksmith.LockReturns(lockFile, nil)
+
+		baseImageURL, err = url.Parse("docker:///cflinuxfs2")
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeBaseImagePuller.FetchBaseImageInfoReturns(groot.BaseImageInfo{
+			LayerInfos: []groot.LayerInfo{
+				{ChainID: "id-1"},
+				{ChainID: "id-2"},
+			},
+		}, nil)
+
+		puller = groot.IamPuller(fakeBaseImagePuller, fakeLocksmith)
+		logger = lagertest.NewTestLogger("puller")
+	})
+
+	AfterEach(func() {
+		Expect(os.Remove(lockFile.Name())).To(Succeed())
+	})
+
+	Describe("Pull", func() {
+		It("pulls the image and returns its chain ids", func() {
+			uidMappings := []groot.IDMappingSpec{{HostID: 50, NamespaceID: 0, Size: 1}}
+			gidMappings := []groot.IDMappingSpec{{HostID: 60, NamespaceID: 0, Size: 1}}
+			chainIDs, err := puller.Pull(logger, groot.PullSpec{
+				BaseImageURL: baseImageURL,
+				UIDMappings:  uidMappings,
+				GIDMappings:  gidMappings,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chainIDs).To(Equal([]string{"id-1", "id-2"}))
+
+			Expect(fakeBaseImagePuller.PullCallCount()).To(Equal(1))
+			_, _, baseImageSpec := fakeBaseImagePuller.PullArgsForCall(0)
+			Expect(baseImageSpec.OwnerUID).To(Equal(50))
+			Expect(baseImageSpec.OwnerGID).To(Equal(60))
+		})
+
+		It("records the pulled volumes as used", func() {
+			_, err := puller.Pull(logger, groot.PullSpec{BaseImageURL: baseImageURL})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeBaseImagePuller.TouchVolumesCallCount()).To(Equal(1))
+			_, volumeIDs := fakeBaseImagePuller.TouchVolumesArgsForCall(0)
+			Expect(volumeIDs).To(Equal([]string{"id-1", "id-2"}))
+		})
+
+		It("holds the global lock while pulling", func() {
+			_, err := puller.Pull(logger, groot.PullSpec{BaseImageURL: baseImageURL})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeLocksmith.LockArgsForCall(0)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeLocksmith.UnlockArgsForCall(0)).To(Equal(lockFile))
+		})
+
+		Context("when fetching the image info fails", func() {
+			BeforeEach(func() {
+				fakeBaseImagePuller.FetchBaseImageInfoReturns(groot.BaseImageInfo{}, errors.New("failed to fetch"))
+			})
+
+			It("returns the error", func() {
+				_, err := puller.Pull(logger, groot.PullSpec{BaseImageURL: baseImageURL})
+				Expect(err).To(MatchError("failed to fetch"))
+				Expect(fakeBaseImagePuller.PullCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when pulling the image fails", func() {
+			BeforeEach(func() {
+				fakeBaseImagePuller.PullReturns(errors.New("failed to pull"))
+			})
+
+			It("returns an error and releases the global lock", func() {
+				_, err := puller.Pull(logger, groot.PullSpec{BaseImageURL: baseImageURL})
+				Expect(err).To(MatchError(ContainSubstring("failed to pull")))
+				Expect(fakeLocksmith.UnlockCallCount()).To(Equal(1))
+			})
+		})
+	})
+})
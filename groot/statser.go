@@ -27,3 +27,17 @@ func (m *Statser) Stats(logger lager.Logger, id string) (VolumeStats, error) {
 
 	return stats, nil
 }
+
+func (m *Statser) AllStats(logger lager.Logger) (map[string]VolumeStats, error) {
+	logger = logger.Session("groot-all-stats")
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	stats, err := m.imageCloner.AllStats(logger)
+	if err != nil {
+		logger.Error("fetching-all-stats", err)
+		return nil, err
+	}
+
+	return stats, nil
+}
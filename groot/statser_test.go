@@ -59,4 +59,38 @@ var _ = Describe("Statser", func() {
 			})
 		})
 	})
+
+	Describe("AllStats", func() {
+		It("asks for all stats from the imageCloner", func() {
+			allStats := map[string]groot.VolumeStats{
+				"image-1": groot.VolumeStats{
+					DiskUsage: groot.DiskUsage{
+						TotalBytesUsed:     1024,
+						ExclusiveBytesUsed: 512,
+					},
+				},
+				"image-2": groot.VolumeStats{
+					DiskUsage: groot.DiskUsage{
+						TotalBytesUsed:     2048,
+						ExclusiveBytesUsed: 0,
+					},
+				},
+			}
+			fakeImageCloner.AllStatsReturns(allStats, nil)
+
+			returnedStats, err := statser.AllStats(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeImageCloner.AllStatsCallCount()).To(Equal(1))
+			Expect(returnedStats).To(Equal(allStats))
+		})
+
+		Context("when imageCloner fails", func() {
+			It("returns an error", func() {
+				fakeImageCloner.AllStatsReturns(nil, errors.New("sorry"))
+
+				_, err := statser.AllStats(logger)
+				Expect(err).To(MatchError(ContainSubstring("sorry")))
+			})
+		})
+	})
 })
@@ -0,0 +1,110 @@
+package integration_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+var _ = Describe("Create with a bundle", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		bundleParent    string
+		bundlePath      string
+		imageID         string
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
+		baseImagePath = integration.CreateBaseImageTar(sourceImagePath).Name()
+		imageID = testhelpers.NewRandomID()
+
+		bundleParent, err = ioutil.TempDir("", "bundles")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chmod(bundleParent, 0777)).To(Succeed())
+		bundlePath = filepath.Join(bundleParent, imageID)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+		Expect(os.RemoveAll(bundleParent)).To(Succeed())
+	})
+
+	Context("when the image is mounted", func() {
+		var containerSpec specs.Spec
+
+		BeforeEach(func() {
+			integration.SkipIfNonRoot(GrootfsTestUid)
+
+			var err error
+			containerSpec, err = Runner.CreateWithBundle(groot.CreateSpec{
+				BaseImageURL: integration.String2URL(baseImagePath),
+				ID:           imageID,
+				Mount:        true,
+			}, bundlePath)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("writes a runc config for the image", func() {
+			contents, err := ioutil.ReadFile(filepath.Join(bundlePath, "config.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var bundleSpec specs.Spec
+			Expect(json.Unmarshal(contents, &bundleSpec)).To(Succeed())
+			Expect(bundleSpec.Root.Path).To(Equal(containerSpec.Root.Path))
+			Expect(bundleSpec.Hostname).To(Equal(imageID))
+			Expect(bundleSpec.Process.Terminal).To(BeFalse())
+			Expect(bundleSpec.Process.Args).NotTo(BeEmpty())
+			Expect(bundleSpec.Linux.Namespaces).NotTo(BeEmpty())
+		})
+
+		It("links the rootfs to the image rootfs", func() {
+			target, err := os.Readlink(filepath.Join(bundlePath, "rootfs"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(containerSpec.Root.Path))
+			Expect(filepath.Join(bundlePath, "rootfs", "foo")).To(BeARegularFile())
+		})
+
+		It("removes the bundle when the image is deleted", func() {
+			Expect(Runner.Delete(imageID)).To(Succeed())
+			Expect(bundlePath).NotTo(BeAnExistingFile())
+		})
+
+		Context("when the bundle already exists", func() {
+			It("fails without creating the image", func() {
+				_, err := Runner.CreateWithBundle(groot.CreateSpec{
+					BaseImageURL: integration.String2URL(baseImagePath),
+					ID:           testhelpers.NewRandomID(),
+					Mount:        true,
+				}, bundlePath)
+				Expect(err).To(MatchError(ContainSubstring("already exists")))
+			})
+		})
+	})
+
+	Context("when the image is not mounted", func() {
+		It("fails", func() {
+			_, err := Runner.CreateWithBundle(groot.CreateSpec{
+				BaseImageURL: integration.String2URL(baseImagePath),
+				ID:           imageID,
+				Mount:        false,
+			}, bundlePath)
+			Expect(err).To(MatchError(ContainSubstring("a bundle can only be written for a mounted image")))
+			Expect(bundlePath).NotTo(BeAnExistingFile())
+		})
+	})
+})
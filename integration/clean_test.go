@@ -75,6 +75,50 @@ var _ = Describe("Clean", func() {
 				Expect(afterContents).To(HaveLen(4))
 			})
 
+			It("reports the removed volumes", func() {
+				report, err := Runner.CleanReport(0, false)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.DryRun).To(BeFalse())
+				Expect(report.Volumes).To(HaveLen(4))
+				Expect(report.ReclaimedBytes).To(BeNumerically(">", 0))
+				Expect(report.Measured).To(BeFalse())
+			})
+
+			Context("and a threshold is given", func() {
+				It("reports the usage before and after", func() {
+					report, err := Runner.CleanReport(1, false)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(report.Measured).To(BeTrue())
+					Expect(report.UsageAfter).To(BeNumerically("<", report.UsageBefore))
+				})
+			})
+
+			Context("and dry run is requested", func() {
+				It("reports the unused volumes without removing them", func() {
+					preContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+					Expect(err).NotTo(HaveOccurred())
+
+					report, err := Runner.CleanReport(0, true)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(report.DryRun).To(BeTrue())
+					Expect(report.Volumes).To(HaveLen(4))
+					var reclaimedBytes int64
+					for _, volume := range report.Volumes {
+						Expect(filepath.Join(StorePath, store.VolumesDirName, volume.ID)).To(BeADirectory())
+						reclaimedBytes += volume.Size
+					}
+					Expect(report.ReclaimedBytes).To(Equal(reclaimedBytes))
+					Expect(report.UsageAfter).To(Equal(report.UsageBefore - reclaimedBytes))
+
+					afterContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(afterContents).To(HaveLen(len(preContents)))
+				})
+			})
+
 			Context("and a threshold is set", func() {
 				var cleanupThresholdInBytes int64
 
@@ -131,6 +175,44 @@ var _ = Describe("Clean", func() {
 					})
 				})
 			})
+
+			Context("and a target is set", func() {
+				Context("and the usage is already below the target", func() {
+					It("does not remove the unused volumes", func() {
+						preContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+						Expect(err).NotTo(HaveOccurred())
+
+						_, err = Runner.CleanWithTarget(0, 500000000)
+						Expect(err).NotTo(HaveOccurred())
+
+						afterContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(afterContents).To(HaveLen(len(preContents)))
+					})
+				})
+
+				Context("and the usage is above the target", func() {
+					It("removes the unused volumes until the target is reached", func() {
+						preContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(preContents).To(HaveLen(8))
+
+						_, err = Runner.CleanWithTarget(0, 1)
+						Expect(err).NotTo(HaveOccurred())
+
+						afterContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(afterContents).To(HaveLen(4))
+					})
+				})
+
+				Context("but it is greater than the threshold", func() {
+					It("returns an error", func() {
+						_, err := Runner.CleanWithTarget(1000, 2000)
+						Expect(err).To(MatchError("invalid argument: clean target cannot be greater than the clean threshold"))
+					})
+				})
+			})
 		})
 	})
 
@@ -0,0 +1,113 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create with copied files", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		hostFilesPath   string
+		spec            groot.CreateSpec
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "local-image-dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(sourceImagePath, "etc"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "etc", "hosts"), []byte("from the image"), 0644)).To(Succeed())
+		baseImagePath = integration.CreateBaseImageTar(sourceImagePath).Name()
+
+		hostFilesPath, err = ioutil.TempDir("", "host-files")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chmod(hostFilesPath, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(hostFilesPath, "hosts"), []byte("127.0.0.1 my-container"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(hostFilesPath, "cert.pem"), []byte("a certificate"), 0644)).To(Succeed())
+
+		spec = groot.CreateSpec{
+			BaseImageURL: integration.String2URL(baseImagePath),
+			ID:           testhelpers.NewRandomID(),
+			Mount:        mountByDefault(),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+		Expect(os.RemoveAll(hostFilesPath)).To(Succeed())
+	})
+
+	It("writes the files into the image upper directory", func() {
+		containerSpec, err := Runner.CreateWithCopies(spec,
+			filepath.Join(hostFilesPath, "hosts")+":/etc/hosts",
+			filepath.Join(hostFilesPath, "cert.pem")+":/etc/ssl/certs/cert.pem:0600",
+		)
+		Expect(err).NotTo(HaveOccurred())
+		upperDir := filepath.Join(filepath.Dir(containerSpec.Root.Path), "diff")
+
+		hostsContents, err := ioutil.ReadFile(filepath.Join(upperDir, "etc", "hosts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(hostsContents)).To(Equal("127.0.0.1 my-container"))
+
+		certStat, err := os.Stat(filepath.Join(upperDir, "etc", "ssl", "certs", "cert.pem"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certStat.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("maps the file owner with the store id mappings", func() {
+		containerSpec, err := Runner.CreateWithCopies(spec, filepath.Join(hostFilesPath, "hosts")+":/etc/hosts")
+		Expect(err).NotTo(HaveOccurred())
+		upperDir := filepath.Join(filepath.Dir(containerSpec.Root.Path), "diff")
+
+		stat, err := os.Stat(filepath.Join(upperDir, "etc", "hosts"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stat.Sys().(*syscall.Stat_t).Uid).To(Equal(uint32(GrootfsTestUid)))
+	})
+
+	Context("when the image is mounted", func() {
+		BeforeEach(func() {
+			integration.SkipIfNonRoot(GrootfsTestUid)
+			spec.Mount = true
+		})
+
+		It("shows the files in the rootfs", func() {
+			containerSpec, err := Runner.CreateWithCopies(spec, filepath.Join(hostFilesPath, "hosts")+":/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+
+			hostsContents, err := ioutil.ReadFile(filepath.Join(containerSpec.Root.Path, "etc", "hosts"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(hostsContents)).To(Equal("127.0.0.1 my-container"))
+		})
+	})
+
+	Context("when the source doesn't exist", func() {
+		It("fails without leaving the image behind", func() {
+			_, err := Runner.CreateWithCopies(spec, "/not/here:/etc/hosts")
+			Expect(err).To(MatchError(ContainSubstring("copying `/not/here`")))
+
+			images, err := ioutil.ReadDir(filepath.Join(StorePath, store.ImageDirName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(images).To(BeEmpty())
+		})
+	})
+
+	Context("when the copy is invalid", func() {
+		It("fails", func() {
+			_, err := Runner.CreateWithCopies(spec, "/etc/hosts:etc/hosts")
+			Expect(err).To(MatchError(ContainSubstring("destination must be an absolute path")))
+		})
+	})
+})
@@ -0,0 +1,99 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create ephemeral images", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		spec            groot.CreateSpec
+	)
+
+	BeforeEach(func() {
+		integration.SkipIfNonRoot(GrootfsTestUid)
+
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "local-image-dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
+		baseImagePath = integration.CreateBaseImageTar(sourceImagePath).Name()
+
+		spec = groot.CreateSpec{
+			BaseImageURL: integration.String2URL(baseImagePath),
+			ID:           testhelpers.NewRandomID(),
+			Mount:        true,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+	})
+
+	It("writes to a tmpfs instead of the store", func() {
+		containerSpec, err := Runner.CreateEphemeral(spec, 10*1024*1024)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(containerSpec.Root.Path, "bar"), []byte("hello"), 0644)).To(Succeed())
+
+		imagePath := filepath.Dir(containerSpec.Root.Path)
+		Expect(filepath.Join(imagePath, "ephemeral", "diff", "bar")).To(BeAnExistingFile())
+		Expect(filepath.Join(imagePath, "diff")).NotTo(BeAnExistingFile())
+	})
+
+	It("limits the writable layer to the ephemeral size", func() {
+		containerSpec, err := Runner.CreateEphemeral(spec, 1024*1024)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(filepath.Join(containerSpec.Root.Path, "bar"), make([]byte, 2*1024*1024), 0644)
+		Expect(err).To(MatchError(ContainSubstring("no space left on device")))
+	})
+
+	It("reports the tmpfs usage as exclusive disk usage", func() {
+		containerSpec, err := Runner.CreateEphemeral(spec, 10*1024*1024)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(containerSpec.Root.Path, "bar"), make([]byte, 1024*1024), 0644)).To(Succeed())
+
+		stats, err := Runner.Stats(spec.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.DiskUsage.ExclusiveBytesUsed).To(Equal(int64(1024 * 1024)))
+	})
+
+	It("tears the tmpfs down on delete", func() {
+		containerSpec, err := Runner.CreateEphemeral(spec, 10*1024*1024)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(Runner.Delete(spec.ID)).To(Succeed())
+		Expect(filepath.Dir(containerSpec.Root.Path)).NotTo(BeAnExistingFile())
+
+		mountInfo, err := ioutil.ReadFile("/proc/self/mountinfo")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(mountInfo)).NotTo(ContainSubstring(filepath.Dir(containerSpec.Root.Path)))
+	})
+
+	Context("when a disk limit is given", func() {
+		It("fails", func() {
+			spec.DiskLimit = 10 * 1024 * 1024
+			_, err := Runner.CreateEphemeral(spec, 10*1024*1024)
+			Expect(err).To(MatchError(ContainSubstring("it can't have a disk limit or exclude the image from quota")))
+		})
+	})
+
+	Context("when the ephemeral size is missing", func() {
+		It("fails", func() {
+			_, err := Runner.CreateEphemeral(spec, 0)
+			Expect(err).To(MatchError(ContainSubstring("an ephemeral image needs a positive ephemeral size")))
+		})
+	})
+})
@@ -0,0 +1,101 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create with extra layers", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		layerSourcePath string
+		layerPath       string
+		spec            groot.CreateSpec
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "local-image-dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "bar"), []byte("goodbye"), 0644)).To(Succeed())
+		baseImagePath = integration.CreateBaseImageTar(sourceImagePath).Name()
+
+		layerSourcePath, err = ioutil.TempDir("", "layer-dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(layerSourcePath, "foo"), []byte("hello-layer"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(layerSourcePath, ".wh.bar"), []byte{}, 0644)).To(Succeed())
+		layerPath = integration.CreateBaseImageTar(layerSourcePath).Name()
+
+		spec = groot.CreateSpec{
+			BaseImageURL: integration.String2URL(baseImagePath),
+			ID:           testhelpers.NewRandomID(),
+			Mount:        mountByDefault(),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+		Expect(os.RemoveAll(layerSourcePath)).To(Succeed())
+		Expect(os.RemoveAll(layerPath)).To(Succeed())
+	})
+
+	It("stacks the layers on top of the base image", func() {
+		containerSpec, err := Runner.CreateWithLayers(spec, layerPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Runner.EnsureMounted(containerSpec)).To(Succeed())
+
+		fooContents, err := ioutil.ReadFile(filepath.Join(containerSpec.Root.Path, "foo"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(fooContents)).To(Equal("hello-layer"))
+		Expect(filepath.Join(containerSpec.Root.Path, "bar")).NotTo(BeAnExistingFile())
+	})
+
+	It("shares the layer volumes between images", func() {
+		_, err := Runner.CreateWithLayers(spec, layerPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		volumes, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(volumes).To(HaveLen(2))
+
+		spec.ID = testhelpers.NewRandomID()
+		_, err = Runner.CreateWithLayers(spec, layerPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		volumes, err = ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(volumes).To(HaveLen(2))
+	})
+
+	It("collects the layer volumes once no image uses them", func() {
+		_, err := Runner.CreateWithLayers(spec, layerPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Runner.Delete(spec.ID)).To(Succeed())
+
+		_, err = Runner.Clean(0)
+		Expect(err).NotTo(HaveOccurred())
+
+		volumes, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(volumes).To(BeEmpty())
+	})
+
+	Context("when the layer doesn't exist", func() {
+		It("fails", func() {
+			_, err := Runner.CreateWithLayers(spec, "/not/here.tar")
+			Expect(err).To(MatchError(ContainSubstring("layer `/not/here.tar` not found")))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create with overlay mount options", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		spec            groot.CreateSpec
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "local-image-dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
+		baseImagePath = integration.CreateBaseImageTar(sourceImagePath).Name()
+
+		spec = groot.CreateSpec{
+			BaseImageURL: integration.String2URL(baseImagePath),
+			ID:           testhelpers.NewRandomID(),
+			Mount:        false,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+	})
+
+	It("returns the options in the overlay mount", func() {
+		containerSpec, err := Runner.CreateWithOverlayMountOptions(spec, "index=off", "redirect_dir=off")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(containerSpec.Mounts).To(HaveLen(1))
+		Expect(containerSpec.Mounts[0].Type).To(Equal("overlay"))
+		Expect(containerSpec.Mounts[0].Options[0]).To(HaveSuffix(",index=off,redirect_dir=off"))
+	})
+
+	Context("when the image is mounted", func() {
+		BeforeEach(func() {
+			integration.SkipIfNonRoot(GrootfsTestUid)
+			spec.Mount = true
+		})
+
+		It("mounts the rootfs with the options", func() {
+			containerSpec, err := Runner.CreateWithOverlayMountOptions(spec, "metacopy=on")
+			Expect(err).NotTo(HaveOccurred())
+
+			mountInfo, err := ioutil.ReadFile("/proc/self/mountinfo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(mountInfo)).To(MatchRegexp("%s .*metacopy=on", containerSpec.Root.Path))
+		})
+	})
+
+	Context("when an option is not supported", func() {
+		It("fails", func() {
+			_, err := Runner.CreateWithOverlayMountOptions(spec, "userxattr")
+			Expect(err).To(MatchError(ContainSubstring("unsupported overlay mount option `userxattr`")))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create read-only images", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		spec            groot.CreateSpec
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "local-image-dir")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
+		baseImagePath = integration.CreateBaseImageTar(sourceImagePath).Name()
+
+		spec = groot.CreateSpec{
+			BaseImageURL: integration.String2URL(baseImagePath),
+			ID:           testhelpers.NewRandomID(),
+			Mount:        mountByDefault(),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+	})
+
+	It("marks the root as read-only in the returned spec", func() {
+		containerSpec, err := Runner.CreateReadOnly(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(containerSpec.Root.Readonly).To(BeTrue())
+	})
+
+	It("doesn't create a writable layer", func() {
+		containerSpec, err := Runner.CreateReadOnly(spec)
+		Expect(err).NotTo(HaveOccurred())
+
+		imagePath := filepath.Dir(containerSpec.Root.Path)
+		Expect(filepath.Join(imagePath, "diff")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(imagePath, "workdir")).NotTo(BeAnExistingFile())
+	})
+
+	It("reports no exclusive disk usage", func() {
+		_, err := Runner.CreateReadOnly(spec)
+		Expect(err).NotTo(HaveOccurred())
+
+		stats, err := Runner.Stats(spec.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats.DiskUsage.ExclusiveBytesUsed).To(BeZero())
+		Expect(stats.DiskUsage.TotalBytesUsed).NotTo(BeZero())
+	})
+
+	It("can be deleted", func() {
+		containerSpec, err := Runner.CreateReadOnly(spec)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(Runner.Delete(spec.ID)).To(Succeed())
+		Expect(filepath.Dir(containerSpec.Root.Path)).NotTo(BeAnExistingFile())
+	})
+
+	Context("when the image is mounted", func() {
+		BeforeEach(func() {
+			integration.SkipIfNonRoot(GrootfsTestUid)
+			spec.Mount = true
+		})
+
+		It("mounts the rootfs read-only", func() {
+			containerSpec, err := Runner.CreateReadOnly(spec)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(containerSpec.Root.Path, "foo"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("hello-world"))
+
+			err = ioutil.WriteFile(filepath.Join(containerSpec.Root.Path, "bar"), []byte{}, 0644)
+			Expect(err).To(MatchError(ContainSubstring("read-only file system")))
+		})
+	})
+
+	Context("when the image is not mounted", func() {
+		BeforeEach(func() {
+			spec.Mount = false
+		})
+
+		It("returns a read-only bind mount of the base volume", func() {
+			containerSpec, err := Runner.CreateReadOnly(spec)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(containerSpec.Mounts).To(HaveLen(1))
+			Expect(containerSpec.Mounts[0].Destination).To(Equal("/"))
+			Expect(containerSpec.Mounts[0].Type).To(Equal("bind"))
+			Expect(containerSpec.Mounts[0].Options).To(Equal([]string{"bind", "ro"}))
+		})
+	})
+})
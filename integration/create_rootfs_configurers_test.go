@@ -0,0 +1,59 @@
+package integration_test
+
+import (
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	runnerpkg "code.cloudfoundry.org/grootfs/integration/runner"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Create with rootfs configurers", func() {
+	var (
+		runner runnerpkg.Runner
+		cfg    config.Config
+		spec   groot.CreateSpec
+	)
+
+	BeforeEach(func() {
+		cfg = config.Config{}
+		cfg.Create.RootFSConfigurers = []string{config.RootFSConfigurerWorkingDir, config.RootFSConfigurerVolumes}
+
+		spec = groot.CreateSpec{
+			BaseImageURL: integration.String2URL("docker:///cfgarden/with-volume"),
+			ID:           testhelpers.NewRandomID(),
+			Mount:        mountByDefault(),
+		}
+	})
+
+	JustBeforeEach(func() {
+		runner = Runner
+		Expect(runner.SetConfig(cfg)).To(Succeed())
+	})
+
+	It("creates the mount points of the image volumes", func() {
+		containerSpec, err := runner.Create(spec)
+		Expect(err).NotTo(HaveOccurred())
+
+		if !mountByDefault() {
+			Skip("the rootfs is only visible when the image is mounted")
+		}
+		Expect(filepath.Join(containerSpec.Root.Path, "foo")).To(BeADirectory())
+	})
+
+	Context("when a configurer is unknown", func() {
+		BeforeEach(func() {
+			cfg.Create.RootFSConfigurers = []string{"hostname"}
+		})
+
+		It("fails", func() {
+			_, err := runner.Create(spec)
+			Expect(err).To(MatchError(ContainSubstring("unknown rootfs configurer `hostname`")))
+		})
+	})
+})
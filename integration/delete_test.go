@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"syscall"
 
 	"code.cloudfoundry.org/grootfs/groot"
 	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
 	"code.cloudfoundry.org/grootfs/store"
 	"code.cloudfoundry.org/grootfs/testhelpers"
 
@@ -58,6 +60,139 @@ var _ = Describe("Delete", func() {
 		Expect(filepath.Dir(containerSpec.Root.Path)).NotTo(BeAnExistingFile())
 	})
 
+	Context("when a process is using the image", func() {
+		var process *exec.Cmd
+
+		BeforeEach(func() {
+			integration.SkipIfNonRoot(GrootfsTestUid)
+		})
+
+		JustBeforeEach(func() {
+			process = exec.Command("sleep", "1000")
+			process.Dir = containerSpec.Root.Path
+			Expect(process.Start()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(process.Process.Kill()).To(Succeed())
+			_ = process.Wait()
+		})
+
+		It("refuses to delete it, listing the process", func() {
+			err := Runner.Delete(randomImageID)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("is in use by processes %d", process.Process.Pid))))
+			Expect(filepath.Join(containerSpec.Root.Path, "foo")).To(BeAnExistingFile())
+		})
+
+		It("deletes it with --force", func() {
+			Expect(Runner.ForceDelete(randomImageID)).To(Succeed())
+			Expect(filepath.Dir(containerSpec.Root.Path)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("when --async is given", func() {
+		It("moves the image to the trash, which clean empties", func() {
+			Expect(Runner.AsyncDelete(randomImageID)).To(Succeed())
+			Expect(filepath.Dir(containerSpec.Root.Path)).NotTo(BeAnExistingFile())
+
+			trashPath := filepath.Join(StorePath, store.TrashDirName)
+			trashedImages, err := ioutil.ReadDir(trashPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trashedImages).To(HaveLen(1))
+
+			_, err = Runner.Clean(0)
+			Expect(err).NotTo(HaveOccurred())
+
+			trashedImages, err = ioutil.ReadDir(trashPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trashedImages).To(BeEmpty())
+		})
+
+		It("releases the image id", func() {
+			Expect(Runner.AsyncDelete(randomImageID)).To(Succeed())
+
+			_, err := Runner.Create(groot.CreateSpec{
+				BaseImageURL: integration.String2URL(baseImagePath),
+				ID:           randomImageID,
+				Mount:        mountByDefault(),
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when deleting several images", func() {
+		var otherImageID string
+
+		JustBeforeEach(func() {
+			otherImageID = testhelpers.NewRandomID()
+			_, err := Runner.Create(groot.CreateSpec{
+				BaseImageURL: integration.String2URL(baseImagePath),
+				ID:           otherImageID,
+				Mount:        mountByDefault(),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("deletes the given images", func() {
+			results, err := Runner.DeleteImages(randomImageID, otherImageID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(ConsistOf(
+				grootfs.DeleteResult{ID: randomImageID, Deleted: true},
+				grootfs.DeleteResult{ID: otherImageID, Deleted: true},
+			))
+
+			Expect(filepath.Join(StorePath, store.ImageDirName, randomImageID)).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(StorePath, store.ImageDirName, otherImageID)).NotTo(BeAnExistingFile())
+		})
+
+		It("reports the images that don't exist as deleted", func() {
+			results, err := Runner.DeleteImages(randomImageID, "not-here")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(Equal([]grootfs.DeleteResult{
+				{ID: randomImageID, Deleted: true},
+				{ID: "not-here", Deleted: true},
+			}))
+
+			Expect(filepath.Join(StorePath, store.ImageDirName, randomImageID)).NotTo(BeAnExistingFile())
+		})
+
+		It("deletes all the images with --all", func() {
+			results, err := Runner.DeleteImages("--all")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+
+			images, err := ioutil.ReadDir(filepath.Join(StorePath, store.ImageDirName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(images).To(BeEmpty())
+		})
+
+		It("only deletes the images older than --older-than", func() {
+			results, err := Runner.DeleteImages("--older-than", "1h")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(BeEmpty())
+			Expect(filepath.Join(StorePath, store.ImageDirName, randomImageID)).To(BeADirectory())
+
+			results, err = Runner.DeleteImages("--older-than", "1ns")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(2))
+		})
+
+		It("collects the unused layers with --with-clean", func() {
+			_, err := Runner.DeleteImages("--all", "--with-clean")
+			Expect(err).NotTo(HaveOccurred())
+
+			volumes, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(volumes).To(BeEmpty())
+		})
+
+		It("refuses ids together with --all", func() {
+			_, err := Runner.DeleteImages("--all", randomImageID)
+			Expect(err).To(HaveOccurred())
+			Expect(filepath.Join(StorePath, store.ImageDirName, randomImageID)).To(BeADirectory())
+		})
+	})
+
 	Context("when there is a file in a directory groot doesn't have search permission on", func() {
 		JustBeforeEach(func() {
 			privateFolder := filepath.Join(containerSpec.Root.Path, "private-folder")
@@ -0,0 +1,78 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+var _ = Describe("Inspect", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		containerSpec   specs.Spec
+		imageID         string
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
+		baseImagePath = integration.CreateBaseImageTar(sourceImagePath).Name()
+		imageID = testhelpers.NewRandomID()
+
+		containerSpec, err = Runner.Create(groot.CreateSpec{
+			BaseImageURL: integration.String2URL(baseImagePath),
+			ID:           imageID,
+			DiskLimit:    10 * 1024 * 1024,
+			Mount:        mountByDefault(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+	})
+
+	It("returns the details of the image", func() {
+		inspection, err := Runner.Inspect(imageID)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(inspection.ID).To(Equal(imageID))
+		Expect(inspection.Path).To(Equal(filepath.Dir(containerSpec.Root.Path)))
+		Expect(inspection.BaseImageURL).To(Equal(baseImagePath))
+		Expect(inspection.DiskLimit).To(Equal(int64(10 * 1024 * 1024)))
+		Expect(inspection.Mounted).To(Equal(mountByDefault()))
+		Expect(inspection.Volumes).To(HaveLen(1))
+		Expect(inspection.Volumes[0].SizeBytes).To(BeNumerically(">", 0))
+		Expect(inspection.Mount.Type).To(Equal("overlay"))
+		Expect(inspection.ProjectID).NotTo(BeZero())
+		Expect(inspection.QuotaBytes).To(BeNumerically(">", 0))
+	})
+
+	It("accepts the image path", func() {
+		inspection, err := Runner.Inspect(filepath.Dir(containerSpec.Root.Path))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inspection.ID).To(Equal(imageID))
+	})
+
+	Context("when the image doesn't exist", func() {
+		It("fails", func() {
+			logBuffer := gbytes.NewBuffer()
+			_, err := Runner.WithStderr(logBuffer).Inspect("not-here")
+			Expect(err).To(HaveOccurred())
+			Expect(logBuffer).To(gbytes.Say(`"id":"not-here"`))
+		})
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -19,7 +20,10 @@ import (
 )
 
 var _ = Describe("List", func() {
-	var containerSpec specs.Spec
+	var (
+		containerSpec specs.Spec
+		baseImageURL  string
+	)
 
 	BeforeEach(func() {
 		sourceImagePath, err := ioutil.TempDir("", "")
@@ -27,6 +31,7 @@ var _ = Describe("List", func() {
 
 		Expect(ioutil.WriteFile(path.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
 		baseImageFile := integration.CreateBaseImageTar(sourceImagePath)
+		baseImageURL = baseImageFile.Name()
 		containerSpec, err = Runner.Create(groot.CreateSpec{
 			BaseImageURL: integration.String2URL(baseImageFile.Name()),
 			ID:           "root-image",
@@ -42,6 +47,21 @@ var _ = Describe("List", func() {
 		Expect(images[0].Path).To(Equal(filepath.Dir(containerSpec.Root.Path)))
 	})
 
+	Describe("--json", func() {
+		It("lists the details of each image", func() {
+			details, err := Runner.ListDetails()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(details).To(HaveLen(1))
+
+			Expect(details[0].ID).To(Equal("root-image"))
+			Expect(details[0].Path).To(Equal(filepath.Dir(containerSpec.Root.Path)))
+			Expect(details[0].BaseImageURL).To(Equal(baseImageURL))
+			Expect(details[0].ChainIDs).To(HaveLen(1))
+			Expect(details[0].Created).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(details[0].Mounted).To(Equal(mountByDefault()))
+		})
+	})
+
 	Describe("--config global flag", func() {
 		var (
 			configDir      string
@@ -0,0 +1,108 @@
+package integration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+var _ = Describe("Mount", func() {
+	var (
+		sourceImagePath string
+		baseImagePath   string
+		containerSpec   specs.Spec
+		imageID         string
+		mount           bool
+	)
+
+	BeforeEach(func() {
+		integration.SkipIfNonRoot(GrootfsTestUid)
+
+		var err error
+		sourceImagePath, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceImagePath, "foo"), []byte("hello-world"), 0644)).To(Succeed())
+		imageID = testhelpers.NewRandomID()
+		mount = false
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceImagePath)).To(Succeed())
+		Expect(os.RemoveAll(baseImagePath)).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		baseImageFile := integration.CreateBaseImageTar(sourceImagePath)
+		baseImagePath = baseImageFile.Name()
+
+		var err error
+		containerSpec, err = Runner.Create(groot.CreateSpec{
+			BaseImageURL: integration.String2URL(baseImagePath),
+			ID:           imageID,
+			Mount:        mount,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("mounts the rootfs of an image created without a mount", func() {
+		Expect(filepath.Join(containerSpec.Root.Path, "foo")).NotTo(BeAnExistingFile())
+
+		Expect(Runner.Mount(imageID)).To(Succeed())
+		Expect(filepath.Join(containerSpec.Root.Path, "foo")).To(BeAnExistingFile())
+	})
+
+	It("accepts the image path", func() {
+		Expect(Runner.Mount(filepath.Dir(containerSpec.Root.Path))).To(Succeed())
+		Expect(filepath.Join(containerSpec.Root.Path, "foo")).To(BeAnExistingFile())
+	})
+
+	Context("when the image doesn't exist", func() {
+		It("fails", func() {
+			err := Runner.Mount("not-here")
+			Expect(err).To(MatchError(ContainSubstring("image not found")))
+		})
+	})
+
+	Describe("unmount", func() {
+		BeforeEach(func() {
+			mount = true
+		})
+
+		It("unmounts the rootfs", func() {
+			Expect(filepath.Join(containerSpec.Root.Path, "foo")).To(BeAnExistingFile())
+
+			Expect(Runner.Unmount(imageID)).To(Succeed())
+			Expect(filepath.Join(containerSpec.Root.Path, "foo")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Describe("--all", func() {
+		BeforeEach(func() {
+			mount = true
+		})
+
+		JustBeforeEach(func() {
+			Expect(Runner.Unmount(imageID)).To(Succeed())
+			Expect(Runner.Mount(imageID)).To(Succeed())
+			Expect(syscall.Unmount(containerSpec.Root.Path, 0)).To(Succeed())
+		})
+
+		It("remounts the images that should be mounted", func() {
+			Expect(filepath.Join(containerSpec.Root.Path, "foo")).NotTo(BeAnExistingFile())
+
+			mounted, err := Runner.MountAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounted).To(ConsistOf(imageID))
+			Expect(filepath.Join(containerSpec.Root.Path, "foo")).To(BeAnExistingFile())
+		})
+	})
+})
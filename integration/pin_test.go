@@ -0,0 +1,117 @@
+package integration_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pin", func() {
+	var (
+		baseImagePath       string
+		pinnedBaseImagePath string
+	)
+
+	BeforeEach(func() {
+		workDir, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		baseImagePath = fmt.Sprintf("oci:///%s/assets/oci-test-image/grootfs-busybox:latest", workDir)
+		pinnedBaseImagePath = fmt.Sprintf("oci:///%s/assets/oci-test-image/4mb-image:latest", workDir)
+
+		_, err = Runner.Create(groot.CreateSpec{
+			ID:           "my-image-1",
+			BaseImageURL: integration.String2URL(baseImagePath),
+			Mount:        mountByDefault(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(Runner.Delete("my-image-1")).To(Succeed())
+	})
+
+	It("pulls the image layers", func() {
+		pin, err := Runner.Pin(pinnedBaseImagePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pin.BaseImageURL).To(Equal(pinnedBaseImagePath))
+		Expect(pin.Digest).To(HavePrefix("sha256:"))
+		Expect(pin.ChainIDs).NotTo(BeEmpty())
+		for _, chainID := range pin.ChainIDs {
+			Expect(filepath.Join(StorePath, store.VolumesDirName, chainID)).To(BeADirectory())
+		}
+	})
+
+	It("lists the pinned image", func() {
+		pin, err := Runner.Pin(pinnedBaseImagePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		pins, err := Runner.Pins()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pins).To(ConsistOf(pin))
+	})
+
+	It("keeps the pinned layers from being cleaned", func() {
+		_, err := Runner.Pin(pinnedBaseImagePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		preContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = Runner.Clean(0)
+		Expect(err).NotTo(HaveOccurred())
+
+		afterContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(afterContents).To(HaveLen(len(preContents)))
+	})
+
+	Context("when the image is unpinned", func() {
+		BeforeEach(func() {
+			_, err := Runner.Pin(pinnedBaseImagePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Runner.Unpin(pinnedBaseImagePath)).To(Succeed())
+		})
+
+		It("is no longer listed", func() {
+			pins, err := Runner.Pins()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pins).To(BeEmpty())
+		})
+
+		It("allows its layers to be cleaned", func() {
+			_, err := Runner.Clean(0)
+			Expect(err).NotTo(HaveOccurred())
+
+			afterContents, err := ioutil.ReadDir(filepath.Join(StorePath, store.VolumesDirName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(afterContents).To(HaveLen(4))
+		})
+	})
+
+	Context("when the image is unpinned by digest", func() {
+		It("is no longer listed", func() {
+			pin, err := Runner.Pin(pinnedBaseImagePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Runner.Unpin(pin.Digest)).To(Succeed())
+
+			pins, err := Runner.Pins()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pins).To(BeEmpty())
+		})
+	})
+
+	Context("when the image is not pinned", func() {
+		It("fails to unpin it", func() {
+			err := Runner.Unpin(pinnedBaseImagePath)
+			Expect(err).To(MatchError(ContainSubstring("is not pinned")))
+		})
+	})
+})
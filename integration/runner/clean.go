@@ -1,6 +1,11 @@
 package runner
 
-import "strconv"
+import (
+	"encoding/json"
+	"strconv"
+
+	"code.cloudfoundry.org/grootfs/groot"
+)
 
 func (r Runner) Clean(threshold int64) (string, error) {
 	args := []string{}
@@ -9,3 +14,30 @@ func (r Runner) Clean(threshold int64) (string, error) {
 
 	return r.RunSubcommand("clean", args...)
 }
+
+func (r Runner) CleanWithTarget(threshold, target int64) (string, error) {
+	args := []string{}
+
+	args = append(args, "--threshold-bytes", strconv.FormatInt(threshold, 10))
+	args = append(args, "--target-bytes", strconv.FormatInt(target, 10))
+
+	return r.RunSubcommand("clean", args...)
+}
+
+func (r Runner) CleanReport(threshold int64, dryRun bool) (groot.CleanReport, error) {
+	args := []string{"--json"}
+
+	args = append(args, "--threshold-bytes", strconv.FormatInt(threshold, 10))
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+
+	output, err := r.RunSubcommand("clean", args...)
+	if err != nil {
+		return groot.CleanReport{}, err
+	}
+
+	var report groot.CleanReport
+	err = json.Unmarshal([]byte(output), &report)
+	return report, err
+}
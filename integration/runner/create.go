@@ -45,6 +45,65 @@ func (r Runner) Create(spec groot.CreateSpec) (specs.Spec, error) {
 	return imageInfo, nil
 }
 
+func (r Runner) CreateWithBundle(spec groot.CreateSpec, bundlePath string) (specs.Spec, error) {
+	return r.createWithFlags(spec, "--bundle", bundlePath)
+}
+
+func (r Runner) CreateWithLayers(spec groot.CreateSpec, layerPaths ...string) (specs.Spec, error) {
+	flags := []string{}
+	for _, layerPath := range layerPaths {
+		flags = append(flags, "--layer", layerPath)
+	}
+
+	return r.createWithFlags(spec, flags...)
+}
+
+func (r Runner) CreateWithCopies(spec groot.CreateSpec, copySpecs ...string) (specs.Spec, error) {
+	flags := []string{}
+	for _, copySpec := range copySpecs {
+		flags = append(flags, "--copy", copySpec)
+	}
+
+	return r.createWithFlags(spec, flags...)
+}
+
+func (r Runner) CreateReadOnly(spec groot.CreateSpec) (specs.Spec, error) {
+	return r.createWithFlags(spec, "--read-only")
+}
+
+func (r Runner) CreateEphemeral(spec groot.CreateSpec, sizeBytes int64) (specs.Spec, error) {
+	return r.createWithFlags(spec, "--ephemeral", "--ephemeral-size", strconv.FormatInt(sizeBytes, 10))
+}
+
+func (r Runner) CreateWithOverlayMountOptions(spec groot.CreateSpec, options ...string) (specs.Spec, error) {
+	flags := []string{}
+	for _, option := range options {
+		flags = append(flags, "--overlay-mount-option", option)
+	}
+
+	return r.createWithFlags(spec, flags...)
+}
+
+// createWithFlags passes create flags that have no groot.CreateSpec field.
+func (r Runner) createWithFlags(spec groot.CreateSpec, flags ...string) (specs.Spec, error) {
+	if !r.skipInitStore {
+		if err := r.initStoreAsRoot(); err != nil {
+			return specs.Spec{}, err
+		}
+	}
+
+	args := append(flags, r.makeCreateArgs(spec)...)
+	output, err := r.RunSubcommand("create", args...)
+	if err != nil {
+		return specs.Spec{}, err
+	}
+
+	imageInfo := specs.Spec{}
+	_ = json.Unmarshal([]byte(output), &imageInfo)
+
+	return imageInfo, nil
+}
+
 func (r Runner) EnsureMounted(containerSpec specs.Spec) error {
 	if len(containerSpec.Mounts) != 0 {
 		for _, mountPoint := range containerSpec.Mounts {
@@ -107,6 +166,10 @@ func (r Runner) makeCreateArgs(spec groot.CreateSpec) []string {
 		args = append(args, "--threshold-bytes", strconv.FormatInt(spec.CleanOnCreateThresholdBytes, 10))
 	}
 
+	if spec.CleanOnCreateTargetBytes > 0 {
+		args = append(args, "--target-bytes", strconv.FormatInt(spec.CleanOnCreateTargetBytes, 10))
+	}
+
 	if spec.Mount {
 		args = append(args, "--with-mount")
 	} else {
@@ -1,5 +1,11 @@
 package runner
 
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+)
+
 func (r Runner) Delete(id string) error {
 	if id == "" {
 		_, err := r.RunSubcommand("delete")
@@ -8,3 +14,32 @@ func (r Runner) Delete(id string) error {
 	_, err := r.RunSubcommand("delete", id)
 	return err
 }
+
+func (r Runner) ForceDelete(id string) error {
+	_, err := r.RunSubcommand("delete", "--force", id)
+	return err
+}
+
+func (r Runner) AsyncDelete(id string) error {
+	_, err := r.RunSubcommand("delete", "--async", id)
+	return err
+}
+
+// DeleteImages runs a bulk delete and returns the result of each image, which
+// is also printed when some of them fail.
+func (r Runner) DeleteImages(args ...string) ([]grootfs.DeleteResult, error) {
+	output, err := r.RunSubcommand("delete", args...)
+	if err != nil {
+		output = err.Error()
+	}
+
+	results := []grootfs.DeleteResult{}
+	if jsonErr := json.Unmarshal([]byte(output), &results); jsonErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, jsonErr
+	}
+
+	return results, err
+}
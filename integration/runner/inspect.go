@@ -0,0 +1,18 @@
+package runner
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/grootfs/groot"
+)
+
+func (r Runner) Inspect(id string) (groot.ImageInspection, error) {
+	output, err := r.RunSubcommand("inspect", id)
+	if err != nil {
+		return groot.ImageInspection{}, err
+	}
+
+	var inspection groot.ImageInspection
+	err = json.Unmarshal([]byte(output), &inspection)
+	return inspection, err
+}
@@ -3,6 +3,7 @@ package runner
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 
 	"code.cloudfoundry.org/grootfs/groot"
 )
@@ -27,3 +28,17 @@ func (r Runner) List() ([]groot.ImageInfo, error) {
 
 	return images, nil
 }
+
+func (r Runner) ListDetails() ([]groot.ImageDetails, error) {
+	output, err := r.RunSubcommand("list", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	details := []groot.ImageDetails{}
+	if err := json.Unmarshal([]byte(output), &details); err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
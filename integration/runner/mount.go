@@ -0,0 +1,28 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+)
+
+func (r Runner) Mount(id string) error {
+	_, err := r.RunSubcommand("mount", id)
+	return err
+}
+
+func (r Runner) MountAll() ([]string, error) {
+	output, err := r.RunSubcommand("mount", "--all")
+
+	mounted := []string{}
+	scanner := bufio.NewScanner(bytes.NewBufferString(output))
+	for scanner.Scan() {
+		mounted = append(mounted, scanner.Text())
+	}
+
+	return mounted, err
+}
+
+func (r Runner) Unmount(id string) error {
+	_, err := r.RunSubcommand("unmount", id)
+	return err
+}
@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/grootfs/groot"
+)
+
+func (r Runner) Pin(baseImage string) (groot.Pin, error) {
+	output, err := r.RunSubcommand("pin", baseImage)
+	if err != nil {
+		return groot.Pin{}, err
+	}
+
+	var pin groot.Pin
+	err = json.Unmarshal([]byte(output), &pin)
+	return pin, err
+}
+
+func (r Runner) Unpin(baseImage string) error {
+	_, err := r.RunSubcommand("unpin", baseImage)
+	return err
+}
+
+func (r Runner) Pins() ([]groot.Pin, error) {
+	output, err := r.RunSubcommand("pins")
+	if err != nil {
+		return nil, err
+	}
+
+	var pins []groot.Pin
+	err = json.Unmarshal([]byte(output), &pins)
+	return pins, err
+}
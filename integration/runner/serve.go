@@ -0,0 +1,26 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/onsi/gomega/gexec"
+)
+
+func (r Runner) StartServe(socketPath string, args ...string) (*gexec.Session, error) {
+	args = append([]string{"--socket", socketPath}, args...)
+	return r.StartSubcommand("serve", args...)
+}
+
+// DaemonClient returns an HTTP client that talks to a daemon started with
+// StartServe. Requests can use any host, e.g. http://grootfs/images.
+func DaemonClient(socketPath string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+}
@@ -26,3 +26,14 @@ func (r Runner) Stats(id string) (groot.VolumeStats, error) {
 	err = json.Unmarshal([]byte(stats), &volumeStats)
 	return volumeStats, err
 }
+
+func (r Runner) StatsAll() (map[string]groot.VolumeStats, error) {
+	stats, err := r.RunSubcommand("stats", "--all")
+	if err != nil {
+		return nil, err
+	}
+
+	var allStats map[string]groot.VolumeStats
+	err = json.Unmarshal([]byte(stats), &allStats)
+	return allStats, err
+}
@@ -0,0 +1,195 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/daemon"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/integration"
+	"code.cloudfoundry.org/grootfs/integration/runner"
+	"code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/grootfs/testhelpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+var _ = Describe("Serve", func() {
+	var (
+		socketDir     string
+		socketPath    string
+		serveArgs     []string
+		session       *gexec.Session
+		client        *http.Client
+		baseImagePath string
+	)
+
+	BeforeEach(func() {
+		var err error
+		socketDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chmod(socketDir, 0777)).To(Succeed())
+		socketPath = filepath.Join(socketDir, "grootfs.sock")
+		serveArgs = []string{}
+
+		workDir, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		baseImagePath = fmt.Sprintf("oci:///%s/assets/oci-test-image/grootfs-busybox:latest", workDir)
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		session, err = Runner.StartServe(socketPath, serveArgs...)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(socketPath).Should(BeAnExistingFile())
+
+		client = runner.DaemonClient(socketPath)
+	})
+
+	AfterEach(func() {
+		session.Terminate()
+		Eventually(session).Should(gexec.Exit(0))
+		Expect(socketPath).NotTo(BeAnExistingFile())
+		Expect(os.RemoveAll(socketDir)).To(Succeed())
+	})
+
+	post := func(path string, body interface{}) *http.Response {
+		requestBody, err := json.Marshal(body)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := client.Post("http://grootfs"+path, "application/json", bytes.NewReader(requestBody))
+		Expect(err).NotTo(HaveOccurred())
+		return response
+	}
+
+	It("creates, stats, lists and deletes images", func() {
+		imageID := testhelpers.NewRandomID()
+		mount := mountByDefault()
+		response := post("/images", daemon.CreateRequest{ID: imageID, BaseImage: baseImagePath, Mount: &mount})
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		var spec specs.Spec
+		Expect(json.NewDecoder(response.Body).Decode(&spec)).To(Succeed())
+		Expect(spec.Root.Path).To(Equal(filepath.Join(StorePath, store.ImageDirName, imageID, "rootfs")))
+
+		response, err := client.Get("http://grootfs/images/" + imageID + "/stats")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		var stats groot.VolumeStats
+		Expect(json.NewDecoder(response.Body).Decode(&stats)).To(Succeed())
+		Expect(stats.DiskUsage.TotalBytesUsed).To(BeNumerically(">", 0))
+
+		response, err = client.Get("http://grootfs/images")
+		Expect(err).NotTo(HaveOccurred())
+		var images []string
+		Expect(json.NewDecoder(response.Body).Decode(&images)).To(Succeed())
+		Expect(images).To(ConsistOf(filepath.Join(StorePath, store.ImageDirName, imageID)))
+
+		request, err := http.NewRequest("DELETE", "http://grootfs/images/"+imageID, nil)
+		Expect(err).NotTo(HaveOccurred())
+		response, err = client.Do(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(filepath.Join(StorePath, store.ImageDirName, imageID)).NotTo(BeADirectory())
+	})
+
+	It("pulls an image and cleans it up", func() {
+		response := post("/pull", daemon.PullRequest{BaseImage: baseImagePath})
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		var pullResponse daemon.PullResponse
+		Expect(json.NewDecoder(response.Body).Decode(&pullResponse)).To(Succeed())
+		Expect(pullResponse.ChainIDs).NotTo(BeEmpty())
+		for _, chainID := range pullResponse.ChainIDs {
+			Expect(filepath.Join(StorePath, store.VolumesDirName, chainID)).To(BeADirectory())
+		}
+
+		response = post("/clean", daemon.CleanRequest{})
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		var report groot.CleanReport
+		Expect(json.NewDecoder(response.Body).Decode(&report)).To(Succeed())
+		Expect(report.Volumes).To(HaveLen(len(pullResponse.ChainIDs)))
+	})
+
+	It("shares the store with the CLI", func() {
+		imageID := testhelpers.NewRandomID()
+		_, err := Runner.Create(groot.CreateSpec{
+			ID:           imageID,
+			BaseImageURL: integration.String2URL(baseImagePath),
+			Mount:        mountByDefault(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := client.Get("http://grootfs/images/" + imageID + "/stats")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(Runner.Delete(imageID)).To(Succeed())
+	})
+
+	It("only lets its own user access the socket", func() {
+		info, err := os.Stat(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm() & 0077).To(BeZero())
+	})
+
+	Context("when the request names host paths", func() {
+		It("rejects it", func() {
+			bundlePath := filepath.Join(socketDir, "bundle")
+			response := post("/images", daemon.CreateRequest{
+				ID:        testhelpers.NewRandomID(),
+				BaseImage: baseImagePath,
+				Bundle:    bundlePath,
+			})
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(filepath.Join(bundlePath, "config.json")).NotTo(BeAnExistingFile())
+
+			response = post("/images", daemon.CreateRequest{
+				ID:        testhelpers.NewRandomID(),
+				BaseImage: baseImagePath,
+				Copies:    []string{"/etc/shadow:/shadow"},
+			})
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("when the image doesn't exist", func() {
+		It("returns not found", func() {
+			response, err := client.Get("http://grootfs/images/not-here/stats")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when a clean interval is set", func() {
+		BeforeEach(func() {
+			serveArgs = []string{"--clean-interval-seconds", "1"}
+		})
+
+		It("cleans unused volumes on schedule", func() {
+			response := post("/pull", daemon.PullRequest{BaseImage: baseImagePath})
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			var pullResponse daemon.PullResponse
+			Expect(json.NewDecoder(response.Body).Decode(&pullResponse)).To(Succeed())
+
+			Eventually(func() bool {
+				for _, chainID := range pullResponse.ChainIDs {
+					if _, err := os.Stat(filepath.Join(StorePath, store.VolumesDirName, chainID)); err == nil {
+						return false
+					}
+				}
+				return true
+			}, 10).Should(BeTrue())
+		})
+	})
+})
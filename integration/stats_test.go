@@ -130,6 +130,62 @@ var _ = Describe("Stats", func() {
 			})
 		})
 
+		Context("when --all is provided", func() {
+			var otherImageID string
+
+			JustBeforeEach(func() {
+				otherImageID = testhelpers.NewRandomID()
+				_, err := Runner.Create(groot.CreateSpec{
+					BaseImageURL: integration.String2URL(baseImagePath),
+					ID:           otherImageID,
+					DiskLimit:    diskLimit,
+					Mount:        mountByDefault(),
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the stats for every image in the store", func() {
+				allStats, err := Runner.StatsAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(allStats).To(HaveLen(2))
+
+				Expect(allStats).To(HaveKey(imageID))
+				Expect(allStats[imageID].DiskUsage.TotalBytesUsed).To(
+					BeNumerically("~", expectedStats.DiskUsage.TotalBytesUsed, 100),
+				)
+				Expect(allStats[imageID].DiskUsage.ExclusiveBytesUsed).To(
+					BeNumerically("~", expectedStats.DiskUsage.ExclusiveBytesUsed, 100),
+				)
+
+				Expect(allStats).To(HaveKey(otherImageID))
+				Expect(allStats[otherImageID].DiskUsage.ExclusiveBytesUsed).To(
+					BeNumerically("~", 0, 100),
+				)
+			})
+
+			Context("when an image is still being created", func() {
+				JustBeforeEach(func() {
+					Expect(os.Mkdir(filepath.Join(StorePath, store.ImageDirName, "half-created"), 0755)).To(Succeed())
+				})
+
+				It("skips it and returns the stats for the other images", func() {
+					allStats, err := Runner.StatsAll()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(allStats).To(HaveLen(2))
+					Expect(allStats).NotTo(HaveKey("half-created"))
+				})
+			})
+
+			It("returns the same stats as querying each image individually", func() {
+				allStats, err := Runner.StatsAll()
+				Expect(err).NotTo(HaveOccurred())
+
+				stats, err := Runner.Stats(imageID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(allStats[imageID]).To(Equal(stats))
+			})
+		})
+
 		Context("when aux binary doesn't have the suid bit", func() {
 			var (
 				tardisBin string
@@ -214,6 +270,13 @@ var _ = Describe("Stats", func() {
 		})
 	})
 
+	Context("when --all is provided together with an image id", func() {
+		It("returns an error", func() {
+			_, err := Runner.RunSubcommand("stats", "--all", "some-id")
+			Expect(err).To(MatchError(ContainSubstring("invalid arguments")))
+		})
+	})
+
 	Context("when the image id is not provided", func() {
 		It("returns an error", func() {
 			_, err := Runner.Stats("")
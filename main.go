@@ -80,6 +80,10 @@ func main() {
 			Usage: "Metron endpoint used to send metrics",
 			Value: "",
 		},
+		cli.BoolFlag{
+			Name:  "preserve-devices",
+			Usage: "Keep the device nodes of the image layers instead of skipping them. Set at init-store; requires root and a store without ID mappings.",
+		},
 	}
 
 	grootfs.Commands = []cli.Command{
@@ -89,8 +93,16 @@ func main() {
 		commands.CreateCommand,
 		commands.DeleteCommand,
 		commands.StatsCommand,
+		commands.InspectCommand,
+		commands.MountCommand,
+		commands.UnmountCommand,
+		commands.PinCommand,
+		commands.UnpinCommand,
+		commands.PinsCommand,
 		commands.CleanCommand,
 		commands.ListCommand,
+		commands.ServeCommand,
+		commands.SnapshotterCommand,
 	}
 
 	grootfs.Before = func(ctx *cli.Context) error {
@@ -108,6 +120,7 @@ func main() {
 			WithLogFile(ctx.GlobalString("log-file")).
 			WithNewuidmapBin(ctx.GlobalString("newuidmap-bin"), ctx.IsSet("newuidmap-bin")).
 			WithNewgidmapBin(ctx.GlobalString("newgidmap-bin"), ctx.IsSet("newgidmap-bin")).
+			WithPreserveDevices(ctx.GlobalBool("preserve-devices"), ctx.IsSet("preserve-devices")).
 			Build()
 		if err != nil {
 			return cli.NewExitError(err.Error(), 1)
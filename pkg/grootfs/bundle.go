@@ -0,0 +1,165 @@
+package grootfs // import "code.cloudfoundry.org/grootfs/pkg/grootfs"
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	errorspkg "github.com/pkg/errors"
+)
+
+// bundleFilename is the file in the image directory recording where the
+// image's bundle was written, so that deleting the image removes it.
+const bundleFilename = "bundle"
+
+// checkBundlePath fails early, before the image is created, when the bundle
+// directory already holds a bundle, or anything named like its files.
+func checkBundlePath(bundlePath string) error {
+	for _, name := range []string{"config.json", "rootfs"} {
+		_, err := os.Lstat(filepath.Join(bundlePath, name))
+		if err == nil {
+			return errorspkg.Errorf("bundle `%s` already exists", bundlePath)
+		}
+		if !os.IsNotExist(err) {
+			return errorspkg.Wrapf(err, "checking bundle `%s`", bundlePath)
+		}
+	}
+
+	return nil
+}
+
+// bundleSpec is the default runc spec running the image's full spec: the
+// process, annotations and volume mounts come from the image, and the user
+// namespace from the store's mappings.
+func bundleSpec(id string, image groot.ImageInfo, idMappings groot.IDMappings) (specs.Spec, error) {
+	fullSpec, err := fullContainerSpec(image)
+	if err != nil {
+		return specs.Spec{}, err
+	}
+
+	spec := specconv.Example()
+	spec.Hostname = id
+	spec.Root = fullSpec.Root
+	spec.Annotations = fullSpec.Annotations
+	spec.Mounts = append(spec.Mounts, fullSpec.Mounts...)
+
+	spec.Process.Terminal = false
+	spec.Process.User = fullSpec.Process.User
+	spec.Process.Cwd = fullSpec.Process.Cwd
+	if len(fullSpec.Process.Args) > 0 {
+		spec.Process.Args = fullSpec.Process.Args
+	}
+	if len(fullSpec.Process.Env) > 0 {
+		spec.Process.Env = fullSpec.Process.Env
+	}
+
+	if len(idMappings.UIDMappings) > 0 || len(idMappings.GIDMappings) > 0 {
+		spec.Linux.Namespaces = append(spec.Linux.Namespaces, specs.LinuxNamespace{Type: specs.UserNamespace})
+		spec.Linux.UIDMappings = linuxIDMappings(idMappings.UIDMappings)
+		spec.Linux.GIDMappings = linuxIDMappings(idMappings.GIDMappings)
+	}
+
+	return *spec, nil
+}
+
+// writeBundle writes config.json and a rootfs link to the image rootfs into
+// bundlePath. The spec's root keeps the absolute image rootfs path, as runc
+// refuses a symlinked root. On failure, whatever it wrote is removed.
+func writeBundle(bundlePath, id string, image groot.ImageInfo, idMappings groot.IDMappings) (err error) {
+	spec, err := bundleSpec(id, image, idMappings)
+	if err != nil {
+		return err
+	}
+
+	bundlePath, err = filepath.Abs(bundlePath)
+	if err != nil {
+		return errorspkg.Wrapf(err, "resolving bundle path `%s`", bundlePath)
+	}
+
+	_, statErr := os.Stat(bundlePath)
+	createdDir := os.IsNotExist(statErr)
+	if err := os.MkdirAll(bundlePath, 0755); err != nil {
+		return errorspkg.Wrapf(err, "creating bundle `%s`", bundlePath)
+	}
+
+	written := []string{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, path := range written {
+			_ = os.Remove(path)
+		}
+		if createdDir {
+			_ = os.Remove(bundlePath)
+		}
+	}()
+
+	contents, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return errorspkg.Wrap(err, "encoding bundle config")
+	}
+	configPath := filepath.Join(bundlePath, "config.json")
+	if err := ioutil.WriteFile(configPath, contents, 0644); err != nil {
+		return errorspkg.Wrapf(err, "writing bundle config in `%s`", bundlePath)
+	}
+	written = append(written, configPath)
+
+	rootfsPath := filepath.Join(bundlePath, "rootfs")
+	if err := os.Symlink(image.Rootfs, rootfsPath); err != nil {
+		return errorspkg.Wrapf(err, "linking bundle rootfs in `%s`", bundlePath)
+	}
+	written = append(written, rootfsPath)
+
+	if err := ioutil.WriteFile(filepath.Join(image.Path, bundleFilename), []byte(bundlePath), 0644); err != nil {
+		return errorspkg.Wrap(err, "recording bundle path")
+	}
+
+	return nil
+}
+
+// removeBundle removes the bundle recorded for the image, if any. The bundle
+// directory itself is only removed when nothing else was put in it.
+func removeBundle(imagePath string) error {
+	contents, err := ioutil.ReadFile(filepath.Join(imagePath, bundleFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errorspkg.Wrap(err, "reading bundle path")
+	}
+	bundlePath := strings.TrimSpace(string(contents))
+
+	for _, name := range []string{"config.json", "rootfs"} {
+		if err := os.Remove(filepath.Join(bundlePath, name)); err != nil && !os.IsNotExist(err) {
+			return errorspkg.Wrapf(err, "removing bundle `%s`", bundlePath)
+		}
+	}
+
+	if err := os.Remove(bundlePath); err != nil && !os.IsNotExist(err) {
+		if entries, readErr := ioutil.ReadDir(bundlePath); readErr == nil && len(entries) > 0 {
+			return nil
+		}
+		return errorspkg.Wrapf(err, "removing bundle `%s`", bundlePath)
+	}
+
+	return nil
+}
+
+func linuxIDMappings(mappings []groot.IDMappingSpec) []specs.LinuxIDMapping {
+	linuxMappings := []specs.LinuxIDMapping{}
+	for _, mapping := range mappings {
+		linuxMappings = append(linuxMappings, specs.LinuxIDMapping{
+			ContainerID: uint32(mapping.NamespaceID),
+			HostID:      uint32(mapping.HostID),
+			Size:        uint32(mapping.Size),
+		})
+	}
+
+	return linuxMappings
+}
@@ -0,0 +1,196 @@
+package grootfs
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+var _ = Describe("Bundle", func() {
+	var (
+		tmpDir     string
+		bundlePath string
+		image      groot.ImageInfo
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "bundle")
+		Expect(err).NotTo(HaveOccurred())
+
+		imagePath := filepath.Join(tmpDir, "images", "my-image")
+		Expect(os.MkdirAll(filepath.Join(imagePath, "rootfs"), 0755)).To(Succeed())
+		bundlePath = filepath.Join(tmpDir, "bundles", "my-image")
+
+		image = groot.ImageInfo{
+			Path:   imagePath,
+			Rootfs: filepath.Join(imagePath, "rootfs"),
+			Image: specsv1.Image{
+				Config: specsv1.ImageConfig{
+					Env:    []string{"PATH=/bin"},
+					Cmd:    []string{"/bin/app"},
+					Labels: map[string]string{"maintainer": "groot"},
+				},
+			},
+			Mounts: []groot.MountInfo{
+				{Destination: "/data", Type: "bind", Source: "/images/vol-1", Options: []string{"bind"}},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("bundleSpec", func() {
+		It("runs the image process in the default runc container", func() {
+			spec, err := bundleSpec("my-image", image, groot.IDMappings{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Hostname).To(Equal("my-image"))
+			Expect(spec.Root.Path).To(Equal(image.Rootfs))
+			Expect(spec.Process.Terminal).To(BeFalse())
+			Expect(spec.Process.Args).To(Equal([]string{"/bin/app"}))
+			Expect(spec.Process.Env).To(Equal([]string{"PATH=/bin"}))
+			Expect(spec.Process.Cwd).To(Equal("/"))
+			Expect(spec.Annotations).To(HaveKeyWithValue("maintainer", "groot"))
+			Expect(spec.Mounts).To(ContainElement(specs.Mount{
+				Destination: "/data", Type: "bind", Source: "/images/vol-1", Options: []string{"bind"},
+			}))
+			destinations := []string{}
+			for _, mount := range spec.Mounts {
+				destinations = append(destinations, mount.Destination)
+			}
+			Expect(destinations).To(ContainElement("/proc"))
+			Expect(spec.Linux.Namespaces).NotTo(ContainElement(specs.LinuxNamespace{Type: specs.UserNamespace}))
+		})
+
+		Context("when the image has no command", func() {
+			It("keeps the default process arguments", func() {
+				image.Image.Config.Cmd = nil
+
+				spec, err := bundleSpec("my-image", image, groot.IDMappings{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.Args).To(Equal([]string{"sh"}))
+			})
+		})
+
+		Context("when the store has id mappings", func() {
+			It("runs the process in a user namespace with the mappings", func() {
+				spec, err := bundleSpec("my-image", image, groot.IDMappings{
+					UIDMappings: []groot.IDMappingSpec{{HostID: 1000, NamespaceID: 0, Size: 1}},
+					GIDMappings: []groot.IDMappingSpec{{HostID: 100000, NamespaceID: 1, Size: 65000}},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(spec.Linux.Namespaces).To(ContainElement(specs.LinuxNamespace{Type: specs.UserNamespace}))
+				Expect(spec.Linux.UIDMappings).To(Equal([]specs.LinuxIDMapping{{HostID: 1000, ContainerID: 0, Size: 1}}))
+				Expect(spec.Linux.GIDMappings).To(Equal([]specs.LinuxIDMapping{{HostID: 100000, ContainerID: 1, Size: 65000}}))
+			})
+		})
+	})
+
+	Describe("writeBundle", func() {
+		It("writes the config and links the rootfs", func() {
+			Expect(writeBundle(bundlePath, "my-image", image, groot.IDMappings{})).To(Succeed())
+
+			contents, err := ioutil.ReadFile(filepath.Join(bundlePath, "config.json"))
+			Expect(err).NotTo(HaveOccurred())
+			var spec specs.Spec
+			Expect(json.Unmarshal(contents, &spec)).To(Succeed())
+			Expect(spec.Root.Path).To(Equal(image.Rootfs))
+
+			target, err := os.Readlink(filepath.Join(bundlePath, "rootfs"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(image.Rootfs))
+		})
+
+		It("records the bundle path in the image", func() {
+			Expect(writeBundle(bundlePath, "my-image", image, groot.IDMappings{})).To(Succeed())
+
+			contents, err := ioutil.ReadFile(filepath.Join(image.Path, bundleFilename))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(bundlePath))
+		})
+
+		Context("when the image user can't be resolved", func() {
+			It("returns an error without writing the bundle", func() {
+				image.Image.Config.User = "nobody"
+
+				Expect(writeBundle(bundlePath, "my-image", image, groot.IDMappings{})).To(MatchError(ContainSubstring("resolving user")))
+				Expect(bundlePath).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Context("when the rootfs can't be linked", func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(filepath.Join(bundlePath, "rootfs"), 0755)).To(Succeed())
+			})
+
+			It("removes the config it wrote", func() {
+				Expect(writeBundle(bundlePath, "my-image", image, groot.IDMappings{})).To(MatchError(ContainSubstring("linking bundle rootfs")))
+
+				Expect(filepath.Join(bundlePath, "config.json")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(bundlePath, "rootfs")).To(BeADirectory())
+				Expect(filepath.Join(image.Path, bundleFilename)).NotTo(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("checkBundlePath", func() {
+		It("accepts a missing directory", func() {
+			Expect(checkBundlePath(bundlePath)).To(Succeed())
+		})
+
+		Context("when the directory already has a bundle", func() {
+			It("returns an error", func() {
+				Expect(writeBundle(bundlePath, "my-image", image, groot.IDMappings{})).To(Succeed())
+				Expect(checkBundlePath(bundlePath)).To(MatchError(ContainSubstring("already exists")))
+			})
+		})
+
+		Context("when the directory already has a rootfs", func() {
+			It("returns an error", func() {
+				Expect(os.MkdirAll(filepath.Join(bundlePath, "rootfs"), 0755)).To(Succeed())
+				Expect(checkBundlePath(bundlePath)).To(MatchError(ContainSubstring("already exists")))
+			})
+		})
+	})
+
+	Describe("removeBundle", func() {
+		BeforeEach(func() {
+			Expect(writeBundle(bundlePath, "my-image", image, groot.IDMappings{})).To(Succeed())
+		})
+
+		It("removes the bundle", func() {
+			Expect(removeBundle(image.Path)).To(Succeed())
+			Expect(bundlePath).NotTo(BeAnExistingFile())
+			Expect(image.Rootfs).To(BeADirectory())
+		})
+
+		Context("when other files were put in the bundle directory", func() {
+			It("only removes the bundle files", func() {
+				Expect(ioutil.WriteFile(filepath.Join(bundlePath, "notes"), []byte{}, 0644)).To(Succeed())
+
+				Expect(removeBundle(image.Path)).To(Succeed())
+				Expect(filepath.Join(bundlePath, "notes")).To(BeAnExistingFile())
+				Expect(filepath.Join(bundlePath, "config.json")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		Context("when the image has no bundle", func() {
+			It("does nothing", func() {
+				Expect(os.Remove(filepath.Join(image.Path, bundleFilename))).To(Succeed())
+				Expect(removeBundle(image.Path)).To(Succeed())
+				Expect(filepath.Join(bundlePath, "config.json")).To(BeAnExistingFile())
+			})
+		})
+	})
+})
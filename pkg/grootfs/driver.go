@@ -0,0 +1,55 @@
+package grootfs // import "code.cloudfoundry.org/grootfs/pkg/grootfs"
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs"
+	"code.cloudfoundry.org/grootfs/store/image_cloner"
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+)
+
+type FileSystemDriver interface {
+	CreateImage(logger lager.Logger, spec image_cloner.ImageDriverSpec) (groot.MountInfo, error)
+	DestroyImage(logger lager.Logger, path string) error
+	ReconcileProjectIDs(logger lager.Logger) ([]uint32, error)
+	FetchStats(logger lager.Logger, path string) (groot.VolumeStats, error)
+	FetchAllStats(logger lager.Logger) (map[string]groot.VolumeStats, error)
+	MountImage(logger lager.Logger, path string) error
+	UnmountImage(logger lager.Logger, path string) error
+	DetachImage(logger lager.Logger, path string) error
+	TrashImage(logger lager.Logger, imagePath, trashPath string) error
+	MountAllImages(logger lager.Logger) ([]string, error)
+	ImageMounted(logger lager.Logger, path string) (bool, error)
+	InspectImage(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error)
+	FlattenVolumes(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error)
+	ConfigureStore(logger lager.Logger, storePath string, ownerUID, ownerGID int) error
+	ValidateFileSystem(logger lager.Logger, path string) error
+	CheckOverlayMountOptions(logger lager.Logger, options []string) error
+	InitFilesystem(logger lager.Logger, filesystemPath, storePath string) error
+	DeInitFilesystem(logger lager.Logger, storePath string) error
+	VolumePath(logger lager.Logger, id string) (string, error)
+	Volumes(logger lager.Logger) ([]string, error)
+	VolumeSize(lager.Logger, string) (int64, error)
+	VolumeLastUsed(logger lager.Logger, id string) (time.Time, error)
+	TouchVolume(logger lager.Logger, id string) error
+	CreateVolume(logger lager.Logger, parentID, id string) (string, error)
+	DestroyVolume(logger lager.Logger, id string) error
+	MoveVolume(logger lager.Logger, from, to string) error
+	WriteVolumeMeta(logger lager.Logger, id string, data base_image_puller.VolumeMeta) error
+	HandleOpaqueWhiteouts(logger lager.Logger, id string, opaqueWhiteouts []string) error
+	ClearProjectIDs(logger lager.Logger, path string) error
+	Marshal(logger lager.Logger) ([]byte, error)
+}
+
+func NewFileSystemDriver(cfg config.Config) (FileSystemDriver, error) {
+	switch cfg.FSDriver {
+	case "overlay-xfs":
+		return overlayxfs.NewDriver(cfg.StorePath, cfg.TardisBin), nil
+	default:
+		return nil, errorspkg.Errorf("filesystem driver not supported: %s", cfg.FSDriver)
+	}
+}
@@ -0,0 +1,66 @@
+package grootfs // import "code.cloudfoundry.org/grootfs/pkg/grootfs"
+
+import (
+	"net/url"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/fetcher/layer_fetcher"
+	"code.cloudfoundry.org/grootfs/fetcher/layer_fetcher/source"
+	"code.cloudfoundry.org/grootfs/fetcher/tar_fetcher"
+	"github.com/containers/image/types"
+)
+
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// FetcherFactory builds the fetcher used to pull a single base image.
+type FetcherFactory func(baseImageURL *url.URL, credentials Credentials) base_image_puller.Fetcher
+
+// DefaultFetcherFactory fetches tarballs from disk and docker or OCI images
+// through their registries, using the registry settings in createConfig.
+func DefaultFetcherFactory(createConfig config.Create) FetcherFactory {
+	return func(baseImageURL *url.URL, credentials Credentials) base_image_puller.Fetcher {
+		if baseImageURL.Scheme == "" {
+			return tar_fetcher.NewTarFetcher(baseImageURL)
+		}
+
+		systemContext := createSystemContext(baseImageURL, createConfig, credentials)
+		skipOCILayerValidation := createConfig.SkipLayerValidation && baseImageURL.Scheme == "oci"
+		layerSource := source.NewLayerSource(systemContext, skipOCILayerValidation, baseImageURL)
+		return layer_fetcher.NewLayerFetcher(&layerSource)
+	}
+}
+
+func createSystemContext(baseImageURL *url.URL, createConfig config.Create, credentials Credentials) types.SystemContext {
+	scheme := baseImageURL.Scheme
+	switch scheme {
+	case "docker":
+		return types.SystemContext{
+			DockerInsecureSkipTLSVerify: skipTLSValidation(baseImageURL, createConfig.InsecureRegistries),
+			DockerAuthConfig: &types.DockerAuthConfig{
+				Username: credentials.Username,
+				Password: credentials.Password,
+			},
+		}
+	case "oci":
+		return types.SystemContext{
+			OCICertPath: createConfig.RemoteLayerClientCertificatesPath,
+		}
+	default:
+		return types.SystemContext{}
+	}
+
+}
+
+func skipTLSValidation(baseImageURL *url.URL, trustedRegistries []string) bool {
+	for _, trustedRegistry := range trustedRegistries {
+		if baseImageURL.Host == trustedRegistry {
+			return true
+		}
+	}
+
+	return false
+}
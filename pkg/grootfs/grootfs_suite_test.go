@@ -0,0 +1,13 @@
+package grootfs_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestGrootfs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Grootfs Suite")
+}
@@ -0,0 +1,107 @@
+package grootfs // import "code.cloudfoundry.org/grootfs/pkg/grootfs"
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	errorspkg "github.com/pkg/errors"
+)
+
+type imageBusyError struct {
+	id   string
+	pids []int
+}
+
+func (e imageBusyError) Error() string {
+	pids := make([]string, len(e.pids))
+	for i, pid := range e.pids {
+		pids[i] = strconv.Itoa(pid)
+	}
+
+	return fmt.Sprintf("image `%s` is in use by processes %s, stop them or force the delete", e.id, strings.Join(pids, ", "))
+}
+
+// imageUsers returns the PIDs of the processes using an image: those whose
+// root or working directory is in it, and those in another mount namespace
+// where it's mounted, like the processes of a container running from it.
+// Processes that can't be inspected, because they exit or belong to another
+// user, are skipped.
+func imageUsers(imagePath string) ([]int, error) {
+	procDirs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "listing processes")
+	}
+
+	ownMountNS, _ := os.Readlink("/proc/self/ns/mnt")
+	mountNSUsesImage := map[string]bool{}
+
+	pids := []int{}
+	for _, procDir := range procDirs {
+		pid, err := strconv.Atoi(procDir.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+
+		procPath := filepath.Join("/proc", procDir.Name())
+		if processUsesImage(procPath, imagePath, ownMountNS, mountNSUsesImage) {
+			pids = append(pids, pid)
+		}
+	}
+
+	sort.Ints(pids)
+	return pids, nil
+}
+
+func processUsesImage(procPath, imagePath, ownMountNS string, mountNSUsesImage map[string]bool) bool {
+	for _, link := range []string{"root", "cwd"} {
+		if target, err := os.Readlink(filepath.Join(procPath, link)); err == nil && isInPath(target, imagePath) {
+			return true
+		}
+	}
+
+	// The image is mounted in our own namespace by the store itself.
+	mountNS, err := os.Readlink(filepath.Join(procPath, "ns", "mnt"))
+	if err != nil || mountNS == ownMountNS {
+		return false
+	}
+
+	usesImage, ok := mountNSUsesImage[mountNS]
+	if !ok {
+		usesImage = mountInfoHasImage(filepath.Join(procPath, "mountinfo"), imagePath)
+		mountNSUsesImage[mountNS] = usesImage
+	}
+
+	return usesImage
+}
+
+// mountInfoHasImage looks for mounts in the image and for overlays whose
+// directories are in it, which is how a container's root shows up once it has
+// pivoted into it.
+func mountInfoHasImage(mountInfoPath, imagePath string) bool {
+	contents, err := ioutil.ReadFile(mountInfoPath)
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		if isInPath(fields[4], imagePath) || strings.Contains(fields[len(fields)-1], imagePath+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isInPath(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, parent+"/")
+}
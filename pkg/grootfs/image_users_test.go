@@ -0,0 +1,108 @@
+package grootfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("imageUsers", func() {
+	var (
+		imagePath string
+		otherPath string
+		processes []*exec.Cmd
+	)
+
+	startProcessIn := func(dir string) int {
+		cmd := exec.Command("sleep", "1000")
+		cmd.Dir = dir
+		Expect(cmd.Start()).To(Succeed())
+		processes = append(processes, cmd)
+		return cmd.Process.Pid
+	}
+
+	BeforeEach(func() {
+		var err error
+		imagePath, err = ioutil.TempDir("", "image")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Mkdir(filepath.Join(imagePath, "rootfs"), 0755)).To(Succeed())
+
+		otherPath, err = ioutil.TempDir("", "other")
+		Expect(err).NotTo(HaveOccurred())
+
+		processes = []*exec.Cmd{}
+	})
+
+	AfterEach(func() {
+		for _, cmd := range processes {
+			Expect(cmd.Process.Kill()).To(Succeed())
+			_ = cmd.Wait()
+		}
+		Expect(os.RemoveAll(imagePath)).To(Succeed())
+		Expect(os.RemoveAll(otherPath)).To(Succeed())
+	})
+
+	It("returns the processes working in the image", func() {
+		pid := startProcessIn(filepath.Join(imagePath, "rootfs"))
+		startProcessIn(otherPath)
+
+		pids, err := imageUsers(imagePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pids).To(Equal([]int{pid}))
+	})
+
+	It("doesn't match images whose path only shares a prefix", func() {
+		Expect(os.Mkdir(imagePath+"-2", 0755)).To(Succeed())
+		defer os.RemoveAll(imagePath + "-2")
+		startProcessIn(imagePath + "-2")
+
+		pids, err := imageUsers(imagePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pids).To(BeEmpty())
+	})
+
+	Describe("mountInfoHasImage", func() {
+		var mountInfoPath string
+
+		BeforeEach(func() {
+			mountInfoPath = filepath.Join(otherPath, "mountinfo")
+		})
+
+		It("finds overlays whose directories are in the image", func() {
+			Expect(ioutil.WriteFile(mountInfoPath, []byte(fmt.Sprintf(
+				"22 1 0:40 / / rw,relatime - overlay overlay rw,lowerdir=/store/l/abc,upperdir=%s/diff,workdir=%s/workdir\n",
+				imagePath, imagePath,
+			)), 0644)).To(Succeed())
+
+			Expect(mountInfoHasImage(mountInfoPath, imagePath)).To(BeTrue())
+		})
+
+		It("finds mounts in the image", func() {
+			Expect(ioutil.WriteFile(mountInfoPath, []byte(fmt.Sprintf(
+				"22 1 0:40 / %s/rootfs rw,relatime - overlay overlay rw\n", imagePath,
+			)), 0644)).To(Succeed())
+
+			Expect(mountInfoHasImage(mountInfoPath, imagePath)).To(BeTrue())
+		})
+
+		It("ignores other mounts", func() {
+			Expect(ioutil.WriteFile(mountInfoPath, []byte(
+				"22 1 0:40 / / rw,relatime - overlay overlay rw,lowerdir=/store/l/abc,upperdir=/store/images/other/diff\n",
+			), 0644)).To(Succeed())
+
+			Expect(mountInfoHasImage(mountInfoPath, imagePath)).To(BeFalse())
+		})
+	})
+
+	Describe("imageBusyError", func() {
+		It("lists the processes", func() {
+			err := imageBusyError{id: "my-image", pids: []int{12, 34}}
+			Expect(err).To(MatchError("image `my-image` is in use by processes 12, 34, stop them or force the delete"))
+		})
+	})
+})
@@ -0,0 +1,303 @@
+package grootfs // import "code.cloudfoundry.org/grootfs/pkg/grootfs"
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"github.com/docker/docker/pkg/system"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	errorspkg "github.com/pkg/errors"
+)
+
+// AnnotationStopSignal is where full specs keep the image's StopSignal, as in
+// the OCI image to runtime spec conversion.
+const AnnotationStopSignal = "org.opencontainers.image.stopSignal"
+
+func containerSpec(image groot.ImageInfo) specs.Spec {
+	spec := specs.Spec{
+		Root: &specs.Root{
+			Path:     image.Rootfs,
+			Readonly: image.ReadOnly,
+		},
+		Process: &specs.Process{
+			Env: image.Image.Config.Env,
+		},
+		Mounts: []specs.Mount{},
+	}
+
+	for _, mount := range image.Mounts {
+		spec.Mounts = append(spec.Mounts, specs.Mount{
+			Destination: mount.Destination,
+			Type:        mount.Type,
+			Source:      mount.Source,
+			Options:     mount.Options,
+		})
+	}
+
+	return spec
+}
+
+// fullContainerSpec adds the process and annotations from the image config to
+// containerSpec. User and group names are resolved through the image's
+// /etc/passwd and /etc/group.
+func fullContainerSpec(image groot.ImageInfo) (specs.Spec, error) {
+	spec := containerSpec(image)
+	spec.Version = specs.Version
+
+	config := image.Image.Config
+	spec.Process.Args = append(append([]string{}, config.Entrypoint...), config.Cmd...)
+	spec.Process.Cwd = config.WorkingDir
+	if spec.Process.Cwd == "" {
+		spec.Process.Cwd = "/"
+	}
+
+	user, err := resolveUser(rootfsLookupDirs(image), config.User)
+	if err != nil {
+		return specs.Spec{}, err
+	}
+	spec.Process.User = user
+
+	spec.Annotations = map[string]string{}
+	for key, value := range config.Labels {
+		spec.Annotations[key] = value
+	}
+	if config.StopSignal != "" {
+		spec.Annotations[AnnotationStopSignal] = config.StopSignal
+	}
+
+	return spec, nil
+}
+
+// rootfsLookupDirs returns the directories to look up rootfs files in, top
+// first. Images that aren't mounted are looked up in their overlay layers.
+func rootfsLookupDirs(image groot.ImageInfo) []string {
+	for _, mount := range image.Mounts {
+		if mount.Destination != "/" {
+			continue
+		}
+
+		// Read-only images with a single base volume are a bind mount of it.
+		if mount.Type == "bind" {
+			return []string{mount.Source}
+		}
+
+		if mount.Type != "overlay" || len(mount.Options) == 0 {
+			continue
+		}
+
+		dirs := []string{}
+		for _, option := range strings.Split(mount.Options[0], ",") {
+			switch {
+			case strings.HasPrefix(option, "upperdir="):
+				dirs = append([]string{strings.TrimPrefix(option, "upperdir=")}, dirs...)
+			case strings.HasPrefix(option, "lowerdir="):
+				dirs = append(dirs, strings.Split(strings.TrimPrefix(option, "lowerdir="), ":")...)
+			}
+		}
+		return dirs
+	}
+
+	return []string{image.Rootfs}
+}
+
+// resolveUser parses the image config user (`user`, `uid`, `user:group`,
+// `uid:gid` and their combinations). A user without a group gets the primary
+// group from /etc/passwd, and the groups it's a member of in /etc/group.
+func resolveUser(lookupDirs []string, configUser string) (specs.User, error) {
+	if configUser == "" {
+		return specs.User{}, nil
+	}
+
+	userPart, groupPart := configUser, ""
+	if parts := strings.SplitN(configUser, ":", 2); len(parts) == 2 {
+		userPart, groupPart = parts[0], parts[1]
+	}
+
+	passwdEntries, err := readEntries(lookupDirs, "etc/passwd")
+	if err != nil {
+		return specs.User{}, err
+	}
+
+	user := specs.User{}
+	var username string
+	uid, err := strconv.ParseUint(userPart, 10, 32)
+	if err == nil {
+		user.UID = uint32(uid)
+		if entry, ok := findEntry(passwdEntries, 2, userPart); ok {
+			username = entry[0]
+			if gid, err := strconv.ParseUint(entry[3], 10, 32); err == nil {
+				user.GID = uint32(gid)
+			}
+		}
+	} else {
+		entry, ok := findEntry(passwdEntries, 0, userPart)
+		if !ok {
+			return specs.User{}, errorspkg.Errorf("resolving user `%s`: no matching entry in /etc/passwd", userPart)
+		}
+		username = entry[0]
+		if user.UID, err = parseID(entry[2]); err != nil {
+			return specs.User{}, errorspkg.Wrapf(err, "resolving user `%s`", userPart)
+		}
+		if user.GID, err = parseID(entry[3]); err != nil {
+			return specs.User{}, errorspkg.Wrapf(err, "resolving user `%s`", userPart)
+		}
+	}
+
+	groupEntries, err := readEntries(lookupDirs, "etc/group")
+	if err != nil {
+		return specs.User{}, err
+	}
+
+	if groupPart != "" {
+		gid, err := strconv.ParseUint(groupPart, 10, 32)
+		if err == nil {
+			user.GID = uint32(gid)
+		} else {
+			entry, ok := findEntry(groupEntries, 0, groupPart)
+			if !ok {
+				return specs.User{}, errorspkg.Errorf("resolving group `%s`: no matching entry in /etc/group", groupPart)
+			}
+			if user.GID, err = parseID(entry[2]); err != nil {
+				return specs.User{}, errorspkg.Wrapf(err, "resolving group `%s`", groupPart)
+			}
+		}
+		return user, nil
+	}
+
+	if username != "" {
+		for _, entry := range groupEntries {
+			if len(entry) < 4 || !containsString(strings.Split(entry[3], ","), username) {
+				continue
+			}
+			gid, err := parseID(entry[2])
+			if err != nil || gid == user.GID {
+				continue
+			}
+			user.AdditionalGids = append(user.AdditionalGids, gid)
+		}
+	}
+
+	return user, nil
+}
+
+// readEntries reads the colon separated entries of the topmost copy of file
+// in lookupDirs, resolved inside the image. A missing file has no entries.
+func readEntries(lookupDirs []string, file string) ([][]string, error) {
+	info, hostPath, err := lookupImageFile(lookupDirs, file)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return [][]string{}, nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errorspkg.Errorf("reading /%s: not a regular file", file)
+	}
+
+	f, err := os.OpenFile(hostPath, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, errorspkg.Wrapf(err, "opening /%s", file)
+	}
+	defer f.Close()
+
+	entries := [][]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, strings.Split(line, ":"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errorspkg.Wrapf(err, "reading /%s", file)
+	}
+
+	return entries, nil
+}
+
+// lookupImageFile returns the topmost copy of file in lookupDirs, top first,
+// and where it was found, or nil when a whiteout removed it or an opaque
+// directory hides it. Symlinks are never followed, as they would be resolved
+// on the host, so a symlinked parent directory is an error.
+func lookupImageFile(lookupDirs []string, file string) (os.FileInfo, string, error) {
+	components := strings.Split(strings.Trim(filepath.Clean(file), "/"), "/")
+
+	for index, dir := range lookupDirs {
+		hostPath := dir
+		opaque := false
+		for n, component := range components {
+			hostPath = filepath.Join(hostPath, component)
+			info, err := os.Lstat(hostPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					break
+				}
+				return nil, "", errorspkg.Wrapf(err, "looking up /%s", file)
+			}
+
+			if info.Mode()&os.ModeCharDevice != 0 {
+				if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Rdev == 0 {
+					return nil, "", nil
+				}
+			}
+			if n == len(components)-1 {
+				return info, hostPath, nil
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				return nil, "", errorspkg.Errorf("looking up /%s: `%s` is a symlink", file, strings.TrimPrefix(hostPath, dir))
+			}
+			if !info.IsDir() {
+				return nil, "", nil
+			}
+
+			if index < len(lookupDirs)-1 && !opaque {
+				value, err := system.Lgetxattr(hostPath, "trusted.overlay.opaque")
+				if err != nil {
+					return nil, "", errorspkg.Wrapf(err, "looking up /%s", file)
+				}
+				opaque = string(value) == "y"
+			}
+		}
+
+		if opaque {
+			return nil, "", nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+func findEntry(entries [][]string, field int, value string) ([]string, bool) {
+	for _, entry := range entries {
+		if len(entry) >= 4 && entry[field] == value {
+			return entry, true
+		}
+	}
+
+	return nil, false
+}
+
+func parseID(id string) (uint32, error) {
+	parsed, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, errorspkg.Wrapf(err, "parsing id `%s`", id)
+	}
+
+	return uint32(parsed), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
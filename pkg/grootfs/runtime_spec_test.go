@@ -0,0 +1,298 @@
+package grootfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"github.com/docker/docker/pkg/system"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+var _ = Describe("Runtime spec", func() {
+	var (
+		rootfsPath string
+		image      groot.ImageInfo
+	)
+
+	BeforeEach(func() {
+		var err error
+		rootfsPath, err = ioutil.TempDir("", "rootfs")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(rootfsPath, "etc"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(rootfsPath, "etc", "passwd"), []byte(
+			"root:x:0:0:root:/root:/bin/sh\n"+
+				"# comment\n"+
+				"alice:x:1000:1000::/home/alice:/bin/sh\n",
+		), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(rootfsPath, "etc", "group"), []byte(
+			"root:x:0:\n"+
+				"alice:x:1000:\n"+
+				"staff:x:50:alice,bob\n"+
+				"wheel:x:10:bob\n",
+		), 0644)).To(Succeed())
+
+		image = groot.ImageInfo{
+			Rootfs: rootfsPath,
+			Image: specsv1.Image{
+				Config: specsv1.ImageConfig{
+					Env:        []string{"PATH=/bin"},
+					Entrypoint: []string{"/bin/entry"},
+					Cmd:        []string{"--flag"},
+					WorkingDir: "/app",
+					User:       "alice",
+					Labels:     map[string]string{"maintainer": "groot"},
+					StopSignal: "SIGQUIT",
+				},
+			},
+			Mounts: []groot.MountInfo{
+				{Destination: "/data", Type: "bind", Source: "/images/vol-1", Options: []string{"bind"}},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(rootfsPath)).To(Succeed())
+	})
+
+	Describe("containerSpec", func() {
+		It("only has the rootfs, the environment and the mounts", func() {
+			spec := containerSpec(image)
+
+			Expect(spec.Root.Path).To(Equal(rootfsPath))
+			Expect(spec.Process).To(Equal(&specs.Process{Env: []string{"PATH=/bin"}}))
+			Expect(spec.Mounts).To(Equal([]specs.Mount{
+				{Destination: "/data", Type: "bind", Source: "/images/vol-1", Options: []string{"bind"}},
+			}))
+			Expect(spec.Annotations).To(BeNil())
+			Expect(spec.Root.Readonly).To(BeFalse())
+		})
+
+		It("marks the root of read-only images as read-only", func() {
+			image.ReadOnly = true
+			Expect(containerSpec(image).Root.Readonly).To(BeTrue())
+		})
+	})
+
+	Describe("fullContainerSpec", func() {
+		It("has the process from the image config", func() {
+			spec, err := fullContainerSpec(image)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Version).To(Equal(specs.Version))
+			Expect(spec.Root.Path).To(Equal(rootfsPath))
+			Expect(spec.Process.Env).To(Equal([]string{"PATH=/bin"}))
+			Expect(spec.Process.Args).To(Equal([]string{"/bin/entry", "--flag"}))
+			Expect(spec.Process.Cwd).To(Equal("/app"))
+			Expect(spec.Mounts).To(HaveLen(1))
+		})
+
+		It("has the labels and stop signal as annotations", func() {
+			spec, err := fullContainerSpec(image)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Annotations).To(Equal(map[string]string{
+				"maintainer":         "groot",
+				AnnotationStopSignal: "SIGQUIT",
+			}))
+		})
+
+		It("resolves the user and its groups through the rootfs", func() {
+			spec, err := fullContainerSpec(image)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Process.User).To(Equal(specs.User{UID: 1000, GID: 1000, AdditionalGids: []uint32{50}}))
+		})
+
+		It("defaults the working directory to /", func() {
+			image.Image.Config.WorkingDir = ""
+
+			spec, err := fullContainerSpec(image)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Process.Cwd).To(Equal("/"))
+		})
+
+		Describe("user formats", func() {
+			It("leaves the user empty when there is none", func() {
+				image.Image.Config.User = ""
+
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.User).To(Equal(specs.User{}))
+			})
+
+			It("resolves the groups of a known uid", func() {
+				image.Image.Config.User = "1000"
+
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.User).To(Equal(specs.User{UID: 1000, GID: 1000, AdditionalGids: []uint32{50}}))
+			})
+
+			It("accepts an unknown uid", func() {
+				image.Image.Config.User = "2000"
+
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.User).To(Equal(specs.User{UID: 2000}))
+			})
+
+			It("resolves user and group names", func() {
+				image.Image.Config.User = "alice:wheel"
+
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.User).To(Equal(specs.User{UID: 1000, GID: 10}))
+			})
+
+			It("accepts a uid and a gid", func() {
+				image.Image.Config.User = "2000:3000"
+
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.User).To(Equal(specs.User{UID: 2000, GID: 3000}))
+			})
+
+			It("resolves a user name with a gid", func() {
+				image.Image.Config.User = "alice:3000"
+
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.User).To(Equal(specs.User{UID: 1000, GID: 3000}))
+			})
+
+			It("resolves a group name with a uid", func() {
+				image.Image.Config.User = "2000:staff"
+
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Process.User).To(Equal(specs.User{UID: 2000, GID: 50}))
+			})
+		})
+
+		Context("when the user doesn't exist", func() {
+			It("returns an error", func() {
+				image.Image.Config.User = "bob"
+
+				_, err := fullContainerSpec(image)
+				Expect(err).To(MatchError(ContainSubstring("resolving user `bob`")))
+			})
+		})
+
+		Context("when the group doesn't exist", func() {
+			It("returns an error", func() {
+				image.Image.Config.User = "alice:admins"
+
+				_, err := fullContainerSpec(image)
+				Expect(err).To(MatchError(ContainSubstring("resolving group `admins`")))
+			})
+		})
+
+		Context("when the image is not mounted", func() {
+			var upperDir string
+
+			BeforeEach(func() {
+				var err error
+				upperDir, err = ioutil.TempDir("", "upper")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(upperDir, "etc"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(upperDir, "etc", "passwd"), []byte("alice:x:1001:1001::/:/bin/sh\n"), 0644)).To(Succeed())
+
+				image.Rootfs = filepath.Join(rootfsPath, "not-mounted")
+				image.Mounts = append([]groot.MountInfo{{
+					Destination: "/",
+					Type:        "overlay",
+					Source:      "overlay",
+					Options:     []string{"lowerdir=" + rootfsPath + ",upperdir=" + upperDir + ",workdir=/work"},
+				}}, image.Mounts...)
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(upperDir)).To(Succeed())
+			})
+
+			It("resolves the user through the overlay layers, top first", func() {
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(spec.Process.User.UID).To(Equal(uint32(1001)))
+				Expect(spec.Process.User.AdditionalGids).To(Equal([]uint32{50}))
+			})
+
+			Context("when a layer has an opaque /etc", func() {
+				BeforeEach(func() {
+					Expect(system.Lsetxattr(filepath.Join(upperDir, "etc"), "trusted.overlay.opaque", []byte("y"), 0)).To(Succeed())
+				})
+
+				It("doesn't look up /etc in the layers below it", func() {
+					spec, err := fullContainerSpec(image)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(spec.Process.User.UID).To(Equal(uint32(1001)))
+					Expect(spec.Process.User.AdditionalGids).To(BeEmpty())
+				})
+			})
+
+			Context("when /etc/passwd is a symlink", func() {
+				BeforeEach(func() {
+					Expect(os.Remove(filepath.Join(upperDir, "etc", "passwd"))).To(Succeed())
+					Expect(os.Symlink("/etc/passwd", filepath.Join(upperDir, "etc", "passwd"))).To(Succeed())
+				})
+
+				It("doesn't follow it", func() {
+					_, err := fullContainerSpec(image)
+					Expect(err).To(MatchError(ContainSubstring("not a regular file")))
+				})
+			})
+
+			Context("when /etc is a symlink", func() {
+				BeforeEach(func() {
+					Expect(os.RemoveAll(filepath.Join(upperDir, "etc"))).To(Succeed())
+					Expect(os.Symlink("/etc", filepath.Join(upperDir, "etc"))).To(Succeed())
+				})
+
+				It("doesn't follow it", func() {
+					_, err := fullContainerSpec(image)
+					Expect(err).To(MatchError(ContainSubstring("`/etc` is a symlink")))
+				})
+			})
+
+			Context("when /etc/passwd was removed by a whiteout", func() {
+				BeforeEach(func() {
+					Expect(os.Remove(filepath.Join(upperDir, "etc", "passwd"))).To(Succeed())
+					Expect(syscall.Mknod(filepath.Join(upperDir, "etc", "passwd"), syscall.S_IFCHR, 0)).To(Succeed())
+				})
+
+				It("doesn't look it up in the layers below", func() {
+					_, err := fullContainerSpec(image)
+					Expect(err).To(MatchError(ContainSubstring("resolving user `alice`")))
+				})
+			})
+		})
+
+		Context("when the image is a read-only bind mount that is not mounted", func() {
+			BeforeEach(func() {
+				image.Rootfs = filepath.Join(rootfsPath, "not-mounted")
+				image.Mounts = append([]groot.MountInfo{{
+					Destination: "/",
+					Type:        "bind",
+					Source:      rootfsPath,
+					Options:     []string{"bind", "ro"},
+				}}, image.Mounts...)
+			})
+
+			It("resolves the user through the bind mount source", func() {
+				spec, err := fullContainerSpec(image)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(spec.Process.User.UID).To(Equal(uint32(1000)))
+			})
+		})
+	})
+})
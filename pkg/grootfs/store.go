@@ -0,0 +1,943 @@
+// Package grootfs lets other Go programs create and manage images in a grootfs
+// store without shelling out to the CLI. The CLI commands are built on it.
+package grootfs // import "code.cloudfoundry.org/grootfs/pkg/grootfs"
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/commandrunner/linux_command_runner"
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	unpackerpkg "code.cloudfoundry.org/grootfs/base_image_puller/unpacker"
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/commands/idfinder"
+	"code.cloudfoundry.org/grootfs/fetcher/cached_fetcher"
+	"code.cloudfoundry.org/grootfs/fetcher/layered_fetcher"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/metrics"
+	"code.cloudfoundry.org/grootfs/snapshotter"
+	storepkg "code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/grootfs/store/dependency_manager"
+	"code.cloudfoundry.org/grootfs/store/file_copier"
+	"code.cloudfoundry.org/grootfs/store/filesystems/namespaced"
+	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs"
+	"code.cloudfoundry.org/grootfs/store/garbage_collector"
+	"code.cloudfoundry.org/grootfs/store/image_cloner"
+	locksmithpkg "code.cloudfoundry.org/grootfs/store/locksmith"
+	"code.cloudfoundry.org/grootfs/store/manager"
+	"code.cloudfoundry.org/grootfs/store/rootfs_configurer"
+	"code.cloudfoundry.org/lager"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	errorspkg "github.com/pkg/errors"
+)
+
+var ErrStoreNotInitialized = errorspkg.New("Store path is not initialized. Please run init-store.")
+
+var ErrPreserveDevicesUnsupported = errorspkg.New("devices can only be preserved by root on a store without ID mappings")
+
+var ErrPreserveDevicesNotInitialized = errorspkg.New("Store was not initialized to preserve devices. Please run init-store with --preserve-devices on a new store.")
+
+type storeNotFoundError struct {
+	storePath string
+}
+
+func (e storeNotFoundError) Error() string {
+	return fmt.Sprintf("no store found at %s", e.storePath)
+}
+
+func IsStoreNotFound(err error) bool {
+	_, ok := errorspkg.Cause(err).(storeNotFoundError)
+	return ok
+}
+
+type imageNotFoundError struct {
+	err error
+}
+
+func (e imageNotFoundError) Error() string {
+	return e.err.Error()
+}
+
+func IsImageNotFound(err error) bool {
+	_, ok := errorspkg.Cause(err).(imageNotFoundError)
+	return ok
+}
+
+type CreateSpec struct {
+	ID                        string
+	BaseImageURL              *url.URL
+	Credentials               Credentials
+	DiskLimit                 int64
+	ExcludeBaseImageFromQuota bool
+	Mount                     bool
+	Clean                     bool
+	Labels                    map[string]string
+	SpecFormat                string
+	// Bundle, when set, is a directory to write a runc bundle for the image
+	// to. It is removed when the image is deleted.
+	Bundle string
+	// Layers are paths to plain or gzipped tar layers stacked, in order, on
+	// top of the base image.
+	Layers []string
+	// Copies are host files written into the image before it is returned.
+	Copies []groot.FileCopy
+	// ReadOnly images have no writable layer and no quota.
+	ReadOnly bool
+	// Ephemeral images keep their writable layer on a tmpfs of
+	// EphemeralSizeBytes, which is their disk limit, instead of the store.
+	Ephemeral          bool
+	EphemeralSizeBytes int64
+	// OverlayMountOptions are extra overlay mount options, like metacopy=on.
+	OverlayMountOptions []string
+}
+
+type DeleteSpec struct {
+	// Force deletes images that processes still use, lazily unmounting them
+	// so the processes keep their view of the image until they exit.
+	Force bool
+	// Async moves images to the store trash instead of removing their files,
+	// which is left to the next clean.
+	Async bool
+}
+
+// BulkDeleteSpec selects the images to delete in one go. Either IDs are
+// given, or every image matching the selectors and created more than
+// OlderThan ago (when set) is deleted.
+type BulkDeleteSpec struct {
+	DeleteSpec
+	IDs       []string
+	Selectors []groot.LabelSelector
+	OlderThan time.Duration
+	// CollectGarbage cleans the layers left unused by the deleted images.
+	CollectGarbage bool
+}
+
+// DeleteResult is the outcome of deleting one of the images of a bulk delete.
+type DeleteResult struct {
+	ID      string `json:"id"`
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+type CleanSpec struct {
+	ThresholdBytes int64
+	TargetBytes    int64
+	DryRun         bool
+}
+
+type PullSpec struct {
+	BaseImageURL *url.URL
+	Credentials  Credentials
+}
+
+type Option func(*Store)
+
+func WithLogger(logger lager.Logger) Option {
+	return func(s *Store) {
+		s.logger = logger
+	}
+}
+
+func WithMetricsEmitter(metricsEmitter groot.MetricsEmitter) Option {
+	return func(s *Store) {
+		s.metricsEmitter = metricsEmitter
+	}
+}
+
+func WithFetcherFactory(fetcherFactory FetcherFactory) Option {
+	return func(s *Store) {
+		s.fetcherFactory = fetcherFactory
+	}
+}
+
+// WithImageInfoCache reuses the base image infos resolved for registry and OCI
+// images across calls, until they expire from the cache.
+func WithImageInfoCache(cache *cached_fetcher.Cache) Option {
+	return func(s *Store) {
+		s.imageInfoCache = cache
+	}
+}
+
+// Store wires the groot operations to the drivers, locksmiths and metadata of
+// a single store. It is safe to share across goroutines: consistency relies on
+// the store's lock files, exactly like separate CLI processes.
+type Store struct {
+	cfg            config.Config
+	logger         lager.Logger
+	metricsEmitter groot.MetricsEmitter
+	fetcherFactory FetcherFactory
+	imageInfoCache *cached_fetcher.Cache
+
+	initialized        bool
+	unpackerErr        error
+	idMappings         groot.IDMappings
+	fsDriver           FileSystemDriver
+	nsFsDriver         *namespaced.Driver
+	unpacker           base_image_puller.Unpacker
+	sharedLocksmith    *locksmithpkg.FileSystem
+	exclusiveLocksmith *locksmithpkg.FileSystem
+	dependencyManager  *dependency_manager.DependencyManager
+	imageCloner        *image_cloner.ImageCloner
+	nsImageCloner      *image_cloner.ImageCloner
+	storeMeasurer      *storepkg.StoreMeasurer
+	cleaner            groot.Cleaner
+}
+
+func NewStore(cfg config.Config, options ...Option) (*Store, error) {
+	s := &Store{cfg: cfg}
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.logger == nil {
+		s.logger = lager.NewLogger("grootfs")
+	}
+	if s.metricsEmitter == nil {
+		s.metricsEmitter = metrics.NewEmitter(s.logger, cfg.MetronEndpoint)
+	}
+	if s.fetcherFactory == nil {
+		s.fetcherFactory = DefaultFetcherFactory(cfg.Create)
+	}
+
+	storePath := cfg.StorePath
+	fsDriver, err := NewFileSystemDriver(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	runner := linux_command_runner.New()
+	var idMapper unpackerpkg.IDMapper
+	if os.Getuid() != 0 {
+		idMapper = unpackerpkg.NewIDMapper(cfg.NewuidmapBin, cfg.NewgidmapBin, runner)
+	}
+
+	// Only operations that pull layers need the namespace file and the
+	// unpacker, so a store that isn't initialized can still be listed,
+	// inspected and have its images deleted.
+	storeNamespacer := groot.NewStoreNamespacer(storePath)
+	manager := manager.New(storePath, storeNamespacer, fsDriver, fsDriver, fsDriver)
+	s.initialized = manager.IsStoreInitialized(s.logger)
+	if s.initialized {
+		s.idMappings, err = storeNamespacer.Read()
+		if err != nil {
+			return nil, errorspkg.Wrap(err, "reading namespace file")
+		}
+
+		// Volumes are shared by every image, so whether their devices are
+		// kept is decided once, at init-store. Only the operations that unpack
+		// layers fail when they can't be kept.
+		preserveDevices, err := groot.DevicesPreserved(storePath)
+		if err != nil {
+			return nil, err
+		}
+		if (cfg.PreserveDevices || preserveDevices) && (os.Geteuid() != 0 || idMapper != nil || len(s.idMappings.UIDMappings) > 0 || len(s.idMappings.GIDMappings) > 0) {
+			s.unpackerErr = ErrPreserveDevicesUnsupported
+		} else if cfg.PreserveDevices && !preserveDevices {
+			s.unpackerErr = ErrPreserveDevicesNotInitialized
+		}
+
+		unpackerStrategy := unpackerpkg.UnpackStrategy{
+			Name:               cfg.FSDriver,
+			WhiteoutDevicePath: filepath.Join(storePath, overlayxfs.WhiteoutDevice),
+			PreserveDevices:    preserveDevices,
+		}
+		if idMapper == nil {
+			s.unpacker, err = unpackerpkg.NewTarUnpacker(unpackerStrategy)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			s.unpacker = unpackerpkg.NewNSIdMapperUnpacker(runner, idMapper, unpackerStrategy)
+		}
+	}
+
+	s.fsDriver = fsDriver
+	s.nsFsDriver = namespaced.New(fsDriver, s.idMappings, idMapper, runner)
+	s.sharedLocksmith = locksmithpkg.NewSharedFileSystem(storePath, s.metricsEmitter)
+	s.exclusiveLocksmith = locksmithpkg.NewExclusiveFileSystem(storePath, s.metricsEmitter)
+	s.dependencyManager = dependency_manager.NewDependencyManager(
+		filepath.Join(storePath, storepkg.MetaDirName, "dependencies"),
+	)
+	s.imageCloner = image_cloner.NewImageCloner(fsDriver, storePath)
+	s.nsImageCloner = image_cloner.NewImageCloner(s.nsFsDriver, storePath)
+
+	retainedLabels := []groot.LabelSelector{}
+	for _, label := range cfg.Clean.RetainLabels {
+		selector, err := groot.ParseLabelSelector(label)
+		if err != nil {
+			return nil, err
+		}
+		retainedLabels = append(retainedLabels, selector)
+	}
+
+	gc := garbage_collector.NewGC(s.nsFsDriver, s.imageCloner, s.dependencyManager, retainedLabels)
+	s.storeMeasurer = storepkg.NewStoreMeasurer(storePath, fsDriver, gc)
+	s.cleaner = groot.IamCleaner(s.exclusiveLocksmith, s.storeMeasurer, gc, s.metricsEmitter)
+
+	return s, nil
+}
+
+// UsingLogger returns a copy of the store that logs to logger, e.g. to tie the
+// logs of an operation to the request that triggered it.
+func (s *Store) UsingLogger(logger lager.Logger) *Store {
+	store := *s
+	store.logger = logger
+	return &store
+}
+
+// DefaultCreateSpec returns a spec with the create settings of the store's
+// configuration, for callers that only want to override some of them.
+func (s *Store) DefaultCreateSpec(id string, baseImageURL *url.URL) CreateSpec {
+	return CreateSpec{
+		ID:                        id,
+		BaseImageURL:              baseImageURL,
+		DiskLimit:                 s.cfg.Create.DiskLimitSizeBytes,
+		ExcludeBaseImageFromQuota: s.cfg.Create.ExcludeImageFromQuota,
+		Mount:                     !s.cfg.Create.WithoutMount,
+		Clean:                     s.cfg.Create.WithClean,
+		SpecFormat:                s.cfg.Create.SpecFormat,
+		OverlayMountOptions:       s.cfg.Create.OverlayMountOptions,
+	}
+}
+
+func (s *Store) DefaultCleanSpec() CleanSpec {
+	return CleanSpec{
+		ThresholdBytes: s.cfg.Clean.ThresholdBytes,
+		TargetBytes:    s.cfg.Clean.TargetBytes,
+	}
+}
+
+func (s *Store) Create(spec CreateSpec) (specs.Spec, error) {
+	if err := s.checkUnpacker(); err != nil {
+		return specs.Spec{}, err
+	}
+
+	if spec.ReadOnly && len(spec.Copies) > 0 {
+		return specs.Spec{}, errorspkg.New("invalid argument: files can't be copied into a read-only image")
+	}
+
+	if err := checkEphemeral(spec); err != nil {
+		return specs.Spec{}, err
+	}
+
+	if err := s.checkOverlayMountOptions(spec); err != nil {
+		return specs.Spec{}, err
+	}
+
+	if spec.Bundle != "" {
+		if !spec.Mount {
+			return specs.Spec{}, errorspkg.New("invalid argument: a bundle can only be written for a mounted image")
+		}
+		if err := checkBundlePath(spec.Bundle); err != nil {
+			return specs.Spec{}, err
+		}
+	}
+
+	fetcher := s.createFetcher(spec.BaseImageURL, spec.Credentials)
+	if len(spec.Layers) > 0 {
+		fetcher = layered_fetcher.NewLayeredFetcher(fetcher, spec.Layers)
+	}
+	defer func() {
+		if err := fetcher.Close(); err != nil {
+			s.logger.Error("closing-fetcher", err)
+		}
+	}()
+
+	creator := groot.IamCreator(
+		s.imageCloner, s.createBaseImagePuller(fetcher), s.sharedLocksmith,
+		s.dependencyManager, s.metricsEmitter, s.cleaner, s.rootFSConfigurer(spec),
+	)
+
+	image, err := creator.Create(s.logger, groot.CreateSpec{
+		ID:                          spec.ID,
+		Mount:                       spec.Mount,
+		BaseImageURL:                spec.BaseImageURL,
+		DiskLimit:                   spec.DiskLimit,
+		ExcludeBaseImageFromQuota:   spec.ExcludeBaseImageFromQuota,
+		UIDMappings:                 s.idMappings.UIDMappings,
+		GIDMappings:                 s.idMappings.GIDMappings,
+		CleanOnCreate:               spec.Clean,
+		CleanOnCreateThresholdBytes: s.cfg.Clean.ThresholdBytes,
+		CleanOnCreateTargetBytes:    s.cfg.Clean.TargetBytes,
+		MaxLayerDepth:               s.cfg.Create.MaxLayerDepth,
+		Labels:                      spec.Labels,
+		ReadOnly:                    spec.ReadOnly,
+		EphemeralSizeBytes:          spec.EphemeralSizeBytes,
+		OverlayMountOptions:         spec.OverlayMountOptions,
+	})
+	if err != nil {
+		return specs.Spec{}, err
+	}
+
+	s.emitStoreMetrics()
+	if spec.Bundle != "" {
+		if err := writeBundle(spec.Bundle, spec.ID, image, s.idMappings); err != nil {
+			if deleteErr := s.Delete(spec.ID); deleteErr != nil {
+				s.logger.Error("deleting-image-after-bundle-failure", deleteErr)
+			}
+			return specs.Spec{}, errorspkg.Wrap(err, "writing bundle")
+		}
+	}
+
+	if spec.SpecFormat == config.SpecFormatFull {
+		fullSpec, err := fullContainerSpec(image)
+		if err != nil {
+			if deleteErr := s.Delete(spec.ID); deleteErr != nil {
+				s.logger.Error("deleting-image-after-spec-failure", deleteErr)
+			}
+			return specs.Spec{}, errorspkg.Wrap(err, "generating runtime spec")
+		}
+		return fullSpec, nil
+	}
+	return containerSpec(image), nil
+}
+
+func checkEphemeral(spec CreateSpec) error {
+	if !spec.Ephemeral {
+		if spec.EphemeralSizeBytes != 0 {
+			return errorspkg.New("invalid argument: an ephemeral size can only be set for an ephemeral image")
+		}
+		return nil
+	}
+
+	if spec.ReadOnly {
+		return errorspkg.New("invalid argument: a read-only image can't be ephemeral")
+	}
+	if spec.DiskLimit != 0 || spec.ExcludeBaseImageFromQuota {
+		return errorspkg.New("invalid argument: the ephemeral size is the disk limit of an ephemeral image, it can't have a disk limit or exclude the image from quota")
+	}
+	if spec.EphemeralSizeBytes <= 0 {
+		return errorspkg.New("invalid argument: an ephemeral image needs a positive ephemeral size")
+	}
+
+	return nil
+}
+
+// checkOverlayMountOptions only accepts the supported overlay mount options,
+// so that callers can't override the layer directories. They are checked
+// against the kernel, unless they are the configured ones that init-store
+// already checked. Without root they can't be checked, and only fail once the
+// image is mounted.
+func (s *Store) checkOverlayMountOptions(spec CreateSpec) error {
+	if err := config.ValidateOverlayMountOptions(spec.OverlayMountOptions); err != nil {
+		return err
+	}
+
+	if spec.ReadOnly || len(spec.OverlayMountOptions) == 0 || os.Geteuid() != 0 {
+		return nil
+	}
+	if sameStrings(spec.OverlayMountOptions, s.cfg.Create.OverlayMountOptions) {
+		return nil
+	}
+
+	return s.fsDriver.CheckOverlayMountOptions(s.logger, spec.OverlayMountOptions)
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rootFSConfigurer chains the configurers enabled in the store config with
+// the file copies of a create, which run last so they can override what the
+// configurers wrote. It returns nil when there is nothing to configure, which
+// is always the case for read-only images.
+func (s *Store) rootFSConfigurer(spec CreateSpec) groot.RootFSConfigurer {
+	if spec.ReadOnly {
+		return nil
+	}
+
+	rootFS := rootfs_configurer.NewRootFS(s.unpacker, s.idMappings, s.imageLookupDirs)
+
+	chain := rootfs_configurer.Chain{}
+	for _, name := range s.cfg.Create.RootFSConfigurers {
+		switch name {
+		case config.RootFSConfigurerWorkingDir:
+			chain = append(chain, rootfs_configurer.NewWorkingDirConfigurer(s.logger, rootFS))
+		case config.RootFSConfigurerVolumes:
+			chain = append(chain, rootfs_configurer.NewVolumesConfigurer(s.logger, rootFS))
+		case config.RootFSConfigurerPasswd:
+			chain = append(chain, rootfs_configurer.NewPasswdConfigurer(s.logger, rootFS))
+		}
+	}
+
+	if len(spec.Copies) > 0 {
+		chain = append(chain, file_copier.NewFileCopier(s.logger, s.unpacker, s.idMappings, s.imageLookupDirs, spec.Copies))
+	}
+
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain
+}
+
+// imageLookupDirs returns the overlay directories of the image with the given
+// upper directory, top first.
+func (s *Store) imageLookupDirs(upperDir string) ([]string, error) {
+	details, err := s.fsDriver.InspectImage(s.logger, filepath.Dir(upperDir))
+	if err != nil {
+		return nil, err
+	}
+	if details.Mount.Type == "" {
+		return nil, errorspkg.New("image layers not found")
+	}
+
+	return rootfsLookupDirs(groot.ImageInfo{Mounts: []groot.MountInfo{details.Mount}}), nil
+}
+
+// Delete removes the image with the given id, or at the given image path.
+// Deleting an image that doesn't exist returns an error that satisfies
+// IsImageNotFound, and deleting an image that processes still use returns an
+// error listing them.
+func (s *Store) Delete(idOrPath string) error {
+	return s.DeleteWithSpec(idOrPath, DeleteSpec{})
+}
+
+func (s *Store) DeleteWithSpec(idOrPath string, spec DeleteSpec) error {
+	defer s.emitUnusedLayersSize()
+
+	return s.deleteImage(idOrPath, spec)
+}
+
+func (s *Store) deleteImage(idOrPath string, spec DeleteSpec) error {
+	id, err := idfinder.FindID(s.cfg.StorePath, idOrPath)
+	if err != nil {
+		return imageNotFoundError{err: err}
+	}
+
+	imagePath := filepath.Join(s.cfg.StorePath, storepkg.ImageDirName, id)
+	if spec.Force {
+		if err := s.fsDriver.DetachImage(s.logger, imagePath); err != nil {
+			return errorspkg.Wrap(err, "detaching image")
+		}
+	} else {
+		pids, err := imageUsers(imagePath)
+		if err != nil {
+			return errorspkg.Wrap(err, "looking for processes using the image")
+		}
+		if len(pids) > 0 {
+			return imageBusyError{id: id, pids: pids}
+		}
+	}
+
+	if err := removeBundle(imagePath); err != nil {
+		s.logger.Error("removing-bundle", err)
+	}
+
+	if spec.Async {
+		return s.trashImage(id, imagePath)
+	}
+
+	deleter := groot.IamDeleter(s.nsImageCloner, s.dependencyManager, s.metricsEmitter)
+	return deleter.Delete(s.logger, id)
+}
+
+// emitUnusedLayersSize measures every unused volume, so bulk deletes only
+// emit it once.
+func (s *Store) emitUnusedLayersSize() {
+	unusedVolumesSize, err := s.storeMeasurer.UnusedVolumesSize(s.logger)
+	if err != nil {
+		s.logger.Error("getting-unused-layers-size", err)
+	}
+	s.metricsEmitter.TryEmitUsage(s.logger, "UnusedLayersSize", unusedVolumesSize, "bytes")
+}
+
+// DeleteImages deletes several images while holding the global lock once,
+// so that no image can be created from their layers in the meantime. A
+// failure to delete an image doesn't stop the others from being deleted: it
+// is reported in the image's result instead.
+func (s *Store) DeleteImages(spec BulkDeleteSpec) ([]DeleteResult, error) {
+	logger := s.logger.Session("bulk-deleting", lager.Data{"spec": spec})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	if err := s.checkStoreExists(); err != nil {
+		return nil, err
+	}
+
+	lockFile, err := s.exclusiveLocksmith.Lock(groot.GlobalLockKey)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "acquiring lock")
+	}
+
+	results, err := s.deleteImages(logger, spec)
+	if unlockErr := s.exclusiveLocksmith.Unlock(lockFile); unlockErr != nil {
+		logger.Error("unlocking-failed", unlockErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The cleaner takes the global lock itself, so it must only run once the
+	// lock is released.
+	if spec.CollectGarbage {
+		if _, err := s.Clean(CleanSpec{}); err != nil {
+			return results, errorspkg.Wrap(err, "collecting garbage")
+		}
+	}
+
+	return results, nil
+}
+
+func (s *Store) deleteImages(logger lager.Logger, spec BulkDeleteSpec) ([]DeleteResult, error) {
+	results := []DeleteResult{}
+
+	ids := spec.IDs
+	if len(ids) == 0 {
+		var err error
+		ids, results, err = s.selectImages(logger, spec.Selectors, spec.OlderThan)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	defer s.emitUnusedLayersSize()
+
+	store := s.UsingLogger(logger)
+	for _, idOrPath := range ids {
+		result := DeleteResult{ID: filepath.Base(idOrPath), Deleted: true}
+		// An image that is already gone, e.g. deleted concurrently, is
+		// as deleted as it gets.
+		if err := store.deleteImage(idOrPath, spec.DeleteSpec); err != nil {
+			if IsImageNotFound(err) {
+				logger.Info("image-not-found", lager.Data{"id": idOrPath})
+			} else {
+				logger.Error("deleting-image-failed", err, lager.Data{"id": idOrPath})
+				result.Deleted = false
+				result.Error = err.Error()
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// selectImages returns the images matching the selectors and created more
+// than olderThan ago. Images whose age can't be found out are reported as
+// failed results instead.
+func (s *Store) selectImages(logger lager.Logger, selectors []groot.LabelSelector, olderThan time.Duration) ([]string, []DeleteResult, error) {
+	images, err := groot.IamLister(s.imageCloner).List(logger, s.cfg.StorePath, selectors...)
+	if err != nil {
+		return nil, nil, errorspkg.Wrap(err, "listing images")
+	}
+
+	ids := []string{}
+	failed := []DeleteResult{}
+	for _, imagePath := range images {
+		id := filepath.Base(imagePath)
+		if olderThan > 0 {
+			metadata, err := groot.ReadImageMetadata(imagePath)
+			if os.IsNotExist(errorspkg.Cause(err)) {
+				continue
+			}
+			if err != nil {
+				logger.Error("reading-image-metadata-failed", err, lager.Data{"path": imagePath})
+				failed = append(failed, DeleteResult{ID: id, Error: err.Error()})
+				continue
+			}
+			if time.Since(metadata.Created) < olderThan {
+				continue
+			}
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, failed, nil
+}
+
+// trashImage moves an image to the store trash and deregisters its
+// dependencies, so that it's gone from the store straight away. Its files are
+// removed when the trash is reaped.
+func (s *Store) trashImage(id, imagePath string) error {
+	logger := s.logger.Session("trashing-image", lager.Data{"imageID": id})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	trashDir := filepath.Join(s.cfg.StorePath, storepkg.TrashDirName)
+	if err := os.MkdirAll(trashDir, 0700); err != nil {
+		logger.Error("creating-trash-dir-failed", err)
+		return errorspkg.Wrap(err, "creating trash")
+	}
+
+	trashPath := filepath.Join(trashDir, fmt.Sprintf("%s-%d", id, time.Now().UnixNano()))
+	if err := s.fsDriver.TrashImage(logger, imagePath, trashPath); err != nil {
+		return err
+	}
+
+	if err := groot.DeregisterImage(s.dependencyManager, id); err != nil && !os.IsNotExist(errorspkg.Cause(err)) {
+		logger.Error("failed-to-deregister-dependencies", err)
+		return err
+	}
+
+	s.emitTrashSize()
+	return nil
+}
+
+// reapTrash destroys the images deleted asynchronously. Failures are logged
+// and the image is left for the next reap. It holds the global lock like the
+// rest of clean, so that concurrent cleans don't destroy the same images.
+func (s *Store) reapTrash() {
+	logger := s.logger.Session("reaping-trash")
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	lockFile, err := s.exclusiveLocksmith.Lock(groot.GlobalLockKey)
+	if err != nil {
+		logger.Error("acquiring-lock-failed", err)
+		return
+	}
+	defer func() {
+		if err := s.exclusiveLocksmith.Unlock(lockFile); err != nil {
+			logger.Error("unlocking-failed", err)
+		}
+	}()
+
+	trashDir := filepath.Join(s.cfg.StorePath, storepkg.TrashDirName)
+	trashed, err := ioutil.ReadDir(trashDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("listing-trash-failed", err)
+		}
+		return
+	}
+
+	for _, image := range trashed {
+		trashPath := filepath.Join(trashDir, image.Name())
+		if err := s.nsFsDriver.DestroyImage(logger, trashPath); err != nil {
+			logger.Error("destroying-trashed-image-failed", err, lager.Data{"path": trashPath})
+		}
+	}
+
+	s.emitTrashSize()
+}
+
+func (s *Store) emitTrashSize() {
+	trashSize, err := s.storeMeasurer.TrashSize(s.logger)
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("getting-trash-size: %s", err))
+	}
+	s.metricsEmitter.TryEmitUsage(s.logger, "TrashSize", trashSize, "bytes")
+}
+
+func (s *Store) Stats(idOrPath string) (groot.VolumeStats, error) {
+	id, err := idfinder.FindID(s.cfg.StorePath, idOrPath)
+	if err != nil {
+		return groot.VolumeStats{}, imageNotFoundError{err: err}
+	}
+
+	return groot.IamStatser(s.imageCloner).Stats(s.logger, id)
+}
+
+// Inspect returns everything the store knows about an image, for debugging.
+func (s *Store) Inspect(idOrPath string) (groot.ImageInspection, error) {
+	id, err := idfinder.FindID(s.cfg.StorePath, idOrPath)
+	if err != nil {
+		return groot.ImageInspection{}, imageNotFoundError{err: err}
+	}
+
+	return groot.IamInspector(s.imageCloner).Inspect(s.logger, id)
+}
+
+func (s *Store) AllStats() (map[string]groot.VolumeStats, error) {
+	return groot.IamStatser(s.imageCloner).AllStats(s.logger)
+}
+
+// List returns the paths of the images that match all of the given selectors.
+func (s *Store) List(selectors ...groot.LabelSelector) ([]string, error) {
+	if err := s.checkStoreExists(); err != nil {
+		return nil, err
+	}
+
+	return groot.IamLister(s.imageCloner).List(s.logger, s.cfg.StorePath, selectors...)
+}
+
+// ListDetails returns the provenance, mount state and disk usage of the images
+// that match all of the given selectors.
+func (s *Store) ListDetails(selectors ...groot.LabelSelector) ([]groot.ImageDetails, error) {
+	if err := s.checkStoreExists(); err != nil {
+		return nil, err
+	}
+
+	return groot.IamLister(s.imageCloner).ListDetails(s.logger, s.cfg.StorePath, selectors...)
+}
+
+func (s *Store) Clean(spec CleanSpec) (groot.CleanReport, error) {
+	if err := s.checkStoreExists(); err != nil {
+		return groot.CleanReport{}, err
+	}
+	if !s.initialized {
+		return groot.CleanReport{}, ErrStoreNotInitialized
+	}
+
+	defer func() {
+		unusedVolumesSize, err := s.storeMeasurer.UnusedVolumesSize(s.logger)
+		if err != nil {
+			s.logger.Error("getting-unused-volumes-size", err)
+		}
+		s.metricsEmitter.TryEmitUsage(s.logger, "UnusedLayersSize", unusedVolumesSize, "bytes")
+	}()
+
+	if spec.DryRun {
+		return s.cleaner.DryRun(s.logger, spec.ThresholdBytes, spec.TargetBytes)
+	}
+
+	s.reapTrash()
+
+	report, err := s.cleaner.Clean(s.logger, spec.ThresholdBytes, spec.TargetBytes)
+	if err != nil {
+		return groot.CleanReport{}, err
+	}
+
+	if _, err := s.fsDriver.ReconcileProjectIDs(s.logger); err != nil {
+		s.logger.Error("reconciling-project-ids", err)
+	}
+
+	if report.Measured && !report.Noop {
+		s.metricsEmitter.TryEmitUsage(s.logger, "StoreUsage", report.UsageAfter, "bytes")
+	}
+
+	return report, nil
+}
+
+// Pull brings the layers of a base image into the store and returns their
+// chain IDs. The layers are collected by the next clean unless an image or a
+// pin starts using them.
+func (s *Store) Pull(spec PullSpec) ([]string, error) {
+	if err := s.checkUnpacker(); err != nil {
+		return nil, err
+	}
+
+	fetcher := s.createFetcher(spec.BaseImageURL, spec.Credentials)
+	defer func() {
+		if err := fetcher.Close(); err != nil {
+			s.logger.Error("closing-fetcher", err)
+		}
+	}()
+
+	puller := groot.IamPuller(s.createBaseImagePuller(fetcher), s.sharedLocksmith)
+	return puller.Pull(s.logger, groot.PullSpec{
+		BaseImageURL: spec.BaseImageURL,
+		UIDMappings:  s.idMappings.UIDMappings,
+		GIDMappings:  s.idMappings.GIDMappings,
+	})
+}
+
+func (s *Store) Pin(spec PullSpec) (groot.Pin, error) {
+	if err := s.checkUnpacker(); err != nil {
+		return groot.Pin{}, err
+	}
+
+	fetcher := s.createFetcher(spec.BaseImageURL, spec.Credentials)
+	defer func() {
+		if err := fetcher.Close(); err != nil {
+			s.logger.Error("closing-fetcher", err)
+		}
+	}()
+
+	pinner := groot.IamPinner(s.createBaseImagePuller(fetcher), s.sharedLocksmith, s.dependencyManager)
+	return pinner.Pin(s.logger, groot.PinSpec{
+		BaseImageURL: spec.BaseImageURL,
+		UIDMappings:  s.idMappings.UIDMappings,
+		GIDMappings:  s.idMappings.GIDMappings,
+	})
+}
+
+func (s *Store) Unpin(ref string) error {
+	return groot.IamUnpinner(s.dependencyManager).Unpin(s.logger, ref)
+}
+
+func (s *Store) Pins() ([]groot.Pin, error) {
+	return groot.IamPinLister(s.dependencyManager).Pins(s.logger)
+}
+
+// Snapshotter returns a containerd snapshotter that keeps its snapshots in
+// the store, next to the images created through the store.
+func (s *Store) Snapshotter() (*snapshotter.Snapshotter, error) {
+	if !s.initialized {
+		return nil, ErrStoreNotInitialized
+	}
+
+	return snapshotter.NewSnapshotter(
+		s.logger, s.cfg.StorePath, s.nsFsDriver, s.dependencyManager,
+		s.sharedLocksmith, s.exclusiveLocksmith,
+	), nil
+}
+
+// checkUnpacker is called by the operations that unpack layers.
+func (s *Store) checkUnpacker() error {
+	if !s.initialized {
+		return ErrStoreNotInitialized
+	}
+
+	return s.unpackerErr
+}
+
+func (s *Store) checkStoreExists() error {
+	if _, err := os.Stat(s.cfg.StorePath); os.IsNotExist(err) {
+		return storeNotFoundError{storePath: s.cfg.StorePath}
+	}
+
+	return nil
+}
+
+func (s *Store) createFetcher(baseImageURL *url.URL, credentials Credentials) base_image_puller.Fetcher {
+	fetcher := s.fetcherFactory(baseImageURL, credentials)
+
+	// Tarballs are read from disk and may change under the same path, so only
+	// registry and OCI image infos are cached.
+	if s.imageInfoCache == nil || baseImageURL.Scheme == "" {
+		return fetcher
+	}
+
+	return cached_fetcher.NewCachedFetcher(fetcher, s.imageInfoCache, credentials.Username+"@"+baseImageURL.String())
+}
+
+func (s *Store) createBaseImagePuller(fetcher base_image_puller.Fetcher) *base_image_puller.BaseImagePuller {
+	return base_image_puller.NewBaseImagePuller(
+		fetcher,
+		s.unpacker,
+		s.nsFsDriver,
+		s.metricsEmitter,
+		s.exclusiveLocksmith,
+	)
+}
+
+func (s *Store) emitStoreMetrics() {
+	usage, err := s.storeMeasurer.Usage(s.logger)
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("measuring-store: %s", err))
+	}
+	s.metricsEmitter.TryEmitUsage(s.logger, "StoreUsage", usage, "bytes")
+
+	unusedVolumesSize, err := s.storeMeasurer.UnusedVolumesSize(s.logger)
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("getting-unused-layers-size: %s", err))
+	}
+	s.metricsEmitter.TryEmitUsage(s.logger, "UnusedLayersSize", unusedVolumesSize, "bytes")
+
+	totalVolumesSize, err := s.storeMeasurer.TotalVolumesSize(s.logger)
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("getting-total-layers-size: %s", err))
+	}
+	s.metricsEmitter.TryEmitUsage(s.logger, "DownloadedLayersSizeInBytes", totalVolumesSize, "bytes")
+
+	commitedQuota, err := s.storeMeasurer.CommittedQuota(s.logger)
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("getting-commited-quota: %s", err))
+	}
+	s.metricsEmitter.TryEmitUsage(s.logger, "CommittedQuotaInBytes", commitedQuota, "bytes")
+}
@@ -0,0 +1,429 @@
+package grootfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/grootfs/commands/config"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/grootfs/pkg/grootfs"
+	"code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		storePath   string
+		cfg         config.Config
+		logger      *lagertest.TestLogger
+		fakeMetrics *grootfakes.FakeMetricsEmitter
+	)
+
+	initializeStore := func() {
+		for _, folderName := range store.StoreFolders {
+			Expect(os.MkdirAll(filepath.Join(storePath, folderName), 0755)).To(Succeed())
+		}
+		Expect(groot.NewStoreNamespacer(storePath).ApplyMappings(nil, nil)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		storePath, err = ioutil.TempDir("", "store")
+		Expect(err).NotTo(HaveOccurred())
+
+		cfg = config.Config{
+			StorePath: storePath,
+			FSDriver:  "overlay-xfs",
+			Create: config.Create{
+				DiskLimitSizeBytes:    1024,
+				ExcludeImageFromQuota: true,
+				WithoutMount:          true,
+				WithClean:             true,
+			},
+			Clean: config.Clean{
+				ThresholdBytes: 2048,
+				TargetBytes:    512,
+			},
+		}
+		logger = lagertest.NewTestLogger("store")
+		fakeMetrics = new(grootfakes.FakeMetricsEmitter)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(storePath)).To(Succeed())
+	})
+
+	Describe("NewStore", func() {
+		Context("when the driver is not supported", func() {
+			BeforeEach(func() {
+				cfg.FSDriver = "kitten-fs"
+			})
+
+			It("returns an error", func() {
+				_, err := grootfs.NewStore(cfg)
+				Expect(err).To(MatchError("filesystem driver not supported: kitten-fs"))
+			})
+		})
+
+		Context("when the namespace file is invalid", func() {
+			BeforeEach(func() {
+				initializeStore()
+				namespaceFile := filepath.Join(storePath, store.MetaDirName, groot.NamespaceFilename)
+				Expect(ioutil.WriteFile(namespaceFile, []byte("not json"), 0644)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, err := grootfs.NewStore(cfg)
+				Expect(err).To(MatchError(ContainSubstring("reading namespace file")))
+			})
+		})
+
+		Context("when devices are preserved on a store with ID mappings", func() {
+			BeforeEach(func() {
+				cfg.PreserveDevices = true
+				for _, folderName := range store.StoreFolders {
+					Expect(os.MkdirAll(filepath.Join(storePath, folderName), 0755)).To(Succeed())
+				}
+				mappings := []groot.IDMappingSpec{{HostID: 1000, NamespaceID: 0, Size: 1}}
+				Expect(groot.NewStoreNamespacer(storePath).ApplyMappings(mappings, mappings)).To(Succeed())
+			})
+
+			It("fails to unpack layers", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.Create(s.DefaultCreateSpec("my-image", &url.URL{Scheme: "docker", Path: "/busybox"}))
+				Expect(err).To(Equal(grootfs.ErrPreserveDevicesUnsupported))
+				_, err = s.Pull(grootfs.PullSpec{BaseImageURL: &url.URL{Scheme: "docker", Path: "/busybox"}})
+				Expect(err).To(Equal(grootfs.ErrPreserveDevicesUnsupported))
+			})
+
+			It("can still list the images", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(s.List()).To(BeEmpty())
+			})
+		})
+
+		Context("when devices are preserved on a store not initialized to preserve them", func() {
+			BeforeEach(func() {
+				cfg.PreserveDevices = true
+				initializeStore()
+			})
+
+			It("fails to unpack layers", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.Pull(grootfs.PullSpec{BaseImageURL: &url.URL{Scheme: "docker", Path: "/busybox"}})
+				Expect(err).To(Equal(grootfs.ErrPreserveDevicesNotInitialized))
+			})
+		})
+
+		Context("when the store was initialized to preserve devices", func() {
+			BeforeEach(func() {
+				initializeStore()
+				Expect(ioutil.WriteFile(filepath.Join(storePath, store.MetaDirName, groot.PreserveDevicesFilename), []byte{}, 0644)).To(Succeed())
+			})
+
+			It("doesn't need them to be preserved by the config", func() {
+				_, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("DefaultCreateSpec", func() {
+		It("uses the create settings from the config", func() {
+			s, err := grootfs.NewStore(cfg)
+			Expect(err).NotTo(HaveOccurred())
+
+			baseImageURL, err := url.Parse("docker:///busybox")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(s.DefaultCreateSpec("my-image", baseImageURL)).To(Equal(grootfs.CreateSpec{
+				ID:                        "my-image",
+				BaseImageURL:              baseImageURL,
+				DiskLimit:                 1024,
+				ExcludeBaseImageFromQuota: true,
+				Mount:                     false,
+				Clean:                     true,
+			}))
+		})
+	})
+
+	Describe("DefaultCleanSpec", func() {
+		It("uses the clean settings from the config", func() {
+			s, err := grootfs.NewStore(cfg)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(s.DefaultCleanSpec()).To(Equal(grootfs.CleanSpec{
+				ThresholdBytes: 2048,
+				TargetBytes:    512,
+			}))
+		})
+	})
+
+	Describe("Create", func() {
+		Context("when the store is not initialized", func() {
+			It("returns an error", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.Create(grootfs.CreateSpec{ID: "my-image"})
+				Expect(err).To(Equal(grootfs.ErrStoreNotInitialized))
+			})
+		})
+
+		Context("when an ephemeral image has a disk limit", func() {
+			It("returns an error", func() {
+				initializeStore()
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				createSpec := s.DefaultCreateSpec("my-image", &url.URL{Scheme: "docker", Path: "/busybox"})
+				createSpec.Ephemeral = true
+				createSpec.EphemeralSizeBytes = 1024
+				_, err = s.Create(createSpec)
+				Expect(err).To(MatchError(ContainSubstring("it can't have a disk limit or exclude the image from quota")))
+			})
+		})
+
+		Context("when the overlay mount options override the layer directories", func() {
+			It("returns an error", func() {
+				initializeStore()
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.Create(grootfs.CreateSpec{ID: "my-image", OverlayMountOptions: []string{"upperdir=/tmp"}})
+				Expect(err).To(MatchError(ContainSubstring("unsupported overlay mount option `upperdir=/tmp`")))
+			})
+		})
+	})
+
+	Describe("Pull", func() {
+		var (
+			fakeFetcher          *base_image_pullerfakes.FakeFetcher
+			requestedURL         *url.URL
+			requestedCredentials grootfs.Credentials
+			s                    *grootfs.Store
+		)
+
+		BeforeEach(func() {
+			initializeStore()
+
+			fakeFetcher = new(base_image_pullerfakes.FakeFetcher)
+			fakeFetcher.BaseImageInfoReturns(groot.BaseImageInfo{}, errors.New("failed to fetch"))
+
+			var err error
+			s, err = grootfs.NewStore(cfg,
+				grootfs.WithLogger(logger),
+				grootfs.WithMetricsEmitter(fakeMetrics),
+				grootfs.WithFetcherFactory(func(baseImageURL *url.URL, credentials grootfs.Credentials) base_image_puller.Fetcher {
+					requestedURL = baseImageURL
+					requestedCredentials = credentials
+					return fakeFetcher
+				}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("uses the configured fetcher factory", func() {
+			baseImageURL, err := url.Parse("docker:///busybox")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = s.Pull(grootfs.PullSpec{
+				BaseImageURL: baseImageURL,
+				Credentials:  grootfs.Credentials{Username: "user", Password: "secret"},
+			})
+			Expect(err).To(MatchError("failed to fetch"))
+
+			Expect(requestedURL).To(Equal(baseImageURL))
+			Expect(requestedCredentials).To(Equal(grootfs.Credentials{Username: "user", Password: "secret"}))
+			Expect(fakeFetcher.BaseImageInfoCallCount()).To(Equal(1))
+			Expect(fakeFetcher.CloseCallCount()).To(Equal(1))
+		})
+
+		It("logs to the configured logger", func() {
+			baseImageURL, err := url.Parse("docker:///busybox")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _ = s.Pull(grootfs.PullSpec{BaseImageURL: baseImageURL})
+			Expect(logger.LogMessages()).To(ContainElement("store.groot-pulling.starting"))
+		})
+
+		Context("when a different logger is used", func() {
+			It("logs to that logger", func() {
+				baseImageURL, err := url.Parse("docker:///busybox")
+				Expect(err).NotTo(HaveOccurred())
+
+				otherLogger := lagertest.NewTestLogger("request")
+				_, _ = s.UsingLogger(otherLogger).Pull(grootfs.PullSpec{BaseImageURL: baseImageURL})
+				Expect(otherLogger.LogMessages()).To(ContainElement("request.groot-pulling.starting"))
+				Expect(logger.LogMessages()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("List", func() {
+		Context("when the store doesn't exist", func() {
+			BeforeEach(func() {
+				cfg.StorePath = filepath.Join(storePath, "not-here")
+			})
+
+			It("returns a store not found error", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.List()
+				Expect(grootfs.IsStoreNotFound(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("no store found at")))
+			})
+		})
+
+		It("lists the images in the store", func() {
+			initializeStore()
+			Expect(os.Mkdir(filepath.Join(storePath, store.ImageDirName, "my-image"), 0755)).To(Succeed())
+
+			s, err := grootfs.NewStore(cfg)
+			Expect(err).NotTo(HaveOccurred())
+
+			images, err := s.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(images).To(ConsistOf(filepath.Join(storePath, store.ImageDirName, "my-image")))
+		})
+	})
+
+	Describe("Delete", func() {
+		Context("when the image doesn't exist", func() {
+			It("returns an image not found error", func() {
+				initializeStore()
+				s, err := grootfs.NewStore(cfg, grootfs.WithMetricsEmitter(fakeMetrics))
+				Expect(err).NotTo(HaveOccurred())
+
+				err = s.Delete("not-here")
+				Expect(grootfs.IsImageNotFound(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("Image `not-here` not found")))
+			})
+		})
+	})
+
+	Describe("DeleteImages", func() {
+		Context("when the store doesn't exist", func() {
+			BeforeEach(func() {
+				cfg.StorePath = filepath.Join(storePath, "not-here")
+			})
+
+			It("returns a store not found error", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.DeleteImages(grootfs.BulkDeleteSpec{IDs: []string{"my-image"}})
+				Expect(grootfs.IsStoreNotFound(err)).To(BeTrue())
+			})
+		})
+
+		Context("when an image doesn't exist", func() {
+			It("reports it as deleted", func() {
+				initializeStore()
+				s, err := grootfs.NewStore(cfg, grootfs.WithMetricsEmitter(fakeMetrics))
+				Expect(err).NotTo(HaveOccurred())
+
+				results, err := s.DeleteImages(grootfs.BulkDeleteSpec{IDs: []string{"not-here"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(HaveLen(1))
+				Expect(results[0].ID).To(Equal("not-here"))
+				Expect(results[0].Deleted).To(BeTrue())
+				Expect(results[0].Error).To(BeEmpty())
+			})
+		})
+
+		Context("when an image's metadata can't be read", func() {
+			It("reports it in the image's result", func() {
+				initializeStore()
+				imagePath := filepath.Join(storePath, store.ImageDirName, "my-image")
+				Expect(os.Mkdir(imagePath, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(imagePath, groot.ImageMetadataFilename), []byte("not-json"), 0644)).To(Succeed())
+
+				s, err := grootfs.NewStore(cfg, grootfs.WithMetricsEmitter(fakeMetrics))
+				Expect(err).NotTo(HaveOccurred())
+
+				results, err := s.DeleteImages(grootfs.BulkDeleteSpec{OlderThan: time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(HaveLen(1))
+				Expect(results[0].ID).To(Equal("my-image"))
+				Expect(results[0].Deleted).To(BeFalse())
+				Expect(results[0].Error).To(ContainSubstring("parsing image metadata"))
+				Expect(imagePath).To(BeADirectory())
+			})
+		})
+
+		Context("when an image has no metadata", func() {
+			It("ages it by the modification time of its directory", func() {
+				initializeStore()
+				imagePath := filepath.Join(storePath, store.ImageDirName, "my-image")
+				Expect(os.Mkdir(imagePath, 0755)).To(Succeed())
+
+				s, err := grootfs.NewStore(cfg, grootfs.WithMetricsEmitter(fakeMetrics))
+				Expect(err).NotTo(HaveOccurred())
+
+				results, err := s.DeleteImages(grootfs.BulkDeleteSpec{OlderThan: time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(BeEmpty())
+				Expect(imagePath).To(BeADirectory())
+			})
+		})
+
+		Context("when no image is older than the given age", func() {
+			It("deletes nothing", func() {
+				initializeStore()
+				imagePath := filepath.Join(storePath, store.ImageDirName, "my-image")
+				Expect(os.Mkdir(imagePath, 0755)).To(Succeed())
+				Expect(groot.WriteImageMetadata(imagePath, groot.ImageMetadata{ID: "my-image", Created: time.Now()})).To(Succeed())
+
+				s, err := grootfs.NewStore(cfg, grootfs.WithMetricsEmitter(fakeMetrics))
+				Expect(err).NotTo(HaveOccurred())
+
+				results, err := s.DeleteImages(grootfs.BulkDeleteSpec{OlderThan: time.Hour})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(results).To(BeEmpty())
+				Expect(imagePath).To(BeADirectory())
+			})
+		})
+	})
+
+	Describe("Stats", func() {
+		Context("when the image doesn't exist", func() {
+			It("returns an image not found error", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.Stats("not-here")
+				Expect(grootfs.IsImageNotFound(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Clean", func() {
+		Context("when the store is not initialized", func() {
+			It("returns an error", func() {
+				s, err := grootfs.NewStore(cfg)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = s.Clean(s.DefaultCleanSpec())
+				Expect(err).To(Equal(grootfs.ErrStoreNotInitialized))
+			})
+		})
+	})
+})
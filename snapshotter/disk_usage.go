@@ -0,0 +1,41 @@
+package snapshotter // import "code.cloudfoundry.org/grootfs/snapshotter"
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/containerd/containerd/snapshots"
+	errorspkg "github.com/pkg/errors"
+)
+
+// diskUsage counts the blocks and inodes used under path, counting hard links
+// only once.
+func diskUsage(path string) (snapshots.Usage, error) {
+	var usage snapshots.Usage
+	seen := map[uint64]struct{}{}
+
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil
+		}
+		if _, ok := seen[stat.Ino]; ok {
+			return nil
+		}
+		seen[stat.Ino] = struct{}{}
+
+		usage.Inodes++
+		usage.Size += stat.Blocks * 512
+		return nil
+	})
+	if err != nil {
+		return snapshots.Usage{}, errorspkg.Wrapf(err, "measuring disk usage of %s", path)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,160 @@
+package snapshotter // import "code.cloudfoundry.org/grootfs/snapshotter"
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+	errorspkg "github.com/pkg/errors"
+)
+
+type snapshot struct {
+	Kind      snapshots.Kind    `json:"kind"`
+	Name      string            `json:"name"`
+	Parent    string            `json:"parent,omitempty"`
+	ID        string            `json:"id,omitempty"`
+	VolumeIDs []string          `json:"volume_ids"`
+	Mount     groot.MountInfo   `json:"mount"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Created   time.Time         `json:"created"`
+	Updated   time.Time         `json:"updated"`
+}
+
+func (s snapshot) info() snapshots.Info {
+	return snapshots.Info{
+		Kind:    s.Kind,
+		Name:    s.Name,
+		Parent:  s.Parent,
+		Labels:  s.Labels,
+		Created: s.Created,
+		Updated: s.Updated,
+	}
+}
+
+func (s snapshot) volumeID() string {
+	return s.VolumeIDs[len(s.VolumeIDs)-1]
+}
+
+func (s snapshot) mounts() []mount.Mount {
+	return []mount.Mount{
+		{
+			Type:    s.Mount.Type,
+			Source:  s.Mount.Source,
+			Options: s.Mount.Options,
+		},
+	}
+}
+
+// metadataStore keeps one file per snapshot. Callers serialise changes with
+// the snapshots lock; files are replaced atomically so reads don't need it.
+type metadataStore struct {
+	path string
+}
+
+func newMetadataStore(storePath string) *metadataStore {
+	return &metadataStore{
+		path: filepath.Join(storePath, store.MetaDirName, SnapshotsDirName),
+	}
+}
+
+func (m *metadataStore) get(key string) (snapshot, error) {
+	contents, err := ioutil.ReadFile(m.filePath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snapshot{}, errorspkg.Wrapf(errdefs.ErrNotFound, "snapshot %s", key)
+		}
+		return snapshot{}, errorspkg.Wrapf(err, "reading snapshot %s", key)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(contents, &snap); err != nil {
+		return snapshot{}, errorspkg.Wrapf(err, "parsing snapshot %s", key)
+	}
+
+	return snap, nil
+}
+
+func (m *metadataStore) exists(key string) bool {
+	_, err := os.Stat(m.filePath(key))
+	return err == nil
+}
+
+func (m *metadataStore) put(snap snapshot) error {
+	if err := os.MkdirAll(m.path, 0755); err != nil {
+		return errorspkg.Wrap(err, "creating snapshots metadata directory")
+	}
+
+	contents, err := json.Marshal(snap)
+	if err != nil {
+		return errorspkg.Wrapf(err, "encoding snapshot %s", snap.Name)
+	}
+
+	tmpFile, err := ioutil.TempFile(m.path, ".tmp-")
+	if err != nil {
+		return errorspkg.Wrap(err, "creating temporary snapshot file")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(contents); err != nil {
+		tmpFile.Close()
+		return errorspkg.Wrapf(err, "writing snapshot %s", snap.Name)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return errorspkg.Wrapf(err, "writing snapshot %s", snap.Name)
+	}
+
+	return os.Rename(tmpFile.Name(), m.filePath(snap.Name))
+}
+
+func (m *metadataStore) remove(key string) error {
+	if err := os.Remove(m.filePath(key)); err != nil && !os.IsNotExist(err) {
+		return errorspkg.Wrapf(err, "removing snapshot %s", key)
+	}
+
+	return nil
+}
+
+func (m *metadataStore) list() ([]snapshot, error) {
+	files, err := ioutil.ReadDir(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []snapshot{}, nil
+		}
+		return nil, errorspkg.Wrap(err, "listing snapshots")
+	}
+
+	all := []snapshot{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(m.path, file.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errorspkg.Wrapf(err, "reading snapshot file %s", file.Name())
+		}
+
+		var snap snapshot
+		if err := json.Unmarshal(contents, &snap); err != nil {
+			return nil, errorspkg.Wrapf(err, "parsing snapshot file %s", file.Name())
+		}
+		all = append(all, snap)
+	}
+
+	return all, nil
+}
+
+func (m *metadataStore) filePath(key string) string {
+	return filepath.Join(m.path, snapshotID(key)+".json")
+}
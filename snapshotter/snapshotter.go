@@ -0,0 +1,665 @@
+// Package snapshotter implements the containerd snapshots API on top of the
+// volumes and images of a grootfs store, so containerd can be pointed at a
+// store as a proxy snapshotter plugin.
+package snapshotter // import "code.cloudfoundry.org/grootfs/snapshotter"
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/dependency_manager"
+	"code.cloudfoundry.org/grootfs/store/image_cloner"
+	"code.cloudfoundry.org/lager"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/filters"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+	digestpkg "github.com/opencontainers/go-digest"
+	errorspkg "github.com/pkg/errors"
+)
+
+const (
+	SnapshotsDirName = "snapshots"
+
+	SnapshotReferenceFormat = dependency_manager.SnapshotReferencePrefix + "%s"
+
+	// LabelDiskLimitSizeBytes sets the disk quota of an active snapshot, in
+	// the same way as `create --disk-limit-size-bytes`.
+	LabelDiskLimitSizeBytes = "grootfs.cloudfoundry.org/disk-limit-size-bytes"
+	// LabelExcludeImageFromQuota makes the quota of an active snapshot only
+	// count what is written to it, like `create --exclude-image-from-quota`.
+	LabelExcludeImageFromQuota = "grootfs.cloudfoundry.org/exclude-image-from-quota"
+
+	snapshotsLockKey = "snapshots"
+)
+
+//go:generate counterfeiter . VolumeDriver
+type VolumeDriver interface {
+	CreateImage(logger lager.Logger, spec image_cloner.ImageDriverSpec) (groot.MountInfo, error)
+	DestroyImage(logger lager.Logger, path string) error
+	VolumePath(logger lager.Logger, id string) (string, error)
+	CreateVolume(logger lager.Logger, parentID, id string) (string, error)
+	DestroyVolume(logger lager.Logger, id string) error
+	WriteVolumeMeta(logger lager.Logger, id string, data base_image_puller.VolumeMeta) error
+	ClearProjectIDs(logger lager.Logger, path string) error
+}
+
+// Snapshotter stores committed snapshots as store volumes and active
+// snapshots as unmounted images under the snapshots directory of the store.
+// Every snapshot registers the volumes it uses with the dependency manager, so
+// `clean` never collects them.
+type Snapshotter struct {
+	logger             lager.Logger
+	storePath          string
+	volumeDriver       VolumeDriver
+	dependencyManager  groot.DependencyManager
+	sharedLocksmith    groot.Locksmith
+	exclusiveLocksmith groot.Locksmith
+	metadata           *metadataStore
+}
+
+func NewSnapshotter(logger lager.Logger, storePath string, volumeDriver VolumeDriver,
+	dependencyManager groot.DependencyManager, sharedLocksmith, exclusiveLocksmith groot.Locksmith,
+) *Snapshotter {
+	return &Snapshotter{
+		logger:             logger,
+		storePath:          storePath,
+		volumeDriver:       volumeDriver,
+		dependencyManager:  dependencyManager,
+		sharedLocksmith:    sharedLocksmith,
+		exclusiveLocksmith: exclusiveLocksmith,
+		metadata:           newMetadataStore(storePath),
+	}
+}
+
+func (s *Snapshotter) Stat(ctx context.Context, key string) (snapshots.Info, error) {
+	logger := s.logger.Session("snapshotter-stat", lager.Data{"key": key})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	snap, err := s.getSnapshot(logger, key)
+	if err != nil {
+		return snapshots.Info{}, err
+	}
+
+	return snap.info(), nil
+}
+
+func (s *Snapshotter) Update(ctx context.Context, info snapshots.Info, fieldpaths ...string) (snapshots.Info, error) {
+	logger := s.logger.Session("snapshotter-update", lager.Data{"key": info.Name, "fieldpaths": fieldpaths})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	lockFile, err := s.exclusiveLocksmith.Lock(snapshotsLockKey)
+	if err != nil {
+		return snapshots.Info{}, errorspkg.Wrap(err, "acquiring snapshots lock")
+	}
+	defer s.unlock(logger, s.exclusiveLocksmith, lockFile)
+
+	snap, err := s.metadata.get(info.Name)
+	if err != nil {
+		return snapshots.Info{}, err
+	}
+
+	if len(fieldpaths) == 0 {
+		snap.Labels = info.Labels
+	}
+	for _, fieldpath := range fieldpaths {
+		switch {
+		case fieldpath == "labels":
+			snap.Labels = info.Labels
+		case strings.HasPrefix(fieldpath, "labels."):
+			if snap.Labels == nil {
+				snap.Labels = map[string]string{}
+			}
+			label := strings.TrimPrefix(fieldpath, "labels.")
+			if value, ok := info.Labels[label]; ok {
+				snap.Labels[label] = value
+			} else {
+				delete(snap.Labels, label)
+			}
+		default:
+			return snapshots.Info{}, errorspkg.Wrapf(errdefs.ErrInvalidArgument, "cannot update %s of snapshot %s", fieldpath, info.Name)
+		}
+	}
+	snap.Updated = time.Now().UTC()
+
+	if err := s.metadata.put(snap); err != nil {
+		return snapshots.Info{}, err
+	}
+
+	return snap.info(), nil
+}
+
+func (s *Snapshotter) Usage(ctx context.Context, key string) (snapshots.Usage, error) {
+	logger := s.logger.Session("snapshotter-usage", lager.Data{"key": key})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	snap, err := s.getSnapshot(logger, key)
+	if err != nil {
+		return snapshots.Usage{}, err
+	}
+
+	if snap.Kind == snapshots.KindCommitted {
+		volumePath, err := s.volumeDriver.VolumePath(logger, snap.volumeID())
+		if err != nil {
+			return snapshots.Usage{}, err
+		}
+		return diskUsage(volumePath)
+	}
+
+	return diskUsage(s.upperDir(snap))
+}
+
+func (s *Snapshotter) Mounts(ctx context.Context, key string) ([]mount.Mount, error) {
+	logger := s.logger.Session("snapshotter-mounts", lager.Data{"key": key})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	snap, err := s.metadata.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if snap.Kind == snapshots.KindCommitted {
+		return nil, errorspkg.Wrapf(errdefs.ErrFailedPrecondition, "snapshot %s is committed and cannot be mounted", key)
+	}
+
+	return snap.mounts(), nil
+}
+
+func (s *Snapshotter) Prepare(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return s.createSnapshot(snapshots.KindActive, key, parent, opts)
+}
+
+func (s *Snapshotter) View(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return s.createSnapshot(snapshots.KindView, key, parent, opts)
+}
+
+func (s *Snapshotter) Commit(ctx context.Context, name, key string, opts ...snapshots.Opt) error {
+	logger := s.logger.Session("snapshotter-commit", lager.Data{"name": name, "key": key})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	// Prevents clean from collecting the new volume before the snapshot
+	// registers it.
+	globalLockFile, err := s.sharedLocksmith.Lock(groot.GlobalLockKey)
+	if err != nil {
+		return errorspkg.Wrap(err, "acquiring global lock")
+	}
+	defer s.unlock(logger, s.sharedLocksmith, globalLockFile)
+
+	lockFile, err := s.exclusiveLocksmith.Lock(snapshotsLockKey)
+	if err != nil {
+		return errorspkg.Wrap(err, "acquiring snapshots lock")
+	}
+	defer s.unlock(logger, s.exclusiveLocksmith, lockFile)
+
+	active, err := s.metadata.get(key)
+	if err != nil {
+		return err
+	}
+	if active.Kind != snapshots.KindActive {
+		return errorspkg.Wrapf(errdefs.ErrFailedPrecondition, "snapshot %s is not active", key)
+	}
+	if s.metadata.exists(name) {
+		return errorspkg.Wrapf(errdefs.ErrAlreadyExists, "snapshot %s", name)
+	}
+
+	info := snapshots.Info{Labels: active.Labels}
+	for _, opt := range opts {
+		if err := opt(&info); err != nil {
+			return err
+		}
+	}
+
+	volumeID := volumeIDFor(name)
+	if _, err := s.volumeDriver.VolumePath(logger, volumeID); err == nil {
+		return errorspkg.Wrapf(errdefs.ErrAlreadyExists, "volume %s", volumeID)
+	}
+
+	usage, err := diskUsage(s.upperDir(active))
+	if err != nil {
+		return err
+	}
+
+	var parentVolumeID string
+	if len(active.VolumeIDs) > 0 {
+		parentVolumeID = active.VolumeIDs[len(active.VolumeIDs)-1]
+	}
+
+	// The upper directory was written under the project quota of the active
+	// snapshot, whose id is released when the snapshot is removed below.
+	if err := s.volumeDriver.ClearProjectIDs(logger, s.upperDir(active)); err != nil {
+		return errorspkg.Wrap(err, "clearing project ids")
+	}
+
+	volumePath, err := s.volumeDriver.CreateVolume(logger, parentVolumeID, volumeID)
+	if err != nil {
+		return errorspkg.Wrap(err, "creating volume")
+	}
+
+	// On failure the changes go back to the active snapshot, which is kept,
+	// and the volume is destroyed.
+	changesMoved := false
+	committed := false
+	defer func() {
+		if !committed {
+			s.rollbackCommit(logger, active, volumeID, volumePath, changesMoved)
+		}
+	}()
+
+	// The upper directory already uses the overlay whiteout format, so it
+	// can be used as a lower layer as it is.
+	if err := os.Remove(volumePath); err != nil {
+		return errorspkg.Wrap(err, "replacing volume directory")
+	}
+	if err := os.Rename(s.upperDir(active), volumePath); err != nil {
+		return errorspkg.Wrap(err, "moving snapshot changes into volume")
+	}
+	changesMoved = true
+
+	if err := s.volumeDriver.WriteVolumeMeta(logger, volumeID, base_image_puller.VolumeMeta{
+		Size:     usage.Size,
+		LastUsed: time.Now().UnixNano(),
+	}); err != nil {
+		return errorspkg.Wrap(err, "writing volume meta")
+	}
+
+	now := time.Now().UTC()
+	committedSnap := snapshot{
+		Kind:      snapshots.KindCommitted,
+		Name:      name,
+		Parent:    active.Parent,
+		VolumeIDs: append(active.VolumeIDs, volumeID),
+		Labels:    info.Labels,
+		Created:   now,
+		Updated:   now,
+	}
+	if err := s.dependencyManager.Register(snapshotReference(name), committedSnap.VolumeIDs); err != nil {
+		return errorspkg.Wrap(err, "registering snapshot")
+	}
+	if err := s.metadata.put(committedSnap); err != nil {
+		if err := s.dependencyManager.Deregister(snapshotReference(name)); err != nil {
+			logger.Error("deregistering-snapshot-failed", err)
+		}
+		return err
+	}
+	committed = true
+
+	return s.removeSnapshot(logger, active)
+}
+
+func (s *Snapshotter) rollbackCommit(logger lager.Logger, active snapshot, volumeID, volumePath string, changesMoved bool) {
+	logger = logger.Session("rolling-back-commit", lager.Data{"volumeID": volumeID})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	if changesMoved {
+		if err := os.Rename(volumePath, s.upperDir(active)); err != nil {
+			// the volume is kept, as it holds the snapshot changes
+			logger.Error("restoring-snapshot-changes-failed", err)
+			return
+		}
+	}
+
+	if err := s.volumeDriver.DestroyVolume(logger, volumeID); err != nil {
+		logger.Error("destroying-volume-failed", err)
+	}
+}
+
+// Remove deletes a snapshot and, for committed snapshots, the volume that
+// holds it. Volumes pulled by the CLI are never removed through here.
+func (s *Snapshotter) Remove(ctx context.Context, key string) error {
+	logger := s.logger.Session("snapshotter-remove", lager.Data{"key": key})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	// Prevents a create from registering the volume of a committed snapshot
+	// between checking it's unused and destroying it.
+	globalLockFile, err := s.exclusiveLocksmith.Lock(groot.GlobalLockKey)
+	if err != nil {
+		return errorspkg.Wrap(err, "acquiring global lock")
+	}
+	defer s.unlock(logger, s.exclusiveLocksmith, globalLockFile)
+
+	lockFile, err := s.exclusiveLocksmith.Lock(snapshotsLockKey)
+	if err != nil {
+		return errorspkg.Wrap(err, "acquiring snapshots lock")
+	}
+	defer s.unlock(logger, s.exclusiveLocksmith, lockFile)
+
+	snap, err := s.metadata.get(key)
+	if err != nil {
+		return err
+	}
+
+	if snap.Kind == snapshots.KindCommitted {
+		all, err := s.metadata.list()
+		if err != nil {
+			return err
+		}
+		for _, other := range all {
+			if other.Parent == key {
+				return errorspkg.Wrapf(errdefs.ErrFailedPrecondition, "snapshot %s has children", key)
+			}
+		}
+	}
+
+	return s.removeSnapshot(logger, snap)
+}
+
+// Walk only visits snapshots created through the snapshotter. containerd
+// removes every snapshot it walks over and doesn't know about, which would
+// otherwise include the layers of images created with the CLI.
+func (s *Snapshotter) Walk(ctx context.Context, fn snapshots.WalkFunc, fs ...string) error {
+	logger := s.logger.Session("snapshotter-walk", lager.Data{"filters": fs})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	filter, err := filters.ParseAll(fs...)
+	if err != nil {
+		return err
+	}
+
+	all, err := s.metadata.list()
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range all {
+		info := snap.info()
+		if !filter.Match(adaptInfo(info)) {
+			continue
+		}
+
+		if err := fn(ctx, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Snapshotter) Close() error {
+	return nil
+}
+
+func (s *Snapshotter) createSnapshot(kind snapshots.Kind, key, parent string, opts []snapshots.Opt) ([]mount.Mount, error) {
+	logger := s.logger.Session("snapshotter-creating-snapshot", lager.Data{"kind": kind.String(), "key": key, "parent": parent})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	var info snapshots.Info
+	for _, opt := range opts {
+		if err := opt(&info); err != nil {
+			return nil, err
+		}
+	}
+
+	diskLimit, excludeImageFromQuota, err := quotaFromLabels(info.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prevents clean from collecting the parent volumes before the snapshot
+	// registers them.
+	globalLockFile, err := s.sharedLocksmith.Lock(groot.GlobalLockKey)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "acquiring global lock")
+	}
+	defer s.unlock(logger, s.sharedLocksmith, globalLockFile)
+
+	lockFile, err := s.exclusiveLocksmith.Lock(snapshotsLockKey)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "acquiring snapshots lock")
+	}
+	defer s.unlock(logger, s.exclusiveLocksmith, lockFile)
+
+	if s.metadata.exists(key) {
+		return nil, errorspkg.Wrapf(errdefs.ErrAlreadyExists, "snapshot %s", key)
+	}
+
+	var volumeIDs []string
+	if parent != "" {
+		parentSnapshot, err := s.getSnapshot(logger, parent)
+		if err != nil {
+			return nil, errorspkg.Wrap(err, "parent")
+		}
+		if parentSnapshot.Kind != snapshots.KindCommitted {
+			return nil, errorspkg.Wrapf(errdefs.ErrInvalidArgument, "parent %s is not committed", parent)
+		}
+		volumeIDs = parentSnapshot.VolumeIDs
+	}
+
+	now := time.Now().UTC()
+	snap := snapshot{
+		Kind:      kind,
+		Name:      key,
+		Parent:    parent,
+		ID:        snapshotID(key),
+		VolumeIDs: volumeIDs,
+		Labels:    info.Labels,
+		Created:   now,
+		Updated:   now,
+	}
+
+	snapshotPath := s.snapshotPath(snap)
+	if err := os.MkdirAll(snapshotPath, 0755); err != nil {
+		return nil, errorspkg.Wrap(err, "creating snapshot directory")
+	}
+
+	mountInfo, err := s.volumeDriver.CreateImage(logger, image_cloner.ImageDriverSpec{
+		BaseVolumeIDs:      volumeIDs,
+		Mount:              false,
+		ImagePath:          snapshotPath,
+		DiskLimit:          diskLimit,
+		ExclusiveDiskLimit: excludeImageFromQuota,
+	})
+	if err != nil {
+		if errD := s.volumeDriver.DestroyImage(logger, snapshotPath); errD != nil {
+			logger.Error("snapshot-cleanup-failed", errD)
+		}
+		return nil, errorspkg.Wrap(err, "creating snapshot")
+	}
+	snap.Mount = mountInfo
+	if len(volumeIDs) == 0 {
+		// Overlay needs at least one lower directory, so a snapshot without a
+		// parent is a bind mount of its upper directory.
+		snap.Mount = groot.MountInfo{
+			Type:    "bind",
+			Source:  s.upperDir(snap),
+			Options: []string{"rbind", "rw"},
+		}
+	}
+	if kind == snapshots.KindView {
+		snap.Mount.Options = readOnly(snap.Mount.Options)
+	}
+
+	if err := s.dependencyManager.Register(snapshotReference(key), volumeIDs); err != nil {
+		return nil, errorspkg.Wrap(err, "registering snapshot")
+	}
+	if err := s.metadata.put(snap); err != nil {
+		return nil, err
+	}
+
+	return snap.mounts(), nil
+}
+
+// removeSnapshot expects the global lock to be held exclusively for committed
+// snapshots.
+func (s *Snapshotter) removeSnapshot(logger lager.Logger, snap snapshot) error {
+	if snap.Kind == snapshots.KindCommitted {
+		// Base layers are named the same way by containerd and the CLI, so an
+		// image may have started using the volume since it was committed. It
+		// is then left for `clean` to collect.
+		if _, err := s.volumeChain(snap.volumeID()); err == nil {
+			logger.Info("volume-still-in-use", lager.Data{"volumeID": snap.volumeID()})
+		} else if err := s.volumeDriver.DestroyVolume(logger, snap.volumeID()); err != nil {
+			return errorspkg.Wrap(err, "destroying volume")
+		}
+	} else {
+		if err := s.volumeDriver.DestroyImage(logger, s.snapshotPath(snap)); err != nil {
+			return errorspkg.Wrap(err, "destroying snapshot")
+		}
+	}
+
+	if err := s.dependencyManager.Deregister(snapshotReference(snap.Name)); err != nil && !os.IsNotExist(err) {
+		logger.Error("deregistering-snapshot-failed", err)
+	}
+
+	return s.metadata.remove(snap.Name)
+}
+
+// getSnapshot also resolves the volumes of images created with the CLI by
+// their volume ID, so snapshots can be prepared on top of them.
+func (s *Snapshotter) getSnapshot(logger lager.Logger, key string) (snapshot, error) {
+	snap, err := s.metadata.get(key)
+	if err == nil || !errdefs.IsNotFound(err) {
+		return snap, err
+	}
+
+	volumeIDs, err := s.volumeChain(key)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	var parent string
+	if len(volumeIDs) > 1 {
+		parent = volumeIDs[len(volumeIDs)-2]
+	}
+
+	return snapshot{
+		Kind:      snapshots.KindCommitted,
+		Name:      key,
+		Parent:    parent,
+		VolumeIDs: volumeIDs,
+	}, nil
+}
+
+func (s *Snapshotter) volumeChain(volumeID string) ([]string, error) {
+	for _, prefix := range []string{groot.ImageReferencePrefix, groot.PinReferencePrefix} {
+		refNames, err := s.dependencyManager.List(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, refName := range refNames {
+			volumeIDs, err := s.dependencyManager.Dependencies(refName)
+			if err != nil {
+				continue
+			}
+
+			for i, id := range volumeIDs {
+				if id == volumeID {
+					return volumeIDs[:i+1], nil
+				}
+			}
+		}
+	}
+
+	return nil, errorspkg.Wrapf(errdefs.ErrNotFound, "snapshot %s", volumeID)
+}
+
+func (s *Snapshotter) snapshotPath(snap snapshot) string {
+	return filepath.Join(s.storePath, SnapshotsDirName, snap.ID)
+}
+
+func (s *Snapshotter) upperDir(snap snapshot) string {
+	return filepath.Join(s.snapshotPath(snap), "diff")
+}
+
+func (s *Snapshotter) unlock(logger lager.Logger, locksmith groot.Locksmith, lockFile *os.File) {
+	if err := locksmith.Unlock(lockFile); err != nil {
+		logger.Error("failed-to-unlock", err)
+	}
+}
+
+func quotaFromLabels(labels map[string]string) (int64, bool, error) {
+	var (
+		diskLimit             int64
+		excludeImageFromQuota bool
+		err                   error
+	)
+
+	if value, ok := labels[LabelDiskLimitSizeBytes]; ok {
+		diskLimit, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || diskLimit < 0 {
+			return 0, false, errorspkg.Wrapf(errdefs.ErrInvalidArgument, "invalid %s label `%s`", LabelDiskLimitSizeBytes, value)
+		}
+	}
+
+	if value, ok := labels[LabelExcludeImageFromQuota]; ok {
+		excludeImageFromQuota, err = strconv.ParseBool(value)
+		if err != nil {
+			return 0, false, errorspkg.Wrapf(errdefs.ErrInvalidArgument, "invalid %s label `%s`", LabelExcludeImageFromQuota, value)
+		}
+	}
+
+	return diskLimit, excludeImageFromQuota, nil
+}
+
+func readOnly(options []string) []string {
+	roOptions := []string{}
+	for _, option := range options {
+		if option != "rw" {
+			roOptions = append(roOptions, option)
+		}
+	}
+
+	return append(roOptions, "ro")
+}
+
+func snapshotReference(key string) string {
+	return fmt.Sprintf(SnapshotReferenceFormat, key)
+}
+
+func snapshotID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// volumeIDFor names the volume of a committed snapshot after its digest when
+// the name is one, which is how containerd names unpacked layers.
+func volumeIDFor(name string) string {
+	if digest, err := digestpkg.Parse(name); err == nil {
+		return digest.Hex()
+	}
+
+	return snapshotID(name)
+}
+
+func adaptInfo(info snapshots.Info) filters.Adaptor {
+	return filters.AdapterFunc(func(fieldpath []string) (string, bool) {
+		if len(fieldpath) == 0 {
+			return "", false
+		}
+
+		switch fieldpath[0] {
+		case "kind":
+			return info.Kind.String(), true
+		case "name":
+			return info.Name, true
+		case "parent":
+			return info.Parent, info.Parent != ""
+		case "labels":
+			if len(fieldpath) < 2 {
+				return "", false
+			}
+			value, ok := info.Labels[strings.Join(fieldpath[1:], ".")]
+			return value, ok
+		}
+
+		return "", false
+	})
+}
@@ -0,0 +1,638 @@
+package snapshotter_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/grootfs/snapshotter"
+	"code.cloudfoundry.org/grootfs/snapshotter/snapshotterfakes"
+	"code.cloudfoundry.org/grootfs/store/dependency_manager"
+	"code.cloudfoundry.org/grootfs/store/image_cloner"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshotter", func() {
+	var (
+		ctx                    context.Context
+		storePath              string
+		volumesPath            string
+		fakeVolumeDriver       *snapshotterfakes.FakeVolumeDriver
+		fakeSharedLocksmith    *grootfakes.FakeLocksmith
+		fakeExclusiveLocksmith *grootfakes.FakeLocksmith
+		dependencyManager      *dependency_manager.DependencyManager
+		sn                     *snapshotter.Snapshotter
+	)
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		storePath, err = ioutil.TempDir("", "snapshotter-store")
+		Expect(err).NotTo(HaveOccurred())
+
+		volumesPath = filepath.Join(storePath, "volumes")
+		Expect(os.MkdirAll(volumesPath, 0755)).To(Succeed())
+		dependenciesPath := filepath.Join(storePath, "meta", "dependencies")
+		Expect(os.MkdirAll(dependenciesPath, 0755)).To(Succeed())
+		dependencyManager = dependency_manager.NewDependencyManager(dependenciesPath)
+
+		fakeVolumeDriver = new(snapshotterfakes.FakeVolumeDriver)
+		fakeVolumeDriver.CreateImageStub = func(_ lager.Logger, spec image_cloner.ImageDriverSpec) (groot.MountInfo, error) {
+			Expect(os.MkdirAll(filepath.Join(spec.ImagePath, "diff"), 0755)).To(Succeed())
+			return groot.MountInfo{
+				Destination: "/",
+				Type:        "overlay",
+				Source:      "overlay",
+				Options:     []string{"lowerdir=/lower,upperdir=" + spec.ImagePath + "/diff"},
+			}, nil
+		}
+		fakeVolumeDriver.VolumePathStub = func(_ lager.Logger, id string) (string, error) {
+			volumePath := filepath.Join(volumesPath, id)
+			if _, err := os.Stat(volumePath); err != nil {
+				return "", err
+			}
+			return volumePath, nil
+		}
+		fakeVolumeDriver.CreateVolumeStub = func(_ lager.Logger, _, id string) (string, error) {
+			volumePath := filepath.Join(volumesPath, id)
+			return volumePath, os.Mkdir(volumePath, 0755)
+		}
+
+		fakeSharedLocksmith = new(grootfakes.FakeLocksmith)
+		fakeExclusiveLocksmith = new(grootfakes.FakeLocksmith)
+
+		sn = snapshotter.NewSnapshotter(
+			lagertest.NewTestLogger("snapshotter"), storePath, fakeVolumeDriver,
+			dependencyManager, fakeSharedLocksmith, fakeExclusiveLocksmith,
+		)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(storePath)).To(Succeed())
+	})
+
+	lastSnapshotPath := func() string {
+		_, spec := fakeVolumeDriver.CreateImageArgsForCall(fakeVolumeDriver.CreateImageCallCount() - 1)
+		return spec.ImagePath
+	}
+
+	Describe("Prepare", func() {
+		It("creates an unmounted image under the snapshots directory", func() {
+			mounts, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeVolumeDriver.CreateImageCallCount()).To(Equal(1))
+			_, spec := fakeVolumeDriver.CreateImageArgsForCall(0)
+			Expect(spec.Mount).To(BeFalse())
+			Expect(spec.BaseVolumeIDs).To(BeEmpty())
+			Expect(filepath.Dir(spec.ImagePath)).To(Equal(filepath.Join(storePath, snapshotter.SnapshotsDirName)))
+
+			Expect(mounts).To(Equal([]mount.Mount{
+				{
+					Type:    "bind",
+					Source:  filepath.Join(spec.ImagePath, "diff"),
+					Options: []string{"rbind", "rw"},
+				},
+			}))
+		})
+
+		It("returns mounts that can be mounted and written to", func() {
+			mounts, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			target, err := ioutil.TempDir("", "snapshot-mount")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(target)
+
+			Expect(mount.All(mounts, target)).To(Succeed())
+			defer func() {
+				Expect(mount.UnmountAll(target, 0)).To(Succeed())
+			}()
+
+			Expect(ioutil.WriteFile(filepath.Join(target, "hello"), []byte("hello"), 0644)).To(Succeed())
+			Expect(filepath.Join(lastSnapshotPath(), "diff", "hello")).To(BeAnExistingFile())
+		})
+
+		Context("when there is a parent", func() {
+			BeforeEach(func() {
+				Expect(dependencyManager.Register("image:my-image", []string{"layer-1"})).To(Succeed())
+			})
+
+			It("returns the overlay mounts of the image", func() {
+				mounts, err := sn.Prepare(ctx, "my-key", "layer-1")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(mounts).To(Equal([]mount.Mount{
+					{
+						Type:    "overlay",
+						Source:  "overlay",
+						Options: []string{"lowerdir=/lower,upperdir=" + lastSnapshotPath() + "/diff"},
+					},
+				}))
+			})
+		})
+
+		It("returns the same mounts from Mounts", func() {
+			mounts, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sn.Mounts(ctx, "my-key")).To(Equal(mounts))
+		})
+
+		It("is an active snapshot", func() {
+			_, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			info, err := sn.Stat(ctx, "my-key")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Kind).To(Equal(snapshots.KindActive))
+			Expect(info.Name).To(Equal("my-key"))
+		})
+
+		It("holds the global lock while creating the snapshot", func() {
+			_, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeSharedLocksmith.LockCallCount()).To(Equal(1))
+			Expect(fakeSharedLocksmith.LockArgsForCall(0)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeSharedLocksmith.UnlockCallCount()).To(Equal(1))
+		})
+
+		It("registers the snapshot's volumes so clean keeps them", func() {
+			_, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			refNames, err := dependencyManager.List(dependency_manager.SnapshotReferencePrefix)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refNames).To(ConsistOf("snapshot:my-key"))
+		})
+
+		Context("when the quota labels are set", func() {
+			It("applies the disk limit", func() {
+				_, err := sn.Prepare(ctx, "my-key", "", snapshots.WithLabels(map[string]string{
+					snapshotter.LabelDiskLimitSizeBytes:    "1048576",
+					snapshotter.LabelExcludeImageFromQuota: "true",
+				}))
+				Expect(err).NotTo(HaveOccurred())
+
+				_, spec := fakeVolumeDriver.CreateImageArgsForCall(0)
+				Expect(spec.DiskLimit).To(Equal(int64(1048576)))
+				Expect(spec.ExclusiveDiskLimit).To(BeTrue())
+			})
+
+			Context("when the disk limit is invalid", func() {
+				It("returns an invalid argument error", func() {
+					_, err := sn.Prepare(ctx, "my-key", "", snapshots.WithLabels(map[string]string{
+						snapshotter.LabelDiskLimitSizeBytes: "-1",
+					}))
+					Expect(errdefs.IsInvalidArgument(err)).To(BeTrue())
+					Expect(fakeVolumeDriver.CreateImageCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when the key already exists", func() {
+			It("returns an already exists error", func() {
+				_, err := sn.Prepare(ctx, "my-key", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = sn.Prepare(ctx, "my-key", "")
+				Expect(errdefs.IsAlreadyExists(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the parent doesn't exist", func() {
+			It("returns a not found error", func() {
+				_, err := sn.Prepare(ctx, "my-key", "not-here")
+				Expect(errdefs.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the parent is a volume of an image created with the CLI", func() {
+			BeforeEach(func() {
+				Expect(dependencyManager.Register("image:my-image", []string{"layer-1", "layer-2", "layer-3"})).To(Succeed())
+			})
+
+			It("uses the volume and the volumes below it", func() {
+				_, err := sn.Prepare(ctx, "my-key", "layer-2")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, spec := fakeVolumeDriver.CreateImageArgsForCall(0)
+				Expect(spec.BaseVolumeIDs).To(Equal([]string{"layer-1", "layer-2"}))
+			})
+
+			It("can be stat'ed as a committed snapshot", func() {
+				info, err := sn.Stat(ctx, "layer-2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Kind).To(Equal(snapshots.KindCommitted))
+				Expect(info.Parent).To(Equal("layer-1"))
+			})
+
+			It("is not walked", func() {
+				walked := []string{}
+				Expect(sn.Walk(ctx, func(_ context.Context, info snapshots.Info) error {
+					walked = append(walked, info.Name)
+					return nil
+				})).To(Succeed())
+				Expect(walked).To(BeEmpty())
+			})
+		})
+
+		Context("when creating the image fails", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.CreateImageStub = nil
+				fakeVolumeDriver.CreateImageReturns(groot.MountInfo{}, errors.New("failed to create"))
+			})
+
+			It("cleans up and doesn't record the snapshot", func() {
+				_, err := sn.Prepare(ctx, "my-key", "")
+				Expect(err).To(MatchError(ContainSubstring("failed to create")))
+				Expect(fakeVolumeDriver.DestroyImageCallCount()).To(Equal(1))
+
+				_, err = sn.Stat(ctx, "my-key")
+				Expect(errdefs.IsNotFound(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("View", func() {
+		It("returns read-only mounts", func() {
+			mounts, err := sn.View(ctx, "my-view", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounts[0].Options).To(ConsistOf("rbind", "ro"))
+
+			info, err := sn.Stat(ctx, "my-view")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Kind).To(Equal(snapshots.KindView))
+		})
+
+		It("returns mounts that can't be written to", func() {
+			mounts, err := sn.View(ctx, "my-view", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			target, err := ioutil.TempDir("", "snapshot-mount")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(target)
+
+			Expect(mount.All(mounts, target)).To(Succeed())
+			defer func() {
+				Expect(mount.UnmountAll(target, 0)).To(Succeed())
+			}()
+
+			err = ioutil.WriteFile(filepath.Join(target, "hello"), []byte("hello"), 0644)
+			Expect(err).To(MatchError(ContainSubstring("read-only file system")))
+		})
+
+		Context("when there is a parent", func() {
+			BeforeEach(func() {
+				Expect(dependencyManager.Register("image:my-image", []string{"layer-1"})).To(Succeed())
+			})
+
+			It("adds the read-only option to the overlay mounts", func() {
+				mounts, err := sn.View(ctx, "my-view", "layer-1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounts[0].Type).To(Equal("overlay"))
+				Expect(mounts[0].Options).To(ContainElement("ro"))
+			})
+		})
+
+		It("cannot be committed", func() {
+			_, err := sn.View(ctx, "my-view", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = sn.Commit(ctx, "my-layer", "my-view")
+			Expect(errdefs.IsFailedPrecondition(err)).To(BeTrue())
+		})
+	})
+
+	Describe("Commit", func() {
+		const layerDigest = "sha256:7b8b61ecc9b9b2d1ad4e0da9e2b0a3f53c3ce5e1b5d3fbf6ad6c5c1a9c9e1c4b"
+		const layerVolumeID = "7b8b61ecc9b9b2d1ad4e0da9e2b0a3f53c3ce5e1b5d3fbf6ad6c5c1a9c9e1c4b"
+
+		var activePath string
+
+		BeforeEach(func() {
+			_, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+			activePath = lastSnapshotPath()
+
+			Expect(ioutil.WriteFile(filepath.Join(activePath, "diff", "hello"), []byte("hello world"), 0644)).To(Succeed())
+		})
+
+		It("moves the changes into a volume named after the digest", func() {
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+
+			Expect(fakeVolumeDriver.CreateVolumeCallCount()).To(Equal(1))
+			_, _, volumeID := fakeVolumeDriver.CreateVolumeArgsForCall(0)
+			Expect(volumeID).To(Equal(layerVolumeID))
+
+			contents, err := ioutil.ReadFile(filepath.Join(volumesPath, layerVolumeID, "hello"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("hello world"))
+		})
+
+		It("clears the project ids of the changes before moving them", func() {
+			fakeVolumeDriver.ClearProjectIDsStub = func(_ lager.Logger, path string) error {
+				Expect(filepath.Join(path, "hello")).To(BeAnExistingFile())
+				return nil
+			}
+
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+
+			Expect(fakeVolumeDriver.ClearProjectIDsCallCount()).To(Equal(1))
+			_, path := fakeVolumeDriver.ClearProjectIDsArgsForCall(0)
+			Expect(path).To(Equal(filepath.Join(activePath, "diff")))
+		})
+
+		Context("when clearing the project ids fails", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.ClearProjectIDsReturns(errors.New("failed to clear"))
+			})
+
+			It("keeps the active snapshot", func() {
+				err := sn.Commit(ctx, layerDigest, "my-key")
+				Expect(err).To(MatchError(ContainSubstring("failed to clear")))
+				Expect(fakeVolumeDriver.CreateVolumeCallCount()).To(BeZero())
+
+				Expect(filepath.Join(activePath, "diff", "hello")).To(BeAnExistingFile())
+				info, err := sn.Stat(ctx, "my-key")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Kind).To(Equal(snapshots.KindActive))
+			})
+		})
+
+		It("holds the global lock shared", func() {
+			fakeSharedLocksmith.LockStub = func(key string) (*os.File, error) {
+				Expect(fakeVolumeDriver.CreateVolumeCallCount()).To(BeZero())
+				return nil, nil
+			}
+
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+
+			Expect(fakeSharedLocksmith.LockCallCount()).To(Equal(2))
+			Expect(fakeSharedLocksmith.LockArgsForCall(1)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeSharedLocksmith.UnlockCallCount()).To(Equal(2))
+		})
+
+		Context("when writing the volume meta fails", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.WriteVolumeMetaReturns(errors.New("failed to write meta"))
+			})
+
+			It("moves the changes back and destroys the volume", func() {
+				err := sn.Commit(ctx, layerDigest, "my-key")
+				Expect(err).To(MatchError(ContainSubstring("failed to write meta")))
+
+				contents, err := ioutil.ReadFile(filepath.Join(activePath, "diff", "hello"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("hello world"))
+
+				Expect(fakeVolumeDriver.DestroyVolumeCallCount()).To(Equal(1))
+				_, volumeID := fakeVolumeDriver.DestroyVolumeArgsForCall(0)
+				Expect(volumeID).To(Equal(layerVolumeID))
+			})
+
+			It("keeps the active snapshot", func() {
+				Expect(sn.Commit(ctx, layerDigest, "my-key")).NotTo(Succeed())
+
+				info, err := sn.Stat(ctx, "my-key")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Kind).To(Equal(snapshots.KindActive))
+
+				_, err = sn.Stat(ctx, layerDigest)
+				Expect(errdefs.IsNotFound(err)).To(BeTrue())
+				refNames, err := dependencyManager.List(dependency_manager.SnapshotReferencePrefix)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(refNames).To(BeEmpty())
+			})
+		})
+
+		It("records the size of the volume", func() {
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+
+			Expect(fakeVolumeDriver.WriteVolumeMetaCallCount()).To(Equal(1))
+			_, volumeID, meta := fakeVolumeDriver.WriteVolumeMetaArgsForCall(0)
+			Expect(volumeID).To(Equal(layerVolumeID))
+			Expect(meta.Size).To(BeNumerically(">", 0))
+		})
+
+		It("replaces the active snapshot with a committed one", func() {
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+
+			Expect(fakeVolumeDriver.DestroyImageCallCount()).To(Equal(1))
+			_, path := fakeVolumeDriver.DestroyImageArgsForCall(0)
+			Expect(path).To(Equal(activePath))
+
+			_, err := sn.Stat(ctx, "my-key")
+			Expect(errdefs.IsNotFound(err)).To(BeTrue())
+
+			info, err := sn.Stat(ctx, layerDigest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Kind).To(Equal(snapshots.KindCommitted))
+
+			Expect(dependencyManager.Dependencies("snapshot:" + layerDigest)).To(Equal([]string{layerVolumeID}))
+		})
+
+		It("can be used as a parent", func() {
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+
+			_, err := sn.Prepare(ctx, "child", layerDigest)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, spec := fakeVolumeDriver.CreateImageArgsForCall(1)
+			Expect(spec.BaseVolumeIDs).To(Equal([]string{layerVolumeID}))
+
+			info, err := sn.Stat(ctx, "child")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Parent).To(Equal(layerDigest))
+		})
+
+		It("cannot be mounted once committed", func() {
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+
+			_, err := sn.Mounts(ctx, layerDigest)
+			Expect(errdefs.IsFailedPrecondition(err)).To(BeTrue())
+		})
+
+		Context("when the volume already exists", func() {
+			It("returns an already exists error", func() {
+				Expect(os.Mkdir(filepath.Join(volumesPath, layerVolumeID), 0755)).To(Succeed())
+
+				err := sn.Commit(ctx, layerDigest, "my-key")
+				Expect(errdefs.IsAlreadyExists(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Remove", func() {
+		const layerDigest = "sha256:7b8b61ecc9b9b2d1ad4e0da9e2b0a3f53c3ce5e1b5d3fbf6ad6c5c1a9c9e1c4b"
+		const layerVolumeID = "7b8b61ecc9b9b2d1ad4e0da9e2b0a3f53c3ce5e1b5d3fbf6ad6c5c1a9c9e1c4b"
+
+		BeforeEach(func() {
+			_, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sn.Commit(ctx, layerDigest, "my-key")).To(Succeed())
+		})
+
+		It("destroys the volume of a committed snapshot", func() {
+			Expect(sn.Remove(ctx, layerDigest)).To(Succeed())
+
+			Expect(fakeVolumeDriver.DestroyVolumeCallCount()).To(Equal(1))
+			_, volumeID := fakeVolumeDriver.DestroyVolumeArgsForCall(0)
+			Expect(volumeID).To(Equal(layerVolumeID))
+
+			_, err := sn.Stat(ctx, layerDigest)
+			Expect(errdefs.IsNotFound(err)).To(BeTrue())
+
+			refNames, err := dependencyManager.List(dependency_manager.SnapshotReferencePrefix)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refNames).To(BeEmpty())
+		})
+
+		It("holds the global lock exclusively", func() {
+			fakeExclusiveLocksmith.LockStub = func(key string) (*os.File, error) {
+				if key == groot.GlobalLockKey {
+					Expect(fakeVolumeDriver.DestroyVolumeCallCount()).To(BeZero())
+				}
+				return nil, nil
+			}
+
+			Expect(sn.Remove(ctx, layerDigest)).To(Succeed())
+
+			lockCalls := fakeExclusiveLocksmith.LockCallCount()
+			Expect(fakeExclusiveLocksmith.LockArgsForCall(lockCalls - 2)).To(Equal(groot.GlobalLockKey))
+			Expect(fakeExclusiveLocksmith.UnlockCallCount()).To(Equal(lockCalls))
+		})
+
+		It("destroys the image of an active snapshot", func() {
+			_, err := sn.Prepare(ctx, "child", layerDigest)
+			Expect(err).NotTo(HaveOccurred())
+			childPath := lastSnapshotPath()
+
+			Expect(sn.Remove(ctx, "child")).To(Succeed())
+
+			_, path := fakeVolumeDriver.DestroyImageArgsForCall(fakeVolumeDriver.DestroyImageCallCount() - 1)
+			Expect(path).To(Equal(childPath))
+		})
+
+		Context("when the snapshot has children", func() {
+			It("returns a failed precondition error", func() {
+				_, err := sn.Prepare(ctx, "child", layerDigest)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = sn.Remove(ctx, layerDigest)
+				Expect(errdefs.IsFailedPrecondition(err)).To(BeTrue())
+				Expect(fakeVolumeDriver.DestroyVolumeCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when an image created with the CLI uses the volume", func() {
+			It("leaves the volume for clean to collect", func() {
+				Expect(dependencyManager.Register("image:my-image", []string{layerVolumeID})).To(Succeed())
+
+				Expect(sn.Remove(ctx, layerDigest)).To(Succeed())
+				Expect(fakeVolumeDriver.DestroyVolumeCallCount()).To(Equal(0))
+
+				_, err := sn.Stat(ctx, layerDigest)
+				Expect(errdefs.IsNotFound(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the snapshot doesn't exist", func() {
+			It("returns a not found error", func() {
+				err := sn.Remove(ctx, "not-here")
+				Expect(errdefs.IsNotFound(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		BeforeEach(func() {
+			_, err := sn.Prepare(ctx, "my-key", "", snapshots.WithLabels(map[string]string{
+				"keep":   "me",
+				"change": "me",
+			}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("updates the given labels", func() {
+			info, err := sn.Update(ctx, snapshots.Info{
+				Name:   "my-key",
+				Labels: map[string]string{"change": "you", "keep": "ignored"},
+			}, "labels.change")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Labels).To(Equal(map[string]string{"keep": "me", "change": "you"}))
+
+			info, err = sn.Stat(ctx, "my-key")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Labels).To(Equal(map[string]string{"keep": "me", "change": "you"}))
+		})
+
+		It("replaces all labels when no field is given", func() {
+			info, err := sn.Update(ctx, snapshots.Info{
+				Name:   "my-key",
+				Labels: map[string]string{"new": "label"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Labels).To(Equal(map[string]string{"new": "label"}))
+		})
+
+		Context("when updating something other than labels", func() {
+			It("returns an invalid argument error", func() {
+				_, err := sn.Update(ctx, snapshots.Info{Name: "my-key"}, "parent")
+				Expect(errdefs.IsInvalidArgument(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Usage", func() {
+		It("measures the changes of an active snapshot", func() {
+			_, err := sn.Prepare(ctx, "my-key", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(lastSnapshotPath(), "diff", "hello"), make([]byte, 8192), 0644)).To(Succeed())
+
+			usage, err := sn.Usage(ctx, "my-key")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(usage.Inodes).To(Equal(int64(2)))
+			Expect(usage.Size).To(BeNumerically(">=", 8192))
+		})
+	})
+
+	Describe("Walk", func() {
+		BeforeEach(func() {
+			_, err := sn.Prepare(ctx, "first", "", snapshots.WithLabels(map[string]string{"app": "a"}))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = sn.View(ctx, "second", "", snapshots.WithLabels(map[string]string{"app": "b"}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("visits every snapshot", func() {
+			walked := []string{}
+			Expect(sn.Walk(ctx, func(_ context.Context, info snapshots.Info) error {
+				walked = append(walked, info.Name)
+				return nil
+			})).To(Succeed())
+			Expect(walked).To(ConsistOf("first", "second"))
+		})
+
+		It("applies the filters", func() {
+			walked := []string{}
+			Expect(sn.Walk(ctx, func(_ context.Context, info snapshots.Info) error {
+				walked = append(walked, info.Name)
+				return nil
+			}, "labels.app==b")).To(Succeed())
+			Expect(walked).To(ConsistOf("second"))
+		})
+	})
+})
@@ -11,6 +11,10 @@ import (
 	errorspkg "github.com/pkg/errors"
 )
 
+// SnapshotReferencePrefix prefixes the dependencies of the snapshots created
+// through the containerd snapshotter.
+const SnapshotReferencePrefix = "snapshot:"
+
 type DependencyManager struct {
 	dependenciesPath string
 }
@@ -21,8 +25,20 @@ func NewDependencyManager(dependenciesPath string) *DependencyManager {
 	}
 }
 
+// dependencies is what is stored for each id. The id is kept in the file
+// because the escaping of the file name can't always be reversed.
+type dependencies struct {
+	ID       string            `json:"id"`
+	ChainIDs []string          `json:"chain_ids"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
 func (d *DependencyManager) Register(id string, chainIDs []string) error {
-	data, err := json.Marshal(chainIDs)
+	return d.RegisterWithMetadata(id, chainIDs, nil)
+}
+
+func (d *DependencyManager) RegisterWithMetadata(id string, chainIDs []string, metadata map[string]string) error {
+	data, err := json.Marshal(dependencies{ID: id, ChainIDs: chainIDs, Metadata: metadata})
 	if err != nil {
 		return err
 	}
@@ -35,7 +51,7 @@ func (d *DependencyManager) Deregister(id string) error {
 }
 
 func (d *DependencyManager) Dependencies(id string) ([]string, error) {
-	f, err := os.Open(d.filePath(id))
+	deps, err := d.read(d.filePath(id))
 	if err != nil && os.IsNotExist(err) {
 		return nil, errorspkg.Errorf("image `%s` not found", id)
 	}
@@ -43,15 +59,87 @@ func (d *DependencyManager) Dependencies(id string) ([]string, error) {
 		return nil, err
 	}
 
-	var chainIDs []string
-	if err := json.NewDecoder(f).Decode(&chainIDs); err != nil {
+	return deps.ChainIDs, nil
+}
+
+func (d *DependencyManager) Metadata(id string) (map[string]string, error) {
+	deps, err := d.read(d.filePath(id))
+	if err != nil && os.IsNotExist(err) {
+		return nil, errorspkg.Errorf("image `%s` not found", id)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	return chainIDs, nil
+	if deps.Metadata == nil {
+		return map[string]string{}, nil
+	}
+	return deps.Metadata, nil
+}
+
+func (d *DependencyManager) List(prefix string) ([]string, error) {
+	files, err := ioutil.ReadDir(d.dependenciesPath)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "listing dependencies")
+	}
+
+	ids := []string{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		// An id with the prefix always has a file name with the escaped prefix,
+		// so the other files don't need to be read.
+		if !strings.HasPrefix(file.Name(), escapeID(prefix)) {
+			continue
+		}
+
+		deps, err := d.read(filepath.Join(d.dependenciesPath, file.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errorspkg.Wrapf(err, "reading dependencies `%s`", file.Name())
+		}
+
+		if strings.HasPrefix(deps.ID, prefix) {
+			ids = append(ids, deps.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (d *DependencyManager) read(path string) (dependencies, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return dependencies{}, err
+	}
+
+	var deps dependencies
+	if err := json.Unmarshal(data, &deps); err == nil {
+		return deps, nil
+	}
+
+	// Files written by older versions only hold the chain ids. They only ever
+	// belonged to images, whose ids can't have slashes, so their file name is
+	// the id itself.
+	var chainIDs []string
+	if err := json.Unmarshal(data, &chainIDs); err != nil {
+		return dependencies{}, err
+	}
+
+	return dependencies{
+		ID:       strings.TrimSuffix(filepath.Base(path), ".json"),
+		ChainIDs: chainIDs,
+	}, nil
 }
 
 func (d *DependencyManager) filePath(id string) string {
-	escapedId := strings.Replace(id, "/", "__", -1)
-	return filepath.Join(d.dependenciesPath, fmt.Sprintf("%s.json", escapedId))
+	return filepath.Join(d.dependenciesPath, fmt.Sprintf("%s.json", escapeID(id)))
+}
+
+func escapeID(id string) string {
+	return strings.Replace(id, "/", "__", -1)
 }
@@ -48,6 +48,15 @@ var _ = Describe("DependencyManager", func() {
 			Expect(path.Join(depsPath, "my__image.json")).To(BeAnExistingFile())
 		})
 
+		It("keeps the original id in the file", func() {
+			Expect(manager.Register("pin:docker:///my__image", []string{"sha256:vol-1"})).To(Succeed())
+			Expect(manager.Register("pin:docker:///my/image", []string{"sha256:vol-2"})).To(Succeed())
+
+			ids, err := manager.List("pin:")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ids).To(ConsistOf("pin:docker:///my__image", "pin:docker:///my/image"))
+		})
+
 		Context("when the base path does not exist", func() {
 			BeforeEach(func() {
 				manager = dependency_manager.NewDependencyManager("/path/to/non/existent/dir")
@@ -61,6 +70,57 @@ var _ = Describe("DependencyManager", func() {
 		})
 	})
 
+	Describe("RegisterWithMetadata", func() {
+		It("keeps the metadata along with the dependencies", func() {
+			Expect(manager.RegisterWithMetadata("pin:sha256:abc", []string{"sha256:vol-1"}, map[string]string{"base_image_url": "docker:///cflinuxfs2"})).To(Succeed())
+
+			dependencies, err := manager.Dependencies("pin:sha256:abc")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(ConsistOf("sha256:vol-1"))
+
+			metadata, err := manager.Metadata("pin:sha256:abc")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(Equal(map[string]string{"base_image_url": "docker:///cflinuxfs2"}))
+		})
+	})
+
+	Describe("Metadata", func() {
+		It("returns an empty map when none was registered", func() {
+			Expect(manager.Register("my-image", []string{"sha256:vol-1"})).To(Succeed())
+
+			metadata, err := manager.Metadata("my-image")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(BeEmpty())
+		})
+
+		Context("when the id is not registered", func() {
+			It("returns an error", func() {
+				_, err := manager.Metadata("my-image")
+				Expect(err).To(MatchError(ContainSubstring("image `my-image` not found")))
+			})
+		})
+	})
+
+	Describe("Dependencies", func() {
+		Context("when the file was written by an older version", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(path.Join(depsPath, "image:my__image.json"), []byte(`["sha256:vol-1"]`), 0666)).To(Succeed())
+			})
+
+			It("reads the chain ids", func() {
+				dependencies, err := manager.Dependencies("image:my__image")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dependencies).To(ConsistOf("sha256:vol-1"))
+			})
+
+			It("lists it under the file name", func() {
+				ids, err := manager.List("image:")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids).To(ConsistOf("image:my__image"))
+			})
+		})
+	})
+
 	Describe("Deregister", func() {
 		It("deregisters the dependencies for a given image", func() {
 			imageID := "my-image"
@@ -88,4 +148,47 @@ var _ = Describe("DependencyManager", func() {
 			})
 		})
 	})
+
+	Describe("List", func() {
+		BeforeEach(func() {
+			Expect(manager.Register("image:my-image", []string{"sha256:vol-1"})).To(Succeed())
+			Expect(manager.Register("pin:docker:///cflinuxfs2", []string{"sha256:vol-2"})).To(Succeed())
+			Expect(manager.Register("pin:oci:///path/to/image", []string{"sha256:vol-3"})).To(Succeed())
+		})
+
+		It("lists the ids with the given prefix", func() {
+			ids, err := manager.List("pin:")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ids).To(ConsistOf("pin:docker:///cflinuxfs2", "pin:oci:///path/to/image"))
+		})
+
+		It("returns ids that can be used to read the dependencies", func() {
+			ids, err := manager.List("pin:docker")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ids).To(HaveLen(1))
+
+			dependencies, err := manager.Dependencies(ids[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(ConsistOf("sha256:vol-2"))
+		})
+
+		Context("when nothing matches the prefix", func() {
+			It("returns an empty list", func() {
+				ids, err := manager.List("baseimage:")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ids).To(BeEmpty())
+			})
+		})
+
+		Context("when the base path does not exist", func() {
+			BeforeEach(func() {
+				manager = dependency_manager.NewDependencyManager("/path/to/non/existent/dir")
+			})
+
+			It("returns an error", func() {
+				_, err := manager.List("pin:")
+				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+			})
+		})
+	})
 })
@@ -0,0 +1,169 @@
+package file_copier // import "code.cloudfoundry.org/grootfs/store/file_copier"
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/rootfs_configurer"
+	"code.cloudfoundry.org/lager"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	errorspkg "github.com/pkg/errors"
+)
+
+// FileCopier is a groot.RootFSConfigurer writing host files into an image's
+// upper directory. The files are streamed as a tar through the store's
+// unpacker, so they get the store's id mappings exactly like layer files.
+type FileCopier struct {
+	logger     lager.Logger
+	unpacker   base_image_puller.Unpacker
+	idMappings groot.IDMappings
+	lookupDirs rootfs_configurer.LookupDirsFunc
+	fileCopies []groot.FileCopy
+}
+
+func NewFileCopier(logger lager.Logger, unpacker base_image_puller.Unpacker, idMappings groot.IDMappings, lookupDirs rootfs_configurer.LookupDirsFunc, fileCopies []groot.FileCopy) *FileCopier {
+	return &FileCopier{
+		logger:     logger,
+		unpacker:   unpacker,
+		idMappings: idMappings,
+		lookupDirs: lookupDirs,
+		fileCopies: fileCopies,
+	}
+}
+
+func (c *FileCopier) Configure(rootFSPath string, baseImage *specsv1.Image) error {
+	logger := c.logger.Session("copying-files", lager.Data{"rootFSPath": rootFSPath, "fileCopies": c.fileCopies})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	// Sources are checked upfront so that a missing file fails the copy with
+	// a clear error instead of a truncated stream.
+	for _, fileCopy := range c.fileCopies {
+		stat, err := os.Stat(fileCopy.Source)
+		if err != nil {
+			return errorspkg.Wrapf(err, "copying `%s`", fileCopy.Source)
+		}
+		if !stat.Mode().IsRegular() {
+			return errorspkg.Errorf("copying `%s`: not a regular file", fileCopy.Source)
+		}
+	}
+
+	dirs, err := c.missingDirs(rootFSPath)
+	if err != nil {
+		return err
+	}
+
+	tarReader, tarWriter := io.Pipe()
+	go func() {
+		tarWriter.CloseWithError(writeTar(tarWriter, dirs, c.fileCopies))
+	}()
+	defer tarReader.Close()
+
+	if _, err := c.unpacker.Unpack(logger, base_image_puller.UnpackSpec{
+		Stream:      tarReader,
+		TargetPath:  rootFSPath,
+		UIDMappings: c.idMappings.UIDMappings,
+		GIDMappings: c.idMappings.GIDMappings,
+	}); err != nil {
+		logger.Error("unpacking-files-failed", err)
+		return errorspkg.Wrap(err, "writing copied files")
+	}
+
+	return nil
+}
+
+// missingDirs returns the parent directories of the destinations that the
+// image doesn't have yet, so that only those are written with a default mode
+// and owner.
+func (c *FileCopier) missingDirs(rootFSPath string) ([]string, error) {
+	lookupDirs, err := c.lookupDirs(rootFSPath)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "looking up image layers")
+	}
+
+	dirs := []string{}
+	writtenDirs := map[string]bool{}
+	for _, fileCopy := range c.fileCopies {
+		parentDirs, ok, err := rootfs_configurer.MissingDirs(lookupDirs, filepath.Dir(fileCopy.Destination))
+		if err != nil {
+			return nil, errorspkg.Wrapf(err, "copying to `%s`", fileCopy.Destination)
+		}
+		if !ok {
+			return nil, errorspkg.Errorf("copying to `%s`: a parent directory is not a directory in the image", fileCopy.Destination)
+		}
+
+		for _, dir := range parentDirs {
+			if !writtenDirs[dir] {
+				writtenDirs[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	return dirs, nil
+}
+
+// writeTar writes the missing parent directories, parents first, followed by
+// the copies.
+func writeTar(writer io.Writer, dirs []string, fileCopies []groot.FileCopy) error {
+	tarWriter := tar.NewWriter(writer)
+
+	for _, dir := range dirs {
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Name:     strings.TrimPrefix(dir, "/") + "/",
+			Typeflag: tar.TypeDir,
+			Mode:     0755,
+		}); err != nil {
+			return errorspkg.Wrapf(err, "writing directory `%s`", dir)
+		}
+	}
+
+	for _, fileCopy := range fileCopies {
+		if err := writeFile(tarWriter, fileCopy); err != nil {
+			return err
+		}
+	}
+
+	return tarWriter.Close()
+}
+
+func writeFile(tarWriter *tar.Writer, fileCopy groot.FileCopy) error {
+	file, err := os.Open(fileCopy.Source)
+	if err != nil {
+		return errorspkg.Wrapf(err, "copying `%s`", fileCopy.Source)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return errorspkg.Wrapf(err, "copying `%s`", fileCopy.Source)
+	}
+
+	mode := int64(stat.Mode().Perm())
+	if fileCopy.HasMode {
+		mode = int64(fileCopy.Mode)
+	}
+
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:     strings.TrimPrefix(fileCopy.Destination, "/"),
+		Typeflag: tar.TypeReg,
+		Mode:     mode,
+		Uid:      fileCopy.UID,
+		Gid:      fileCopy.GID,
+		Size:     stat.Size(),
+		ModTime:  stat.ModTime(),
+	}); err != nil {
+		return errorspkg.Wrapf(err, "writing `%s`", fileCopy.Destination)
+	}
+
+	if _, err := io.CopyN(tarWriter, file, stat.Size()); err != nil {
+		return errorspkg.Wrapf(err, "copying `%s`", fileCopy.Source)
+	}
+
+	return nil
+}
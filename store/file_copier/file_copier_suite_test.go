@@ -0,0 +1,13 @@
+package file_copier_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFileCopier(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "File Copier Suite")
+}
@@ -0,0 +1,226 @@
+package file_copier_test
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/file_copier"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type tarEntry struct {
+	header   tar.Header
+	contents string
+}
+
+var _ = Describe("FileCopier", func() {
+	var (
+		logger       lager.Logger
+		fakeUnpacker *base_image_pullerfakes.FakeUnpacker
+		idMappings   groot.IDMappings
+		sourceDir    string
+		upperDir     string
+		lowerDir     string
+		fileCopies   []groot.FileCopy
+
+		unpackedEntries []tarEntry
+		unpackSpec      base_image_puller.UnpackSpec
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceDir, err = ioutil.TempDir("", "file-copier")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(sourceDir, "hosts"), []byte("127.0.0.1 localhost"), 0640)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(sourceDir, "cert.pem"), []byte("cert"), 0644)).To(Succeed())
+		upperDir, err = ioutil.TempDir("", "upper")
+		Expect(err).NotTo(HaveOccurred())
+		lowerDir, err = ioutil.TempDir("", "lower")
+		Expect(err).NotTo(HaveOccurred())
+
+		logger = lagertest.NewTestLogger("file-copier")
+		idMappings = groot.IDMappings{
+			UIDMappings: []groot.IDMappingSpec{{HostID: 1000, NamespaceID: 0, Size: 1}},
+			GIDMappings: []groot.IDMappingSpec{{HostID: 1001, NamespaceID: 0, Size: 1}},
+		}
+		fileCopies = []groot.FileCopy{
+			{Source: filepath.Join(sourceDir, "hosts"), Destination: "/etc/hosts"},
+			{Source: filepath.Join(sourceDir, "cert.pem"), Destination: "/etc/ssl/cert.pem", Mode: 0600, HasMode: true, UID: 10, GID: 20},
+		}
+
+		unpackedEntries = []tarEntry{}
+		fakeUnpacker = new(base_image_pullerfakes.FakeUnpacker)
+		fakeUnpacker.UnpackStub = func(_ lager.Logger, spec base_image_puller.UnpackSpec) (base_image_puller.UnpackOutput, error) {
+			unpackSpec = spec
+			tarReader := tar.NewReader(spec.Stream)
+			for {
+				header, err := tarReader.Next()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadAll(tarReader)
+				Expect(err).NotTo(HaveOccurred())
+				unpackedEntries = append(unpackedEntries, tarEntry{header: *header, contents: string(contents)})
+			}
+
+			return base_image_puller.UnpackOutput{}, nil
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceDir)).To(Succeed())
+		Expect(os.RemoveAll(upperDir)).To(Succeed())
+		Expect(os.RemoveAll(lowerDir)).To(Succeed())
+	})
+
+	configure := func() error {
+		copier := file_copier.NewFileCopier(logger, fakeUnpacker, idMappings, func(dir string) ([]string, error) {
+			Expect(dir).To(Equal(upperDir))
+			return []string{upperDir, lowerDir}, nil
+		}, fileCopies)
+		return copier.Configure(upperDir, &specsv1.Image{})
+	}
+
+	entryNamed := func(name string) tarEntry {
+		for _, entry := range unpackedEntries {
+			if entry.header.Name == name {
+				return entry
+			}
+		}
+		Fail("no entry named " + name)
+		return tarEntry{}
+	}
+
+	It("unpacks the files into the given path with the store id mappings", func() {
+		Expect(configure()).To(Succeed())
+
+		Expect(fakeUnpacker.UnpackCallCount()).To(Equal(1))
+		Expect(unpackSpec.TargetPath).To(Equal(upperDir))
+		Expect(unpackSpec.UIDMappings).To(Equal(idMappings.UIDMappings))
+		Expect(unpackSpec.GIDMappings).To(Equal(idMappings.GIDMappings))
+	})
+
+	It("writes the missing parent directories once, before the files", func() {
+		Expect(configure()).To(Succeed())
+
+		names := []string{}
+		for _, entry := range unpackedEntries {
+			names = append(names, entry.header.Name)
+		}
+		Expect(names).To(Equal([]string{"etc/", "etc/ssl/", "etc/hosts", "etc/ssl/cert.pem"}))
+		Expect(unpackedEntries[0].header.Typeflag).To(Equal(byte(tar.TypeDir)))
+		Expect(unpackedEntries[0].header.Mode).To(Equal(int64(0755)))
+	})
+
+	It("writes the file contents", func() {
+		Expect(configure()).To(Succeed())
+
+		Expect(entryNamed("etc/hosts").contents).To(Equal("127.0.0.1 localhost"))
+		Expect(entryNamed("etc/ssl/cert.pem").contents).To(Equal("cert"))
+	})
+
+	It("keeps the source mode and root ownership by default", func() {
+		Expect(configure()).To(Succeed())
+
+		header := entryNamed("etc/hosts").header
+		Expect(header.Mode).To(Equal(int64(0640)))
+		Expect(header.Uid).To(Equal(0))
+		Expect(header.Gid).To(Equal(0))
+	})
+
+	It("uses the given mode and ownership", func() {
+		Expect(configure()).To(Succeed())
+
+		header := entryNamed("etc/ssl/cert.pem").header
+		Expect(header.Mode).To(Equal(int64(0600)))
+		Expect(header.Uid).To(Equal(10))
+		Expect(header.Gid).To(Equal(20))
+	})
+
+	Context("when the parent directories exist in the image", func() {
+		BeforeEach(func() {
+			Expect(os.Mkdir(filepath.Join(lowerDir, "etc"), 0750)).To(Succeed())
+			Expect(os.Mkdir(filepath.Join(lowerDir, "tmp"), 01777)).To(Succeed())
+			Expect(os.Chmod(filepath.Join(lowerDir, "tmp"), 01777)).To(Succeed())
+			fileCopies = append(fileCopies, groot.FileCopy{Source: filepath.Join(sourceDir, "hosts"), Destination: "/tmp/hosts"})
+		})
+
+		It("only writes the missing ones, leaving the existing ones as they are", func() {
+			Expect(configure()).To(Succeed())
+
+			names := []string{}
+			for _, entry := range unpackedEntries {
+				names = append(names, entry.header.Name)
+			}
+			Expect(names).To(Equal([]string{"etc/ssl/", "etc/hosts", "etc/ssl/cert.pem", "tmp/hosts"}))
+		})
+	})
+
+	Context("when a parent directory is a symlink in the image", func() {
+		BeforeEach(func() {
+			Expect(os.Mkdir(filepath.Join(lowerDir, "etc"), 0755)).To(Succeed())
+			Expect(os.Symlink("/etc", filepath.Join(upperDir, "etc"))).To(Succeed())
+		})
+
+		It("returns an error without unpacking", func() {
+			Expect(configure()).To(MatchError(ContainSubstring("copying to `/etc/hosts`")))
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when looking up the image layers fails", func() {
+		It("returns an error", func() {
+			copier := file_copier.NewFileCopier(logger, fakeUnpacker, idMappings, func(string) ([]string, error) {
+				return nil, errors.New("no layers")
+			}, fileCopies)
+
+			Expect(copier.Configure(upperDir, &specsv1.Image{})).To(MatchError(ContainSubstring("no layers")))
+		})
+	})
+
+	Context("when a source doesn't exist", func() {
+		BeforeEach(func() {
+			fileCopies[1].Source = filepath.Join(sourceDir, "not-here")
+		})
+
+		It("returns an error without unpacking", func() {
+			Expect(configure()).To(MatchError(ContainSubstring("copying `%s`", fileCopies[1].Source)))
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when a source is a directory", func() {
+		BeforeEach(func() {
+			fileCopies[0].Source = sourceDir
+		})
+
+		It("returns an error", func() {
+			Expect(configure()).To(MatchError(ContainSubstring("not a regular file")))
+		})
+	})
+
+	Context("when unpacking fails", func() {
+		BeforeEach(func() {
+			fakeUnpacker.UnpackStub = nil
+			fakeUnpacker.UnpackReturns(base_image_puller.UnpackOutput{}, errors.New("failed to unpack"))
+		})
+
+		It("returns the error", func() {
+			Expect(configure()).To(MatchError("writing copied files: failed to unpack"))
+		})
+	})
+})
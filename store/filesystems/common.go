@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/grootfs/base_image_puller"
 	"code.cloudfoundry.org/grootfs/store"
@@ -17,26 +19,48 @@ import (
 )
 
 func WriteVolumeMeta(logger lager.Logger, storePath, id string, metadata base_image_puller.VolumeMeta) error {
-	metaFile, err := os.Create(VolumeMetaFilePath(storePath, id))
+	metaFilePath := VolumeMetaFilePath(storePath, id)
+	metaFile, err := ioutil.TempFile(filepath.Dir(metaFilePath), filepath.Base(metaFilePath))
 	if err != nil {
 		return errorspkg.Wrap(err, "creating metadata file")
 	}
+	defer os.Remove(metaFile.Name())
+	defer metaFile.Close()
 
 	if err = json.NewEncoder(metaFile).Encode(metadata); err != nil {
 		return errorspkg.Wrap(err, "writing metadata file")
 	}
 
+	if err = metaFile.Chmod(0644); err != nil {
+		return errorspkg.Wrap(err, "changing metadata file permissions")
+	}
+
+	// Metadata is rewritten whenever a volume is used, rename it into place so
+	// concurrent readers never see a partially written file.
+	if err = os.Rename(metaFile.Name(), metaFilePath); err != nil {
+		return errorspkg.Wrap(err, "moving metadata file")
+	}
+
 	return nil
 }
 
-func VolumeSize(logger lager.Logger, storePath, id string) (int64, error) {
+func ReadVolumeMeta(logger lager.Logger, storePath, id string) (base_image_puller.VolumeMeta, error) {
 	metaFile, err := os.Open(VolumeMetaFilePath(storePath, id))
 	if err != nil {
-		return 0, err
+		return base_image_puller.VolumeMeta{}, err
 	}
+	defer metaFile.Close()
 
 	var metadata base_image_puller.VolumeMeta
-	err = json.NewDecoder(metaFile).Decode(&metadata)
+	if err := json.NewDecoder(metaFile).Decode(&metadata); err != nil {
+		return base_image_puller.VolumeMeta{}, err
+	}
+
+	return metadata, nil
+}
+
+func VolumeSize(logger lager.Logger, storePath, id string) (int64, error) {
+	metadata, err := ReadVolumeMeta(logger, storePath, id)
 	if err != nil {
 		return 0, err
 	}
@@ -44,6 +68,29 @@ func VolumeSize(logger lager.Logger, storePath, id string) (int64, error) {
 	return metadata.Size, nil
 }
 
+func VolumeLastUsed(logger lager.Logger, storePath, id string) (time.Time, error) {
+	metadata, err := ReadVolumeMeta(logger, storePath, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if metadata.LastUsed == 0 {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(0, metadata.LastUsed), nil
+}
+
+func TouchVolumeMeta(logger lager.Logger, storePath, id string, usedAt time.Time) error {
+	metadata, err := ReadVolumeMeta(logger, storePath, id)
+	if err != nil {
+		return errorspkg.Wrap(err, "reading metadata file")
+	}
+
+	metadata.LastUsed = usedAt.UnixNano()
+	return WriteVolumeMeta(logger, storePath, id, metadata)
+}
+
 func VolumeMetaFilePath(storePath, id string) string {
 	id = strings.Replace(id, "gc.", "", 1)
 	return filepath.Join(storePath, store.MetaDirName, fmt.Sprintf("volume-%s", id))
@@ -6,7 +6,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/store"
 	"code.cloudfoundry.org/grootfs/store/filesystems"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
@@ -49,6 +52,88 @@ var _ = Describe("Common", func() {
 		})
 	})
 
+	Describe("volume metadata", func() {
+		var storePath string
+
+		BeforeEach(func() {
+			var err error
+			storePath, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Mkdir(filepath.Join(storePath, store.MetaDirName), 0755)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(storePath)).To(Succeed())
+		})
+
+		It("writes and reads the volume metadata", func() {
+			metadata := base_image_puller.VolumeMeta{Size: 1024, LastUsed: 12345}
+			Expect(filesystems.WriteVolumeMeta(logger, storePath, "volume-id", metadata)).To(Succeed())
+
+			readMetadata, err := filesystems.ReadVolumeMeta(logger, storePath, "volume-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readMetadata).To(Equal(metadata))
+
+			size, err := filesystems.VolumeSize(logger, storePath, "volume-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(BeEquivalentTo(1024))
+		})
+
+		It("doesn't leave temporary files behind", func() {
+			Expect(filesystems.WriteVolumeMeta(logger, storePath, "volume-id", base_image_puller.VolumeMeta{})).To(Succeed())
+
+			files, err := ioutil.ReadDir(filepath.Join(storePath, store.MetaDirName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Name()).To(Equal("volume-volume-id"))
+		})
+
+		Describe("TouchVolumeMeta", func() {
+			It("records the last use time and keeps the size", func() {
+				Expect(filesystems.WriteVolumeMeta(logger, storePath, "volume-id", base_image_puller.VolumeMeta{Size: 1024})).To(Succeed())
+
+				usedAt := time.Now()
+				Expect(filesystems.TouchVolumeMeta(logger, storePath, "volume-id", usedAt)).To(Succeed())
+
+				lastUsed, err := filesystems.VolumeLastUsed(logger, storePath, "volume-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lastUsed.UnixNano()).To(Equal(usedAt.UnixNano()))
+
+				size, err := filesystems.VolumeSize(logger, storePath, "volume-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(size).To(BeEquivalentTo(1024))
+			})
+
+			It("touches volumes marked for garbage collection", func() {
+				Expect(filesystems.WriteVolumeMeta(logger, storePath, "volume-id", base_image_puller.VolumeMeta{Size: 1024})).To(Succeed())
+				Expect(filesystems.TouchVolumeMeta(logger, storePath, "gc.volume-id", time.Now())).To(Succeed())
+
+				lastUsed, err := filesystems.VolumeLastUsed(logger, storePath, "volume-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lastUsed.IsZero()).To(BeFalse())
+			})
+
+			Context("when the volume has no metadata", func() {
+				It("returns an error", func() {
+					err := filesystems.TouchVolumeMeta(logger, storePath, "volume-id", time.Now())
+					Expect(err).To(MatchError(ContainSubstring("reading metadata file")))
+				})
+			})
+		})
+
+		Describe("VolumeLastUsed", func() {
+			Context("when the volume was never touched", func() {
+				It("returns the zero time", func() {
+					Expect(filesystems.WriteVolumeMeta(logger, storePath, "volume-id", base_image_puller.VolumeMeta{Size: 1024})).To(Succeed())
+
+					lastUsed, err := filesystems.VolumeLastUsed(logger, storePath, "volume-id")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(lastUsed.IsZero()).To(BeTrue())
+				})
+			})
+		})
+	})
+
 })
 
 func writeFile(path string, size int64) {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"syscall"
+	"time"
 
 	"code.cloudfoundry.org/commandrunner"
 	"code.cloudfoundry.org/grootfs/base_image_puller"
@@ -25,14 +26,25 @@ type internalDriver interface {
 	CreateVolume(logger lager.Logger, parentID string, id string) (string, error)
 	DestroyVolume(logger lager.Logger, id string) error
 	HandleOpaqueWhiteouts(logger lager.Logger, id string, opaqueWhiteouts []string) error
+	ClearProjectIDs(logger lager.Logger, path string) error
 	MoveVolume(logger lager.Logger, from, to string) error
 	VolumePath(logger lager.Logger, id string) (string, error)
 	Volumes(logger lager.Logger) ([]string, error)
+	VolumeSize(logger lager.Logger, id string) (int64, error)
+	VolumeLastUsed(logger lager.Logger, id string) (time.Time, error)
+	TouchVolume(logger lager.Logger, id string) error
 	WriteVolumeMeta(logger lager.Logger, id string, data base_image_puller.VolumeMeta) error
 
 	CreateImage(logger lager.Logger, spec image_cloner.ImageDriverSpec) (groot.MountInfo, error)
 	DestroyImage(logger lager.Logger, path string) error
 	FetchStats(logger lager.Logger, path string) (groot.VolumeStats, error)
+	FetchAllStats(logger lager.Logger) (map[string]groot.VolumeStats, error)
+	MountImage(logger lager.Logger, path string) error
+	UnmountImage(logger lager.Logger, path string) error
+	MountAllImages(logger lager.Logger) ([]string, error)
+	ImageMounted(logger lager.Logger, path string) (bool, error)
+	InspectImage(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error)
+	FlattenVolumes(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error)
 
 	Marshal(logger lager.Logger) ([]byte, error)
 }
@@ -207,6 +219,18 @@ func (d *Driver) MoveVolume(logger lager.Logger, from, to string) error {
 	return d.driver.MoveVolume(logger, from, to)
 }
 
+func (d *Driver) VolumeSize(logger lager.Logger, id string) (int64, error) {
+	return d.driver.VolumeSize(logger, id)
+}
+
+func (d *Driver) VolumeLastUsed(logger lager.Logger, id string) (time.Time, error) {
+	return d.driver.VolumeLastUsed(logger, id)
+}
+
+func (d *Driver) TouchVolume(logger lager.Logger, id string) error {
+	return d.driver.TouchVolume(logger, id)
+}
+
 func (d *Driver) WriteVolumeMeta(logger lager.Logger, id string, data base_image_puller.VolumeMeta) error {
 	return d.driver.WriteVolumeMeta(logger, id, data)
 }
@@ -215,6 +239,10 @@ func (d *Driver) HandleOpaqueWhiteouts(logger lager.Logger, id string, opaqueWhi
 	return d.driver.HandleOpaqueWhiteouts(logger, id, opaqueWhiteouts)
 }
 
+func (d *Driver) ClearProjectIDs(logger lager.Logger, path string) error {
+	return d.driver.ClearProjectIDs(logger, path)
+}
+
 func (d *Driver) CreateImage(logger lager.Logger, spec image_cloner.ImageDriverSpec) (groot.MountInfo, error) {
 	return d.driver.CreateImage(logger, spec)
 }
@@ -271,6 +299,34 @@ func (d *Driver) FetchStats(logger lager.Logger, path string) (groot.VolumeStats
 	return d.driver.FetchStats(logger, path)
 }
 
+func (d *Driver) FetchAllStats(logger lager.Logger) (map[string]groot.VolumeStats, error) {
+	return d.driver.FetchAllStats(logger)
+}
+
+func (d *Driver) MountImage(logger lager.Logger, path string) error {
+	return d.driver.MountImage(logger, path)
+}
+
+func (d *Driver) UnmountImage(logger lager.Logger, path string) error {
+	return d.driver.UnmountImage(logger, path)
+}
+
+func (d *Driver) MountAllImages(logger lager.Logger) ([]string, error) {
+	return d.driver.MountAllImages(logger)
+}
+
+func (d *Driver) ImageMounted(logger lager.Logger, path string) (bool, error) {
+	return d.driver.ImageMounted(logger, path)
+}
+
+func (d *Driver) InspectImage(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error) {
+	return d.driver.InspectImage(logger, path)
+}
+
+func (d *Driver) FlattenVolumes(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error) {
+	return d.driver.FlattenVolumes(logger, volumeIDs, maxDepth)
+}
+
 func specToDriver(spec spec.DriverSpec) (internalDriver, error) {
 	switch spec.Type {
 	case "overlay-xfs":
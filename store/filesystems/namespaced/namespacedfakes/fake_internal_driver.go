@@ -3,6 +3,7 @@ package namespacedfakes
 
 import (
 	"sync"
+	"time"
 
 	"code.cloudfoundry.org/grootfs/base_image_puller"
 	"code.cloudfoundry.org/grootfs/groot"
@@ -51,6 +52,18 @@ type FakeInternalDriver struct {
 	handleOpaqueWhiteoutsReturnsOnCall map[int]struct {
 		result1 error
 	}
+	ClearProjectIDsStub        func(logger lager.Logger, path string) error
+	clearProjectIDsMutex       sync.RWMutex
+	clearProjectIDsArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	clearProjectIDsReturns struct {
+		result1 error
+	}
+	clearProjectIDsReturnsOnCall map[int]struct {
+		result1 error
+	}
 	MoveVolumeStub        func(logger lager.Logger, from, to string) error
 	moveVolumeMutex       sync.RWMutex
 	moveVolumeArgsForCall []struct {
@@ -91,6 +104,46 @@ type FakeInternalDriver struct {
 		result1 []string
 		result2 error
 	}
+	VolumeSizeStub        func(logger lager.Logger, id string) (int64, error)
+	volumeSizeMutex       sync.RWMutex
+	volumeSizeArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	volumeSizeReturns struct {
+		result1 int64
+		result2 error
+	}
+	volumeSizeReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
+	VolumeLastUsedStub        func(logger lager.Logger, id string) (time.Time, error)
+	volumeLastUsedMutex       sync.RWMutex
+	volumeLastUsedArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	volumeLastUsedReturns struct {
+		result1 time.Time
+		result2 error
+	}
+	volumeLastUsedReturnsOnCall map[int]struct {
+		result1 time.Time
+		result2 error
+	}
+	TouchVolumeStub        func(logger lager.Logger, id string) error
+	touchVolumeMutex       sync.RWMutex
+	touchVolumeArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	touchVolumeReturns struct {
+		result1 error
+	}
+	touchVolumeReturnsOnCall map[int]struct {
+		result1 error
+	}
 	WriteVolumeMetaStub        func(logger lager.Logger, id string, data base_image_puller.VolumeMeta) error
 	writeVolumeMetaMutex       sync.RWMutex
 	writeVolumeMetaArgsForCall []struct {
@@ -144,6 +197,99 @@ type FakeInternalDriver struct {
 		result1 groot.VolumeStats
 		result2 error
 	}
+	FetchAllStatsStub        func(logger lager.Logger) (map[string]groot.VolumeStats, error)
+	fetchAllStatsMutex       sync.RWMutex
+	fetchAllStatsArgsForCall []struct {
+		logger lager.Logger
+	}
+	fetchAllStatsReturns struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}
+	fetchAllStatsReturnsOnCall map[int]struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}
+	MountImageStub        func(logger lager.Logger, path string) error
+	mountImageMutex       sync.RWMutex
+	mountImageArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	mountImageReturns struct {
+		result1 error
+	}
+	mountImageReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UnmountImageStub        func(logger lager.Logger, path string) error
+	unmountImageMutex       sync.RWMutex
+	unmountImageArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	unmountImageReturns struct {
+		result1 error
+	}
+	unmountImageReturnsOnCall map[int]struct {
+		result1 error
+	}
+	MountAllImagesStub        func(logger lager.Logger) ([]string, error)
+	mountAllImagesMutex       sync.RWMutex
+	mountAllImagesArgsForCall []struct {
+		logger lager.Logger
+	}
+	mountAllImagesReturns struct {
+		result1 []string
+		result2 error
+	}
+	mountAllImagesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	ImageMountedStub        func(logger lager.Logger, path string) (bool, error)
+	imageMountedMutex       sync.RWMutex
+	imageMountedArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	imageMountedReturns struct {
+		result1 bool
+		result2 error
+	}
+	imageMountedReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	InspectImageStub        func(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error)
+	inspectImageMutex       sync.RWMutex
+	inspectImageArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	inspectImageReturns struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}
+	inspectImageReturnsOnCall map[int]struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}
+	FlattenVolumesStub        func(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error)
+	flattenVolumesMutex       sync.RWMutex
+	flattenVolumesArgsForCall []struct {
+		logger    lager.Logger
+		volumeIDs []string
+		maxDepth  int
+	}
+	flattenVolumesReturns struct {
+		result1 []string
+		result2 error
+	}
+	flattenVolumesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	MarshalStub        func(logger lager.Logger) ([]byte, error)
 	marshalMutex       sync.RWMutex
 	marshalArgsForCall []struct {
@@ -318,6 +464,55 @@ func (fake *FakeInternalDriver) HandleOpaqueWhiteoutsReturnsOnCall(i int, result
 	}{result1}
 }
 
+func (fake *FakeInternalDriver) ClearProjectIDs(logger lager.Logger, path string) error {
+	fake.clearProjectIDsMutex.Lock()
+	ret, specificReturn := fake.clearProjectIDsReturnsOnCall[len(fake.clearProjectIDsArgsForCall)]
+	fake.clearProjectIDsArgsForCall = append(fake.clearProjectIDsArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("ClearProjectIDs", []interface{}{logger, path})
+	fake.clearProjectIDsMutex.Unlock()
+	if fake.ClearProjectIDsStub != nil {
+		return fake.ClearProjectIDsStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.clearProjectIDsReturns.result1
+}
+
+func (fake *FakeInternalDriver) ClearProjectIDsCallCount() int {
+	fake.clearProjectIDsMutex.RLock()
+	defer fake.clearProjectIDsMutex.RUnlock()
+	return len(fake.clearProjectIDsArgsForCall)
+}
+
+func (fake *FakeInternalDriver) ClearProjectIDsArgsForCall(i int) (lager.Logger, string) {
+	fake.clearProjectIDsMutex.RLock()
+	defer fake.clearProjectIDsMutex.RUnlock()
+	return fake.clearProjectIDsArgsForCall[i].logger, fake.clearProjectIDsArgsForCall[i].path
+}
+
+func (fake *FakeInternalDriver) ClearProjectIDsReturns(result1 error) {
+	fake.ClearProjectIDsStub = nil
+	fake.clearProjectIDsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeInternalDriver) ClearProjectIDsReturnsOnCall(i int, result1 error) {
+	fake.ClearProjectIDsStub = nil
+	if fake.clearProjectIDsReturnsOnCall == nil {
+		fake.clearProjectIDsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.clearProjectIDsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeInternalDriver) MoveVolume(logger lager.Logger, from string, to string) error {
 	fake.moveVolumeMutex.Lock()
 	ret, specificReturn := fake.moveVolumeReturnsOnCall[len(fake.moveVolumeArgsForCall)]
@@ -471,6 +666,159 @@ func (fake *FakeInternalDriver) VolumesReturnsOnCall(i int, result1 []string, re
 	}{result1, result2}
 }
 
+func (fake *FakeInternalDriver) VolumeSize(logger lager.Logger, id string) (int64, error) {
+	fake.volumeSizeMutex.Lock()
+	ret, specificReturn := fake.volumeSizeReturnsOnCall[len(fake.volumeSizeArgsForCall)]
+	fake.volumeSizeArgsForCall = append(fake.volumeSizeArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("VolumeSize", []interface{}{logger, id})
+	fake.volumeSizeMutex.Unlock()
+	if fake.VolumeSizeStub != nil {
+		return fake.VolumeSizeStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.volumeSizeReturns.result1, fake.volumeSizeReturns.result2
+}
+
+func (fake *FakeInternalDriver) VolumeSizeCallCount() int {
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	return len(fake.volumeSizeArgsForCall)
+}
+
+func (fake *FakeInternalDriver) VolumeSizeArgsForCall(i int) (lager.Logger, string) {
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	return fake.volumeSizeArgsForCall[i].logger, fake.volumeSizeArgsForCall[i].id
+}
+
+func (fake *FakeInternalDriver) VolumeSizeReturns(result1 int64, result2 error) {
+	fake.VolumeSizeStub = nil
+	fake.volumeSizeReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) VolumeSizeReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.VolumeSizeStub = nil
+	if fake.volumeSizeReturnsOnCall == nil {
+		fake.volumeSizeReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.volumeSizeReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) VolumeLastUsed(logger lager.Logger, id string) (time.Time, error) {
+	fake.volumeLastUsedMutex.Lock()
+	ret, specificReturn := fake.volumeLastUsedReturnsOnCall[len(fake.volumeLastUsedArgsForCall)]
+	fake.volumeLastUsedArgsForCall = append(fake.volumeLastUsedArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("VolumeLastUsed", []interface{}{logger, id})
+	fake.volumeLastUsedMutex.Unlock()
+	if fake.VolumeLastUsedStub != nil {
+		return fake.VolumeLastUsedStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.volumeLastUsedReturns.result1, fake.volumeLastUsedReturns.result2
+}
+
+func (fake *FakeInternalDriver) VolumeLastUsedCallCount() int {
+	fake.volumeLastUsedMutex.RLock()
+	defer fake.volumeLastUsedMutex.RUnlock()
+	return len(fake.volumeLastUsedArgsForCall)
+}
+
+func (fake *FakeInternalDriver) VolumeLastUsedArgsForCall(i int) (lager.Logger, string) {
+	fake.volumeLastUsedMutex.RLock()
+	defer fake.volumeLastUsedMutex.RUnlock()
+	return fake.volumeLastUsedArgsForCall[i].logger, fake.volumeLastUsedArgsForCall[i].id
+}
+
+func (fake *FakeInternalDriver) VolumeLastUsedReturns(result1 time.Time, result2 error) {
+	fake.VolumeLastUsedStub = nil
+	fake.volumeLastUsedReturns = struct {
+		result1 time.Time
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) VolumeLastUsedReturnsOnCall(i int, result1 time.Time, result2 error) {
+	fake.VolumeLastUsedStub = nil
+	if fake.volumeLastUsedReturnsOnCall == nil {
+		fake.volumeLastUsedReturnsOnCall = make(map[int]struct {
+			result1 time.Time
+			result2 error
+		})
+	}
+	fake.volumeLastUsedReturnsOnCall[i] = struct {
+		result1 time.Time
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) TouchVolume(logger lager.Logger, id string) error {
+	fake.touchVolumeMutex.Lock()
+	ret, specificReturn := fake.touchVolumeReturnsOnCall[len(fake.touchVolumeArgsForCall)]
+	fake.touchVolumeArgsForCall = append(fake.touchVolumeArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("TouchVolume", []interface{}{logger, id})
+	fake.touchVolumeMutex.Unlock()
+	if fake.TouchVolumeStub != nil {
+		return fake.TouchVolumeStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.touchVolumeReturns.result1
+}
+
+func (fake *FakeInternalDriver) TouchVolumeCallCount() int {
+	fake.touchVolumeMutex.RLock()
+	defer fake.touchVolumeMutex.RUnlock()
+	return len(fake.touchVolumeArgsForCall)
+}
+
+func (fake *FakeInternalDriver) TouchVolumeArgsForCall(i int) (lager.Logger, string) {
+	fake.touchVolumeMutex.RLock()
+	defer fake.touchVolumeMutex.RUnlock()
+	return fake.touchVolumeArgsForCall[i].logger, fake.touchVolumeArgsForCall[i].id
+}
+
+func (fake *FakeInternalDriver) TouchVolumeReturns(result1 error) {
+	fake.TouchVolumeStub = nil
+	fake.touchVolumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeInternalDriver) TouchVolumeReturnsOnCall(i int, result1 error) {
+	fake.TouchVolumeStub = nil
+	if fake.touchVolumeReturnsOnCall == nil {
+		fake.touchVolumeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.touchVolumeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeInternalDriver) WriteVolumeMeta(logger lager.Logger, id string, data base_image_puller.VolumeMeta) error {
 	fake.writeVolumeMetaMutex.Lock()
 	ret, specificReturn := fake.writeVolumeMetaReturnsOnCall[len(fake.writeVolumeMetaArgsForCall)]
@@ -674,6 +1022,368 @@ func (fake *FakeInternalDriver) FetchStatsReturnsOnCall(i int, result1 groot.Vol
 	}{result1, result2}
 }
 
+func (fake *FakeInternalDriver) FetchAllStats(logger lager.Logger) (map[string]groot.VolumeStats, error) {
+	fake.fetchAllStatsMutex.Lock()
+	ret, specificReturn := fake.fetchAllStatsReturnsOnCall[len(fake.fetchAllStatsArgsForCall)]
+	fake.fetchAllStatsArgsForCall = append(fake.fetchAllStatsArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("FetchAllStats", []interface{}{logger})
+	fake.fetchAllStatsMutex.Unlock()
+	if fake.FetchAllStatsStub != nil {
+		return fake.FetchAllStatsStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.fetchAllStatsReturns.result1, fake.fetchAllStatsReturns.result2
+}
+
+func (fake *FakeInternalDriver) FetchAllStatsCallCount() int {
+	fake.fetchAllStatsMutex.RLock()
+	defer fake.fetchAllStatsMutex.RUnlock()
+	return len(fake.fetchAllStatsArgsForCall)
+}
+
+func (fake *FakeInternalDriver) FetchAllStatsArgsForCall(i int) lager.Logger {
+	fake.fetchAllStatsMutex.RLock()
+	defer fake.fetchAllStatsMutex.RUnlock()
+	return fake.fetchAllStatsArgsForCall[i].logger
+}
+
+func (fake *FakeInternalDriver) FetchAllStatsReturns(result1 map[string]groot.VolumeStats, result2 error) {
+	fake.FetchAllStatsStub = nil
+	fake.fetchAllStatsReturns = struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) FetchAllStatsReturnsOnCall(i int, result1 map[string]groot.VolumeStats, result2 error) {
+	fake.FetchAllStatsStub = nil
+	if fake.fetchAllStatsReturnsOnCall == nil {
+		fake.fetchAllStatsReturnsOnCall = make(map[int]struct {
+			result1 map[string]groot.VolumeStats
+			result2 error
+		})
+	}
+	fake.fetchAllStatsReturnsOnCall[i] = struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) MountImage(logger lager.Logger, path string) error {
+	fake.mountImageMutex.Lock()
+	ret, specificReturn := fake.mountImageReturnsOnCall[len(fake.mountImageArgsForCall)]
+	fake.mountImageArgsForCall = append(fake.mountImageArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("MountImage", []interface{}{logger, path})
+	fake.mountImageMutex.Unlock()
+	if fake.MountImageStub != nil {
+		return fake.MountImageStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.mountImageReturns.result1
+}
+
+func (fake *FakeInternalDriver) MountImageCallCount() int {
+	fake.mountImageMutex.RLock()
+	defer fake.mountImageMutex.RUnlock()
+	return len(fake.mountImageArgsForCall)
+}
+
+func (fake *FakeInternalDriver) MountImageArgsForCall(i int) (lager.Logger, string) {
+	fake.mountImageMutex.RLock()
+	defer fake.mountImageMutex.RUnlock()
+	return fake.mountImageArgsForCall[i].logger, fake.mountImageArgsForCall[i].path
+}
+
+func (fake *FakeInternalDriver) MountImageReturns(result1 error) {
+	fake.MountImageStub = nil
+	fake.mountImageReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeInternalDriver) MountImageReturnsOnCall(i int, result1 error) {
+	fake.MountImageStub = nil
+	if fake.mountImageReturnsOnCall == nil {
+		fake.mountImageReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.mountImageReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeInternalDriver) UnmountImage(logger lager.Logger, path string) error {
+	fake.unmountImageMutex.Lock()
+	ret, specificReturn := fake.unmountImageReturnsOnCall[len(fake.unmountImageArgsForCall)]
+	fake.unmountImageArgsForCall = append(fake.unmountImageArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("UnmountImage", []interface{}{logger, path})
+	fake.unmountImageMutex.Unlock()
+	if fake.UnmountImageStub != nil {
+		return fake.UnmountImageStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.unmountImageReturns.result1
+}
+
+func (fake *FakeInternalDriver) UnmountImageCallCount() int {
+	fake.unmountImageMutex.RLock()
+	defer fake.unmountImageMutex.RUnlock()
+	return len(fake.unmountImageArgsForCall)
+}
+
+func (fake *FakeInternalDriver) UnmountImageArgsForCall(i int) (lager.Logger, string) {
+	fake.unmountImageMutex.RLock()
+	defer fake.unmountImageMutex.RUnlock()
+	return fake.unmountImageArgsForCall[i].logger, fake.unmountImageArgsForCall[i].path
+}
+
+func (fake *FakeInternalDriver) UnmountImageReturns(result1 error) {
+	fake.UnmountImageStub = nil
+	fake.unmountImageReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeInternalDriver) UnmountImageReturnsOnCall(i int, result1 error) {
+	fake.UnmountImageStub = nil
+	if fake.unmountImageReturnsOnCall == nil {
+		fake.unmountImageReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.unmountImageReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeInternalDriver) MountAllImages(logger lager.Logger) ([]string, error) {
+	fake.mountAllImagesMutex.Lock()
+	ret, specificReturn := fake.mountAllImagesReturnsOnCall[len(fake.mountAllImagesArgsForCall)]
+	fake.mountAllImagesArgsForCall = append(fake.mountAllImagesArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("MountAllImages", []interface{}{logger})
+	fake.mountAllImagesMutex.Unlock()
+	if fake.MountAllImagesStub != nil {
+		return fake.MountAllImagesStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.mountAllImagesReturns.result1, fake.mountAllImagesReturns.result2
+}
+
+func (fake *FakeInternalDriver) MountAllImagesCallCount() int {
+	fake.mountAllImagesMutex.RLock()
+	defer fake.mountAllImagesMutex.RUnlock()
+	return len(fake.mountAllImagesArgsForCall)
+}
+
+func (fake *FakeInternalDriver) MountAllImagesArgsForCall(i int) lager.Logger {
+	fake.mountAllImagesMutex.RLock()
+	defer fake.mountAllImagesMutex.RUnlock()
+	return fake.mountAllImagesArgsForCall[i].logger
+}
+
+func (fake *FakeInternalDriver) MountAllImagesReturns(result1 []string, result2 error) {
+	fake.MountAllImagesStub = nil
+	fake.mountAllImagesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) MountAllImagesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.MountAllImagesStub = nil
+	if fake.mountAllImagesReturnsOnCall == nil {
+		fake.mountAllImagesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.mountAllImagesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) ImageMounted(logger lager.Logger, path string) (bool, error) {
+	fake.imageMountedMutex.Lock()
+	ret, specificReturn := fake.imageMountedReturnsOnCall[len(fake.imageMountedArgsForCall)]
+	fake.imageMountedArgsForCall = append(fake.imageMountedArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("ImageMounted", []interface{}{logger, path})
+	fake.imageMountedMutex.Unlock()
+	if fake.ImageMountedStub != nil {
+		return fake.ImageMountedStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.imageMountedReturns.result1, fake.imageMountedReturns.result2
+}
+
+func (fake *FakeInternalDriver) ImageMountedCallCount() int {
+	fake.imageMountedMutex.RLock()
+	defer fake.imageMountedMutex.RUnlock()
+	return len(fake.imageMountedArgsForCall)
+}
+
+func (fake *FakeInternalDriver) ImageMountedArgsForCall(i int) (lager.Logger, string) {
+	fake.imageMountedMutex.RLock()
+	defer fake.imageMountedMutex.RUnlock()
+	return fake.imageMountedArgsForCall[i].logger, fake.imageMountedArgsForCall[i].path
+}
+
+func (fake *FakeInternalDriver) ImageMountedReturns(result1 bool, result2 error) {
+	fake.ImageMountedStub = nil
+	fake.imageMountedReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) ImageMountedReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.ImageMountedStub = nil
+	if fake.imageMountedReturnsOnCall == nil {
+		fake.imageMountedReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.imageMountedReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) InspectImage(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error) {
+	fake.inspectImageMutex.Lock()
+	ret, specificReturn := fake.inspectImageReturnsOnCall[len(fake.inspectImageArgsForCall)]
+	fake.inspectImageArgsForCall = append(fake.inspectImageArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("InspectImage", []interface{}{logger, path})
+	fake.inspectImageMutex.Unlock()
+	if fake.InspectImageStub != nil {
+		return fake.InspectImageStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.inspectImageReturns.result1, fake.inspectImageReturns.result2
+}
+
+func (fake *FakeInternalDriver) InspectImageCallCount() int {
+	fake.inspectImageMutex.RLock()
+	defer fake.inspectImageMutex.RUnlock()
+	return len(fake.inspectImageArgsForCall)
+}
+
+func (fake *FakeInternalDriver) InspectImageArgsForCall(i int) (lager.Logger, string) {
+	fake.inspectImageMutex.RLock()
+	defer fake.inspectImageMutex.RUnlock()
+	return fake.inspectImageArgsForCall[i].logger, fake.inspectImageArgsForCall[i].path
+}
+
+func (fake *FakeInternalDriver) InspectImageReturns(result1 groot.ImageFilesystemDetails, result2 error) {
+	fake.InspectImageStub = nil
+	fake.inspectImageReturns = struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) InspectImageReturnsOnCall(i int, result1 groot.ImageFilesystemDetails, result2 error) {
+	fake.InspectImageStub = nil
+	if fake.inspectImageReturnsOnCall == nil {
+		fake.inspectImageReturnsOnCall = make(map[int]struct {
+			result1 groot.ImageFilesystemDetails
+			result2 error
+		})
+	}
+	fake.inspectImageReturnsOnCall[i] = struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) FlattenVolumes(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error) {
+	var volumeIDsCopy []string
+	if volumeIDs != nil {
+		volumeIDsCopy = make([]string, len(volumeIDs))
+		copy(volumeIDsCopy, volumeIDs)
+	}
+	fake.flattenVolumesMutex.Lock()
+	ret, specificReturn := fake.flattenVolumesReturnsOnCall[len(fake.flattenVolumesArgsForCall)]
+	fake.flattenVolumesArgsForCall = append(fake.flattenVolumesArgsForCall, struct {
+		logger    lager.Logger
+		volumeIDs []string
+		maxDepth  int
+	}{logger, volumeIDsCopy, maxDepth})
+	fake.recordInvocation("FlattenVolumes", []interface{}{logger, volumeIDsCopy, maxDepth})
+	fake.flattenVolumesMutex.Unlock()
+	if fake.FlattenVolumesStub != nil {
+		return fake.FlattenVolumesStub(logger, volumeIDs, maxDepth)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.flattenVolumesReturns.result1, fake.flattenVolumesReturns.result2
+}
+
+func (fake *FakeInternalDriver) FlattenVolumesCallCount() int {
+	fake.flattenVolumesMutex.RLock()
+	defer fake.flattenVolumesMutex.RUnlock()
+	return len(fake.flattenVolumesArgsForCall)
+}
+
+func (fake *FakeInternalDriver) FlattenVolumesArgsForCall(i int) (lager.Logger, []string, int) {
+	fake.flattenVolumesMutex.RLock()
+	defer fake.flattenVolumesMutex.RUnlock()
+	return fake.flattenVolumesArgsForCall[i].logger, fake.flattenVolumesArgsForCall[i].volumeIDs, fake.flattenVolumesArgsForCall[i].maxDepth
+}
+
+func (fake *FakeInternalDriver) FlattenVolumesReturns(result1 []string, result2 error) {
+	fake.FlattenVolumesStub = nil
+	fake.flattenVolumesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInternalDriver) FlattenVolumesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.FlattenVolumesStub = nil
+	if fake.flattenVolumesReturnsOnCall == nil {
+		fake.flattenVolumesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.flattenVolumesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeInternalDriver) Marshal(logger lager.Logger) ([]byte, error) {
 	fake.marshalMutex.Lock()
 	ret, specificReturn := fake.marshalReturnsOnCall[len(fake.marshalArgsForCall)]
@@ -734,12 +1444,20 @@ func (fake *FakeInternalDriver) Invocations() map[string][][]interface{} {
 	defer fake.destroyVolumeMutex.RUnlock()
 	fake.handleOpaqueWhiteoutsMutex.RLock()
 	defer fake.handleOpaqueWhiteoutsMutex.RUnlock()
+	fake.clearProjectIDsMutex.RLock()
+	defer fake.clearProjectIDsMutex.RUnlock()
 	fake.moveVolumeMutex.RLock()
 	defer fake.moveVolumeMutex.RUnlock()
 	fake.volumePathMutex.RLock()
 	defer fake.volumePathMutex.RUnlock()
 	fake.volumesMutex.RLock()
 	defer fake.volumesMutex.RUnlock()
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	fake.volumeLastUsedMutex.RLock()
+	defer fake.volumeLastUsedMutex.RUnlock()
+	fake.touchVolumeMutex.RLock()
+	defer fake.touchVolumeMutex.RUnlock()
 	fake.writeVolumeMetaMutex.RLock()
 	defer fake.writeVolumeMetaMutex.RUnlock()
 	fake.createImageMutex.RLock()
@@ -748,6 +1466,20 @@ func (fake *FakeInternalDriver) Invocations() map[string][][]interface{} {
 	defer fake.destroyImageMutex.RUnlock()
 	fake.fetchStatsMutex.RLock()
 	defer fake.fetchStatsMutex.RUnlock()
+	fake.fetchAllStatsMutex.RLock()
+	defer fake.fetchAllStatsMutex.RUnlock()
+	fake.mountImageMutex.RLock()
+	defer fake.mountImageMutex.RUnlock()
+	fake.unmountImageMutex.RLock()
+	defer fake.unmountImageMutex.RUnlock()
+	fake.mountAllImagesMutex.RLock()
+	defer fake.mountAllImagesMutex.RUnlock()
+	fake.imageMountedMutex.RLock()
+	defer fake.imageMountedMutex.RUnlock()
+	fake.inspectImageMutex.RLock()
+	defer fake.inspectImageMutex.RUnlock()
+	fake.flattenVolumesMutex.RLock()
+	defer fake.flattenVolumesMutex.RUnlock()
 	fake.marshalMutex.RLock()
 	defer fake.marshalMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path"
@@ -13,12 +14,15 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"code.cloudfoundry.org/grootfs/base_image_puller"
 	"code.cloudfoundry.org/grootfs/groot"
 	"code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/grootfs/store/dependency_manager"
 	"code.cloudfoundry.org/grootfs/store/filesystems"
 	quotapkg "code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/quota"
+	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/tardis/ids"
 	"code.cloudfoundry.org/grootfs/store/filesystems/spec"
 	"code.cloudfoundry.org/grootfs/store/image_cloner"
 	"code.cloudfoundry.org/lager"
@@ -32,12 +36,16 @@ const (
 	IDDir             = "projectids"
 	WorkDir           = "workdir"
 	RootfsDir         = "rootfs"
+	EphemeralDir      = "ephemeral"
 	imageInfoName     = "image_info"
+	imageVolumesName  = "image_volumes"
 	imageQuotaName    = "image_quota"
 	WhiteoutDevice    = "whiteout_dev"
 	LinksDirName      = "l"
 	maxDestroyRetries = 5
 	MinQuota          = 1024 * 256
+
+	FlattenedVolumePrefix = "flat-"
 )
 
 func NewDriver(storePath, tardisBinPath string) *Driver {
@@ -130,6 +138,54 @@ func (d *Driver) ValidateFileSystem(logger lager.Logger, path string) error {
 	return nil
 }
 
+// CheckOverlayMountOptions mounts a throwaway overlay in the store with the
+// given extra options, to find out whether the kernel supports them.
+func (d *Driver) CheckOverlayMountOptions(logger lager.Logger, options []string) error {
+	logger = logger.Session("overlayxfs-checking-overlay-mount-options", lager.Data{"options": options})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	if len(options) == 0 {
+		return nil
+	}
+
+	tempDir := filepath.Join(d.storePath, store.TempDirName)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return errorspkg.Wrap(err, "creating store temp folder")
+	}
+
+	checkDir, err := ioutil.TempDir(tempDir, "overlay-mount-options-")
+	if err != nil {
+		return errorspkg.Wrap(err, "creating overlay check folder")
+	}
+	defer func() {
+		if err := os.RemoveAll(checkDir); err != nil {
+			logger.Error("removing-overlay-check-folder-failed", err)
+		}
+	}()
+
+	directories := map[string]string{}
+	for _, name := range []string{"lower", UpperDir, WorkDir, RootfsDir} {
+		directories[name] = filepath.Join(checkDir, name)
+	}
+	if err := d.createImageDirectories(logger, directories); err != nil {
+		return err
+	}
+
+	mountData := d.formatMountData([]string{directories["lower"]}, directories[WorkDir], directories[UpperDir], options, false)
+	if err := syscall.Mount("overlay", directories[RootfsDir], "overlay", 0, mountData); err != nil {
+		logger.Error("mounting-overlay-failed", err, lager.Data{"mountData": mountData})
+		return errorspkg.Wrapf(err, "overlay mount options %s are not supported by the kernel", strings.Join(options, ","))
+	}
+
+	if err := syscall.Unmount(directories[RootfsDir], 0); err != nil {
+		logger.Error("unmounting-overlay-failed", err)
+		return errorspkg.Wrap(err, "unmounting overlay check")
+	}
+
+	return nil
+}
+
 func (d *Driver) VolumePath(logger lager.Logger, id string) (string, error) {
 	volPath := filepath.Join(d.storePath, store.VolumesDirName, id)
 	_, err := os.Stat(volPath)
@@ -249,21 +305,38 @@ func (d *Driver) CreateImage(logger lager.Logger, spec image_cloner.ImageDriverS
 		return groot.MountInfo{}, errorspkg.Wrap(err, "generating lowerdir paths failed")
 	}
 
-	if err := d.applyDiskLimit(logger, spec, baseVolumeSize); err != nil {
-		return groot.MountInfo{}, errorspkg.Wrap(err, "applying disk limits")
+	if spec.ReadOnly {
+		return d.createReadOnlyImage(logger, spec, baseVolumePaths, baseVolumeSize)
 	}
 
-	upperDir := filepath.Join(spec.ImagePath, UpperDir)
-	workDir := filepath.Join(spec.ImagePath, WorkDir)
+	volumes := imageVolumes{
+		BaseVolumeIDs:       spec.BaseVolumeIDs,
+		Mount:               spec.Mount,
+		EphemeralSizeBytes:  spec.EphemeralSizeBytes,
+		OverlayMountOptions: spec.OverlayMountOptions,
+	}
+	upperDir, workDir := imageLayerDirs(spec.ImagePath, volumes)
 	rootfsDir := filepath.Join(spec.ImagePath, RootfsDir)
 
-	directories := map[string]string{
-		"upperdir": upperDir,
-		"workdir":  workDir,
-		"rootfs":   rootfsDir,
+	if volumes.EphemeralSizeBytes > 0 {
+		if err := d.mountEphemeralDir(logger, spec.ImagePath, volumes.EphemeralSizeBytes); err != nil {
+			return groot.MountInfo{}, err
+		}
+	} else {
+		if err := d.applyDiskLimit(logger, spec, baseVolumeSize); err != nil {
+			return groot.MountInfo{}, errorspkg.Wrap(err, "applying disk limits")
+		}
+
+		directories := map[string]string{
+			"upperdir": upperDir,
+			"workdir":  workDir,
+		}
+		if err := d.createImageDirectories(logger, directories); err != nil {
+			return groot.MountInfo{}, err
+		}
 	}
 
-	if err := d.createImageDirectories(logger, directories); err != nil {
+	if err := d.createImageDirectories(logger, map[string]string{"rootfs": rootfsDir}); err != nil {
 		return groot.MountInfo{}, err
 	}
 
@@ -272,7 +345,7 @@ func (d *Driver) CreateImage(logger lager.Logger, spec image_cloner.ImageDriverS
 	}
 
 	if spec.Mount {
-		mountData := d.formatMountData(baseVolumePaths, workDir, upperDir, false)
+		mountData := d.formatMountData(baseVolumePaths, workDir, upperDir, volumes.OverlayMountOptions, false)
 		if err := d.mountImage(logger, rootfsDir, mountData); err != nil {
 			return groot.MountInfo{}, err
 		}
@@ -283,14 +356,540 @@ func (d *Driver) CreateImage(logger lager.Logger, spec image_cloner.ImageDriverS
 		return groot.MountInfo{}, errorspkg.Wrapf(err, "writing image info %s", imageInfoFileName)
 	}
 
+	if err := d.writeImageVolumes(spec.ImagePath, volumes); err != nil {
+		return groot.MountInfo{}, err
+	}
+
 	return groot.MountInfo{
 		Destination: "/",
 		Source:      "overlay",
 		Type:        "overlay",
-		Options:     []string{d.formatMountData(baseVolumePaths, workDir, upperDir, true)},
+		Options:     []string{d.formatMountData(baseVolumePaths, workDir, upperDir, volumes.OverlayMountOptions, true)},
 	}, nil
 }
 
+// createReadOnlyImage creates an image without upper and work directories, and
+// without a quota. Its rootfs is a read-only overlay of the base volumes, or a
+// read-only bind mount of the base volume when there is only one.
+func (d *Driver) createReadOnlyImage(logger lager.Logger, spec image_cloner.ImageDriverSpec, baseVolumePaths []string, baseVolumeSize int64) (groot.MountInfo, error) {
+	if len(baseVolumePaths) == 0 {
+		return groot.MountInfo{}, errorspkg.New("a read-only image needs at least one base volume")
+	}
+
+	rootfsDir := filepath.Join(spec.ImagePath, RootfsDir)
+	if err := d.createImageDirectories(logger, map[string]string{"rootfs": rootfsDir}); err != nil {
+		return groot.MountInfo{}, err
+	}
+
+	if spec.Mount {
+		if err := d.mountReadOnlyImage(logger, rootfsDir, baseVolumePaths); err != nil {
+			return groot.MountInfo{}, err
+		}
+	}
+
+	imageInfoFileName := filepath.Join(spec.ImagePath, imageInfoName)
+	if err := ioutil.WriteFile(imageInfoFileName, []byte(strconv.FormatInt(baseVolumeSize, 10)), 0600); err != nil {
+		return groot.MountInfo{}, errorspkg.Wrapf(err, "writing image info %s", imageInfoFileName)
+	}
+
+	if err := d.writeImageVolumes(spec.ImagePath, imageVolumes{BaseVolumeIDs: spec.BaseVolumeIDs, Mount: spec.Mount, ReadOnly: true}); err != nil {
+		return groot.MountInfo{}, err
+	}
+
+	return d.readOnlyMountInfo(baseVolumePaths), nil
+}
+
+func (d *Driver) mountReadOnlyImage(logger lager.Logger, rootfsDir string, lowerDirs []string) error {
+	logger = logger.Session("mounting-read-only-rootfs", lager.Data{"lowerDirs": lowerDirs, "rootfsDir": rootfsDir})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	if err := os.Chdir(d.storePath); err != nil {
+		return errorspkg.Wrap(err, "failed to change directory to the store path")
+	}
+
+	// Overlay needs at least two lower directories without an upper one.
+	if len(lowerDirs) > 1 {
+		mountData := fmt.Sprintf("lowerdir=%s", strings.Join(lowerDirs, ":"))
+		if err := syscall.Mount("overlay", rootfsDir, "overlay", syscall.MS_RDONLY, mountData); err != nil {
+			logger.Error("mounting-overlay-failed", err, lager.Data{"mountData": mountData})
+			return errorspkg.Wrap(err, "mounting read-only overlay")
+		}
+		return nil
+	}
+
+	if err := syscall.Mount(filepath.Join(d.storePath, lowerDirs[0]), rootfsDir, "", syscall.MS_BIND, ""); err != nil {
+		logger.Error("bind-mounting-volume-failed", err)
+		return errorspkg.Wrap(err, "bind mounting base volume")
+	}
+
+	if err := syscall.Mount("", rootfsDir, "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+		logger.Error("remounting-read-only-failed", err)
+		if err := syscall.Unmount(rootfsDir, 0); err != nil {
+			logger.Error("unmounting-rootfs-failed", err)
+		}
+		return errorspkg.Wrap(err, "remounting base volume read-only")
+	}
+
+	return nil
+}
+
+// readOnlyMountInfo describes the rootfs mount of a read-only image with the
+// given lower directories, relative to the store.
+func (d *Driver) readOnlyMountInfo(lowerDirs []string) groot.MountInfo {
+	absoluteLowerDirs := []string{}
+	for _, lowerDir := range lowerDirs {
+		absoluteLowerDirs = append(absoluteLowerDirs, filepath.Join(d.storePath, lowerDir))
+	}
+
+	if len(absoluteLowerDirs) == 1 {
+		return groot.MountInfo{
+			Destination: "/",
+			Source:      absoluteLowerDirs[0],
+			Type:        "bind",
+			Options:     []string{"bind", "ro"},
+		}
+	}
+
+	return groot.MountInfo{
+		Destination: "/",
+		Source:      "overlay",
+		Type:        "overlay",
+		Options:     []string{"lowerdir=" + strings.Join(absoluteLowerDirs, ":"), "ro"},
+	}
+}
+
+// mountEphemeralDir mounts the tmpfs holding the upper and work directories of
+// an ephemeral image, unless it is already mounted. Its size is the disk limit
+// of the image, and its contents are lost when it is unmounted.
+func (d *Driver) mountEphemeralDir(logger lager.Logger, imagePath string, sizeBytes int64) error {
+	logger = logger.Session("mounting-ephemeral-dir", lager.Data{"imagePath": imagePath, "sizeBytes": sizeBytes})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	if os.Geteuid() != 0 {
+		return errorspkg.New("ephemeral images require root privileges")
+	}
+
+	ephemeralDir := filepath.Join(imagePath, EphemeralDir)
+	if err := os.Mkdir(ephemeralDir, 0755); err != nil && !os.IsExist(err) {
+		logger.Error("creating-ephemeral-folder-failed", err)
+		return errorspkg.Wrap(err, "creating ephemeral folder")
+	}
+
+	mounted, err := isMountpoint(ephemeralDir)
+	if err != nil {
+		return errorspkg.Wrap(err, "checking if the ephemeral folder is mounted")
+	}
+	if mounted {
+		return nil
+	}
+
+	mountData := fmt.Sprintf("size=%d,mode=0755", sizeBytes)
+	if err := syscall.Mount("tmpfs", ephemeralDir, "tmpfs", 0, mountData); err != nil {
+		logger.Error("mounting-tmpfs-failed", err, lager.Data{"mountData": mountData})
+		return errorspkg.Wrap(err, "mounting ephemeral tmpfs")
+	}
+
+	upperDir, workDir := imageLayerDirs(imagePath, imageVolumes{EphemeralSizeBytes: sizeBytes})
+	return d.createImageDirectories(logger, map[string]string{
+		"upperdir": upperDir,
+		"workdir":  workDir,
+	})
+}
+
+// imageLayerDirs returns the upper and work directories of an image, which
+// ephemeral images keep on their tmpfs.
+func imageLayerDirs(imagePath string, volumes imageVolumes) (string, string) {
+	layersDir := imagePath
+	if volumes.EphemeralSizeBytes > 0 {
+		layersDir = filepath.Join(imagePath, EphemeralDir)
+	}
+
+	return filepath.Join(layersDir, UpperDir), filepath.Join(layersDir, WorkDir)
+}
+
+func (d *Driver) MountImage(logger lager.Logger, imagePath string) error {
+	logger = logger.Session("overlayxfs-mounting-image", lager.Data{"imagePath": imagePath})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	volumes, err := d.readImageVolumes(imagePath)
+	if err != nil {
+		logger.Error("reading-image-volumes-failed", err)
+		return err
+	}
+
+	rootfsDir := filepath.Join(imagePath, RootfsDir)
+	mounted, err := isMountpoint(rootfsDir)
+	if err != nil {
+		logger.Error("checking-rootfs-mountpoint-failed", err)
+		return errorspkg.Wrap(err, "checking if rootfs is mounted")
+	}
+
+	if !mounted {
+		baseVolumePaths, _, err := d.getLowerDirs(logger, volumes.BaseVolumeIDs)
+		if err != nil {
+			logger.Error("generating-lowerdir-paths-failed", err)
+			return errorspkg.Wrap(err, "generating lowerdir paths failed")
+		}
+
+		if volumes.ReadOnly {
+			if err := d.mountReadOnlyImage(logger, rootfsDir, baseVolumePaths); err != nil {
+				return err
+			}
+		} else {
+			// The tmpfs of ephemeral images doesn't survive reboots, they
+			// start over with empty upper directories.
+			if volumes.EphemeralSizeBytes > 0 {
+				if err := d.mountEphemeralDir(logger, imagePath, volumes.EphemeralSizeBytes); err != nil {
+					return err
+				}
+			}
+
+			if err := os.Chdir(d.storePath); err != nil {
+				return errorspkg.Wrap(err, "failed to change directory to the store path")
+			}
+
+			upperDir, workDir := imageLayerDirs(imagePath, volumes)
+
+			// A volatile overlay marks its work directory as unusable when
+			// unmounted, so it's mounted again with an empty one.
+			if containsOption(volumes.OverlayMountOptions, "volatile") {
+				if err := clearDir(workDir); err != nil {
+					logger.Error("clearing-work-dir-failed", err)
+					return errorspkg.Wrap(err, "clearing the work directory of a volatile image")
+				}
+			}
+
+			mountData := d.formatMountData(baseVolumePaths, workDir, upperDir, volumes.OverlayMountOptions, false)
+			if err := d.mountImage(logger, rootfsDir, mountData); err != nil {
+				return err
+			}
+		}
+	}
+
+	volumes.Mount = true
+	return d.writeImageVolumes(imagePath, volumes)
+}
+
+func (d *Driver) UnmountImage(logger lager.Logger, imagePath string) error {
+	logger = logger.Session("overlayxfs-unmounting-image", lager.Data{"imagePath": imagePath})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	volumes, err := d.readImageVolumes(imagePath)
+	if err != nil {
+		logger.Error("reading-image-volumes-failed", err)
+		return err
+	}
+
+	rootfsDir := filepath.Join(imagePath, RootfsDir)
+	if err := syscall.Unmount(rootfsDir, 0); err != nil && err != syscall.EINVAL {
+		logger.Error("unmounting-rootfs-failed", err)
+		return errorspkg.Wrap(err, "unmounting rootfs")
+	}
+
+	volumes.Mount = false
+	return d.writeImageVolumes(imagePath, volumes)
+}
+
+func (d *Driver) ImageMounted(logger lager.Logger, imagePath string) (bool, error) {
+	return isMountpoint(filepath.Join(imagePath, RootfsDir))
+}
+
+// InspectImage reports broken images too: missing volumes are flagged instead
+// of failing, and the mount is left empty when it can't be rebuilt.
+func (d *Driver) InspectImage(logger lager.Logger, imagePath string) (groot.ImageFilesystemDetails, error) {
+	logger = logger.Session("overlayxfs-inspecting-image", lager.Data{"imagePath": imagePath})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	volumes, err := d.readImageVolumes(imagePath)
+	if err != nil {
+		logger.Error("reading-image-volumes-failed", err)
+		return groot.ImageFilesystemDetails{}, err
+	}
+
+	details := groot.ImageFilesystemDetails{Volumes: []groot.VolumeDetails{}}
+	for _, volumeID := range volumes.BaseVolumeIDs {
+		volumeDetails := groot.VolumeDetails{ID: volumeID}
+		if _, err := os.Stat(filepath.Join(d.storePath, store.VolumesDirName, volumeID)); os.IsNotExist(err) {
+			volumeDetails.Missing = true
+		} else if volumeDetails.SizeBytes, err = d.VolumeSize(logger, volumeID); err != nil {
+			logger.Error("fetching-volume-size-failed", err, lager.Data{"volumeID": volumeID})
+		}
+		details.Volumes = append(details.Volumes, volumeDetails)
+	}
+
+	if lowerDirs, err := d.linkedLowerDirs(volumes.BaseVolumeIDs); err != nil {
+		logger.Error("reading-lowerdir-links-failed", err)
+	} else if volumes.ReadOnly {
+		details.Mount = d.readOnlyMountInfo(lowerDirs)
+	} else {
+		upperDir, workDir := imageLayerDirs(imagePath, volumes)
+		details.Mount = groot.MountInfo{
+			Destination: "/",
+			Source:      "overlay",
+			Type:        "overlay",
+			Options:     []string{d.formatMountData(lowerDirs, workDir, upperDir, volumes.OverlayMountOptions, true)},
+		}
+	}
+
+	if details.ProjectID, err = quotapkg.GetProjectID(logger, imagePath); err != nil {
+		logger.Error("fetching-project-id-failed", err)
+	}
+
+	quota, err := ioutil.ReadFile(filepath.Join(imagePath, imageQuotaName))
+	if err != nil && !os.IsNotExist(err) {
+		return groot.ImageFilesystemDetails{}, errorspkg.Wrap(err, "reading image quota")
+	}
+	if err == nil {
+		if details.QuotaBytes, err = strconv.ParseInt(string(quota), 10, 64); err != nil {
+			return groot.ImageFilesystemDetails{}, errorspkg.Wrap(err, "parsing image quota")
+		}
+	}
+
+	return details, nil
+}
+
+func (d *Driver) MountAllImages(logger lager.Logger) ([]string, error) {
+	logger = logger.Session("overlayxfs-mounting-all-images")
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	imagesPath := filepath.Join(d.storePath, store.ImageDirName)
+	images, err := ioutil.ReadDir(imagesPath)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "reading images directory")
+	}
+
+	mountedImages := []string{}
+	failedImages := []string{}
+	for _, image := range images {
+		imagePath := filepath.Join(imagesPath, image.Name())
+
+		// Images created by older versions don't record whether they were
+		// mounted, they are left to their owners to mount.
+		if _, err := os.Stat(filepath.Join(imagePath, imageVolumesName)); os.IsNotExist(err) {
+			logger.Info("skipping-image-without-image-volumes", lager.Data{"imagePath": imagePath})
+			continue
+		}
+
+		volumes, err := d.readImageVolumes(imagePath)
+		if err != nil {
+			logger.Error("reading-image-volumes-failed", err, lager.Data{"imagePath": imagePath})
+			failedImages = append(failedImages, image.Name())
+			continue
+		}
+
+		if !volumes.Mount {
+			continue
+		}
+
+		if err := d.MountImage(logger, imagePath); err != nil {
+			logger.Error("mounting-image-failed", err, lager.Data{"imagePath": imagePath})
+			failedImages = append(failedImages, image.Name())
+			continue
+		}
+		mountedImages = append(mountedImages, image.Name())
+	}
+
+	if len(failedImages) > 0 {
+		return mountedImages, errorspkg.Errorf("failed to mount images: %s", strings.Join(failedImages, ", "))
+	}
+
+	return mountedImages, nil
+}
+
+func (d *Driver) FlattenVolumes(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error) {
+	if maxDepth <= 0 || len(volumeIDs) <= maxDepth {
+		return volumeIDs, nil
+	}
+
+	logger = logger.Session("overlayxfs-flattening-volumes", lager.Data{"volumeIDs": volumeIDs, "maxDepth": maxDepth})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	if maxDepth < 2 {
+		return nil, errorspkg.Errorf("max layer depth must be at least 2, got %d", maxDepth)
+	}
+
+	squashedCount := len(volumeIDs) - maxDepth + 1
+	squashedIDs := volumeIDs[:squashedCount]
+	flattenedIDs := []string{FlattenedVolumePrefix + squashedIDs[squashedCount-1]}
+	flattenedIDs = append(flattenedIDs, volumeIDs[squashedCount:]...)
+
+	if _, err := d.VolumePath(logger, flattenedIDs[0]); err == nil {
+		return flattenedIDs, nil
+	}
+
+	// Each flattening mount is itself bound by maxDepth, so long runs are
+	// squashed in chunks, carrying the previous result as the bottom layer.
+	// The intermediate results built here are only needed to build the next
+	// chunk. Those that already existed may be the bottom layer of other images.
+	chunk := []string{}
+	intermediateID := ""
+	for _, id := range squashedIDs {
+		chunk = append(chunk, id)
+		if len(chunk) < maxDepth && id != squashedIDs[squashedCount-1] {
+			continue
+		}
+
+		flatID, created, err := d.flattenVolume(logger, chunk, FlattenedVolumePrefix+id)
+		if intermediateID != "" {
+			d.destroyIntermediateVolume(logger, intermediateID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		intermediateID = ""
+		if created {
+			intermediateID = flatID
+		}
+		chunk = []string{flatID}
+	}
+
+	return flattenedIDs, nil
+}
+
+// destroyIntermediateVolume removes a volume built only to flatten the next
+// chunk, unless an image, pin or snapshot registered it in the meantime.
+func (d *Driver) destroyIntermediateVolume(logger lager.Logger, id string) {
+	referenced, err := d.volumeReferenced(id)
+	if err != nil {
+		logger.Error("checking-intermediate-volume-references-failed", err, lager.Data{"volumeID": id})
+		return
+	}
+	if referenced {
+		logger.Info("keeping-referenced-intermediate-volume", lager.Data{"volumeID": id})
+		return
+	}
+
+	if err := d.DestroyVolume(logger, id); err != nil {
+		logger.Error("destroying-intermediate-volume-failed", err, lager.Data{"volumeID": id})
+	}
+}
+
+func (d *Driver) volumeReferenced(id string) (bool, error) {
+	dependencyManager := dependency_manager.NewDependencyManager(
+		filepath.Join(d.storePath, store.MetaDirName, "dependencies"),
+	)
+
+	refs, err := dependencyManager.List("")
+	if os.IsNotExist(errorspkg.Cause(err)) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, ref := range refs {
+		chainIDs, err := dependencyManager.Dependencies(ref)
+		if err != nil {
+			// deregistered since it was listed
+			continue
+		}
+
+		for _, chainID := range chainIDs {
+			if chainID == id {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// flattenVolume returns whether it built the volume, or found it already
+// built.
+func (d *Driver) flattenVolume(logger lager.Logger, volumeIDs []string, flatID string) (_ string, _ bool, err error) {
+	logger = logger.Session("flatten-volume", lager.Data{"volumeIDs": volumeIDs, "flatID": flatID})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	if _, err := d.VolumePath(logger, flatID); err == nil {
+		return flatID, false, nil
+	}
+
+	if os.Geteuid() != 0 {
+		return "", false, errorspkg.New("flattening volumes requires root privileges")
+	}
+
+	sourcePath, err := d.VolumePath(logger, volumeIDs[0])
+	if err != nil {
+		return "", false, errorspkg.Wrapf(err, "flattening volume %s", flatID)
+	}
+
+	if len(volumeIDs) > 1 {
+		lowerDirs, _, err := d.getLowerDirs(logger, volumeIDs)
+		if err != nil {
+			return "", false, errorspkg.Wrap(err, "generating lowerdir paths failed")
+		}
+
+		sourcePath, err = ioutil.TempDir(filepath.Join(d.storePath, store.TempDirName), "flatten-")
+		if err != nil {
+			return "", false, errorspkg.Wrap(err, "creating flattening mountpoint")
+		}
+		defer os.RemoveAll(sourcePath)
+
+		if err := os.Chdir(d.storePath); err != nil {
+			return "", false, errorspkg.Wrap(err, "failed to change directory to the store path")
+		}
+
+		mountData := fmt.Sprintf("lowerdir=%s", strings.Join(lowerDirs, ":"))
+		if err := syscall.Mount("overlay", sourcePath, "overlay", syscall.MS_RDONLY, mountData); err != nil {
+			logger.Error("mounting-lowerdirs-failed", err, lager.Data{"mountData": mountData})
+			return "", false, errorspkg.Wrap(err, "mounting volumes to flatten")
+		}
+		defer func() {
+			if err := syscall.Unmount(sourcePath, 0); err != nil {
+				logger.Error("unmounting-lowerdirs-failed", err)
+			}
+		}()
+	}
+
+	tempID := fmt.Sprintf("%s-incomplete-%d-%d", flatID, time.Now().UnixNano(), rand.Int())
+	tempPath, err := d.CreateVolume(logger, "", tempID)
+	if err != nil {
+		return "", false, errorspkg.Wrapf(err, "creating volume %s", flatID)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if destroyErr := d.DestroyVolume(logger, tempID); destroyErr != nil {
+			logger.Error("destroying-incomplete-volume-failed", destroyErr)
+		}
+		metaPath := filesystems.VolumeMetaFilePath(d.storePath, flatID)
+		if removeErr := os.Remove(metaPath); removeErr != nil && !os.IsNotExist(removeErr) {
+			logger.Error("removing-incomplete-volume-meta-failed", removeErr)
+		}
+	}()
+
+	if output, err := exec.Command("cp", "-a", sourcePath+"/.", tempPath).CombinedOutput(); err != nil {
+		logger.Error("copying-volume-contents-failed", err, lager.Data{"output": string(output)})
+		return "", false, errorspkg.Wrapf(err, "copying volumes to flatten: %s", string(output))
+	}
+
+	size, err := filesystems.CalculatePathSize(logger, tempPath)
+	if err != nil {
+		return "", false, errorspkg.Wrapf(err, "calculating size of volume %s", flatID)
+	}
+
+	if err := d.WriteVolumeMeta(logger, flatID, base_image_puller.VolumeMeta{Size: size, LastUsed: time.Now().UnixNano()}); err != nil {
+		return "", false, errorspkg.Wrapf(err, "writing volume `%s` metadata", flatID)
+	}
+
+	if err := d.MoveVolume(logger, tempPath, filepath.Join(d.storePath, store.VolumesDirName, flatID)); err != nil {
+		return "", false, errorspkg.Wrapf(err, "moving volume %s", flatID)
+	}
+
+	return flatID, true, nil
+}
+
 func (d *Driver) MoveVolume(logger lager.Logger, from, to string) error {
 	logger = logger.Session("overlayxfs-moving-volume", lager.Data{"from": from, "to": to})
 	logger.Debug("starting")
@@ -359,6 +958,32 @@ func (d *Driver) HandleOpaqueWhiteouts(logger lager.Logger, id string, opaqueWhi
 	return nil
 }
 
+// ClearProjectIDs takes the files under path out of the project quota they
+// were written under. Files that move from an image into a volume must not
+// keep counting against the image's project id once it is released.
+func (d *Driver) ClearProjectIDs(logger lager.Logger, path string) error {
+	logger = logger.Session("overlayxfs-clearing-project-ids", lager.Data{"path": path})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	projectID, err := quotapkg.GetProjectID(logger, path)
+	if err != nil {
+		return errorspkg.Wrap(err, "fetching project id")
+	}
+
+	if projectID == 0 {
+		logger.Debug("no-project-id")
+		return nil
+	}
+
+	if output, err := d.runTardis(logger, "clear-project-ids", "--path", path); err != nil {
+		logger.Error("clearing-project-ids-failed", err)
+		return errorspkg.Wrapf(err, "clear project ids: %s", output.String())
+	}
+
+	return nil
+}
+
 func (d *Driver) WriteVolumeMeta(logger lager.Logger, id string, metadata base_image_puller.VolumeMeta) error {
 	logger = logger.Session("overlayxfs-writing-volume-metadata", lager.Data{"volumeID": id})
 	logger.Debug("starting")
@@ -410,7 +1035,7 @@ func (d *Driver) createImageDirectories(logger lager.Logger, directories map[str
 	return nil
 }
 
-func (d *Driver) formatMountData(lowerDirs []string, workDir, upperDir string, absolute bool) string {
+func (d *Driver) formatMountData(lowerDirs []string, workDir, upperDir string, options []string, absolute bool) string {
 	if absolute {
 		for i, lowerDir := range lowerDirs {
 			lowerDirs[i] = filepath.Join(d.storePath, lowerDir)
@@ -418,7 +1043,38 @@ func (d *Driver) formatMountData(lowerDirs []string, workDir, upperDir string, a
 	}
 
 	lowerDirsOpt := strings.Join(lowerDirs, ":")
-	return fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", lowerDirsOpt, upperDir, workDir)
+	mountData := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", lowerDirsOpt, upperDir, workDir)
+	for _, option := range options {
+		mountData += "," + option
+	}
+
+	return mountData
+}
+
+func containsOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clearDir removes the contents of dir, keeping its mode and owner.
+func clearDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (d *Driver) mountImage(logger lager.Logger, rootfsDir, mountData string) error {
@@ -462,6 +1118,20 @@ func (d *Driver) getLowerDirs(logger lager.Logger, volumeIDs []string) ([]string
 	return baseVolumePaths, totalVolumeSize, nil
 }
 
+// linkedLowerDirs is getLowerDirs without the volume size checks.
+func (d *Driver) linkedLowerDirs(volumeIDs []string) ([]string, error) {
+	lowerDirs := []string{}
+	for i := len(volumeIDs) - 1; i >= 0; i-- {
+		shortId, err := ioutil.ReadFile(filepath.Join(d.storePath, LinksDirName, volumeIDs[i]))
+		if err != nil {
+			return nil, errorspkg.Wrapf(err, "reading short id of volume %s", volumeIDs[i])
+		}
+		lowerDirs = append(lowerDirs, filepath.Join(LinksDirName, string(shortId)))
+	}
+
+	return lowerDirs, nil
+}
+
 func (d *Driver) DestroyImage(logger lager.Logger, imagePath string) error {
 	logger = logger.Session("overlayxfs-destroying-image", lager.Data{"imagePath": imagePath})
 	logger.Info("starting")
@@ -470,7 +1140,7 @@ func (d *Driver) DestroyImage(logger lager.Logger, imagePath string) error {
 	projectID, err := quotapkg.GetProjectID(logger, imagePath)
 	if err != nil {
 		logger.Error("fetching-project-id-failed", err)
-		logger.Info("skipping-project-id-folder-removal")
+		logger.Info("skipping-project-id-release")
 	}
 
 	if err := ensureImageDestroyed(logger, imagePath); err != nil {
@@ -479,19 +1149,102 @@ func (d *Driver) DestroyImage(logger lager.Logger, imagePath string) error {
 	}
 
 	if projectID != 0 {
-		if err := os.RemoveAll(filepath.Join(d.storePath, IDDir, strconv.Itoa(int(projectID)))); err != nil {
-			logger.Error("removing-project-id-folder-failed", err)
+		if err := ids.NewAllocator(filepath.Join(d.storePath, IDDir)).Release(logger, projectID); err != nil {
+			logger.Error("releasing-project-id-failed", err)
 		}
 	}
 
 	return nil
 }
 
+// TrashImage unmounts an image and renames it to trashPath, which must be on
+// the store filesystem, to be destroyed later. Its project id moves with it,
+// so that its quota isn't handed out again before its files are gone.
+func (d *Driver) TrashImage(logger lager.Logger, imagePath, trashPath string) error {
+	logger = logger.Session("overlayxfs-trashing-image", lager.Data{"imagePath": imagePath, "trashPath": trashPath})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	projectID, err := quotapkg.GetProjectID(logger, imagePath)
+	if err != nil {
+		logger.Error("fetching-project-id-failed", err)
+	}
+
+	for _, dir := range []string{RootfsDir, EphemeralDir} {
+		if err := unmountIfMounted(filepath.Join(imagePath, dir), 0); err != nil {
+			logger.Error("unmounting-image-failed", err, lager.Data{"dir": dir})
+			return err
+		}
+	}
+
+	if err := os.Rename(imagePath, trashPath); err != nil {
+		logger.Error("renaming-image-failed", err)
+		return errorspkg.Wrap(err, "moving image to the trash")
+	}
+
+	if projectID != 0 {
+		if err := ids.NewAllocator(filepath.Join(d.storePath, IDDir)).Transfer(logger, projectID, trashPath); err != nil {
+			logger.Error("transferring-project-id-failed", err)
+
+			// Otherwise the project id would be released while the trashed
+			// image still uses it.
+			if renameErr := os.Rename(trashPath, imagePath); renameErr != nil {
+				logger.Error("moving-image-back-failed", renameErr)
+			}
+			return errorspkg.Wrap(err, "transferring the project id to the trash")
+		}
+	}
+
+	return nil
+}
+
+// DetachImage lazily unmounts the rootfs of an image, and its tmpfs if it's
+// ephemeral, so that it can be destroyed while processes still use it. They
+// keep their view of the image until they exit.
+func (d *Driver) DetachImage(logger lager.Logger, imagePath string) error {
+	logger = logger.Session("overlayxfs-detaching-image", lager.Data{"imagePath": imagePath})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	for _, dir := range []string{RootfsDir, EphemeralDir} {
+		if err := unmountIfMounted(filepath.Join(imagePath, dir), syscall.MNT_DETACH); err != nil {
+			logger.Error("detaching-image-failed", err, lager.Data{"dir": dir})
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Driver) ReconcileProjectIDs(logger lager.Logger) ([]uint32, error) {
+	logger = logger.Session("overlayxfs-reconciling-project-ids")
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	released, err := ids.NewAllocator(filepath.Join(d.storePath, IDDir)).Reconcile(logger)
+	if err != nil {
+		logger.Error("reconciling-project-ids-failed", err)
+		return nil, errorspkg.Wrap(err, "reconciling project ids")
+	}
+
+	return released, nil
+}
+
 func (d *Driver) FetchStats(logger lager.Logger, imagePath string) (groot.VolumeStats, error) {
 	logger = logger.Session("overlayxfs-fetching-stats", lager.Data{"imagePath": imagePath})
 	logger.Debug("starting")
 	defer logger.Debug("ending")
 
+	// Read-only images have no upper directory to use disk space, and the
+	// upper directory of ephemeral images isn't covered by the store quotas.
+	if volumes, err := d.readImageVolumes(imagePath); err == nil && (volumes.ReadOnly || volumes.EphemeralSizeBytes > 0) {
+		stats, err := d.unquotedImageStats(imagePath, volumes)
+		if err != nil {
+			logger.Error("fetching-unquoted-stats-failed", err)
+		}
+		return stats, err
+	}
+
 	output, err := d.runTardis(logger, "stats", "--volume-path", imagePath)
 	if err != nil {
 		logger.Error("fetching-stats-failed", err, lager.Data{"imagePath": imagePath})
@@ -507,6 +1260,43 @@ func (d *Driver) FetchStats(logger lager.Logger, imagePath string) (groot.Volume
 	return stats, nil
 }
 
+func (d *Driver) FetchAllStats(logger lager.Logger) (map[string]groot.VolumeStats, error) {
+	logger = logger.Session("overlayxfs-fetching-all-stats", lager.Data{"storePath": d.storePath})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	output, err := d.runTardis(logger, "stats", "--all", "--store-path", d.storePath)
+	if err != nil {
+		logger.Error("fetching-all-stats-failed", err)
+		return nil, errorspkg.Wrap(err, "fetch all stats")
+	}
+
+	allStats := map[string]groot.VolumeStats{}
+	if err := json.Unmarshal(output.Bytes(), &allStats); err != nil {
+		logger.Error("unmarshaling-json-stats-failed", err, lager.Data{"stats": output.String()})
+		return nil, errorspkg.Wrapf(err, "fetch all stats: %s", output.String())
+	}
+
+	for id := range allStats {
+		imagePath := filepath.Join(d.storePath, store.ImageDirName, id)
+		volumes, err := d.readImageVolumes(imagePath)
+		if err != nil || volumes.EphemeralSizeBytes == 0 {
+			continue
+		}
+
+		stats, err := d.unquotedImageStats(imagePath, volumes)
+		if err != nil {
+			// The image is being deleted.
+			logger.Info("skipping-ephemeral-image", lager.Data{"imagePath": imagePath, "error": err.Error()})
+			delete(allStats, id)
+			continue
+		}
+		allStats[id] = stats
+	}
+
+	return allStats, nil
+}
+
 func (d *Driver) Marshal(logger lager.Logger) ([]byte, error) {
 	driverSpec := spec.DriverSpec{
 		Type:           "overlay-xfs",
@@ -527,6 +1317,22 @@ func (d *Driver) VolumeSize(logger lager.Logger, id string) (int64, error) {
 	return filesystems.VolumeSize(logger, d.storePath, id)
 }
 
+func (d *Driver) VolumeLastUsed(logger lager.Logger, id string) (time.Time, error) {
+	logger = logger.Session("overlayxfs-volume-last-used", lager.Data{"volumeID": id})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	return filesystems.VolumeLastUsed(logger, d.storePath, id)
+}
+
+func (d *Driver) TouchVolume(logger lager.Logger, id string) error {
+	logger = logger.Session("overlayxfs-touching-volume", lager.Data{"volumeID": id})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	return filesystems.TouchVolumeMeta(logger, d.storePath, id, time.Now())
+}
+
 func (d *Driver) createWhiteoutDevice(logger lager.Logger, storePath string, ownerUID, ownerGID int) error {
 	whiteoutDevicePath := filepath.Join(storePath, WhiteoutDevice)
 	if _, err := os.Stat(whiteoutDevicePath); os.IsNotExist(err) {
@@ -676,11 +1482,131 @@ func (d *Driver) applyDiskLimit(logger lager.Logger, spec image_cloner.ImageDriv
 	return nil
 }
 
+type imageVolumes struct {
+	BaseVolumeIDs      []string `json:"base_volume_ids"`
+	Mount              bool     `json:"mount"`
+	ReadOnly           bool     `json:"read_only,omitempty"`
+	EphemeralSizeBytes int64    `json:"ephemeral_size_bytes,omitempty"`
+	// OverlayMountOptions are extra options the image's overlay is mounted
+	// with. Read-only images ignore them.
+	OverlayMountOptions []string `json:"overlay_mount_options,omitempty"`
+}
+
+// unquotedImageStats are the stats of images without a project quota: the
+// usage of their tmpfs for ephemeral images, nothing for read-only ones.
+func (d *Driver) unquotedImageStats(imagePath string, volumes imageVolumes) (groot.VolumeStats, error) {
+	volumeSize, err := d.readImageInfo(imagePath)
+	if err != nil {
+		return groot.VolumeStats{}, err
+	}
+
+	var exclusiveSize int64
+	if volumes.EphemeralSizeBytes > 0 {
+		ephemeralDir := filepath.Join(imagePath, EphemeralDir)
+		mounted, err := isMountpoint(ephemeralDir)
+		if err != nil {
+			return groot.VolumeStats{}, errorspkg.Wrap(err, "checking if the ephemeral folder is mounted")
+		}
+
+		if mounted {
+			var statfs syscall.Statfs_t
+			if err := syscall.Statfs(ephemeralDir, &statfs); err != nil {
+				return groot.VolumeStats{}, errorspkg.Wrap(err, "reading ephemeral tmpfs usage")
+			}
+			exclusiveSize = int64(statfs.Blocks-statfs.Bfree) * statfs.Bsize
+		}
+	}
+
+	return groot.VolumeStats{
+		DiskUsage: groot.DiskUsage{
+			ExclusiveBytesUsed: exclusiveSize,
+			TotalBytesUsed:     volumeSize + exclusiveSize,
+		},
+	}, nil
+}
+
+func (d *Driver) readImageInfo(imagePath string) (int64, error) {
+	imageInfoFileName := filepath.Join(imagePath, imageInfoName)
+	contents, err := ioutil.ReadFile(imageInfoFileName)
+	if err != nil {
+		return 0, errorspkg.Wrapf(err, "reading image info %s", imageInfoFileName)
+	}
+
+	volumeSize, err := strconv.ParseInt(string(contents), 10, 64)
+	if err != nil {
+		return 0, errorspkg.Wrapf(err, "parsing image info %s", imageInfoFileName)
+	}
+
+	return volumeSize, nil
+}
+
+func (d *Driver) writeImageVolumes(imagePath string, volumes imageVolumes) error {
+	contents, err := json.Marshal(volumes)
+	if err != nil {
+		return errorspkg.Wrap(err, "encoding image volumes")
+	}
+
+	imageVolumesFileName := filepath.Join(imagePath, imageVolumesName)
+	if err := ioutil.WriteFile(imageVolumesFileName, contents, 0600); err != nil {
+		return errorspkg.Wrapf(err, "writing image volumes %s", imageVolumesFileName)
+	}
+
+	return nil
+}
+
+func (d *Driver) readImageVolumes(imagePath string) (imageVolumes, error) {
+	imageVolumesFileName := filepath.Join(imagePath, imageVolumesName)
+	contents, err := ioutil.ReadFile(imageVolumesFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return imageVolumes{}, errorspkg.Errorf("image %s has no recorded base volumes", filepath.Base(imagePath))
+		}
+		return imageVolumes{}, errorspkg.Wrapf(err, "reading image volumes %s", imageVolumesFileName)
+	}
+
+	var volumes imageVolumes
+	if err := json.Unmarshal(contents, &volumes); err != nil {
+		return imageVolumes{}, errorspkg.Wrapf(err, "parsing image volumes %s", imageVolumesFileName)
+	}
+
+	return volumes, nil
+}
+
+// ensureImageDestroyed doesn't remove an image it can't unmount, as the
+// removal would strip the files from under the processes using it.
 func ensureImageDestroyed(logger lager.Logger, imagePath string) error {
-	if err := syscall.Unmount(filepath.Join(imagePath, RootfsDir), 0); err != nil {
-		logger.Info("unmount image path failed", lager.Data{"path": imagePath, "error": err})
+	for _, dir := range []string{RootfsDir, EphemeralDir} {
+		if err := unmountIfMounted(filepath.Join(imagePath, dir), 0); err != nil {
+			logger.Error("unmounting-image-failed", err, lager.Data{"dir": dir})
+			return err
+		}
 	}
-	return os.RemoveAll(imagePath)
+
+	for attempt := 1; ; attempt++ {
+		err := os.RemoveAll(imagePath)
+		if err == nil || attempt == maxDestroyRetries {
+			return err
+		}
+
+		logger.Info("removing-image-path-failed-retrying", lager.Data{"attempt": attempt, "error": err.Error()})
+		time.Sleep(time.Duration(1<<uint(attempt-1)) * 100 * time.Millisecond)
+	}
+}
+
+func unmountIfMounted(path string, flags int) error {
+	mounted, err := isMountpoint(path)
+	if os.IsNotExist(errorspkg.Cause(err)) {
+		return nil
+	}
+	if err != nil {
+		return errorspkg.Wrapf(err, "checking if %s is mounted", path)
+	}
+
+	if !mounted {
+		return nil
+	}
+
+	return errorspkg.Wrapf(syscall.Unmount(path, flags), "unmounting %s", path)
 }
 
 func getDeviceForFile(path string) (uint64, error) {
@@ -707,5 +1633,31 @@ func isMountpoint(path string) (bool, error) {
 		return false, err
 	}
 
-	return dev != parentDev, nil
+	if dev != parentDev {
+		return true, nil
+	}
+
+	// Bind mounts from the same filesystem keep the device of their parent.
+	return inMountInfo(path)
+}
+
+func inMountInfo(path string) (bool, error) {
+	mountInfo, err := ioutil.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return false, errorspkg.Wrap(err, "reading mountinfo")
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, errorspkg.Wrap(err, "resolving mount point")
+	}
+
+	for _, line := range strings.Split(string(mountInfo), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 4 && fields[4] == absPath {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
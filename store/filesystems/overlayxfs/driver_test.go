@@ -16,9 +16,13 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/groot"
 	"code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/grootfs/store/dependency_manager"
 	"code.cloudfoundry.org/grootfs/store/filesystems"
 	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs"
+	quotapkg "code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/quota"
+	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/tardis/ids"
 	"code.cloudfoundry.org/grootfs/store/image_cloner"
 	"code.cloudfoundry.org/grootfs/testhelpers"
 	"code.cloudfoundry.org/lager/lagertest"
@@ -265,6 +269,38 @@ var _ = Describe("Driver", func() {
 			)))
 		})
 
+		Context("when overlay mount options are given", func() {
+			BeforeEach(func() {
+				spec.OverlayMountOptions = []string{"metacopy=on"}
+			})
+
+			It("mounts the overlay with them", func() {
+				_, err := driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				mountInfo, err := ioutil.ReadFile("/proc/self/mountinfo")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(mountInfo)).To(MatchRegexp("%s .*metacopy=on", filepath.Join(spec.ImagePath, overlayxfs.RootfsDir)))
+			})
+
+			It("returns them in the mountJson object", func() {
+				mountJson, err := driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(mountJson.Options).To(HaveLen(1))
+				Expect(mountJson.Options[0]).To(HaveSuffix(",workdir=%s,metacopy=on", filepath.Join(spec.ImagePath, overlayxfs.WorkDir)))
+			})
+
+			It("keeps them when the image is inspected", func() {
+				_, err := driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				details, err := driver.InspectImage(logger, spec.ImagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(details.Mount.Options[0]).To(HaveSuffix(",metacopy=on"))
+			})
+		})
+
 		Context("when a volume metadata file is missing", func() {
 			BeforeEach(func() {
 				metaFilePath := filepath.Join(storePath, store.MetaDirName, "volume-"+layer1ID)
@@ -352,6 +388,158 @@ var _ = Describe("Driver", func() {
 			})
 		})
 
+		Context("when the image is read-only", func() {
+			BeforeEach(func() {
+				spec.ReadOnly = true
+				spec.DiskLimit = 10 * 1024 * 1024
+			})
+
+			It("only creates the rootfs directory, without a quota", func() {
+				_, err := driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(filepath.Join(spec.ImagePath, overlayxfs.UpperDir)).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(spec.ImagePath, overlayxfs.WorkDir)).ToNot(BeAnExistingFile())
+				Expect(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir)).To(BeADirectory())
+
+				projectID, err := quotapkg.GetProjectID(logger, spec.ImagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(projectID).To(BeZero())
+			})
+
+			It("bind mounts the single base volume read-only", func() {
+				mountJson, err := driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "file-hello"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(BeEquivalentTo("hello-1"))
+
+				err = ioutil.WriteFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "new-file"), []byte{}, 0644)
+				Expect(err).To(MatchError(ContainSubstring("read-only file system")))
+
+				Expect(mountJson.Type).To(Equal("bind"))
+				Expect(mountJson.Destination).To(Equal("/"))
+				Expect(mountJson.Source).To(HavePrefix(filepath.Join(storePath, overlayxfs.LinksDirName)))
+				Expect(mountJson.Options).To(Equal([]string{"bind", "ro"}))
+
+				mounted, err := driver.ImageMounted(logger, spec.ImagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounted).To(BeTrue())
+			})
+
+			Context("when there are multiple base volumes", func() {
+				BeforeEach(func() {
+					spec.BaseVolumeIDs = []string{layer1ID, layer2ID}
+				})
+
+				It("mounts a read-only overlay of the base volumes", func() {
+					mountJson, err := driver.CreateImage(logger, spec)
+					Expect(err).ToNot(HaveOccurred())
+
+					contents, err := ioutil.ReadFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "file-bye"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(contents).To(BeEquivalentTo("bye-2"))
+
+					err = ioutil.WriteFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "new-file"), []byte{}, 0644)
+					Expect(err).To(MatchError(ContainSubstring("read-only file system")))
+
+					Expect(mountJson.Type).To(Equal("overlay"))
+					Expect(mountJson.Options).To(HaveLen(2))
+					Expect(mountJson.Options[0]).To(MatchRegexp("^lowerdir=%s[^:]*:%s[^:]*$",
+						filepath.Join(storePath, overlayxfs.LinksDirName),
+						filepath.Join(storePath, overlayxfs.LinksDirName),
+					))
+					Expect(mountJson.Options[1]).To(Equal("ro"))
+				})
+			})
+
+			Context("when Mount is false", func() {
+				BeforeEach(func() {
+					spec.Mount = false
+				})
+
+				It("mounts the rootfs read-only when MountImage is called", func() {
+					_, err := driver.CreateImage(logger, spec)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "file-hello")).ToNot(BeAnExistingFile())
+
+					Expect(driver.MountImage(logger, spec.ImagePath)).To(Succeed())
+					Expect(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "file-hello")).To(BeAnExistingFile())
+					err = ioutil.WriteFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "new-file"), []byte{}, 0644)
+					Expect(err).To(MatchError(ContainSubstring("read-only file system")))
+				})
+			})
+
+			Context("when there are no base volumes", func() {
+				BeforeEach(func() {
+					spec.BaseVolumeIDs = []string{}
+				})
+
+				It("returns an error", func() {
+					_, err := driver.CreateImage(logger, spec)
+					Expect(err).To(MatchError("a read-only image needs at least one base volume"))
+				})
+			})
+		})
+
+		Context("when the image is ephemeral", func() {
+			BeforeEach(func() {
+				spec.EphemeralSizeBytes = 4 * 1024 * 1024
+				spec.DiskLimit = 10 * 1024 * 1024
+			})
+
+			It("keeps the upper and work dirs on a tmpfs, without a quota", func() {
+				_, err := driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				ephemeralDir := filepath.Join(spec.ImagePath, overlayxfs.EphemeralDir)
+				Expect(filepath.Join(ephemeralDir, overlayxfs.UpperDir)).To(BeADirectory())
+				Expect(filepath.Join(ephemeralDir, overlayxfs.WorkDir)).To(BeADirectory())
+				Expect(filepath.Join(spec.ImagePath, overlayxfs.UpperDir)).ToNot(BeAnExistingFile())
+
+				var statfs syscall.Statfs_t
+				Expect(syscall.Statfs(ephemeralDir, &statfs)).To(Succeed())
+				Expect(statfs.Type).To(BeEquivalentTo(0x01021994))
+				Expect(int64(statfs.Blocks) * statfs.Bsize).To(Equal(spec.EphemeralSizeBytes))
+
+				projectID, err := quotapkg.GetProjectID(logger, spec.ImagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(projectID).To(BeZero())
+			})
+
+			It("limits the writable layer to the tmpfs size", func() {
+				mountJson, err := driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mountJson.Options[0]).To(ContainSubstring("upperdir=" + filepath.Join(spec.ImagePath, overlayxfs.EphemeralDir, overlayxfs.UpperDir)))
+
+				dd := exec.Command("dd", "if=/dev/zero", fmt.Sprintf("of=%s/rootfs/file-1", spec.ImagePath), "count=5", "bs=1M")
+				sess, err := gexec.Start(dd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess).Should(gexec.Exit(1))
+				Expect(sess.Err).To(gbytes.Say("No space left on device"))
+			})
+
+			Context("when the tmpfs is gone, as after a reboot", func() {
+				BeforeEach(func() {
+					spec.Mount = false
+				})
+
+				It("mounts a new one when MountImage is called", func() {
+					_, err := driver.CreateImage(logger, spec)
+					Expect(err).ToNot(HaveOccurred())
+
+					ephemeralDir := filepath.Join(spec.ImagePath, overlayxfs.EphemeralDir)
+					Expect(syscall.Unmount(ephemeralDir, 0)).To(Succeed())
+
+					Expect(driver.MountImage(logger, spec.ImagePath)).To(Succeed())
+					Expect(filepath.Join(ephemeralDir, overlayxfs.UpperDir)).To(BeADirectory())
+					Expect(ioutil.WriteFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "new-file"), []byte{}, 0644)).To(Succeed())
+					Expect(filepath.Join(ephemeralDir, overlayxfs.UpperDir, "new-file")).To(BeAnExistingFile())
+				})
+			})
+		})
+
 		Context("image_info", func() {
 			BeforeEach(func() {
 				volumeID := randVolumeID()
@@ -646,22 +834,38 @@ var _ = Describe("Driver", func() {
 			Expect(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir)).ToNot(BeAnExistingFile())
 		})
 
+		Context("when the image is ephemeral", func() {
+			BeforeEach(func() {
+				spec.EphemeralSizeBytes = 1024 * 1024
+			})
+
+			It("unmounts the tmpfs and removes the image path", func() {
+				Expect(driver.DestroyImage(logger, spec.ImagePath)).To(Succeed())
+				Expect(spec.ImagePath).ToNot(BeAnExistingFile())
+
+				mountInfo, err := ioutil.ReadFile("/proc/self/mountinfo")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(mountInfo)).NotTo(ContainSubstring(spec.ImagePath))
+			})
+		})
+
 		Context("projectids", func() {
 			BeforeEach(func() {
 				spec.DiskLimit = 1000000000
 			})
 
-			It("removes the projectid directory for that image", func() {
-				projectidsDir := filepath.Join(storePath, overlayxfs.IDDir)
-				ids, err := ioutil.ReadDir(projectidsDir)
+			It("releases the project id so that it can be reused", func() {
+				projectID, err := quotapkg.GetProjectID(logger, spec.ImagePath)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(ids).To(HaveLen(1))
+				Expect(projectID).NotTo(BeZero())
 
 				Expect(driver.DestroyImage(logger, spec.ImagePath)).To(Succeed())
 
-				ids, err = ioutil.ReadDir(projectidsDir)
+				Expect(os.Mkdir(spec.ImagePath, 0755)).To(Succeed())
+				_, err = driver.CreateImage(logger, spec)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(ids).To(BeEmpty())
+
+				Expect(quotapkg.GetProjectID(logger, spec.ImagePath)).To(Equal(projectID))
 			})
 		})
 
@@ -685,6 +889,401 @@ var _ = Describe("Driver", func() {
 		})
 	})
 
+	Describe("DetachImage", func() {
+		var busyFile *os.File
+
+		BeforeEach(func() {
+			volumeID := randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			busyFile, err = os.Create(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "busyfile"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(busyFile.Close()).To(Succeed())
+		})
+
+		It("lets a busy image be destroyed", func() {
+			Expect(driver.DestroyImage(logger, spec.ImagePath)).NotTo(Succeed())
+			Expect(filepath.Join(spec.ImagePath, overlayxfs.UpperDir)).To(BeADirectory())
+
+			Expect(driver.DetachImage(logger, spec.ImagePath)).To(Succeed())
+			Expect(driver.DestroyImage(logger, spec.ImagePath)).To(Succeed())
+			Expect(spec.ImagePath).NotTo(BeAnExistingFile())
+
+			_, err := busyFile.WriteString("still writable")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the image is not mounted", func() {
+			It("does nothing", func() {
+				Expect(syscall.Unmount(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir), syscall.MNT_DETACH)).To(Succeed())
+				Expect(driver.DetachImage(logger, spec.ImagePath)).To(Succeed())
+			})
+		})
+	})
+
+	Describe("MountImage", func() {
+		BeforeEach(func() {
+			volumeID := randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+			Expect(ioutil.WriteFile(filepath.Join(storePath, store.VolumesDirName, volumeID, "a-file"), []byte("hello"), 0644)).To(Succeed())
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			spec.Mount = false
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("mounts the rootfs using the recorded base volumes", func() {
+			Expect(driver.MountImage(logger, spec.ImagePath)).To(Succeed())
+
+			contents, err := ioutil.ReadFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "a-file"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("hello"))
+		})
+
+		It("doesn't fail when the rootfs is already mounted", func() {
+			Expect(driver.MountImage(logger, spec.ImagePath)).To(Succeed())
+			Expect(driver.MountImage(logger, spec.ImagePath)).To(Succeed())
+		})
+
+		Context("when the image is volatile", func() {
+			BeforeEach(func() {
+				tmpDir, err := ioutil.TempDir(filepath.Join(storePath, store.ImageDirName), "")
+				Expect(err).NotTo(HaveOccurred())
+				spec.ImagePath = tmpDir
+				spec.Mount = true
+				spec.OverlayMountOptions = []string{"volatile"}
+				_, err = driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(ioutil.WriteFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "b-file"), []byte("world"), 0644)).To(Succeed())
+				Expect(driver.UnmountImage(logger, spec.ImagePath)).To(Succeed())
+			})
+
+			It("mounts it again with an empty work directory", func() {
+				Expect(driver.MountImage(logger, spec.ImagePath)).To(Succeed())
+
+				contents, err := ioutil.ReadFile(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "b-file"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("world"))
+			})
+		})
+
+		Context("when the image has no recorded base volumes", func() {
+			BeforeEach(func() {
+				Expect(os.Remove(filepath.Join(spec.ImagePath, "image_volumes"))).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				err := driver.MountImage(logger, spec.ImagePath)
+				Expect(err).To(MatchError(ContainSubstring("has no recorded base volumes")))
+			})
+		})
+	})
+
+	Describe("InspectImage", func() {
+		var volumeID string
+
+		BeforeEach(func() {
+			volumeID = randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			spec.DiskLimit = 10 * 1024 * 1024
+			spec.ExclusiveDiskLimit = true
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the volume chain with the volume sizes", func() {
+			details, err := driver.InspectImage(logger, spec.ImagePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(details.Volumes).To(Equal([]groot.VolumeDetails{{ID: volumeID, SizeBytes: 3145728}}))
+		})
+
+		It("returns the overlay mount", func() {
+			details, err := driver.InspectImage(logger, spec.ImagePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(details.Mount.Type).To(Equal("overlay"))
+			Expect(details.Mount.Options).To(HaveLen(1))
+			Expect(details.Mount.Options[0]).To(ContainSubstring("lowerdir=" + filepath.Join(storePath, overlayxfs.LinksDirName)))
+			Expect(details.Mount.Options[0]).To(ContainSubstring("upperdir=" + filepath.Join(spec.ImagePath, overlayxfs.UpperDir)))
+		})
+
+		It("returns the project id and the quota", func() {
+			details, err := driver.InspectImage(logger, spec.ImagePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(details.ProjectID).NotTo(BeZero())
+			Expect(details.QuotaBytes).To(Equal(int64(10 * 1024 * 1024)))
+		})
+
+		Context("when a base volume is missing", func() {
+			BeforeEach(func() {
+				testhelpers.CleanUpOverlayMounts(storePath)
+				Expect(os.RemoveAll(filepath.Join(storePath, store.VolumesDirName, volumeID))).To(Succeed())
+			})
+
+			It("flags the volume as missing", func() {
+				details, err := driver.InspectImage(logger, spec.ImagePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(details.Volumes).To(Equal([]groot.VolumeDetails{{ID: volumeID, Missing: true}}))
+			})
+		})
+
+		Context("when the image has no recorded base volumes", func() {
+			BeforeEach(func() {
+				Expect(os.Remove(filepath.Join(spec.ImagePath, "image_volumes"))).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, err := driver.InspectImage(logger, spec.ImagePath)
+				Expect(err).To(MatchError(ContainSubstring("has no recorded base volumes")))
+			})
+		})
+	})
+
+	Describe("UnmountImage", func() {
+		BeforeEach(func() {
+			volumeID := randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+			Expect(ioutil.WriteFile(filepath.Join(storePath, store.VolumesDirName, volumeID, "a-file"), []byte("hello"), 0644)).To(Succeed())
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("unmounts the rootfs", func() {
+			Expect(driver.UnmountImage(logger, spec.ImagePath)).To(Succeed())
+			Expect(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir, "a-file")).NotTo(BeAnExistingFile())
+		})
+
+		It("doesn't fail when the rootfs is not mounted", func() {
+			Expect(driver.UnmountImage(logger, spec.ImagePath)).To(Succeed())
+			Expect(driver.UnmountImage(logger, spec.ImagePath)).To(Succeed())
+		})
+	})
+
+	Describe("MountAllImages", func() {
+		var (
+			unmountedSpec image_cloner.ImageDriverSpec
+			volumeID      string
+		)
+
+		BeforeEach(func() {
+			volumeID = randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			unmountedImagePath := filepath.Join(storePath, store.ImageDirName, "unmounted-image")
+			Expect(os.Mkdir(unmountedImagePath, 0755)).To(Succeed())
+			unmountedSpec = image_cloner.ImageDriverSpec{
+				ImagePath:     unmountedImagePath,
+				BaseVolumeIDs: []string{volumeID},
+				Mount:         false,
+			}
+			_, err = driver.CreateImage(logger, unmountedSpec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(syscall.Unmount(filepath.Join(spec.ImagePath, overlayxfs.RootfsDir), 0)).To(Succeed())
+		})
+
+		It("mounts the images that were created with a mount", func() {
+			mounted, err := driver.MountAllImages(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounted).To(ConsistOf(randomImageID))
+		})
+
+		Context("when an image was mounted explicitly", func() {
+			BeforeEach(func() {
+				Expect(driver.MountImage(logger, unmountedSpec.ImagePath)).To(Succeed())
+				Expect(syscall.Unmount(filepath.Join(unmountedSpec.ImagePath, overlayxfs.RootfsDir), 0)).To(Succeed())
+			})
+
+			It("mounts it as well", func() {
+				mounted, err := driver.MountAllImages(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounted).To(ConsistOf(randomImageID, "unmounted-image"))
+			})
+		})
+
+		Context("when an image was unmounted explicitly", func() {
+			BeforeEach(func() {
+				Expect(driver.UnmountImage(logger, spec.ImagePath)).To(Succeed())
+			})
+
+			It("leaves it unmounted", func() {
+				mounted, err := driver.MountAllImages(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounted).To(BeEmpty())
+			})
+		})
+
+		Context("when an image doesn't record its volumes", func() {
+			BeforeEach(func() {
+				Expect(os.Remove(filepath.Join(unmountedSpec.ImagePath, "image_volumes"))).To(Succeed())
+			})
+
+			It("skips it", func() {
+				mounted, err := driver.MountAllImages(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounted).To(ConsistOf(randomImageID))
+			})
+		})
+	})
+
+	Describe("TrashImage", func() {
+		var (
+			projectID uint32
+			trashPath string
+		)
+
+		BeforeEach(func() {
+			volumeID := randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			spec.DiskLimit = 1000000000
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			projectID, err = quotapkg.GetProjectID(logger, spec.ImagePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.MkdirAll(filepath.Join(storePath, store.TrashDirName), 0700)).To(Succeed())
+			trashPath = filepath.Join(storePath, store.TrashDirName, "trashed-image")
+		})
+
+		It("unmounts the image and moves it to the trash", func() {
+			Expect(driver.TrashImage(logger, spec.ImagePath, trashPath)).To(Succeed())
+
+			Expect(spec.ImagePath).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(trashPath, overlayxfs.UpperDir)).To(BeADirectory())
+
+			mountInfo, err := ioutil.ReadFile("/proc/self/mountinfo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(mountInfo)).NotTo(ContainSubstring(trashPath))
+			Expect(string(mountInfo)).NotTo(ContainSubstring(spec.ImagePath))
+		})
+
+		It("keeps the project id until the trashed image is destroyed", func() {
+			Expect(driver.TrashImage(logger, spec.ImagePath, trashPath)).To(Succeed())
+
+			released, err := driver.ReconcileProjectIDs(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeEmpty())
+
+			Expect(driver.DestroyImage(logger, trashPath)).To(Succeed())
+			Expect(trashPath).NotTo(BeAnExistingFile())
+
+			Expect(os.Mkdir(spec.ImagePath, 0755)).To(Succeed())
+			_, err = driver.CreateImage(logger, spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quotapkg.GetProjectID(logger, spec.ImagePath)).To(Equal(projectID))
+		})
+
+		Context("when the project id can't be transferred", func() {
+			BeforeEach(func() {
+				allocationsPath := filepath.Join(storePath, overlayxfs.IDDir, ids.AllocationsFileName)
+				Expect(ioutil.WriteFile(allocationsPath, []byte{}, 0644)).To(Succeed())
+			})
+
+			It("moves the image back and returns an error", func() {
+				err := driver.TrashImage(logger, spec.ImagePath, trashPath)
+				Expect(err).To(MatchError(ContainSubstring("transferring the project id")))
+
+				Expect(filepath.Join(spec.ImagePath, overlayxfs.UpperDir)).To(BeADirectory())
+				Expect(trashPath).NotTo(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("ReconcileProjectIDs", func() {
+		var projectID uint32
+
+		BeforeEach(func() {
+			volumeID := randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			spec.DiskLimit = 1000000000
+			spec.Mount = false
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			projectID, err = quotapkg.GetProjectID(logger, spec.ImagePath)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("doesn't release project ids of existing images", func() {
+			released, err := driver.ReconcileProjectIDs(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeEmpty())
+		})
+
+		Context("when the image directory is gone", func() {
+			BeforeEach(func() {
+				Expect(os.RemoveAll(spec.ImagePath)).To(Succeed())
+			})
+
+			It("releases its project id", func() {
+				released, err := driver.ReconcileProjectIDs(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(released).To(ConsistOf(projectID))
+			})
+		})
+	})
+
+	Describe("ClearProjectIDs", func() {
+		var upperDir string
+
+		BeforeEach(func() {
+			volumeID := randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3145728)
+
+			spec.BaseVolumeIDs = []string{volumeID}
+			spec.DiskLimit = 1000000000
+			spec.Mount = false
+			_, err := driver.CreateImage(logger, spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			upperDir = filepath.Join(spec.ImagePath, overlayxfs.UpperDir)
+			Expect(os.MkdirAll(filepath.Join(upperDir, "dir"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(upperDir, "dir", "file"), []byte("hello"), 0644)).To(Succeed())
+			Expect(quotapkg.GetProjectID(logger, filepath.Join(upperDir, "dir"))).NotTo(BeZero())
+		})
+
+		It("takes the directories under the path out of the image's project", func() {
+			Expect(driver.ClearProjectIDs(logger, upperDir)).To(Succeed())
+
+			Expect(quotapkg.GetProjectID(logger, upperDir)).To(BeZero())
+			Expect(quotapkg.GetProjectID(logger, filepath.Join(upperDir, "dir"))).To(BeZero())
+			Expect(quotapkg.GetProjectID(logger, spec.ImagePath)).NotTo(BeZero())
+		})
+
+		It("keeps the files", func() {
+			Expect(driver.ClearProjectIDs(logger, upperDir)).To(Succeed())
+
+			contents, err := ioutil.ReadFile(filepath.Join(upperDir, "dir", "file"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("hello"))
+		})
+	})
+
 	Describe("FetchStats", func() {
 		BeforeEach(func() {
 			volumeID := randVolumeID()
@@ -709,6 +1308,51 @@ var _ = Describe("Driver", func() {
 			Expect(stats.DiskUsage.TotalBytesUsed).To(Equal(int64(3000000 + 4202496)))
 		})
 
+		Context("when the image is read-only", func() {
+			BeforeEach(func() {
+				tmpDir, err := ioutil.TempDir(filepath.Join(storePath, store.ImageDirName), "")
+				Expect(err).NotTo(HaveOccurred())
+				spec.ImagePath = tmpDir
+				spec.DiskLimit = 0
+				spec.ReadOnly = true
+				_, err = driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("reports no exclusive usage", func() {
+				stats, err := driver.FetchStats(logger, spec.ImagePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stats.DiskUsage.ExclusiveBytesUsed).To(BeZero())
+				Expect(stats.DiskUsage.TotalBytesUsed).To(Equal(int64(3000000)))
+			})
+		})
+
+		Context("when the image is ephemeral", func() {
+			BeforeEach(func() {
+				tmpDir, err := ioutil.TempDir(filepath.Join(storePath, store.ImageDirName), "")
+				Expect(err).NotTo(HaveOccurred())
+				spec.ImagePath = tmpDir
+				spec.DiskLimit = 0
+				spec.EphemeralSizeBytes = 8 * 1024 * 1024
+				_, err = driver.CreateImage(logger, spec)
+				Expect(err).ToNot(HaveOccurred())
+
+				dd := exec.Command("dd", "if=/dev/zero", fmt.Sprintf("of=%s/rootfs/file-1", spec.ImagePath), "count=2", "bs=1M")
+				sess, err := gexec.Start(dd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(sess).Should(gexec.Exit(0))
+			})
+
+			It("reports the usage of the tmpfs", func() {
+				stats, err := driver.FetchStats(logger, spec.ImagePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stats.DiskUsage.ExclusiveBytesUsed).To(Equal(int64(2 * 1024 * 1024)))
+				Expect(stats.DiskUsage.TotalBytesUsed).To(Equal(int64(3000000 + 2*1024*1024)))
+			})
+		})
+
 		Context("when path does not exist", func() {
 			var imagePath string
 
@@ -778,6 +1422,23 @@ var _ = Describe("Driver", func() {
 		})
 	})
 
+	Describe("CheckOverlayMountOptions", func() {
+		It("succeeds when the kernel supports the options", func() {
+			Expect(driver.CheckOverlayMountOptions(logger, []string{"index=off", "redirect_dir=off"})).To(Succeed())
+
+			contents, err := ioutil.ReadDir(filepath.Join(storePath, store.TempDirName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(BeEmpty())
+		})
+
+		Context("when the kernel rejects the options", func() {
+			It("returns an error", func() {
+				err := driver.CheckOverlayMountOptions(logger, []string{"redirect_dir=sideways"})
+				Expect(err).To(MatchError(ContainSubstring("overlay mount options redirect_dir=sideways are not supported by the kernel")))
+			})
+		})
+	})
+
 	Describe("ConfigureStore", func() {
 		const (
 			currentUID = 2001
@@ -1166,6 +1827,144 @@ var _ = Describe("Driver", func() {
 		})
 	})
 
+	Describe("FlattenVolumes", func() {
+		var volumeIDs []string
+
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(storePath, store.TempDirName), 0777)).To(Succeed())
+
+			volumeIDs = []string{}
+			for i := 0; i < 4; i++ {
+				volumeID := fmt.Sprintf("%s-%d", randVolumeID(), i)
+				volumePath := createVolume(storePath, driver, "", volumeID, 1024)
+				Expect(ioutil.WriteFile(filepath.Join(volumePath, fmt.Sprintf("file-%d", i)), []byte(volumeID), 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(volumePath, "top"), []byte(volumeID), 0644)).To(Succeed())
+				volumeIDs = append(volumeIDs, volumeID)
+			}
+		})
+
+		Context("when the volumes are within the max depth", func() {
+			It("returns the volumes unchanged", func() {
+				flattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flattenedIDs).To(Equal(volumeIDs))
+			})
+		})
+
+		Context("when the max depth is 0", func() {
+			It("returns the volumes unchanged", func() {
+				flattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flattenedIDs).To(Equal(volumeIDs))
+			})
+		})
+
+		Context("when the volumes exceed the max depth", func() {
+			It("squashes the bottom volumes into a single volume", func() {
+				flattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flattenedIDs).To(Equal([]string{overlayxfs.FlattenedVolumePrefix + volumeIDs[2], volumeIDs[3]}))
+
+				flatPath, err := driver.VolumePath(logger, flattenedIDs[0])
+				Expect(err).NotTo(HaveOccurred())
+				for i := 0; i < 3; i++ {
+					Expect(filepath.Join(flatPath, fmt.Sprintf("file-%d", i))).To(BeAnExistingFile())
+				}
+				Expect(filepath.Join(flatPath, "file-3")).NotTo(BeAnExistingFile())
+
+				contents, err := ioutil.ReadFile(filepath.Join(flatPath, "top"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(volumeIDs[2]))
+			})
+
+			It("records the size of the flattened volume", func() {
+				flattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 2)
+				Expect(err).NotTo(HaveOccurred())
+
+				size, err := driver.VolumeSize(logger, flattenedIDs[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(size).To(BeNumerically(">", 0))
+			})
+
+			It("doesn't leave mounts, intermediate or temporary volumes behind", func() {
+				flattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 2)
+				Expect(err).NotTo(HaveOccurred())
+
+				volumes, err := driver.Volumes(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(volumes).To(ConsistOf(append(volumeIDs, flattenedIDs[0])))
+
+				mounts, err := ioutil.ReadFile("/proc/self/mounts")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(mounts)).NotTo(ContainSubstring(filepath.Join(storePath, store.TempDirName)))
+			})
+
+			It("reuses an existing flattened volume", func() {
+				flattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 2)
+				Expect(err).NotTo(HaveOccurred())
+
+				flatPath, err := driver.VolumePath(logger, flattenedIDs[0])
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ioutil.WriteFile(filepath.Join(flatPath, "marker"), []byte{}, 0644)).To(Succeed())
+
+				secondFlattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(secondFlattenedIDs).To(Equal(flattenedIDs))
+				Expect(filepath.Join(flatPath, "marker")).To(BeAnExistingFile())
+			})
+
+			Context("when a shallower image of the same chain was flattened first", func() {
+				var shallowerIDs []string
+
+				BeforeEach(func() {
+					var err error
+					shallowerIDs, err = driver.FlattenVolumes(logger, volumeIDs[:3], 2)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(shallowerIDs).To(Equal([]string{overlayxfs.FlattenedVolumePrefix + volumeIDs[1], volumeIDs[2]}))
+				})
+
+				It("keeps the flattened volume of the shallower image", func() {
+					flattenedIDs, err := driver.FlattenVolumes(logger, volumeIDs, 2)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(flattenedIDs).To(Equal([]string{overlayxfs.FlattenedVolumePrefix + volumeIDs[2], volumeIDs[3]}))
+
+					volumes, err := driver.Volumes(logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(volumes).To(ConsistOf(append(volumeIDs, shallowerIDs[0], flattenedIDs[0])))
+
+					shallowerPath, err := driver.VolumePath(logger, shallowerIDs[0])
+					Expect(err).NotTo(HaveOccurred())
+					Expect(filepath.Join(shallowerPath, "file-0")).To(BeAnExistingFile())
+					Expect(filepath.Join(shallowerPath, "file-1")).To(BeAnExistingFile())
+				})
+			})
+
+			Context("when an intermediate volume is registered as a dependency", func() {
+				BeforeEach(func() {
+					dependenciesPath := filepath.Join(storePath, store.MetaDirName, "dependencies")
+					Expect(os.MkdirAll(dependenciesPath, 0755)).To(Succeed())
+					intermediateID := overlayxfs.FlattenedVolumePrefix + volumeIDs[1]
+					Expect(dependency_manager.NewDependencyManager(dependenciesPath).Register("image:other-image", []string{intermediateID, volumeIDs[2]})).To(Succeed())
+				})
+
+				It("keeps it", func() {
+					_, err := driver.FlattenVolumes(logger, volumeIDs, 2)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = driver.VolumePath(logger, overlayxfs.FlattenedVolumePrefix+volumeIDs[1])
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when the max depth is 1", func() {
+			It("returns an error", func() {
+				_, err := driver.FlattenVolumes(logger, volumeIDs, 1)
+				Expect(err).To(MatchError(ContainSubstring("max layer depth must be at least 2")))
+			})
+		})
+	})
+
 	Describe("VolumeSize", func() {
 		It("returns the volume size", func() {
 			volumeID := randVolumeID()
@@ -1175,6 +1974,28 @@ var _ = Describe("Driver", func() {
 			Expect(size).To(BeEquivalentTo(3000000))
 		})
 	})
+
+	Describe("TouchVolume", func() {
+		It("records the volume's last use", func() {
+			volumeID := randVolumeID()
+			createVolume(storePath, driver, "parent-id", volumeID, 3000000)
+
+			lastUsed, err := driver.VolumeLastUsed(logger, volumeID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastUsed.IsZero()).To(BeTrue())
+
+			beforeTouch := time.Now()
+			Expect(driver.TouchVolume(logger, volumeID)).To(Succeed())
+
+			lastUsed, err = driver.VolumeLastUsed(logger, volumeID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastUsed).To(BeTemporally(">=", beforeTouch))
+
+			size, err := driver.VolumeSize(logger, volumeID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(BeEquivalentTo(3000000))
+		})
+	})
 })
 
 func randVolumeID() string {
@@ -50,6 +50,14 @@ struct fsxattr {
 #ifndef Q_XGETPQUOTA
 #define Q_XGETPQUOTA QCMD(Q_XGETQUOTA, PRJQUOTA)
 #endif
+
+#ifndef Q_XGETNEXTQUOTA
+#define Q_XGETNEXTQUOTA XQM_CMD(9)
+#endif
+
+#ifndef Q_XGETNEXTPQUOTA
+#define Q_XGETNEXTPQUOTA QCMD(Q_XGETNEXTQUOTA, PRJQUOTA)
+#endif
 */
 import "C"
 import (
@@ -107,6 +115,85 @@ func Get(logger lager.Logger, path string) (Quota, error) {
 	return quota, nil
 }
 
+func GetAll(logger lager.Logger, storePath string) (map[uint32]Quota, error) {
+	logger = logger.Session("get-all-quotas", lager.Data{"storePath": storePath})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	storeDevicePath, err := ensureStoreDevicePath(storePath)
+	if err != nil {
+		logger.Error("ensuring-backing-fs-device-failed", err)
+		return nil, err
+	}
+
+	var cs = C.CString(storeDevicePath)
+	defer C.free(unsafe.Pointer(cs))
+
+	quotas := map[uint32]Quota{}
+	var nextID uint32
+	for {
+		var d C.fs_disk_quota_t
+		_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, C.Q_XGETNEXTPQUOTA,
+			uintptr(unsafe.Pointer(cs)), uintptr(C.__u32(nextID)),
+			uintptr(unsafe.Pointer(&d)), 0, 0)
+		if errno == unix.ENOENT {
+			break
+		}
+		if errno != 0 {
+			logger.Error("getting-next-quota-failed", errno, lager.Data{"projectID": nextID})
+			return nil, errors.Errorf("getting next quota from projid %d: %v",
+				nextID, errno.Error())
+		}
+
+		projectID := uint32(d.d_id)
+		quotas[projectID] = Quota{
+			Size:   uint64(d.d_blk_hardlimit) * 512,
+			BCount: uint64(d.d_bcount) * 512,
+		}
+
+		nextID = projectID + 1
+		if nextID == 0 {
+			break
+		}
+	}
+
+	return quotas, nil
+}
+
+func GetByID(logger lager.Logger, storePath string, projectID uint32) (Quota, error) {
+	logger = logger.Session("get-quota-by-id", lager.Data{"storePath": storePath, "projectID": projectID})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	storeDevicePath, err := ensureStoreDevicePath(storePath)
+	if err != nil {
+		logger.Error("ensuring-backing-fs-device-failed", err)
+		return Quota{}, err
+	}
+
+	var d C.fs_disk_quota_t
+
+	var cs = C.CString(storeDevicePath)
+	defer C.free(unsafe.Pointer(cs))
+
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, C.Q_XGETPQUOTA,
+		uintptr(unsafe.Pointer(cs)), uintptr(C.__u32(projectID)),
+		uintptr(unsafe.Pointer(&d)), 0, 0)
+	if errno == unix.ENOENT {
+		return Quota{}, nil
+	}
+	if errno != 0 {
+		logger.Error("getting-quota-for-project-id-failed", errno)
+		return Quota{}, errors.Errorf("getting quota limit for projid %d: %v",
+			projectID, errno.Error())
+	}
+
+	return Quota{
+		Size:   uint64(d.d_blk_hardlimit) * 512,
+		BCount: uint64(d.d_bcount) * 512,
+	}, nil
+}
+
 func Set(logger lager.Logger, projectID uint32, path string, quotaSize uint64) error {
 	logger = logger.Session("set-quota", lager.Data{"projectID": projectID})
 	logger.Debug("starting")
@@ -123,6 +210,34 @@ func Set(logger lager.Logger, projectID uint32, path string, quotaSize uint64) e
 		return err
 	}
 
+	if err := setLimit(storeDevicePath, projectID, quotaSize); err != nil {
+		logger.Error("setting-quota-to-project-id-failed", err)
+		return err
+	}
+
+	return nil
+}
+
+func Reset(logger lager.Logger, storePath string, projectID uint32) error {
+	logger = logger.Session("reset-quota", lager.Data{"storePath": storePath, "projectID": projectID})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	storeDevicePath, err := ensureStoreDevicePath(storePath)
+	if err != nil {
+		logger.Error("ensuring-backing-fs-device-failed", err)
+		return err
+	}
+
+	if err := setLimit(storeDevicePath, projectID, 0); err != nil {
+		logger.Error("resetting-quota-for-project-id-failed", err)
+		return err
+	}
+
+	return nil
+}
+
+func setLimit(storeDevicePath string, projectID uint32, quotaSize uint64) error {
 	var d C.fs_disk_quota_t
 	d.d_version = C.FS_DQUOT_VERSION
 	d.d_id = C.__u32(projectID)
@@ -139,7 +254,6 @@ func Set(logger lager.Logger, projectID uint32, path string, quotaSize uint64) e
 		uintptr(unsafe.Pointer(cs)), uintptr(d.d_id),
 		uintptr(unsafe.Pointer(&d)), 0, 0)
 	if errno != 0 {
-		logger.Error("setting-quota-to-project-id-failed", errno)
 		return errors.Errorf("setting quota limit for projid %d: %v",
 			projectID, errno.Error())
 	}
@@ -191,10 +305,62 @@ func setProjectID(projectID uint32, path string) error {
 	return nil
 }
 
+// ClearProjectIDs takes the directories and regular files under path out of
+// their project, so that their usage no longer counts against its quota.
+// Symlinks and device nodes can't be opened to change it, but they hold no
+// blocks.
+func ClearProjectIDs(logger lager.Logger, path string) error {
+	logger = logger.Session("clear-projectids", lager.Data{"path": path})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		return clearProjectID(filePath)
+	})
+}
+
+func clearProjectID(path string) error {
+	file, err := os.OpenFile(path, os.O_RDONLY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return errors.Wrapf(err, "opening %s", path)
+	}
+	defer file.Close()
+
+	fsx, err := getFdXattr(file.Fd())
+	if err != nil {
+		return errors.Wrapf(err, "getting extended attributes for %s", path)
+	}
+
+	fsx.fsx_projid = 0
+	fsx.fsx_xflags &^= C.FS_XFLAG_PROJINHERIT
+
+	if err := setFdXattr(file.Fd(), fsx); err != nil {
+		return errors.Wrapf(err, "setting extended attributes for %s", path)
+	}
+
+	return nil
+}
+
 func getXattr(dir *C.DIR) (C.struct_fsxattr, error) {
+	return getFdXattr(getDirFd(dir))
+}
+
+func setXattr(dir *C.DIR, fsx C.struct_fsxattr) error {
+	return setFdXattr(getDirFd(dir), fsx)
+}
+
+func getFdXattr(fd uintptr) (C.struct_fsxattr, error) {
 	var fsx C.struct_fsxattr
 
-	_, _, errno := unix.Syscall(unix.SYS_IOCTL, getDirFd(dir), C.FS_IOC_FSGETXATTR,
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, C.FS_IOC_FSGETXATTR,
 		uintptr(unsafe.Pointer(&fsx)))
 	if errno != 0 {
 		return fsx, errno
@@ -203,8 +369,8 @@ func getXattr(dir *C.DIR) (C.struct_fsxattr, error) {
 	return fsx, nil
 }
 
-func setXattr(dir *C.DIR, fsx C.struct_fsxattr) error {
-	_, _, errno := unix.Syscall(unix.SYS_IOCTL, getDirFd(dir), C.FS_IOC_FSSETXATTR,
+func setFdXattr(fd uintptr, fsx C.struct_fsxattr) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, C.FS_IOC_FSSETXATTR,
 		uintptr(unsafe.Pointer(&fsx)))
 	if errno != 0 {
 		return errno
@@ -214,8 +380,10 @@ func setXattr(dir *C.DIR, fsx C.struct_fsxattr) error {
 }
 
 func getStoreDevicePath(imagePath string) (string, error) {
-	basePath := filepath.Dir(filepath.Dir(imagePath))
+	return ensureStoreDevicePath(filepath.Dir(filepath.Dir(imagePath)))
+}
 
+func ensureStoreDevicePath(basePath string) (string, error) {
 	storeDevicePath := path.Join(basePath, "storeDevice")
 	if _, err := os.Stat(storeDevicePath); err == nil {
 		return storeDevicePath, nil
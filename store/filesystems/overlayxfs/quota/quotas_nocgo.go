@@ -12,12 +12,32 @@ func Get(logger lager.Logger, path string) (Quota, error) {
 	return Quota{}, nil
 }
 
+func GetAll(logger lager.Logger, storePath string) (map[uint32]Quota, error) {
+	logger.Fatal("running-without-cgo-support", errors.New("can't run without cgo support"))
+	return nil, nil
+}
+
+func GetByID(logger lager.Logger, storePath string, projectID uint32) (Quota, error) {
+	logger.Fatal("running-without-cgo-support", errors.New("can't run without cgo support"))
+	return Quota{}, nil
+}
+
 func Set(logger lager.Logger, projectID uint32, path string, quotaSize uint64) error {
 	logger.Fatal("running-without-cgo-support", errors.New("can't run without cgo support"))
 	return nil
 }
 
+func Reset(logger lager.Logger, storePath string, projectID uint32) error {
+	logger.Fatal("running-without-cgo-support", errors.New("can't run without cgo support"))
+	return nil
+}
+
 func GetProjectID(logger lager.Logger, path string) (uint32, error) {
 	logger.Fatal("running-without-cgo-support", errors.New("can't run without cgo support"))
 	return 0, nil
 }
+
+func ClearProjectIDs(logger lager.Logger, path string) error {
+	logger.Fatal("running-without-cgo-support", errors.New("can't run without cgo support"))
+	return nil
+}
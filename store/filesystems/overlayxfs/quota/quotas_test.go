@@ -100,6 +100,43 @@ var _ = Describe("Quotas", func() {
 		})
 	})
 
+	Describe("GetByID", func() {
+		BeforeEach(func() {
+			quota.Set(logger, 600, directory, 10*1024*1024)
+			Eventually(writeFile(filepath.Join(directory, "small-file"), 1024)).Should(gexec.Exit(0))
+		})
+
+		It("returns the quota with usage for the project id", func() {
+			quota, err := quota.GetByID(logger, XfsMountPoint, 600)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quota.Size).To(Equal(uint64(10 * 1024 * 1024)))
+			Expect(quota.BCount).To(Equal(uint64(1024 * 1024)))
+		})
+
+		Context("when the project id has never been used", func() {
+			It("returns an empty quota", func() {
+				quota, err := quota.GetByID(logger, XfsMountPoint, 987654)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quota.Size).To(Equal(uint64(0)))
+				Expect(quota.BCount).To(Equal(uint64(0)))
+			})
+		})
+	})
+
+	Describe("Reset", func() {
+		BeforeEach(func() {
+			quota.Set(logger, 700, directory, 10*1024*1024)
+		})
+
+		It("removes the limit of the project id", func() {
+			Expect(quota.Reset(logger, XfsMountPoint, 700)).To(Succeed())
+
+			quota, err := quota.GetByID(logger, XfsMountPoint, 700)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quota.Size).To(Equal(uint64(0)))
+		})
+	})
+
 	Describe("GetProjectID", func() {
 		BeforeEach(func() {
 			quota.Set(logger, 1024, directory, 10*1024*1024)
@@ -0,0 +1,38 @@
+package commands // import "code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/tardis/commands"
+
+import (
+	"os"
+
+	quotapkg "code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/quota"
+	"code.cloudfoundry.org/lager"
+	errorspkg "github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var ClearProjectIDsCommand = cli.Command{
+	Name:        "clear-project-ids",
+	Usage:       "clear-project-ids --path <path>",
+	Description: "Take the files under a directory out of their project quota.",
+
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "Path to the directory",
+		},
+	},
+
+	Action: func(ctx *cli.Context) error {
+		logger := lager.NewLogger("tardis")
+		logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.DEBUG))
+		logger.Info("starting")
+		defer logger.Info("ending")
+
+		path := ctx.String("path")
+		if err := quotapkg.ClearProjectIDs(logger, path); err != nil {
+			logger.Error("clearing-project-ids-failed", err)
+			return errorspkg.Wrapf(err, "clearing project ids of %s", path)
+		}
+
+		return nil
+	},
+}
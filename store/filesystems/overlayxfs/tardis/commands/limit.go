@@ -38,8 +38,8 @@ var LimitCommand = cli.Command{
 		imagesPath := filepath.Dir(imagePath)
 
 		diskLimit := uint64(ctx.Int64("disk-limit-bytes"))
-		idDiscoverer := ids.NewDiscoverer(filepath.Join(filepath.Dir(imagesPath), overlayxfs.IDDir))
-		projectID, err := idDiscoverer.Alloc(logger)
+		idAllocator := ids.NewAllocator(filepath.Join(filepath.Dir(imagesPath), overlayxfs.IDDir))
+		projectID, err := idAllocator.Alloc(logger, imagePath)
 		if err != nil {
 			logger.Error("allocating-project-id", err)
 			return errorspkg.Wrap(err, "allocating project id")
@@ -11,7 +11,7 @@ import (
 
 var StatsCommand = cli.Command{
 	Name:        "stats",
-	Usage:       "stats --volume-path <path>",
+	Usage:       "stats --volume-path <path> | stats --all --store-path <path>",
 	Description: "Get stats for a volume",
 
 	Flags: []cli.Flag{
@@ -19,17 +19,40 @@ var StatsCommand = cli.Command{
 			Name:  "volume-path",
 			Usage: "Path to the volume",
 		},
+		cli.BoolFlag{
+			Name:  "all",
+			Usage: "Get stats for all images in the store",
+		},
+		cli.StringFlag{
+			Name:  "store-path",
+			Usage: "Path to the store (used with --all)",
+		},
 	},
 
 	Action: func(ctx *cli.Context) error {
 		logger := lager.NewLogger("tardis")
 		logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.DEBUG))
 
-		volumeStats, err := stats.VolumeStats(
-			logger,
-			ctx.String("volume-path"),
+		var (
+			volumeStats interface{}
+			err         error
 		)
 
+		if ctx.Bool("all") {
+			if ctx.String("store-path") == "" {
+				return cli.NewExitError("--store-path is required when using --all", 1)
+			}
+			volumeStats, err = stats.AllVolumeStats(
+				logger,
+				ctx.String("store-path"),
+			)
+		} else {
+			volumeStats, err = stats.VolumeStats(
+				logger,
+				ctx.String("volume-path"),
+			)
+		}
+
 		if err != nil {
 			logger.Error("fetching-volume-stats", err)
 			return cli.NewExitError(err.Error(), 1)
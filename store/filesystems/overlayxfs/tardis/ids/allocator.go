@@ -0,0 +1,374 @@
+package ids
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"code.cloudfoundry.org/grootfs/store"
+	quotapkg "code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/quota"
+	"code.cloudfoundry.org/lager"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	AllocationsFileName = "allocations.json"
+	firstProjectID      = 2
+)
+
+// allocations are the allocated IDs, with the image directory that owns each
+// of them, and the released IDs waiting to be reused. The bitmap is what
+// tells whether an ID is allocated: owners are only known for IDs allocated
+// by this allocator.
+type allocations struct {
+	Next     uint32            `json:"next"`
+	Bitmap   []byte            `json:"bitmap"`
+	FreeList []uint32          `json:"free_list"`
+	Owners   map[uint32]string `json:"owners"`
+}
+
+func (a *allocations) isAllocated(id uint32) bool {
+	idx := id / 8
+	if int(idx) >= len(a.Bitmap) {
+		return false
+	}
+	return a.Bitmap[idx]&(1<<(id%8)) != 0
+}
+
+func (a *allocations) allocate(id uint32, owner string) {
+	idx := int(id / 8)
+	if idx >= len(a.Bitmap) {
+		a.Bitmap = append(a.Bitmap, make([]byte, idx-len(a.Bitmap)+1)...)
+	}
+	a.Bitmap[idx] |= 1 << (id % 8)
+	a.Owners[id] = owner
+	if id >= a.Next {
+		a.Next = id + 1
+	}
+}
+
+func (a *allocations) release(id uint32) bool {
+	if !a.isAllocated(id) {
+		return false
+	}
+	a.Bitmap[id/8] &^= 1 << (id % 8)
+	delete(a.Owners, id)
+	a.FreeList = append(a.FreeList, id)
+	return true
+}
+
+func NewAllocator(idsPath string) *Allocator {
+	return &Allocator{
+		idsPath:   idsPath,
+		storePath: filepath.Dir(idsPath),
+	}
+}
+
+// Allocator hands out XFS project IDs. Allocations are persisted in the
+// project ids directory and every operation holds an exclusive lock on that
+// file, so it's safe to use across concurrent grootfs/tardis processes.
+type Allocator struct {
+	idsPath   string
+	storePath string
+}
+
+func (i *Allocator) Alloc(logger lager.Logger, imagePath string) (projId uint32, err error) {
+	logger = logger.Session("project-id-allocation", lager.Data{"imagePath": imagePath})
+	logger.Debug("starting")
+	defer func() {
+		logger.Debug("ending", lager.Data{"projectID": projId})
+	}()
+
+	err = i.withAllocations(logger, func(allocs *allocations) error {
+		// Released IDs that files still charge blocks to stay in the free
+		// list, and are tried again by the next allocations.
+		stillInUse := []uint32{}
+		defer func() {
+			allocs.FreeList = append(allocs.FreeList, stillInUse...)
+		}()
+
+		for len(allocs.FreeList) > 0 {
+			id := allocs.FreeList[0]
+			allocs.FreeList = allocs.FreeList[1:]
+			if allocs.isAllocated(id) {
+				continue
+			}
+
+			if err := i.resetQuota(logger, id); err != nil {
+				logger.Info("skipping-project-id-in-use", lager.Data{"projectID": id, "error": err.Error()})
+				stillInUse = append(stillInUse, id)
+				continue
+			}
+
+			allocs.allocate(id, imagePath)
+			projId = id
+			return nil
+		}
+
+		projId = allocs.Next
+		allocs.allocate(projId, imagePath)
+		return nil
+	})
+
+	return projId, err
+}
+
+func (i *Allocator) Release(logger lager.Logger, projectID uint32) error {
+	logger = logger.Session("project-id-release", lager.Data{"projectID": projectID})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	return i.withAllocations(logger, func(allocs *allocations) error {
+		if !allocs.release(projectID) {
+			logger.Debug("project-id-not-allocated")
+		}
+		return nil
+	})
+}
+
+// Transfer records a new owner for an allocated project ID, when its image
+// directory moves, so that reconciliation doesn't release it while the
+// directory still uses it.
+func (i *Allocator) Transfer(logger lager.Logger, projectID uint32, owner string) error {
+	logger = logger.Session("project-id-transfer", lager.Data{"projectID": projectID, "owner": owner})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	return i.withAllocations(logger, func(allocs *allocations) error {
+		if !allocs.isAllocated(projectID) {
+			logger.Debug("project-id-not-allocated")
+			return nil
+		}
+		allocs.Owners[projectID] = owner
+		return nil
+	})
+}
+
+// Reconcile releases project IDs whose image directory no longer exists. IDs
+// imported from older stores without a known owner are only released once no
+// files charge blocks to them, which can only be checked by root: the store
+// owner of rootless stores keeps them allocated.
+func (i *Allocator) Reconcile(logger lager.Logger) (released []uint32, err error) {
+	logger = logger.Session("project-id-reconciliation")
+	logger.Debug("starting")
+	defer func() {
+		logger.Debug("ending", lager.Data{"released": released})
+	}()
+
+	err = i.withAllocations(logger, func(allocs *allocations) error {
+		for id, owner := range allocs.Owners {
+			if owner != "" {
+				if _, err := os.Stat(owner); err == nil || !os.IsNotExist(err) {
+					continue
+				}
+			} else if err := i.checkUnused(logger, id); err != nil {
+				logger.Info("keeping-ownerless-project-id", lager.Data{"projectID": id, "error": err.Error()})
+				continue
+			}
+
+			allocs.release(id)
+			released = append(released, id)
+		}
+		return nil
+	})
+
+	return released, err
+}
+
+func (i *Allocator) checkUnused(logger lager.Logger, projectID uint32) error {
+	quota, err := quotapkg.GetByID(logger, i.storePath, projectID)
+	if err != nil {
+		return errors.Wrapf(err, "checking quota for project id %d", projectID)
+	}
+
+	if quota.BCount != 0 {
+		return errors.Errorf("project id %d still has %d bytes accounted", projectID, quota.BCount)
+	}
+
+	return nil
+}
+
+// resetQuota clears the limit of a released project ID before it's reused,
+// unless files still charge blocks to it.
+func (i *Allocator) resetQuota(logger lager.Logger, projectID uint32) error {
+	if err := i.checkUnused(logger, projectID); err != nil {
+		return err
+	}
+
+	if err := quotapkg.Reset(logger, i.storePath, projectID); err != nil {
+		return errors.Wrapf(err, "resetting quota for project id %d", projectID)
+	}
+
+	return nil
+}
+
+// withAllocations runs fn on the allocations under an exclusive lock on the ids
+// directory, and replaces the allocations file with the result. The file is
+// replaced atomically, so that a crash leaves either the old or the new
+// allocations behind.
+func (i *Allocator) withAllocations(logger lager.Logger, fn func(*allocations) error) error {
+	idsDir, err := os.Open(i.idsPath)
+	if err != nil {
+		return errors.Wrap(err, "opening project ids directory")
+	}
+	defer idsDir.Close()
+
+	fd := int(idsDir.Fd())
+	if err := syscall.Flock(fd, syscall.LOCK_EX); err != nil {
+		return errors.Wrap(err, "locking project ids directory")
+	}
+	defer syscall.Flock(fd, syscall.LOCK_UN)
+
+	allocs := &allocations{Next: firstProjectID, Owners: map[uint32]string{}}
+	legacyDirs := []string{}
+	contents, err := ioutil.ReadFile(i.allocationsPath())
+	if os.IsNotExist(err) {
+		if legacyDirs, err = i.importLegacyIDs(logger, allocs); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return errors.Wrap(err, "reading allocations file")
+	} else if len(contents) == 0 {
+		// never written this way, so the allocations were lost: the legacy
+		// directories may be gone already, so they can't be imported again
+		return errors.New("allocations file is empty")
+	} else if err := json.Unmarshal(contents, allocs); err != nil {
+		return errors.Wrap(err, "parsing allocations file")
+	}
+
+	// files written before the bitmap was persisted only have the owners
+	for id := range allocs.Owners {
+		allocs.allocate(id, allocs.Owners[id])
+	}
+
+	if err := fn(allocs); err != nil {
+		return err
+	}
+
+	if err := i.writeAllocations(allocs); err != nil {
+		return err
+	}
+
+	// legacy directories are only removed once their IDs are persisted
+	for _, dir := range legacyDirs {
+		if err := os.Remove(dir); err != nil {
+			logger.Error("removing-legacy-project-id-failed", err, lager.Data{"path": dir})
+		}
+	}
+
+	return nil
+}
+
+func (i *Allocator) writeAllocations(allocs *allocations) error {
+	contents, err := json.Marshal(allocs)
+	if err != nil {
+		return errors.Wrap(err, "encoding allocations")
+	}
+
+	tempFile, err := ioutil.TempFile(i.idsPath, AllocationsFileName+".")
+	if err != nil {
+		return errors.Wrap(err, "creating allocations file")
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	if _, err := tempFile.Write(contents); err != nil {
+		return errors.Wrap(err, "writing allocations file")
+	}
+
+	if err := tempFile.Chmod(0644); err != nil {
+		return errors.Wrap(err, "chmoding allocations file")
+	}
+
+	if err := i.matchOwnership(tempFile); err != nil {
+		return err
+	}
+
+	if err := tempFile.Sync(); err != nil {
+		return errors.Wrap(err, "syncing allocations file")
+	}
+
+	if err := os.Rename(tempFile.Name(), i.allocationsPath()); err != nil {
+		return errors.Wrap(err, "replacing allocations file")
+	}
+
+	return nil
+}
+
+func (i *Allocator) allocationsPath() string {
+	return filepath.Join(i.idsPath, AllocationsFileName)
+}
+
+// The allocations file is written by tardis, which runs as root, but
+// releasing IDs happens as the store owner.
+func (i *Allocator) matchOwnership(allocsFile *os.File) error {
+	idsInfo, err := os.Stat(i.idsPath)
+	if err != nil {
+		return errors.Wrap(err, "reading directory")
+	}
+	fileInfo, err := allocsFile.Stat()
+	if err != nil {
+		return errors.Wrap(err, "stating allocations file")
+	}
+
+	idsStat := idsInfo.Sys().(*syscall.Stat_t)
+	fileStat := fileInfo.Sys().(*syscall.Stat_t)
+	if idsStat.Uid == fileStat.Uid && idsStat.Gid == fileStat.Gid {
+		return nil
+	}
+
+	if err := allocsFile.Chown(int(idsStat.Uid), int(idsStat.Gid)); err != nil {
+		return errors.Wrap(err, "chowning allocations file")
+	}
+
+	return nil
+}
+
+// Older stores track each allocated ID as a directory named after it. The
+// directories are returned to be removed once the IDs are persisted.
+func (i *Allocator) importLegacyIDs(logger lager.Logger, allocs *allocations) ([]string, error) {
+	entries, err := ioutil.ReadDir(i.idsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading directory")
+	}
+
+	owners := i.imageOwners(logger)
+	legacyDirs := []string{}
+	for _, entry := range entries {
+		id, err := strconv.ParseUint(entry.Name(), 10, 32)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		allocs.allocate(uint32(id), owners[uint32(id)])
+		legacyDirs = append(legacyDirs, filepath.Join(i.idsPath, entry.Name()))
+	}
+
+	return legacyDirs, nil
+}
+
+func (i *Allocator) imageOwners(logger lager.Logger) map[uint32]string {
+	owners := map[uint32]string{}
+
+	imagesPath := filepath.Join(i.storePath, store.ImageDirName)
+	images, err := ioutil.ReadDir(imagesPath)
+	if err != nil {
+		logger.Error("reading-images-failed", err)
+		return owners
+	}
+
+	for _, image := range images {
+		imagePath := filepath.Join(imagesPath, image.Name())
+		projectID, err := quotapkg.GetProjectID(logger, imagePath)
+		if err != nil || projectID == 0 {
+			continue
+		}
+		owners[projectID] = imagePath
+	}
+
+	return owners
+}
@@ -0,0 +1,341 @@
+package ids_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"code.cloudfoundry.org/grootfs/store"
+	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs"
+	quotapkg "code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/quota"
+	"code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/tardis/ids"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Allocator", func() {
+	var (
+		logger     lager.Logger
+		allocator  *ids.Allocator
+		idDirPath  string
+		imagesPath string
+		imagePath  string
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-logger")
+		idDirPath = filepath.Join(StorePath, overlayxfs.IDDir)
+		imagesPath = filepath.Join(StorePath, store.ImageDirName)
+		allocator = ids.NewAllocator(idDirPath)
+
+		Expect(os.MkdirAll(StorePath, 0777)).To(Succeed())
+		Expect(os.MkdirAll(idDirPath, 0777)).To(Succeed())
+		Expect(os.MkdirAll(imagesPath, 0777)).To(Succeed())
+
+		imagePath = filepath.Join(imagesPath, "my-image")
+		Expect(os.Mkdir(imagePath, 0755)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(idDirPath)).To(Succeed())
+		Expect(os.RemoveAll(imagesPath)).To(Succeed())
+	})
+
+	Describe("Alloc", func() {
+		Context("when the id dir is empty", func() {
+			It("allocates the first available number, which starts at 2", func() {
+				id, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(uint32(2)))
+			})
+
+			It("always allocates unique numbers", func() {
+				id, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(uint32(2)))
+
+				id, err = allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(uint32(3)))
+
+				id, err = allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(uint32(4)))
+			})
+
+			It("persists the allocations in the id dir", func() {
+				_, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(idDirPath, ids.AllocationsFileName)).To(BeAnExistingFile())
+				entries, err := ioutil.ReadDir(idDirPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1), "temporary allocation files were left behind")
+
+				id, err := ids.NewAllocator(idDirPath).Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(uint32(3)))
+			})
+
+			It("can be ran in parallel, without colisions", func() {
+				concurrency := 1000
+				ids := make([]int, concurrency)
+				wg := sync.WaitGroup{}
+
+				wg.Add(concurrency)
+				for i := 0; i < concurrency; i++ {
+					go func(i int) {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						id, err := allocator.Alloc(logger, imagePath)
+						Expect(err).NotTo(HaveOccurred())
+						ids[i] = int(id)
+					}(i)
+				}
+
+				wg.Wait()
+				Expect(Duplicates(ids)).To(BeEmpty())
+			})
+		})
+
+		Context("when an id has been released", func() {
+			var releasedID uint32
+
+			BeforeEach(func() {
+				var err error
+				releasedID, err = allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(quotapkg.Set(logger, releasedID, imagePath, 1024*1024)).To(Succeed())
+				Expect(allocator.Release(logger, releasedID)).To(Succeed())
+			})
+
+			It("reuses it", func() {
+				id, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(releasedID))
+			})
+
+			It("resets the quota of the reused id", func() {
+				_, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				quota, err := quotapkg.GetByID(logger, StorePath, releasedID)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(quota.Size).To(Equal(uint64(0)))
+			})
+
+			Context("when the released id still has usage accounted", func() {
+				BeforeEach(func() {
+					Expect(writeFile(filepath.Join(imagePath, "leftover"), 1024)).To(Succeed())
+				})
+
+				It("skips it", func() {
+					id, err := allocator.Alloc(logger, imagePath)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(id).NotTo(Equal(releasedID))
+				})
+
+				It("reuses it once the usage is gone", func() {
+					_, err := allocator.Alloc(logger, imagePath)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(os.Remove(filepath.Join(imagePath, "leftover"))).To(Succeed())
+					id, err := allocator.Alloc(logger, imagePath)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(id).To(Equal(releasedID))
+				})
+			})
+		})
+
+		Context("when the store has legacy project id directories", func() {
+			BeforeEach(func() {
+				Expect(os.Mkdir(filepath.Join(idDirPath, "2"), 0755)).To(Succeed())
+				Expect(os.Mkdir(filepath.Join(idDirPath, "5"), 0755)).To(Succeed())
+			})
+
+			It("allocates after the highest legacy id", func() {
+				id, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(uint32(6)))
+			})
+
+			It("removes the legacy directories", func() {
+				_, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(idDirPath, "2")).NotTo(BeADirectory())
+				Expect(filepath.Join(idDirPath, "5")).NotTo(BeADirectory())
+			})
+
+			It("releases the unused legacy ids no image owns on reconcile", func() {
+				released, err := allocator.Reconcile(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(released).To(ConsistOf(uint32(2), uint32(5)))
+			})
+
+			Context("when files still charge blocks to a legacy id no image owns", func() {
+				var chargedPath string
+
+				BeforeEach(func() {
+					chargedPath = filepath.Join(StorePath, "charged")
+					Expect(os.Mkdir(chargedPath, 0755)).To(Succeed())
+					Expect(quotapkg.Set(logger, 5, chargedPath, 1024*1024)).To(Succeed())
+					Expect(writeFile(filepath.Join(chargedPath, "data"), 64)).To(Succeed())
+				})
+
+				AfterEach(func() {
+					Expect(os.RemoveAll(chargedPath)).To(Succeed())
+				})
+
+				It("keeps it allocated on reconcile", func() {
+					released, err := allocator.Reconcile(logger)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(released).To(ConsistOf(uint32(2)))
+				})
+
+				It("doesn't reset its quota when it's freed", func() {
+					Expect(allocator.Release(logger, 5)).To(Succeed())
+					_, err := allocator.Alloc(logger, imagePath)
+					Expect(err).NotTo(HaveOccurred())
+
+					quota, err := quotapkg.GetByID(logger, StorePath, 5)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(quota.Size).To(Equal(uint64(1024 * 1024)))
+				})
+			})
+		})
+
+		Context("when there's an error reading the ids dir", func() {
+			BeforeEach(func() {
+				Expect(os.Remove(idDirPath)).To(Succeed())
+			})
+
+			It("returns an error", func() {
+				_, err := allocator.Alloc(logger, imagePath)
+				Expect(err).To(MatchError(ContainSubstring("opening project ids directory")))
+			})
+		})
+
+		Context("when the allocations file is empty", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(filepath.Join(idDirPath, ids.AllocationsFileName), []byte{}, 0644)).To(Succeed())
+				Expect(os.Mkdir(filepath.Join(idDirPath, "2"), 0755)).To(Succeed())
+			})
+
+			It("returns an error instead of importing legacy ids", func() {
+				_, err := allocator.Alloc(logger, imagePath)
+				Expect(err).To(MatchError(ContainSubstring("allocations file is empty")))
+				Expect(filepath.Join(idDirPath, "2")).To(BeADirectory())
+			})
+		})
+	})
+
+	Describe("Release", func() {
+		Context("when the id is not allocated", func() {
+			It("doesn't fail", func() {
+				Expect(allocator.Release(logger, 42)).To(Succeed())
+			})
+
+			It("doesn't make the id available", func() {
+				Expect(allocator.Release(logger, 42)).To(Succeed())
+
+				id, err := allocator.Alloc(logger, imagePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(uint32(2)))
+			})
+		})
+	})
+
+	Describe("Transfer", func() {
+		It("keeps the id allocated while the new owner exists", func() {
+			id, err := allocator.Alloc(logger, imagePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			movedImagePath := filepath.Join(imagesPath, "moved-image")
+			Expect(os.Rename(imagePath, movedImagePath)).To(Succeed())
+			Expect(allocator.Transfer(logger, id, movedImagePath)).To(Succeed())
+
+			released, err := allocator.Reconcile(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeEmpty())
+
+			Expect(os.Remove(movedImagePath)).To(Succeed())
+			released, err = allocator.Reconcile(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(ConsistOf(id))
+		})
+
+		Context("when the id is not allocated", func() {
+			It("doesn't allocate it", func() {
+				Expect(allocator.Transfer(logger, 42, filepath.Join(imagesPath, "not-here"))).To(Succeed())
+
+				released, err := allocator.Reconcile(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(released).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Reconcile", func() {
+		var goneImagePath string
+
+		BeforeEach(func() {
+			goneImagePath = filepath.Join(imagesPath, "gone-image")
+			Expect(os.Mkdir(goneImagePath, 0755)).To(Succeed())
+
+			_, err := allocator.Alloc(logger, imagePath)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = allocator.Alloc(logger, goneImagePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.Remove(goneImagePath)).To(Succeed())
+		})
+
+		It("releases the ids of images that no longer exist", func() {
+			released, err := allocator.Reconcile(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(ConsistOf(uint32(3)))
+
+			id, err := allocator.Alloc(logger, imagePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal(uint32(3)))
+		})
+
+		It("is idempotent", func() {
+			_, err := allocator.Reconcile(logger)
+			Expect(err).NotTo(HaveOccurred())
+
+			released, err := allocator.Reconcile(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(released).To(BeEmpty())
+		})
+	})
+})
+
+func writeFile(path string, sizeKb int) error {
+	return exec.Command("dd", "if=/dev/zero", fmt.Sprintf("of=%s", path), "bs=1K", fmt.Sprintf("count=%d", sizeKb)).Run()
+}
+
+func Duplicates(input []int) []int {
+	u := make([]int, 0, len(input))
+	m := make(map[int]bool)
+
+	for _, val := range input {
+		if _, ok := m[val]; !ok {
+			m[val] = true
+		} else {
+			u = append(u, val)
+		}
+	}
+
+	return u
+}
@@ -24,6 +24,7 @@ func main() {
 		commands.LimitCommand,
 		commands.StatsCommand,
 		commands.HandleOpqWhiteoutsCommand,
+		commands.ClearProjectIDsCommand,
 	}
 
 	tardis.Run(os.Args)
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store"
 	quotapkg "code.cloudfoundry.org/grootfs/store/filesystems/overlayxfs/quota"
 	"code.cloudfoundry.org/lager"
 	errorspkg "github.com/pkg/errors"
@@ -44,6 +45,60 @@ func VolumeStats(logger lager.Logger, imagePath string) (groot.VolumeStats, erro
 	}, nil
 }
 
+func AllVolumeStats(logger lager.Logger, storePath string) (map[string]groot.VolumeStats, error) {
+	logger = logger.Session("overlayxfs-fetching-all-stats", lager.Data{"storePath": storePath})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	imagesPath := filepath.Join(storePath, store.ImageDirName)
+	images, err := ioutil.ReadDir(imagesPath)
+	if err != nil {
+		logger.Error("listing-images-failed", err)
+		return nil, errorspkg.Wrapf(err, "listing images %s", imagesPath)
+	}
+
+	quotas, err := quotapkg.GetAll(logger, storePath)
+	if err != nil {
+		logger.Error("get-all-quotas-failed", err)
+		return nil, errorspkg.Wrapf(err, "getting quotas for store %s", storePath)
+	}
+
+	// Stats don't take the store lock, so images can be half created or
+	// deleted while they are listed. Those are skipped rather than failing the
+	// stats of every other image.
+	allStats := map[string]groot.VolumeStats{}
+	for _, image := range images {
+		imagePath := filepath.Join(imagesPath, image.Name())
+
+		projectID, err := quotapkg.GetProjectID(logger, imagePath)
+		if err != nil {
+			logger.Info("skipping-image-without-project-id", lager.Data{"imagePath": imagePath, "error": err.Error()})
+			continue
+		}
+
+		var exclusiveSize int64
+		if projectID != 0 {
+			exclusiveSize = int64(quotas[projectID].BCount)
+		}
+
+		volumeSize, err := readImageInfo(logger, imagePath)
+		if err != nil {
+			logger.Info("skipping-image-without-image-info", lager.Data{"imagePath": imagePath, "error": err.Error()})
+			continue
+		}
+
+		allStats[image.Name()] = groot.VolumeStats{
+			DiskUsage: groot.DiskUsage{
+				ExclusiveBytesUsed: exclusiveSize,
+				TotalBytesUsed:     volumeSize + exclusiveSize,
+			},
+		}
+	}
+
+	logger.Debug("usage", lager.Data{"totalImages": len(allStats)})
+	return allStats, nil
+}
+
 func listQuotaUsage(logger lager.Logger, imagePath string) (int64, error) {
 	logger = logger.Session("listing-quota-usage", lager.Data{"imagePath": imagePath})
 	logger.Debug("starting")
@@ -8,6 +8,17 @@ import (
 )
 
 type FakeDependencyManager struct {
+	DeregisterStub        func(id string) error
+	deregisterMutex       sync.RWMutex
+	deregisterArgsForCall []struct {
+		id string
+	}
+	deregisterReturns struct {
+		result1 error
+	}
+	deregisterReturnsOnCall map[int]struct {
+		result1 error
+	}
 	DependenciesStub        func(id string) ([]string, error)
 	dependenciesMutex       sync.RWMutex
 	dependenciesArgsForCall []struct {
@@ -21,10 +32,84 @@ type FakeDependencyManager struct {
 		result1 []string
 		result2 error
 	}
+	MetadataStub        func(id string) (map[string]string, error)
+	metadataMutex       sync.RWMutex
+	metadataArgsForCall []struct {
+		id string
+	}
+	metadataReturns struct {
+		result1 map[string]string
+		result2 error
+	}
+	metadataReturnsOnCall map[int]struct {
+		result1 map[string]string
+		result2 error
+	}
+	ListStub        func(prefix string) ([]string, error)
+	listMutex       sync.RWMutex
+	listArgsForCall []struct {
+		prefix string
+	}
+	listReturns struct {
+		result1 []string
+		result2 error
+	}
+	listReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeDependencyManager) Deregister(id string) error {
+	fake.deregisterMutex.Lock()
+	ret, specificReturn := fake.deregisterReturnsOnCall[len(fake.deregisterArgsForCall)]
+	fake.deregisterArgsForCall = append(fake.deregisterArgsForCall, struct {
+		id string
+	}{id})
+	fake.recordInvocation("Deregister", []interface{}{id})
+	fake.deregisterMutex.Unlock()
+	if fake.DeregisterStub != nil {
+		return fake.DeregisterStub(id)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.deregisterReturns.result1
+}
+
+func (fake *FakeDependencyManager) DeregisterCallCount() int {
+	fake.deregisterMutex.RLock()
+	defer fake.deregisterMutex.RUnlock()
+	return len(fake.deregisterArgsForCall)
+}
+
+func (fake *FakeDependencyManager) DeregisterArgsForCall(i int) string {
+	fake.deregisterMutex.RLock()
+	defer fake.deregisterMutex.RUnlock()
+	return fake.deregisterArgsForCall[i].id
+}
+
+func (fake *FakeDependencyManager) DeregisterReturns(result1 error) {
+	fake.DeregisterStub = nil
+	fake.deregisterReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDependencyManager) DeregisterReturnsOnCall(i int, result1 error) {
+	fake.DeregisterStub = nil
+	if fake.deregisterReturnsOnCall == nil {
+		fake.deregisterReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deregisterReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeDependencyManager) Dependencies(id string) ([]string, error) {
 	fake.dependenciesMutex.Lock()
 	ret, specificReturn := fake.dependenciesReturnsOnCall[len(fake.dependenciesArgsForCall)]
@@ -76,11 +161,119 @@ func (fake *FakeDependencyManager) DependenciesReturnsOnCall(i int, result1 []st
 	}{result1, result2}
 }
 
+func (fake *FakeDependencyManager) Metadata(id string) (map[string]string, error) {
+	fake.metadataMutex.Lock()
+	ret, specificReturn := fake.metadataReturnsOnCall[len(fake.metadataArgsForCall)]
+	fake.metadataArgsForCall = append(fake.metadataArgsForCall, struct {
+		id string
+	}{id})
+	fake.recordInvocation("Metadata", []interface{}{id})
+	fake.metadataMutex.Unlock()
+	if fake.MetadataStub != nil {
+		return fake.MetadataStub(id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.metadataReturns.result1, fake.metadataReturns.result2
+}
+
+func (fake *FakeDependencyManager) MetadataCallCount() int {
+	fake.metadataMutex.RLock()
+	defer fake.metadataMutex.RUnlock()
+	return len(fake.metadataArgsForCall)
+}
+
+func (fake *FakeDependencyManager) MetadataArgsForCall(i int) string {
+	fake.metadataMutex.RLock()
+	defer fake.metadataMutex.RUnlock()
+	return fake.metadataArgsForCall[i].id
+}
+
+func (fake *FakeDependencyManager) MetadataReturns(result1 map[string]string, result2 error) {
+	fake.MetadataStub = nil
+	fake.metadataReturns = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) MetadataReturnsOnCall(i int, result1 map[string]string, result2 error) {
+	fake.MetadataStub = nil
+	if fake.metadataReturnsOnCall == nil {
+		fake.metadataReturnsOnCall = make(map[int]struct {
+			result1 map[string]string
+			result2 error
+		})
+	}
+	fake.metadataReturnsOnCall[i] = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) List(prefix string) ([]string, error) {
+	fake.listMutex.Lock()
+	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
+	fake.listArgsForCall = append(fake.listArgsForCall, struct {
+		prefix string
+	}{prefix})
+	fake.recordInvocation("List", []interface{}{prefix})
+	fake.listMutex.Unlock()
+	if fake.ListStub != nil {
+		return fake.ListStub(prefix)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.listReturns.result1, fake.listReturns.result2
+}
+
+func (fake *FakeDependencyManager) ListCallCount() int {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return len(fake.listArgsForCall)
+}
+
+func (fake *FakeDependencyManager) ListArgsForCall(i int) string {
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
+	return fake.listArgsForCall[i].prefix
+}
+
+func (fake *FakeDependencyManager) ListReturns(result1 []string, result2 error) {
+	fake.ListStub = nil
+	fake.listReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDependencyManager) ListReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.ListStub = nil
+	if fake.listReturnsOnCall == nil {
+		fake.listReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.listReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeDependencyManager) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.deregisterMutex.RLock()
+	defer fake.deregisterMutex.RUnlock()
 	fake.dependenciesMutex.RLock()
 	defer fake.dependenciesMutex.RUnlock()
+	fake.metadataMutex.RLock()
+	defer fake.metadataMutex.RUnlock()
+	fake.listMutex.RLock()
+	defer fake.listMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -3,6 +3,7 @@ package garbage_collectorfakes
 
 import (
 	"sync"
+	"time"
 
 	"code.cloudfoundry.org/grootfs/store/garbage_collector"
 	"code.cloudfoundry.org/lager"
@@ -61,6 +62,34 @@ type FakeVolumeDriver struct {
 		result1 []string
 		result2 error
 	}
+	VolumeSizeStub        func(logger lager.Logger, id string) (int64, error)
+	volumeSizeMutex       sync.RWMutex
+	volumeSizeArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	volumeSizeReturns struct {
+		result1 int64
+		result2 error
+	}
+	volumeSizeReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
+	VolumeLastUsedStub        func(logger lager.Logger, id string) (time.Time, error)
+	volumeLastUsedMutex       sync.RWMutex
+	volumeLastUsedArgsForCall []struct {
+		logger lager.Logger
+		id     string
+	}
+	volumeLastUsedReturns struct {
+		result1 time.Time
+		result2 error
+	}
+	volumeLastUsedReturnsOnCall map[int]struct {
+		result1 time.Time
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -267,6 +296,110 @@ func (fake *FakeVolumeDriver) VolumesReturnsOnCall(i int, result1 []string, resu
 	}{result1, result2}
 }
 
+func (fake *FakeVolumeDriver) VolumeSize(logger lager.Logger, id string) (int64, error) {
+	fake.volumeSizeMutex.Lock()
+	ret, specificReturn := fake.volumeSizeReturnsOnCall[len(fake.volumeSizeArgsForCall)]
+	fake.volumeSizeArgsForCall = append(fake.volumeSizeArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("VolumeSize", []interface{}{logger, id})
+	fake.volumeSizeMutex.Unlock()
+	if fake.VolumeSizeStub != nil {
+		return fake.VolumeSizeStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.volumeSizeReturns.result1, fake.volumeSizeReturns.result2
+}
+
+func (fake *FakeVolumeDriver) VolumeSizeCallCount() int {
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	return len(fake.volumeSizeArgsForCall)
+}
+
+func (fake *FakeVolumeDriver) VolumeSizeArgsForCall(i int) (lager.Logger, string) {
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	return fake.volumeSizeArgsForCall[i].logger, fake.volumeSizeArgsForCall[i].id
+}
+
+func (fake *FakeVolumeDriver) VolumeSizeReturns(result1 int64, result2 error) {
+	fake.VolumeSizeStub = nil
+	fake.volumeSizeReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeVolumeDriver) VolumeSizeReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.VolumeSizeStub = nil
+	if fake.volumeSizeReturnsOnCall == nil {
+		fake.volumeSizeReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.volumeSizeReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeVolumeDriver) VolumeLastUsed(logger lager.Logger, id string) (time.Time, error) {
+	fake.volumeLastUsedMutex.Lock()
+	ret, specificReturn := fake.volumeLastUsedReturnsOnCall[len(fake.volumeLastUsedArgsForCall)]
+	fake.volumeLastUsedArgsForCall = append(fake.volumeLastUsedArgsForCall, struct {
+		logger lager.Logger
+		id     string
+	}{logger, id})
+	fake.recordInvocation("VolumeLastUsed", []interface{}{logger, id})
+	fake.volumeLastUsedMutex.Unlock()
+	if fake.VolumeLastUsedStub != nil {
+		return fake.VolumeLastUsedStub(logger, id)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.volumeLastUsedReturns.result1, fake.volumeLastUsedReturns.result2
+}
+
+func (fake *FakeVolumeDriver) VolumeLastUsedCallCount() int {
+	fake.volumeLastUsedMutex.RLock()
+	defer fake.volumeLastUsedMutex.RUnlock()
+	return len(fake.volumeLastUsedArgsForCall)
+}
+
+func (fake *FakeVolumeDriver) VolumeLastUsedArgsForCall(i int) (lager.Logger, string) {
+	fake.volumeLastUsedMutex.RLock()
+	defer fake.volumeLastUsedMutex.RUnlock()
+	return fake.volumeLastUsedArgsForCall[i].logger, fake.volumeLastUsedArgsForCall[i].id
+}
+
+func (fake *FakeVolumeDriver) VolumeLastUsedReturns(result1 time.Time, result2 error) {
+	fake.VolumeLastUsedStub = nil
+	fake.volumeLastUsedReturns = struct {
+		result1 time.Time
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeVolumeDriver) VolumeLastUsedReturnsOnCall(i int, result1 time.Time, result2 error) {
+	fake.VolumeLastUsedStub = nil
+	if fake.volumeLastUsedReturnsOnCall == nil {
+		fake.volumeLastUsedReturnsOnCall = make(map[int]struct {
+			result1 time.Time
+			result2 error
+		})
+	}
+	fake.volumeLastUsedReturnsOnCall[i] = struct {
+		result1 time.Time
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeVolumeDriver) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -278,6 +411,10 @@ func (fake *FakeVolumeDriver) Invocations() map[string][][]interface{} {
 	defer fake.destroyVolumeMutex.RUnlock()
 	fake.volumesMutex.RLock()
 	defer fake.volumesMutex.RUnlock()
+	fake.volumeSizeMutex.RLock()
+	defer fake.volumeSizeMutex.RUnlock()
+	fake.volumeLastUsedMutex.RLock()
+	defer fake.volumeLastUsedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -3,9 +3,13 @@ package garbage_collector
 import (
 	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/dependency_manager"
 	"code.cloudfoundry.org/lager"
 	errorspkg "github.com/pkg/errors"
 )
@@ -19,7 +23,10 @@ type ImageCloner interface {
 }
 
 type DependencyManager interface {
+	Deregister(id string) error
 	Dependencies(id string) ([]string, error)
+	List(prefix string) ([]string, error)
+	Metadata(id string) (map[string]string, error)
 }
 
 type VolumeDriver interface {
@@ -27,19 +34,25 @@ type VolumeDriver interface {
 	MoveVolume(logger lager.Logger, from, to string) error
 	DestroyVolume(logger lager.Logger, id string) error
 	Volumes(logger lager.Logger) ([]string, error)
+	VolumeSize(logger lager.Logger, id string) (int64, error)
+	VolumeLastUsed(logger lager.Logger, id string) (time.Time, error)
 }
 
 type GarbageCollector struct {
 	volumeDriver      VolumeDriver
 	imageCloner       ImageCloner
 	dependencyManager DependencyManager
+	retainedLabels    []groot.LabelSelector
 }
 
-func NewGC(volumeDriver VolumeDriver, imageCloner ImageCloner, dependencyManager DependencyManager) *GarbageCollector {
+// NewGC never collects volumes referenced by an image with a label matching
+// one of retainedLabels, including images that were deleted.
+func NewGC(volumeDriver VolumeDriver, imageCloner ImageCloner, dependencyManager DependencyManager, retainedLabels []groot.LabelSelector) *GarbageCollector {
 	return &GarbageCollector{
 		volumeDriver:      volumeDriver,
 		imageCloner:       imageCloner,
 		dependencyManager: dependencyManager,
+		retainedLabels:    retainedLabels,
 	}
 }
 
@@ -78,12 +91,125 @@ func (g *GarbageCollector) MarkUnused(logger lager.Logger, unusedVolumes []strin
 	return nil
 }
 
+type volumeUsage struct {
+	id       string
+	size     int64
+	lastUsed time.Time
+}
+
+type byLastUsed []volumeUsage
+
+func (v byLastUsed) Len() int      { return len(v) }
+func (v byLastUsed) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v byLastUsed) Less(i, j int) bool {
+	if v[i].lastUsed.Equal(v[j].lastUsed) {
+		return v[i].id < v[j].id
+	}
+	return v[i].lastUsed.Before(v[j].lastUsed)
+}
+
+func (g *GarbageCollector) LeastRecentlyUsed(logger lager.Logger, volumeIDs []string, bytesToFree int64) ([]string, error) {
+	logger = logger.Session("garbage-collector-least-recently-used", lager.Data{"volumeIDs": volumeIDs, "bytesToFree": bytesToFree})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	if bytesToFree <= 0 {
+		return []string{}, nil
+	}
+
+	volumes := []volumeUsage{}
+	for _, volID := range volumeIDs {
+		size, err := g.volumeDriver.VolumeSize(logger, volID)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errorspkg.Wrapf(err, "fetching size of volume `%s`", volID)
+		}
+
+		// Volumes that were never touched, or have no metadata, get the zero
+		// time and are evicted first.
+		lastUsed, err := g.volumeDriver.VolumeLastUsed(logger, volID)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errorspkg.Wrapf(err, "fetching last use of volume `%s`", volID)
+		}
+
+		volumes = append(volumes, volumeUsage{id: volID, size: size, lastUsed: lastUsed})
+	}
+	sort.Sort(byLastUsed(volumes))
+
+	var freedBytes int64
+	evictable := []string{}
+	for _, volume := range volumes {
+		if freedBytes >= bytesToFree {
+			break
+		}
+
+		evictable = append(evictable, volume.id)
+		freedBytes += volume.size
+	}
+
+	logger.Debug("selected-volumes", lager.Data{"evictable": evictable, "freedBytes": freedBytes})
+	return evictable, nil
+}
+
 func (g *GarbageCollector) Collect(logger lager.Logger) error {
 	logger = logger.Session("garbage-collector-collect")
 	logger.Info("starting")
 	defer logger.Info("ending")
 
-	return g.collectVolumes(logger)
+	if err := g.collectVolumes(logger); err != nil {
+		return err
+	}
+
+	g.pruneDeletedImages(logger)
+	return nil
+}
+
+// pruneDeletedImages deregisters the deleted images none of whose volumes are
+// left, as there's nothing for their labels to retain anymore.
+func (g *GarbageCollector) pruneDeletedImages(logger lager.Logger) {
+	refNames, err := g.dependencyManager.List(groot.DeletedImageReferencePrefix)
+	if err != nil {
+		logger.Error("listing-deleted-images-failed", err)
+		return
+	}
+	if len(refNames) == 0 {
+		return
+	}
+
+	volumes, err := g.volumeDriver.Volumes(logger)
+	if err != nil {
+		logger.Error("listing-volumes-failed", err)
+		return
+	}
+	existingVolumes := map[string]struct{}{}
+	for _, volumeID := range volumes {
+		existingVolumes[volumeID] = struct{}{}
+	}
+
+	for _, refName := range refNames {
+		chainIDs, err := g.dependencyManager.Dependencies(refName)
+		if err != nil {
+			logger.Error("fetching-deleted-image-dependencies-failed", err, lager.Data{"refName": refName})
+			continue
+		}
+
+		if anyExists(existingVolumes, chainIDs) {
+			continue
+		}
+
+		if err := g.dependencyManager.Deregister(refName); err != nil {
+			logger.Error("deregistering-deleted-image-failed", err, lager.Data{"refName": refName})
+		}
+	}
+}
+
+func anyExists(volumes map[string]struct{}, ids []string) bool {
+	for _, id := range ids {
+		if _, ok := volumes[id]; ok {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (g *GarbageCollector) collectVolumes(logger lager.Logger) error {
@@ -162,6 +288,26 @@ func (g *GarbageCollector) UnusedVolumes(logger lager.Logger) ([]string, error)
 		g.removeDependencyFromOrphanList(orphanedVolumes, usedVolumes)
 	}
 
+	// Pins and containerd snapshots keep their volumes without an image.
+	for _, prefix := range []string{groot.PinReferencePrefix, dependency_manager.SnapshotReferencePrefix} {
+		refNames, err := g.dependencyManager.List(prefix)
+		if err != nil {
+			return nil, errorspkg.Wrapf(err, "failed to retrieve `%s` references", prefix)
+		}
+
+		for _, refName := range refNames {
+			retainedVolumes, err := g.dependencyManager.Dependencies(refName)
+			if err != nil {
+				return nil, err
+			}
+			g.removeDependencyFromOrphanList(orphanedVolumes, retainedVolumes)
+		}
+	}
+
+	if err := g.removeRetainedFromOrphanList(logger, orphanedVolumes); err != nil {
+		return nil, err
+	}
+
 	orphanedVolumeIDs := []string{}
 	for id := range orphanedVolumes {
 		orphanedVolumeIDs = append(orphanedVolumeIDs, id)
@@ -169,6 +315,44 @@ func (g *GarbageCollector) UnusedVolumes(logger lager.Logger) ([]string, error)
 	return orphanedVolumeIDs, nil
 }
 
+// Image labels are registered with the image dependencies, and kept with them
+// once the image is deleted. Registered images are checked too, for one whose
+// deletion failed after its directory was removed.
+func (g *GarbageCollector) removeRetainedFromOrphanList(logger lager.Logger, orphanedVolumes map[string]struct{}) error {
+	if len(g.retainedLabels) == 0 {
+		return nil
+	}
+
+	refNames := []string{}
+	for _, prefix := range []string{groot.ImageReferencePrefix, groot.DeletedImageReferencePrefix} {
+		prefixRefNames, err := g.dependencyManager.List(prefix)
+		if err != nil {
+			return errorspkg.Wrapf(err, "failed to retrieve `%s` references", prefix)
+		}
+		refNames = append(refNames, prefixRefNames...)
+	}
+
+	for _, refName := range refNames {
+		labels, err := g.dependencyManager.Metadata(refName)
+		if err != nil {
+			logger.Error("fetching-image-labels-failed", err, lager.Data{"refName": refName})
+			continue
+		}
+
+		if !groot.MatchesAnyLabel(g.retainedLabels, labels) {
+			continue
+		}
+
+		retainedVolumes, err := g.dependencyManager.Dependencies(refName)
+		if err != nil {
+			return err
+		}
+		g.removeDependencyFromOrphanList(orphanedVolumes, retainedVolumes)
+	}
+
+	return nil
+}
+
 func (g *GarbageCollector) removeDependencyFromOrphanList(volumesList map[string]struct{}, usedVolumes []string) {
 	for _, volumeID := range usedVolumes {
 		delete(volumesList, volumeID)
@@ -2,8 +2,11 @@ package garbage_collector_test
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
+	"time"
 
+	"code.cloudfoundry.org/grootfs/groot"
 	"code.cloudfoundry.org/grootfs/store/garbage_collector"
 	"code.cloudfoundry.org/grootfs/store/garbage_collector/garbage_collectorfakes"
 	"code.cloudfoundry.org/lager"
@@ -21,18 +24,20 @@ var _ = Describe("Gc", func() {
 		fakeVolumeDriver      *garbage_collectorfakes.FakeVolumeDriver
 		fakeDependencyManager *garbage_collectorfakes.FakeDependencyManager
 		fakeImageCloner       *garbage_collectorfakes.FakeImageCloner
+		retainedLabels        []groot.LabelSelector
 	)
 
 	BeforeEach(func() {
 		fakeImageCloner = new(garbage_collectorfakes.FakeImageCloner)
 		fakeVolumeDriver = new(garbage_collectorfakes.FakeVolumeDriver)
 		fakeDependencyManager = new(garbage_collectorfakes.FakeDependencyManager)
+		retainedLabels = nil
 
 		logger = lagertest.NewTestLogger("garbage_collector")
 	})
 
 	JustBeforeEach(func() {
-		garbageCollector = garbage_collector.NewGC(fakeVolumeDriver, fakeImageCloner, fakeDependencyManager)
+		garbageCollector = garbage_collector.NewGC(fakeVolumeDriver, fakeImageCloner, fakeDependencyManager, retainedLabels)
 	})
 
 	Describe("UnusedVolumes", func() {
@@ -73,6 +78,169 @@ var _ = Describe("Gc", func() {
 			Expect(unusedVolumes).To(ConsistOf("sha256ubuntu", "sha256privateubuntu", "unusedLayerVolume", "unusedLocalVolume-timestamp"))
 		})
 
+		Context("when a base image is pinned", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.ListReturns([]string{"pin:docker:///ubuntu"}, nil)
+				fakeDependencyManager.DependenciesStub = func(id string) ([]string, error) {
+					return map[string][]string{
+						"image:idA":            []string{"volDocker1", "volDocker2"},
+						"image:idB":            []string{"volDocker1", "volDocker3"},
+						"image:idLocal":        []string{"usedLocalVolume-timestamp"},
+						"pin:docker:///ubuntu": []string{"sha256ubuntu"},
+					}[id], nil
+				}
+			})
+
+			It("doesn't consider the pinned volumes unused", func() {
+				unusedVolumes, err := garbageCollector.UnusedVolumes(logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(unusedVolumes).To(ConsistOf("sha256privateubuntu", "unusedLayerVolume", "unusedLocalVolume-timestamp"))
+				Expect(fakeDependencyManager.ListArgsForCall(0)).To(Equal("pin:"))
+			})
+		})
+
+		Context("when a snapshot uses a volume", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.ListStub = func(prefix string) ([]string, error) {
+					if prefix == "snapshot:" {
+						return []string{"snapshot:default/1/my-snapshot"}, nil
+					}
+					return []string{}, nil
+				}
+				fakeDependencyManager.DependenciesStub = func(id string) ([]string, error) {
+					return map[string][]string{
+						"image:idA":                      []string{"volDocker1", "volDocker2"},
+						"image:idB":                      []string{"volDocker1", "volDocker3"},
+						"image:idLocal":                  []string{"usedLocalVolume-timestamp"},
+						"snapshot:default/1/my-snapshot": []string{"sha256ubuntu", "unusedLayerVolume"},
+					}[id], nil
+				}
+			})
+
+			It("doesn't consider the snapshot's volumes unused", func() {
+				unusedVolumes, err := garbageCollector.UnusedVolumes(logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(unusedVolumes).To(ConsistOf("sha256privateubuntu", "unusedLocalVolume-timestamp"))
+			})
+		})
+
+		Context("when labels are retained", func() {
+			BeforeEach(func() {
+				retainedLabels = []groot.LabelSelector{
+					{Key: "tenant", Value: "acme", HasValue: true},
+					{Key: "keep"},
+				}
+
+				// image:idGone was not deregistered when its image was deleted
+				fakeDependencyManager.ListStub = func(prefix string) ([]string, error) {
+					if prefix == "image:" {
+						return []string{"image:idA", "image:idB", "image:idLocal", "image:idGone"}, nil
+					}
+					return []string{}, nil
+				}
+				fakeDependencyManager.MetadataStub = func(id string) (map[string]string, error) {
+					return map[string]map[string]string{
+						"image:idA":    {"tenant": "other"},
+						"image:idGone": {"keep": "anything"},
+					}[id], nil
+				}
+				fakeDependencyManager.DependenciesStub = func(id string) ([]string, error) {
+					return map[string][]string{
+						"image:idA":     []string{"volDocker1", "volDocker2"},
+						"image:idB":     []string{"volDocker1", "volDocker3"},
+						"image:idLocal": []string{"usedLocalVolume-timestamp"},
+						"image:idGone":  []string{"sha256ubuntu", "unusedLayerVolume"},
+					}[id], nil
+				}
+			})
+
+			It("doesn't consider the volumes referenced by matching images unused", func() {
+				unusedVolumes, err := garbageCollector.UnusedVolumes(logger)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(unusedVolumes).To(ConsistOf("sha256privateubuntu", "unusedLocalVolume-timestamp"))
+			})
+
+			Context("when deleted images had labels", func() {
+				BeforeEach(func() {
+					fakeDependencyManager.ListStub = func(prefix string) ([]string, error) {
+						switch prefix {
+						case "image:":
+							return []string{"image:idA", "image:idB", "image:idLocal"}, nil
+						case "deleted-image:":
+							return []string{"deleted-image:idOld@1", "deleted-image:idOther@2"}, nil
+						}
+						return []string{}, nil
+					}
+					fakeDependencyManager.MetadataStub = func(id string) (map[string]string, error) {
+						return map[string]map[string]string{
+							"deleted-image:idOld@1":   {"tenant": "acme"},
+							"deleted-image:idOther@2": {"tenant": "other"},
+						}[id], nil
+					}
+					fakeDependencyManager.DependenciesStub = func(id string) ([]string, error) {
+						return map[string][]string{
+							"image:idA":               []string{"volDocker1", "volDocker2"},
+							"image:idB":               []string{"volDocker1", "volDocker3"},
+							"image:idLocal":           []string{"usedLocalVolume-timestamp"},
+							"deleted-image:idOld@1":   []string{"sha256ubuntu"},
+							"deleted-image:idOther@2": []string{"sha256privateubuntu"},
+						}[id], nil
+					}
+				})
+
+				It("doesn't consider the volumes of the matching ones unused", func() {
+					unusedVolumes, err := garbageCollector.UnusedVolumes(logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(unusedVolumes).To(ConsistOf("sha256privateubuntu", "unusedLayerVolume", "unusedLocalVolume-timestamp"))
+				})
+			})
+
+			Context("when the labels of an image can't be read", func() {
+				BeforeEach(func() {
+					fakeDependencyManager.MetadataStub = nil
+					fakeDependencyManager.MetadataReturns(nil, errors.New("no metadata"))
+				})
+
+				It("doesn't retain its volumes", func() {
+					unusedVolumes, err := garbageCollector.UnusedVolumes(logger)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(unusedVolumes).To(ConsistOf("sha256ubuntu", "sha256privateubuntu", "unusedLayerVolume", "unusedLocalVolume-timestamp"))
+				})
+			})
+
+			Context("when listing the images fails", func() {
+				BeforeEach(func() {
+					fakeDependencyManager.ListStub = func(prefix string) ([]string, error) {
+						if prefix == "image:" {
+							return nil, errors.New("failed to list images")
+						}
+						return []string{}, nil
+					}
+				})
+
+				It("returns an error", func() {
+					_, err := garbageCollector.UnusedVolumes(logger)
+					Expect(err).To(MatchError(ContainSubstring("failed to list images")))
+				})
+			})
+		})
+
+		Context("when listing the pins fails", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.ListReturns(nil, errors.New("failed to list pins"))
+			})
+
+			It("returns an error", func() {
+				_, err := garbageCollector.UnusedVolumes(logger)
+				Expect(err).To(MatchError(ContainSubstring("failed to list pins")))
+			})
+		})
+
 		Context("when retrieving images fails", func() {
 			BeforeEach(func() {
 				fakeImageCloner.ImageIDsReturns(nil, errors.New("failed to retrieve images"))
@@ -223,6 +391,36 @@ var _ = Describe("Gc", func() {
 			Expect(volumes).To(ContainElement("gc.vol-f"))
 		})
 
+		Context("when there are deleted images", func() {
+			BeforeEach(func() {
+				fakeDependencyManager.ListStub = func(prefix string) ([]string, error) {
+					if prefix == "deleted-image:" {
+						return []string{"deleted-image:idOld@1", "deleted-image:idGone@2"}, nil
+					}
+					return []string{}, nil
+				}
+				fakeDependencyManager.DependenciesStub = func(id string) ([]string, error) {
+					return map[string][]string{
+						"deleted-image:idOld@1":  []string{"vol-b", "vol-a"},
+						"deleted-image:idGone@2": []string{"vol-b", "vol-c"},
+					}[id], nil
+				}
+				fakeVolumeDriver.VolumesStub = func(_ lager.Logger) ([]string, error) {
+					if fakeVolumeDriver.DestroyVolumeCallCount() == 0 {
+						return []string{"vol-a", "gc.vol-b", "gc.vol-c"}, nil
+					}
+					return []string{"vol-a"}, nil
+				}
+			})
+
+			It("deregisters the ones whose volumes are all gone", func() {
+				Expect(garbageCollector.Collect(logger)).To(Succeed())
+
+				Expect(fakeDependencyManager.DeregisterCallCount()).To(Equal(1))
+				Expect(fakeDependencyManager.DeregisterArgsForCall(0)).To(Equal("deleted-image:idGone@2"))
+			})
+		})
+
 		Context("when destroying a volume fails", func() {
 			BeforeEach(func() {
 				fakeVolumeDriver.DestroyVolumeStub = func(_ lager.Logger, volID string) error {
@@ -240,4 +438,92 @@ var _ = Describe("Gc", func() {
 			})
 		})
 	})
+
+	Describe("LeastRecentlyUsed", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Now()
+			sizes := map[string]int64{
+				"old-volume":    100,
+				"recent-volume": 200,
+				"newest-volume": 300,
+				"never-used":    50,
+			}
+			lastUses := map[string]time.Time{
+				"old-volume":    now.Add(-2 * time.Hour),
+				"recent-volume": now.Add(-1 * time.Hour),
+				"newest-volume": now,
+			}
+
+			fakeVolumeDriver.VolumeSizeStub = func(_ lager.Logger, id string) (int64, error) {
+				return sizes[id], nil
+			}
+			fakeVolumeDriver.VolumeLastUsedStub = func(_ lager.Logger, id string) (time.Time, error) {
+				return lastUses[id], nil
+			}
+		})
+
+		It("selects the least recently used volumes until enough bytes are freed", func() {
+			volumes, err := garbageCollector.LeastRecentlyUsed(logger, []string{"newest-volume", "recent-volume", "old-volume", "never-used"}, 300)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(volumes).To(Equal([]string{"never-used", "old-volume", "recent-volume"}))
+		})
+
+		It("stops as soon as the target is reached", func() {
+			volumes, err := garbageCollector.LeastRecentlyUsed(logger, []string{"newest-volume", "recent-volume", "old-volume", "never-used"}, 150)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(volumes).To(Equal([]string{"never-used", "old-volume"}))
+		})
+
+		It("selects every volume when the target can't be reached", func() {
+			volumes, err := garbageCollector.LeastRecentlyUsed(logger, []string{"newest-volume", "recent-volume", "old-volume", "never-used"}, 10000)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(volumes).To(Equal([]string{"never-used", "old-volume", "recent-volume", "newest-volume"}))
+		})
+
+		Context("when there are no bytes to free", func() {
+			It("selects no volumes", func() {
+				volumes, err := garbageCollector.LeastRecentlyUsed(logger, []string{"newest-volume", "old-volume"}, 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(volumes).To(BeEmpty())
+				Expect(fakeVolumeDriver.VolumeSizeCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the volume metadata is missing", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.VolumeSizeReturns(0, os.ErrNotExist)
+				fakeVolumeDriver.VolumeLastUsedReturns(time.Time{}, os.ErrNotExist)
+			})
+
+			It("selects the volumes first", func() {
+				volumes, err := garbageCollector.LeastRecentlyUsed(logger, []string{"volume-b", "volume-a"}, 1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(volumes).To(Equal([]string{"volume-a", "volume-b"}))
+			})
+		})
+
+		Context("when fetching the volume size fails", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.VolumeSizeReturns(0, errors.New("failed to get size"))
+			})
+
+			It("returns an error", func() {
+				_, err := garbageCollector.LeastRecentlyUsed(logger, []string{"old-volume"}, 100)
+				Expect(err).To(MatchError(ContainSubstring("failed to get size")))
+			})
+		})
+
+		Context("when fetching the last use fails", func() {
+			BeforeEach(func() {
+				fakeVolumeDriver.VolumeLastUsedReturns(time.Time{}, errors.New("failed to get last use"))
+			})
+
+			It("returns an error", func() {
+				_, err := garbageCollector.LeastRecentlyUsed(logger, []string{"old-volume"}, 100)
+				Expect(err).To(MatchError(ContainSubstring("failed to get last use")))
+			})
+		})
+	})
 })
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"code.cloudfoundry.org/grootfs/groot"
 	"code.cloudfoundry.org/grootfs/store"
@@ -17,11 +19,14 @@ import (
 )
 
 type ImageDriverSpec struct {
-	BaseVolumeIDs      []string
-	Mount              bool
-	ImagePath          string
-	DiskLimit          int64
-	ExclusiveDiskLimit bool
+	BaseVolumeIDs       []string
+	Mount               bool
+	ImagePath           string
+	DiskLimit           int64
+	ExclusiveDiskLimit  bool
+	ReadOnly            bool
+	EphemeralSizeBytes  int64
+	OverlayMountOptions []string
 }
 
 //go:generate counterfeiter . ImageDriver
@@ -29,6 +34,13 @@ type ImageDriver interface {
 	CreateImage(logger lager.Logger, spec ImageDriverSpec) (groot.MountInfo, error)
 	DestroyImage(logger lager.Logger, path string) error
 	FetchStats(logger lager.Logger, path string) (groot.VolumeStats, error)
+	FetchAllStats(logger lager.Logger) (map[string]groot.VolumeStats, error)
+	MountImage(logger lager.Logger, path string) error
+	UnmountImage(logger lager.Logger, path string) error
+	MountAllImages(logger lager.Logger) ([]string, error)
+	ImageMounted(logger lager.Logger, path string) (bool, error)
+	InspectImage(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error)
+	FlattenVolumes(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error)
 }
 
 type ImageCloner struct {
@@ -91,12 +103,23 @@ func (b *ImageCloner) Create(logger lager.Logger, spec groot.ImageSpec) (groot.I
 		return groot.ImageInfo{}, errorspkg.Wrap(err, "making image path")
 	}
 
+	baseVolumeIDs := spec.BaseVolumeIDs
+	if spec.MaxLayerDepth > 0 {
+		if baseVolumeIDs, err = b.imageDriver.FlattenVolumes(logger, spec.BaseVolumeIDs, spec.MaxLayerDepth); err != nil {
+			logger.Error("flattening-volumes-failed", err, lager.Data{"maxLayerDepth": spec.MaxLayerDepth})
+			return groot.ImageInfo{}, errorspkg.Wrap(err, "flattening base volumes")
+		}
+	}
+
 	imageDriverSpec := ImageDriverSpec{
-		BaseVolumeIDs:      spec.BaseVolumeIDs,
-		Mount:              spec.Mount,
-		ImagePath:          imagePath,
-		DiskLimit:          spec.DiskLimit,
-		ExclusiveDiskLimit: spec.ExcludeBaseImageFromQuota,
+		BaseVolumeIDs:       baseVolumeIDs,
+		Mount:               spec.Mount,
+		ImagePath:           imagePath,
+		DiskLimit:           spec.DiskLimit,
+		ExclusiveDiskLimit:  spec.ExcludeBaseImageFromQuota,
+		ReadOnly:            spec.ReadOnly,
+		EphemeralSizeBytes:  spec.EphemeralSizeBytes,
+		OverlayMountOptions: spec.OverlayMountOptions,
 	}
 
 	var mountInfo groot.MountInfo
@@ -118,6 +141,37 @@ func (b *ImageCloner) Create(logger lager.Logger, spec groot.ImageSpec) (groot.I
 		logger.Error("creating-image-object", err)
 		return groot.ImageInfo{}, errorspkg.Wrap(err, "creating image object")
 	}
+	imageInfo.BaseVolumeIDs = baseVolumeIDs
+	imageInfo.UpperDir = upperDir(mountInfo)
+	imageInfo.ReadOnly = spec.ReadOnly
+
+	if len(spec.Labels) > 0 {
+		if err = groot.WriteImageLabels(imagePath, spec.Labels); err != nil {
+			logger.Error("writing-image-labels-failed", err)
+			return groot.ImageInfo{}, err
+		}
+	}
+
+	if err = groot.WriteImageMetadata(imagePath, groot.ImageMetadata{
+		ID:                 spec.ID,
+		Created:            time.Now(),
+		BaseImageURL:       spec.BaseImageURL,
+		BaseImageDigest:    spec.BaseImageDigest,
+		ExtraLayerDiffIDs:  spec.ExtraLayerDiffIDs,
+		ChainIDs:           spec.BaseVolumeIDs,
+		DiskLimit:          spec.DiskLimit,
+		ExclusiveDiskLimit: spec.ExcludeBaseImageFromQuota,
+		ReadOnly:           spec.ReadOnly,
+		EphemeralSizeBytes: spec.EphemeralSizeBytes,
+	}); err != nil {
+		logger.Error("writing-image-metadata-failed", err)
+		return groot.ImageInfo{}, err
+	}
+
+	if err = groot.WriteBaseImageConfig(imagePath, spec.BaseImage); err != nil {
+		logger.Error("writing-base-image-config-failed", err)
+		return groot.ImageInfo{}, err
+	}
 
 	if err := b.createVolumesSources(imageInfo.Mounts, spec.OwnerUID, spec.OwnerGID); err != nil {
 		return groot.ImageInfo{}, errorspkg.Wrap(err, "creating volume source")
@@ -196,6 +250,133 @@ func (b *ImageCloner) Stats(logger lager.Logger, id string) (groot.VolumeStats,
 	return b.imageDriver.FetchStats(logger, imagePath)
 }
 
+func (b *ImageCloner) AllStats(logger lager.Logger) (map[string]groot.VolumeStats, error) {
+	logger = logger.Session("fetching-all-stats")
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	return b.imageDriver.FetchAllStats(logger)
+}
+
+func (b *ImageCloner) Mount(logger lager.Logger, id string) error {
+	logger = logger.Session("mounting-image", lager.Data{"id": id})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	if ok, err := b.Exists(id); !ok {
+		logger.Error("checking-image-path-failed", err)
+		return errorspkg.Errorf("image not found: %s", id)
+	}
+
+	return b.imageDriver.MountImage(logger, b.imagePath(id))
+}
+
+func (b *ImageCloner) Unmount(logger lager.Logger, id string) error {
+	logger = logger.Session("unmounting-image", lager.Data{"id": id})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	if ok, err := b.Exists(id); !ok {
+		logger.Error("checking-image-path-failed", err)
+		return errorspkg.Errorf("image not found: %s", id)
+	}
+
+	return b.imageDriver.UnmountImage(logger, b.imagePath(id))
+}
+
+func (b *ImageCloner) MountAll(logger lager.Logger) ([]string, error) {
+	logger = logger.Session("mounting-all-images")
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	return b.imageDriver.MountAllImages(logger)
+}
+
+// Details combines the metadata recorded when the image was created with its
+// current mount state and disk usage.
+func (b *ImageCloner) Details(logger lager.Logger, id string) (groot.ImageDetails, error) {
+	logger = logger.Session("fetching-details", lager.Data{"id": id})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	if ok, err := b.Exists(id); !ok {
+		logger.Error("checking-image-path-failed", err)
+		return groot.ImageDetails{}, errorspkg.Errorf("image not found: %s", id)
+	}
+
+	imagePath := b.imagePath(id)
+	metadata, err := groot.ReadImageMetadata(imagePath)
+	if err != nil {
+		return groot.ImageDetails{}, err
+	}
+
+	labels, err := groot.ReadImageLabels(imagePath)
+	if err != nil {
+		return groot.ImageDetails{}, err
+	}
+
+	mounted, err := b.imageDriver.ImageMounted(logger, imagePath)
+	if err != nil {
+		logger.Error("checking-image-mounted-failed", err)
+		return groot.ImageDetails{}, errorspkg.Wrap(err, "checking if image is mounted")
+	}
+
+	stats, err := b.imageDriver.FetchStats(logger, imagePath)
+	if err != nil {
+		logger.Error("fetching-stats-failed", err)
+		return groot.ImageDetails{}, err
+	}
+
+	return groot.ImageDetails{
+		ImageMetadata: metadata,
+		Path:          imagePath,
+		Labels:        labels,
+		Mounted:       mounted,
+		DiskUsage:     stats.DiskUsage,
+	}, nil
+}
+
+func (b *ImageCloner) Inspect(logger lager.Logger, id string) (groot.ImageInspection, error) {
+	logger = logger.Session("inspecting-image", lager.Data{"id": id})
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	details, err := b.Details(logger, id)
+	if err != nil {
+		return groot.ImageInspection{}, err
+	}
+
+	filesystemDetails, err := b.imageDriver.InspectImage(logger, details.Path)
+	if err != nil {
+		logger.Error("inspecting-image-filesystem-failed", err)
+		return groot.ImageInspection{}, errorspkg.Wrap(err, "inspecting image filesystem")
+	}
+
+	baseImage, err := groot.ReadBaseImageConfig(details.Path)
+	if err != nil {
+		return groot.ImageInspection{}, err
+	}
+
+	// Passing mount=true leaves out the rootfs mount, which is already part
+	// of the filesystem details.
+	imageInfo, err := b.imageInfo(filepath.Join(details.Path, "rootfs"), details.Path, baseImage, groot.MountInfo{}, true)
+	if err != nil {
+		return groot.ImageInspection{}, err
+	}
+
+	bindVolumes := imageInfo.Mounts
+	if bindVolumes == nil {
+		bindVolumes = []groot.MountInfo{}
+	}
+
+	return groot.ImageInspection{
+		ImageDetails:           details,
+		ImageFilesystemDetails: filesystemDetails,
+		BaseImage:              baseImage,
+		BindVolumes:            bindVolumes,
+	}, nil
+}
+
 var OpenFile = os.OpenFile
 
 func (b *ImageCloner) imageInfo(rootfsPath, imagePath string, baseImage specsv1.Image, mountJson groot.MountInfo, mount bool) (groot.ImageInfo, error) {
@@ -224,6 +405,20 @@ func (b *ImageCloner) imageInfo(rootfsPath, imagePath string, baseImage specsv1.
 	return imageInfo, nil
 }
 
+// upperDir reads the upper directory from the overlay options of the image
+// mount.
+func upperDir(mountInfo groot.MountInfo) string {
+	for _, options := range mountInfo.Options {
+		for _, option := range strings.Split(options, ",") {
+			if strings.HasPrefix(option, "upperdir=") {
+				return strings.TrimPrefix(option, "upperdir=")
+			}
+		}
+	}
+
+	return ""
+}
+
 func (b *ImageCloner) imagePath(id string) string {
 	return path.Join(b.storePath, store.ImageDirName, id)
 }
@@ -113,6 +113,17 @@ var _ = Describe("Image", func() {
 			Expect(spec.ImagePath).To(Equal(image.Path))
 		})
 
+		It("returns the upper directory from the image mount options", func() {
+			fakeImageDriver.CreateImageReturns(groot.MountInfo{
+				Type:    "overlay",
+				Options: []string{"lowerdir=/volumes/id-1,upperdir=/images/some-id/diff,workdir=/images/some-id/workdir"},
+			}, nil)
+
+			image, err := imageCloner.Create(logger, groot.ImageSpec{ID: "some-id", BaseImage: imageConfig, Mount: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(image.UpperDir).To(Equal("/images/some-id/diff"))
+		})
+
 		Context("when mounting is skipped", func() {
 			It("returns a image with mount information", func() {
 				image, err := imageCloner.Create(logger, groot.ImageSpec{ID: "some-id", BaseImage: imageConfig, Mount: false})
@@ -249,6 +260,59 @@ var _ = Describe("Image", func() {
 			})
 		})
 
+		It("records the image metadata", func() {
+			image, err := imageCloner.Create(logger, groot.ImageSpec{
+				ID:                        "some-id",
+				BaseImage:                 imageConfig,
+				BaseImageURL:              "docker:///ubuntu",
+				BaseImageDigest:           "sha256:ubuntu-digest",
+				ExtraLayerDiffIDs:         []string{"sha256:extra-diff"},
+				BaseVolumeIDs:             []string{"id-1", "id-2"},
+				DiskLimit:                 1024,
+				ExcludeBaseImageFromQuota: true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			metadata, err := groot.ReadImageMetadata(image.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata.ID).To(Equal("some-id"))
+			Expect(metadata.Created).To(BeTemporally("~", time.Now(), time.Minute))
+			Expect(metadata.BaseImageURL).To(Equal("docker:///ubuntu"))
+			Expect(metadata.BaseImageDigest).To(Equal("sha256:ubuntu-digest"))
+			Expect(metadata.ExtraLayerDiffIDs).To(Equal([]string{"sha256:extra-diff"}))
+			Expect(metadata.ChainIDs).To(Equal([]string{"id-1", "id-2"}))
+			Expect(metadata.DiskLimit).To(Equal(int64(1024)))
+			Expect(metadata.ExclusiveDiskLimit).To(BeTrue())
+		})
+
+		It("records the base image config", func() {
+			image, err := imageCloner.Create(logger, groot.ImageSpec{ID: "some-id", BaseImage: imageConfig})
+			Expect(err).NotTo(HaveOccurred())
+
+			config, err := groot.ReadBaseImageConfig(image.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Created.Unix()).To(Equal(imageConfig.Created.Unix()))
+		})
+
+		It("doesn't record labels for images without any", func() {
+			image, err := imageCloner.Create(logger, groot.ImageSpec{ID: "some-id", BaseImage: imageConfig})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(image.Path, groot.ImageLabelsFilename)).NotTo(BeAnExistingFile())
+		})
+
+		Context("when labels are given", func() {
+			It("records them in the image", func() {
+				labels := map[string]string{"tenant": "acme"}
+				image, err := imageCloner.Create(logger, groot.ImageSpec{ID: "some-id", BaseImage: imageConfig, Labels: labels})
+				Expect(err).NotTo(HaveOccurred())
+
+				imageLabels, err := groot.ReadImageLabels(image.Path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imageLabels).To(Equal(labels))
+			})
+		})
+
 		Context("when calling it with two different ids", func() {
 			It("returns two different image paths", func() {
 				image, err := imageCloner.Create(logger, groot.ImageSpec{ID: "some-id", BaseImage: imageConfig})
@@ -319,6 +383,131 @@ var _ = Describe("Image", func() {
 				})
 			})
 		})
+
+		Context("when the image is read-only", func() {
+			It("creates a read-only image and records it", func() {
+				image, err := imageCloner.Create(logger, groot.ImageSpec{
+					ID:        "some-id",
+					ReadOnly:  true,
+					BaseImage: imageConfig,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image.ReadOnly).To(BeTrue())
+
+				_, spec := fakeImageDriver.CreateImageArgsForCall(0)
+				Expect(spec.ReadOnly).To(BeTrue())
+
+				metadata, err := groot.ReadImageMetadata(image.Path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metadata.ReadOnly).To(BeTrue())
+			})
+		})
+
+		It("passes the overlay mount options to the image driver", func() {
+			_, err := imageCloner.Create(logger, groot.ImageSpec{
+				ID:                  "some-id",
+				OverlayMountOptions: []string{"metacopy=on"},
+				BaseImage:           imageConfig,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, spec := fakeImageDriver.CreateImageArgsForCall(0)
+			Expect(spec.OverlayMountOptions).To(Equal([]string{"metacopy=on"}))
+		})
+
+		Context("when the image is ephemeral", func() {
+			It("passes the ephemeral size to the image driver and records it", func() {
+				image, err := imageCloner.Create(logger, groot.ImageSpec{
+					ID:                 "some-id",
+					EphemeralSizeBytes: 1024,
+					BaseImage:          imageConfig,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, spec := fakeImageDriver.CreateImageArgsForCall(0)
+				Expect(spec.EphemeralSizeBytes).To(Equal(int64(1024)))
+
+				metadata, err := groot.ReadImageMetadata(image.Path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metadata.EphemeralSizeBytes).To(Equal(int64(1024)))
+			})
+		})
+
+		Context("when a max layer depth is not set", func() {
+			It("doesn't flatten the base volumes", func() {
+				image, err := imageCloner.Create(logger, groot.ImageSpec{
+					ID:            "some-id",
+					BaseVolumeIDs: []string{"id-1", "id-2", "id-3"},
+					BaseImage:     imageConfig,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeImageDriver.FlattenVolumesCallCount()).To(Equal(0))
+				Expect(image.BaseVolumeIDs).To(Equal([]string{"id-1", "id-2", "id-3"}))
+			})
+		})
+
+		Context("when a max layer depth is set", func() {
+			BeforeEach(func() {
+				fakeImageDriver.FlattenVolumesReturns([]string{"flat-id-2", "id-3"}, nil)
+			})
+
+			It("flattens the base volumes", func() {
+				_, err := imageCloner.Create(logger, groot.ImageSpec{
+					ID:            "some-id",
+					BaseVolumeIDs: []string{"id-1", "id-2", "id-3"},
+					MaxLayerDepth: 2,
+					BaseImage:     imageConfig,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeImageDriver.FlattenVolumesCallCount()).To(Equal(1))
+				_, volumeIDs, maxDepth := fakeImageDriver.FlattenVolumesArgsForCall(0)
+				Expect(volumeIDs).To(Equal([]string{"id-1", "id-2", "id-3"}))
+				Expect(maxDepth).To(Equal(2))
+			})
+
+			It("creates the image from the flattened volumes", func() {
+				image, err := imageCloner.Create(logger, groot.ImageSpec{
+					ID:            "some-id",
+					BaseVolumeIDs: []string{"id-1", "id-2", "id-3"},
+					MaxLayerDepth: 2,
+					BaseImage:     imageConfig,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, spec := fakeImageDriver.CreateImageArgsForCall(0)
+				Expect(spec.BaseVolumeIDs).To(Equal([]string{"flat-id-2", "id-3"}))
+				Expect(image.BaseVolumeIDs).To(Equal([]string{"flat-id-2", "id-3"}))
+			})
+
+			Context("when flattening fails", func() {
+				BeforeEach(func() {
+					fakeImageDriver.FlattenVolumesReturns(nil, errors.New("failed to flatten"))
+				})
+
+				It("returns an error", func() {
+					_, err := imageCloner.Create(logger, groot.ImageSpec{
+						ID:            "some-id",
+						BaseVolumeIDs: []string{"id-1", "id-2", "id-3"},
+						MaxLayerDepth: 2,
+						BaseImage:     imageConfig,
+					})
+					Expect(err).To(MatchError(ContainSubstring("failed to flatten")))
+				})
+
+				It("removes the image", func() {
+					_, err := imageCloner.Create(logger, groot.ImageSpec{
+						ID:            "some-id",
+						BaseVolumeIDs: []string{"id-1", "id-2", "id-3"},
+						MaxLayerDepth: 2,
+						BaseImage:     imageConfig,
+					})
+					Expect(err).To(HaveOccurred())
+					Expect(filepath.Join(imagesPath, "some-id")).NotTo(BeADirectory())
+				})
+			})
+		})
 	})
 
 	Describe("Destroy", func() {
@@ -469,4 +658,239 @@ var _ = Describe("Image", func() {
 			})
 		})
 	})
+
+	Describe("Details", func() {
+		var imagePath string
+
+		BeforeEach(func() {
+			imagePath = path.Join(storePath, store.ImageDirName, "some-id")
+			Expect(os.MkdirAll(imagePath, 0755)).To(Succeed())
+			Expect(groot.WriteImageMetadata(imagePath, groot.ImageMetadata{ID: "some-id", BaseImageURL: "docker:///ubuntu"})).To(Succeed())
+			Expect(groot.WriteImageLabels(imagePath, map[string]string{"tenant": "acme"})).To(Succeed())
+
+			fakeImageDriver.ImageMountedReturns(true, nil)
+			fakeImageDriver.FetchStatsReturns(groot.VolumeStats{
+				DiskUsage: groot.DiskUsage{TotalBytesUsed: 2048, ExclusiveBytesUsed: 1024},
+			}, nil)
+		})
+
+		It("returns the recorded metadata with the mount state and disk usage", func() {
+			details, err := imageCloner.Details(logger, "some-id")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(details).To(Equal(groot.ImageDetails{
+				ImageMetadata: groot.ImageMetadata{ID: "some-id", BaseImageURL: "docker:///ubuntu"},
+				Path:          imagePath,
+				Labels:        map[string]string{"tenant": "acme"},
+				Mounted:       true,
+				DiskUsage:     groot.DiskUsage{TotalBytesUsed: 2048, ExclusiveBytesUsed: 1024},
+			}))
+
+			_, mountedPath := fakeImageDriver.ImageMountedArgsForCall(0)
+			Expect(mountedPath).To(Equal(imagePath))
+		})
+
+		Context("when the image has no recorded metadata", func() {
+			BeforeEach(func() {
+				Expect(os.Remove(filepath.Join(imagePath, groot.ImageMetadataFilename))).To(Succeed())
+			})
+
+			It("returns the id and the creation time of the image directory", func() {
+				details, err := imageCloner.Details(logger, "some-id")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(details.ID).To(Equal("some-id"))
+				Expect(details.Created).To(BeTemporally("~", time.Now(), time.Minute))
+				Expect(details.BaseImageURL).To(BeEmpty())
+			})
+		})
+
+		Context("when image does not exist", func() {
+			It("returns an error", func() {
+				_, err := imageCloner.Details(logger, "cake")
+				Expect(err).To(MatchError(ContainSubstring("image not found")))
+			})
+		})
+
+		Context("when checking the mount state fails", func() {
+			It("returns an error", func() {
+				fakeImageDriver.ImageMountedReturns(false, errors.New("failed to stat"))
+
+				_, err := imageCloner.Details(logger, "some-id")
+				Expect(err).To(MatchError(ContainSubstring("failed to stat")))
+			})
+		})
+
+		Context("when fetching the stats fails", func() {
+			It("returns an error", func() {
+				fakeImageDriver.FetchStatsReturns(groot.VolumeStats{}, errors.New("failed"))
+
+				_, err := imageCloner.Details(logger, "some-id")
+				Expect(err).To(MatchError("failed"))
+			})
+		})
+	})
+
+	Describe("Inspect", func() {
+		var (
+			imagePath         string
+			filesystemDetails groot.ImageFilesystemDetails
+		)
+
+		BeforeEach(func() {
+			imagePath = path.Join(storePath, store.ImageDirName, "some-id")
+			Expect(os.MkdirAll(imagePath, 0755)).To(Succeed())
+			Expect(groot.WriteImageMetadata(imagePath, groot.ImageMetadata{ID: "some-id"})).To(Succeed())
+			Expect(groot.WriteBaseImageConfig(imagePath, specsv1.Image{
+				Config: specsv1.ImageConfig{
+					Volumes: map[string]struct{}{"/data": struct{}{}},
+				},
+			})).To(Succeed())
+
+			filesystemDetails = groot.ImageFilesystemDetails{
+				Volumes:    []groot.VolumeDetails{{ID: "volume-1", SizeBytes: 1024}},
+				Mount:      groot.MountInfo{Type: "overlay", Options: []string{"lowerdir=..."}},
+				ProjectID:  10,
+				QuotaBytes: 2048,
+			}
+			fakeImageDriver.InspectImageReturns(filesystemDetails, nil)
+			fakeImageDriver.ImageMountedReturns(true, nil)
+		})
+
+		It("returns the details, filesystem details and base image config", func() {
+			inspection, err := imageCloner.Inspect(logger, "some-id")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inspection.ID).To(Equal("some-id"))
+			Expect(inspection.Mounted).To(BeTrue())
+			Expect(inspection.ImageFilesystemDetails).To(Equal(filesystemDetails))
+			Expect(inspection.BaseImage.Config.Volumes).To(HaveKey("/data"))
+
+			_, inspectedPath := fakeImageDriver.InspectImageArgsForCall(0)
+			Expect(inspectedPath).To(Equal(imagePath))
+		})
+
+		It("returns the bind volume sources", func() {
+			inspection, err := imageCloner.Inspect(logger, "some-id")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inspection.BindVolumes).To(HaveLen(1))
+			Expect(inspection.BindVolumes[0].Destination).To(Equal("/data"))
+			Expect(inspection.BindVolumes[0].Type).To(Equal("bind"))
+			Expect(filepath.Dir(inspection.BindVolumes[0].Source)).To(Equal(imagePath))
+		})
+
+		Context("when image does not exist", func() {
+			It("returns an error", func() {
+				_, err := imageCloner.Inspect(logger, "cake")
+				Expect(err).To(MatchError(ContainSubstring("image not found")))
+			})
+		})
+
+		Context("when the image driver fails", func() {
+			It("returns an error", func() {
+				fakeImageDriver.InspectImageReturns(groot.ImageFilesystemDetails{}, errors.New("failed"))
+
+				_, err := imageCloner.Inspect(logger, "some-id")
+				Expect(err).To(MatchError(ContainSubstring("inspecting image filesystem: failed")))
+			})
+		})
+	})
+
+	Describe("AllStats", func() {
+		It("returns the stats for all images from the image driver", func() {
+			allStats := map[string]groot.VolumeStats{
+				"some-id": groot.VolumeStats{
+					DiskUsage: groot.DiskUsage{
+						TotalBytesUsed:     int64(2048),
+						ExclusiveBytesUsed: int64(1024),
+					},
+				},
+			}
+			fakeImageDriver.FetchAllStatsReturns(allStats, nil)
+
+			returnedStats, err := imageCloner.AllStats(logger)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeImageDriver.FetchAllStatsCallCount()).To(Equal(1))
+			Expect(returnedStats).To(Equal(allStats))
+		})
+
+		Context("when the image driver fails", func() {
+			It("returns an error", func() {
+				fakeImageDriver.FetchAllStatsReturns(nil, errors.New("failed"))
+
+				_, err := imageCloner.AllStats(logger)
+				Expect(err).To(MatchError("failed"))
+			})
+		})
+	})
+
+	Describe("Mount", func() {
+		var imagePath string
+
+		BeforeEach(func() {
+			imagePath = path.Join(storePath, store.ImageDirName, "some-id")
+			Expect(os.MkdirAll(imagePath, 0755)).To(Succeed())
+		})
+
+		It("asks the image driver to mount the image", func() {
+			Expect(imageCloner.Mount(logger, "some-id")).To(Succeed())
+
+			Expect(fakeImageDriver.MountImageCallCount()).To(Equal(1))
+			_, receivedImagePath := fakeImageDriver.MountImageArgsForCall(0)
+			Expect(receivedImagePath).To(Equal(imagePath))
+		})
+
+		Context("when image does not exist", func() {
+			It("returns an error", func() {
+				err := imageCloner.Mount(logger, "not-here")
+				Expect(err).To(MatchError(ContainSubstring("image not found: not-here")))
+				Expect(fakeImageDriver.MountImageCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the image driver fails", func() {
+			It("returns an error", func() {
+				fakeImageDriver.MountImageReturns(errors.New("failed"))
+
+				err := imageCloner.Mount(logger, "some-id")
+				Expect(err).To(MatchError("failed"))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		var imagePath string
+
+		BeforeEach(func() {
+			imagePath = path.Join(storePath, store.ImageDirName, "some-id")
+			Expect(os.MkdirAll(imagePath, 0755)).To(Succeed())
+		})
+
+		It("asks the image driver to unmount the image", func() {
+			Expect(imageCloner.Unmount(logger, "some-id")).To(Succeed())
+
+			Expect(fakeImageDriver.UnmountImageCallCount()).To(Equal(1))
+			_, receivedImagePath := fakeImageDriver.UnmountImageArgsForCall(0)
+			Expect(receivedImagePath).To(Equal(imagePath))
+		})
+
+		Context("when image does not exist", func() {
+			It("returns an error", func() {
+				err := imageCloner.Unmount(logger, "not-here")
+				Expect(err).To(MatchError(ContainSubstring("image not found: not-here")))
+			})
+		})
+	})
+
+	Describe("MountAll", func() {
+		It("returns the images mounted by the image driver", func() {
+			fakeImageDriver.MountAllImagesReturns([]string{"some-id"}, nil)
+
+			mounted, err := imageCloner.MountAll(logger)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeImageDriver.MountAllImagesCallCount()).To(Equal(1))
+			Expect(mounted).To(Equal([]string{"some-id"}))
+		})
+	})
 })
@@ -50,6 +50,99 @@ type FakeImageDriver struct {
 		result1 groot.VolumeStats
 		result2 error
 	}
+	FetchAllStatsStub        func(logger lager.Logger) (map[string]groot.VolumeStats, error)
+	fetchAllStatsMutex       sync.RWMutex
+	fetchAllStatsArgsForCall []struct {
+		logger lager.Logger
+	}
+	fetchAllStatsReturns struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}
+	fetchAllStatsReturnsOnCall map[int]struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}
+	MountImageStub        func(logger lager.Logger, path string) error
+	mountImageMutex       sync.RWMutex
+	mountImageArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	mountImageReturns struct {
+		result1 error
+	}
+	mountImageReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UnmountImageStub        func(logger lager.Logger, path string) error
+	unmountImageMutex       sync.RWMutex
+	unmountImageArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	unmountImageReturns struct {
+		result1 error
+	}
+	unmountImageReturnsOnCall map[int]struct {
+		result1 error
+	}
+	MountAllImagesStub        func(logger lager.Logger) ([]string, error)
+	mountAllImagesMutex       sync.RWMutex
+	mountAllImagesArgsForCall []struct {
+		logger lager.Logger
+	}
+	mountAllImagesReturns struct {
+		result1 []string
+		result2 error
+	}
+	mountAllImagesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	ImageMountedStub        func(logger lager.Logger, path string) (bool, error)
+	imageMountedMutex       sync.RWMutex
+	imageMountedArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	imageMountedReturns struct {
+		result1 bool
+		result2 error
+	}
+	imageMountedReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	InspectImageStub        func(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error)
+	inspectImageMutex       sync.RWMutex
+	inspectImageArgsForCall []struct {
+		logger lager.Logger
+		path   string
+	}
+	inspectImageReturns struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}
+	inspectImageReturnsOnCall map[int]struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}
+	FlattenVolumesStub        func(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error)
+	flattenVolumesMutex       sync.RWMutex
+	flattenVolumesArgsForCall []struct {
+		logger    lager.Logger
+		volumeIDs []string
+		maxDepth  int
+	}
+	flattenVolumesReturns struct {
+		result1 []string
+		result2 error
+	}
+	flattenVolumesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -207,6 +300,368 @@ func (fake *FakeImageDriver) FetchStatsReturnsOnCall(i int, result1 groot.Volume
 	}{result1, result2}
 }
 
+func (fake *FakeImageDriver) FetchAllStats(logger lager.Logger) (map[string]groot.VolumeStats, error) {
+	fake.fetchAllStatsMutex.Lock()
+	ret, specificReturn := fake.fetchAllStatsReturnsOnCall[len(fake.fetchAllStatsArgsForCall)]
+	fake.fetchAllStatsArgsForCall = append(fake.fetchAllStatsArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("FetchAllStats", []interface{}{logger})
+	fake.fetchAllStatsMutex.Unlock()
+	if fake.FetchAllStatsStub != nil {
+		return fake.FetchAllStatsStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.fetchAllStatsReturns.result1, fake.fetchAllStatsReturns.result2
+}
+
+func (fake *FakeImageDriver) FetchAllStatsCallCount() int {
+	fake.fetchAllStatsMutex.RLock()
+	defer fake.fetchAllStatsMutex.RUnlock()
+	return len(fake.fetchAllStatsArgsForCall)
+}
+
+func (fake *FakeImageDriver) FetchAllStatsArgsForCall(i int) lager.Logger {
+	fake.fetchAllStatsMutex.RLock()
+	defer fake.fetchAllStatsMutex.RUnlock()
+	return fake.fetchAllStatsArgsForCall[i].logger
+}
+
+func (fake *FakeImageDriver) FetchAllStatsReturns(result1 map[string]groot.VolumeStats, result2 error) {
+	fake.FetchAllStatsStub = nil
+	fake.fetchAllStatsReturns = struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) FetchAllStatsReturnsOnCall(i int, result1 map[string]groot.VolumeStats, result2 error) {
+	fake.FetchAllStatsStub = nil
+	if fake.fetchAllStatsReturnsOnCall == nil {
+		fake.fetchAllStatsReturnsOnCall = make(map[int]struct {
+			result1 map[string]groot.VolumeStats
+			result2 error
+		})
+	}
+	fake.fetchAllStatsReturnsOnCall[i] = struct {
+		result1 map[string]groot.VolumeStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) MountImage(logger lager.Logger, path string) error {
+	fake.mountImageMutex.Lock()
+	ret, specificReturn := fake.mountImageReturnsOnCall[len(fake.mountImageArgsForCall)]
+	fake.mountImageArgsForCall = append(fake.mountImageArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("MountImage", []interface{}{logger, path})
+	fake.mountImageMutex.Unlock()
+	if fake.MountImageStub != nil {
+		return fake.MountImageStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.mountImageReturns.result1
+}
+
+func (fake *FakeImageDriver) MountImageCallCount() int {
+	fake.mountImageMutex.RLock()
+	defer fake.mountImageMutex.RUnlock()
+	return len(fake.mountImageArgsForCall)
+}
+
+func (fake *FakeImageDriver) MountImageArgsForCall(i int) (lager.Logger, string) {
+	fake.mountImageMutex.RLock()
+	defer fake.mountImageMutex.RUnlock()
+	return fake.mountImageArgsForCall[i].logger, fake.mountImageArgsForCall[i].path
+}
+
+func (fake *FakeImageDriver) MountImageReturns(result1 error) {
+	fake.MountImageStub = nil
+	fake.mountImageReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageDriver) MountImageReturnsOnCall(i int, result1 error) {
+	fake.MountImageStub = nil
+	if fake.mountImageReturnsOnCall == nil {
+		fake.mountImageReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.mountImageReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageDriver) UnmountImage(logger lager.Logger, path string) error {
+	fake.unmountImageMutex.Lock()
+	ret, specificReturn := fake.unmountImageReturnsOnCall[len(fake.unmountImageArgsForCall)]
+	fake.unmountImageArgsForCall = append(fake.unmountImageArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("UnmountImage", []interface{}{logger, path})
+	fake.unmountImageMutex.Unlock()
+	if fake.UnmountImageStub != nil {
+		return fake.UnmountImageStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.unmountImageReturns.result1
+}
+
+func (fake *FakeImageDriver) UnmountImageCallCount() int {
+	fake.unmountImageMutex.RLock()
+	defer fake.unmountImageMutex.RUnlock()
+	return len(fake.unmountImageArgsForCall)
+}
+
+func (fake *FakeImageDriver) UnmountImageArgsForCall(i int) (lager.Logger, string) {
+	fake.unmountImageMutex.RLock()
+	defer fake.unmountImageMutex.RUnlock()
+	return fake.unmountImageArgsForCall[i].logger, fake.unmountImageArgsForCall[i].path
+}
+
+func (fake *FakeImageDriver) UnmountImageReturns(result1 error) {
+	fake.UnmountImageStub = nil
+	fake.unmountImageReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageDriver) UnmountImageReturnsOnCall(i int, result1 error) {
+	fake.UnmountImageStub = nil
+	if fake.unmountImageReturnsOnCall == nil {
+		fake.unmountImageReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.unmountImageReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeImageDriver) MountAllImages(logger lager.Logger) ([]string, error) {
+	fake.mountAllImagesMutex.Lock()
+	ret, specificReturn := fake.mountAllImagesReturnsOnCall[len(fake.mountAllImagesArgsForCall)]
+	fake.mountAllImagesArgsForCall = append(fake.mountAllImagesArgsForCall, struct {
+		logger lager.Logger
+	}{logger})
+	fake.recordInvocation("MountAllImages", []interface{}{logger})
+	fake.mountAllImagesMutex.Unlock()
+	if fake.MountAllImagesStub != nil {
+		return fake.MountAllImagesStub(logger)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.mountAllImagesReturns.result1, fake.mountAllImagesReturns.result2
+}
+
+func (fake *FakeImageDriver) MountAllImagesCallCount() int {
+	fake.mountAllImagesMutex.RLock()
+	defer fake.mountAllImagesMutex.RUnlock()
+	return len(fake.mountAllImagesArgsForCall)
+}
+
+func (fake *FakeImageDriver) MountAllImagesArgsForCall(i int) lager.Logger {
+	fake.mountAllImagesMutex.RLock()
+	defer fake.mountAllImagesMutex.RUnlock()
+	return fake.mountAllImagesArgsForCall[i].logger
+}
+
+func (fake *FakeImageDriver) MountAllImagesReturns(result1 []string, result2 error) {
+	fake.MountAllImagesStub = nil
+	fake.mountAllImagesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) MountAllImagesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.MountAllImagesStub = nil
+	if fake.mountAllImagesReturnsOnCall == nil {
+		fake.mountAllImagesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.mountAllImagesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) ImageMounted(logger lager.Logger, path string) (bool, error) {
+	fake.imageMountedMutex.Lock()
+	ret, specificReturn := fake.imageMountedReturnsOnCall[len(fake.imageMountedArgsForCall)]
+	fake.imageMountedArgsForCall = append(fake.imageMountedArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("ImageMounted", []interface{}{logger, path})
+	fake.imageMountedMutex.Unlock()
+	if fake.ImageMountedStub != nil {
+		return fake.ImageMountedStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.imageMountedReturns.result1, fake.imageMountedReturns.result2
+}
+
+func (fake *FakeImageDriver) ImageMountedCallCount() int {
+	fake.imageMountedMutex.RLock()
+	defer fake.imageMountedMutex.RUnlock()
+	return len(fake.imageMountedArgsForCall)
+}
+
+func (fake *FakeImageDriver) ImageMountedArgsForCall(i int) (lager.Logger, string) {
+	fake.imageMountedMutex.RLock()
+	defer fake.imageMountedMutex.RUnlock()
+	return fake.imageMountedArgsForCall[i].logger, fake.imageMountedArgsForCall[i].path
+}
+
+func (fake *FakeImageDriver) ImageMountedReturns(result1 bool, result2 error) {
+	fake.ImageMountedStub = nil
+	fake.imageMountedReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) ImageMountedReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.ImageMountedStub = nil
+	if fake.imageMountedReturnsOnCall == nil {
+		fake.imageMountedReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.imageMountedReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) InspectImage(logger lager.Logger, path string) (groot.ImageFilesystemDetails, error) {
+	fake.inspectImageMutex.Lock()
+	ret, specificReturn := fake.inspectImageReturnsOnCall[len(fake.inspectImageArgsForCall)]
+	fake.inspectImageArgsForCall = append(fake.inspectImageArgsForCall, struct {
+		logger lager.Logger
+		path   string
+	}{logger, path})
+	fake.recordInvocation("InspectImage", []interface{}{logger, path})
+	fake.inspectImageMutex.Unlock()
+	if fake.InspectImageStub != nil {
+		return fake.InspectImageStub(logger, path)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.inspectImageReturns.result1, fake.inspectImageReturns.result2
+}
+
+func (fake *FakeImageDriver) InspectImageCallCount() int {
+	fake.inspectImageMutex.RLock()
+	defer fake.inspectImageMutex.RUnlock()
+	return len(fake.inspectImageArgsForCall)
+}
+
+func (fake *FakeImageDriver) InspectImageArgsForCall(i int) (lager.Logger, string) {
+	fake.inspectImageMutex.RLock()
+	defer fake.inspectImageMutex.RUnlock()
+	return fake.inspectImageArgsForCall[i].logger, fake.inspectImageArgsForCall[i].path
+}
+
+func (fake *FakeImageDriver) InspectImageReturns(result1 groot.ImageFilesystemDetails, result2 error) {
+	fake.InspectImageStub = nil
+	fake.inspectImageReturns = struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) InspectImageReturnsOnCall(i int, result1 groot.ImageFilesystemDetails, result2 error) {
+	fake.InspectImageStub = nil
+	if fake.inspectImageReturnsOnCall == nil {
+		fake.inspectImageReturnsOnCall = make(map[int]struct {
+			result1 groot.ImageFilesystemDetails
+			result2 error
+		})
+	}
+	fake.inspectImageReturnsOnCall[i] = struct {
+		result1 groot.ImageFilesystemDetails
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) FlattenVolumes(logger lager.Logger, volumeIDs []string, maxDepth int) ([]string, error) {
+	var volumeIDsCopy []string
+	if volumeIDs != nil {
+		volumeIDsCopy = make([]string, len(volumeIDs))
+		copy(volumeIDsCopy, volumeIDs)
+	}
+	fake.flattenVolumesMutex.Lock()
+	ret, specificReturn := fake.flattenVolumesReturnsOnCall[len(fake.flattenVolumesArgsForCall)]
+	fake.flattenVolumesArgsForCall = append(fake.flattenVolumesArgsForCall, struct {
+		logger    lager.Logger
+		volumeIDs []string
+		maxDepth  int
+	}{logger, volumeIDsCopy, maxDepth})
+	fake.recordInvocation("FlattenVolumes", []interface{}{logger, volumeIDsCopy, maxDepth})
+	fake.flattenVolumesMutex.Unlock()
+	if fake.FlattenVolumesStub != nil {
+		return fake.FlattenVolumesStub(logger, volumeIDs, maxDepth)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.flattenVolumesReturns.result1, fake.flattenVolumesReturns.result2
+}
+
+func (fake *FakeImageDriver) FlattenVolumesCallCount() int {
+	fake.flattenVolumesMutex.RLock()
+	defer fake.flattenVolumesMutex.RUnlock()
+	return len(fake.flattenVolumesArgsForCall)
+}
+
+func (fake *FakeImageDriver) FlattenVolumesArgsForCall(i int) (lager.Logger, []string, int) {
+	fake.flattenVolumesMutex.RLock()
+	defer fake.flattenVolumesMutex.RUnlock()
+	return fake.flattenVolumesArgsForCall[i].logger, fake.flattenVolumesArgsForCall[i].volumeIDs, fake.flattenVolumesArgsForCall[i].maxDepth
+}
+
+func (fake *FakeImageDriver) FlattenVolumesReturns(result1 []string, result2 error) {
+	fake.FlattenVolumesStub = nil
+	fake.flattenVolumesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImageDriver) FlattenVolumesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.FlattenVolumesStub = nil
+	if fake.flattenVolumesReturnsOnCall == nil {
+		fake.flattenVolumesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.flattenVolumesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeImageDriver) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -216,6 +671,20 @@ func (fake *FakeImageDriver) Invocations() map[string][][]interface{} {
 	defer fake.destroyImageMutex.RUnlock()
 	fake.fetchStatsMutex.RLock()
 	defer fake.fetchStatsMutex.RUnlock()
+	fake.fetchAllStatsMutex.RLock()
+	defer fake.fetchAllStatsMutex.RUnlock()
+	fake.mountImageMutex.RLock()
+	defer fake.mountImageMutex.RUnlock()
+	fake.unmountImageMutex.RLock()
+	defer fake.unmountImageMutex.RUnlock()
+	fake.mountAllImagesMutex.RLock()
+	defer fake.mountAllImagesMutex.RUnlock()
+	fake.imageMountedMutex.RLock()
+	defer fake.imageMountedMutex.RUnlock()
+	fake.inspectImageMutex.RLock()
+	defer fake.inspectImageMutex.RUnlock()
+	fake.flattenVolumesMutex.RLock()
+	defer fake.flattenVolumesMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
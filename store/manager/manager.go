@@ -38,9 +38,10 @@ type StoreNamespacer interface {
 }
 
 type InitSpec struct {
-	UIDMappings    []groot.IDMappingSpec
-	GIDMappings    []groot.IDMappingSpec
-	StoreSizeBytes int64
+	UIDMappings     []groot.IDMappingSpec
+	GIDMappings     []groot.IDMappingSpec
+	StoreSizeBytes  int64
+	PreserveDevices bool
 }
 
 func New(storePath string, storeNamespacer StoreNamespacer, volumeDriver base_image_puller.VolumeDriver, imageDriver image_cloner.ImageDriver, storeDriver StoreDriver) *Manager {
@@ -88,12 +89,20 @@ func (m *Manager) InitStore(logger lager.Logger, spec InitSpec) error {
 		return errorspkg.Wrap(err, "initializing store")
 	}
 
+	_, err = os.Stat(filepath.Join(m.storePath, store.MetaDirName, groot.NamespaceFilename))
+	reinitializing := err == nil
+
 	err = m.storeNamespacer.ApplyMappings(spec.UIDMappings, spec.GIDMappings)
 	if err != nil {
 		logger.Error("applying-namespace-mappings-failed", err)
 		return err
 	}
 
+	if err := m.applyPreserveDevices(spec.PreserveDevices, reinitializing); err != nil {
+		logger.Error("applying-preserve-devices-failed", err)
+		return err
+	}
+
 	ownerUID, ownerGID := m.findStoreOwner(spec.UIDMappings, spec.GIDMappings)
 	if err := os.Chown(m.storePath, ownerUID, ownerGID); err != nil {
 		logger.Error("chowning-store-path-failed", err, lager.Data{"uid": ownerUID, "gid": ownerGID})
@@ -121,6 +130,34 @@ func (m *Manager) IsStoreInitialized(logger lager.Logger) bool {
 	return true
 }
 
+func (m *Manager) applyPreserveDevices(preserve, reinitializing bool) error {
+	preserved, err := groot.DevicesPreserved(m.storePath)
+	if err != nil {
+		return err
+	}
+	if preserved == preserve {
+		return nil
+	}
+	if reinitializing {
+		return errorspkg.New("provided preserve devices setting does not match the one already configured in the store")
+	}
+
+	preserveDevicesPath := filepath.Join(m.storePath, store.MetaDirName, groot.PreserveDevicesFilename)
+	if !preserve {
+		// left behind by an init-store that failed before writing the namespace
+		if err := os.Remove(preserveDevicesPath); err != nil {
+			return errorspkg.Wrap(err, "removing preserve devices file")
+		}
+		return nil
+	}
+
+	if err := ioutil.WriteFile(preserveDevicesPath, []byte{}, 0644); err != nil {
+		return errorspkg.Wrap(err, "writing preserve devices file")
+	}
+
+	return nil
+}
+
 func (m *Manager) configureStore(logger lager.Logger, ownerUID, ownerGID int) error {
 	logger = logger.Session("store-manager-configure-store", lager.Data{"storePath": m.storePath, "ownerUID": ownerUID, "ownerGID": ownerGID})
 	logger.Debug("starting")
@@ -137,6 +137,56 @@ var _ = Describe("Manager", func() {
 			Expect(stat.Mode().Perm()).To(Equal(os.FileMode(0700)))
 		})
 
+		It("doesn't record the store as preserving devices", func() {
+			Expect(manager.InitStore(logger, spec)).To(Succeed())
+			Expect(filepath.Join(storePath, store.MetaDirName, groot.PreserveDevicesFilename)).NotTo(BeAnExistingFile())
+		})
+
+		Context("when devices are preserved", func() {
+			BeforeEach(func() {
+				spec.PreserveDevices = true
+			})
+
+			It("records it in the store", func() {
+				Expect(manager.InitStore(logger, spec)).To(Succeed())
+
+				preserved, err := groot.DevicesPreserved(storePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(preserved).To(BeTrue())
+			})
+
+			Context("when the store is already initialized without preserving devices", func() {
+				BeforeEach(func() {
+					Expect(os.MkdirAll(filepath.Join(storePath, store.MetaDirName), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(storePath, store.MetaDirName, groot.NamespaceFilename), []byte("{}"), 0644)).To(Succeed())
+				})
+
+				It("returns an error", func() {
+					err := manager.InitStore(logger, spec)
+					Expect(err).To(MatchError(ContainSubstring("preserve devices setting does not match")))
+					Expect(filepath.Join(storePath, store.MetaDirName, groot.PreserveDevicesFilename)).NotTo(BeAnExistingFile())
+				})
+			})
+
+			Context("when the store is already initialized preserving devices", func() {
+				BeforeEach(func() {
+					Expect(os.MkdirAll(filepath.Join(storePath, store.MetaDirName), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(storePath, store.MetaDirName, groot.NamespaceFilename), []byte("{}"), 0644)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(storePath, store.MetaDirName, groot.PreserveDevicesFilename), []byte{}, 0644)).To(Succeed())
+				})
+
+				It("succeeds", func() {
+					Expect(manager.InitStore(logger, spec)).To(Succeed())
+				})
+
+				It("returns an error when devices are no longer preserved", func() {
+					spec.PreserveDevices = false
+					err := manager.InitStore(logger, spec)
+					Expect(err).To(MatchError(ContainSubstring("preserve devices setting does not match")))
+				})
+			})
+		})
+
 		Context("when store driver configuration fails", func() {
 			It("returns an error", func() {
 				storeDriver.ConfigureStoreReturns(errors.New("configuration failed"))
@@ -67,6 +67,10 @@ func (s *StoreMeasurer) TotalVolumesSize(logger lager.Logger) (int64, error) {
 	return s.countVolumesSize(logger, vols)
 }
 
+func (s *StoreMeasurer) VolumeSize(logger lager.Logger, id string) (int64, error) {
+	return s.volumeDriver.VolumeSize(logger, id)
+}
+
 func (s *StoreMeasurer) countVolumesSize(logger lager.Logger, volumes []string) (int64, error) {
 	var size int64
 	for _, volume := range volumes {
@@ -109,6 +113,34 @@ func (s *StoreMeasurer) CommittedQuota(logger lager.Logger) (int64, error) {
 	return totalCommittedSpace, nil
 }
 
+// TrashSize is the disk space used by images deleted asynchronously that
+// haven't been reaped yet.
+func (s *StoreMeasurer) TrashSize(logger lager.Logger) (int64, error) {
+	logger = logger.Session("measuring-trash")
+	logger.Debug("starting")
+	defer logger.Debug("ending")
+
+	var size int64
+	err := filepath.Walk(filepath.Join(s.storePath, TrashDirName), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			size += stat.Blocks * 512
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errorspkg.Wrap(err, "measuring trash")
+	}
+
+	return size, nil
+}
+
 func readImageQuota(imageDir string) (int64, error) {
 	quotaFilePath := filepath.Join(imageDir, "image_quota")
 	imageQuotaBytes, err := ioutil.ReadFile(quotaFilePath)
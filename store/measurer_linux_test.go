@@ -206,6 +206,27 @@ var _ = Describe("Measurer", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
+
+	Describe("TrashSize", func() {
+		It("measures the space used by the trashed images", func() {
+			trashedImagePath := filepath.Join(storePath, store.TrashDirName, "my-image-123")
+			Expect(os.MkdirAll(filepath.Join(trashedImagePath, "diff"), 0744)).To(Succeed())
+			Expect(writeFile(filepath.Join(trashedImagePath, "diff", "my-file"), 2048*1024)).To(Succeed())
+
+			trashSize, err := storeMeasurer.TrashSize(logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trashSize).To(BeNumerically(">=", 2048*1024))
+			Expect(trashSize).To(BeNumerically("<", 2048*1024+64*1024))
+		})
+
+		Context("when there is no trash", func() {
+			It("returns 0", func() {
+				trashSize, err := storeMeasurer.TrashSize(logger)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(trashSize).To(BeZero())
+			})
+		})
+	})
 })
 
 func writeFile(path string, size int64) error {
@@ -0,0 +1,19 @@
+package rootfs_configurer
+
+import (
+	"code.cloudfoundry.org/grootfs/groot"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Chain runs its configurers in order, stopping at the first failure.
+type Chain []groot.RootFSConfigurer
+
+func (c Chain) Configure(rootFSPath string, baseImage *specsv1.Image) error {
+	for _, configurer := range c {
+		if err := configurer.Configure(rootFSPath, baseImage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package rootfs_configurer_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/groot/grootfakes"
+	"code.cloudfoundry.org/grootfs/store/rootfs_configurer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var _ = Describe("Chain", func() {
+	var (
+		fakeConfigurer1 *grootfakes.FakeRootFSConfigurer
+		fakeConfigurer2 *grootfakes.FakeRootFSConfigurer
+		chain           rootfs_configurer.Chain
+		baseImage       *specsv1.Image
+	)
+
+	BeforeEach(func() {
+		fakeConfigurer1 = new(grootfakes.FakeRootFSConfigurer)
+		fakeConfigurer2 = new(grootfakes.FakeRootFSConfigurer)
+		chain = rootfs_configurer.Chain([]groot.RootFSConfigurer{fakeConfigurer1, fakeConfigurer2})
+		baseImage = &specsv1.Image{Author: "me"}
+	})
+
+	It("runs every configurer with the same rootfs and base image", func() {
+		Expect(chain.Configure("/images/my-image/diff", baseImage)).To(Succeed())
+
+		for _, fakeConfigurer := range []*grootfakes.FakeRootFSConfigurer{fakeConfigurer1, fakeConfigurer2} {
+			Expect(fakeConfigurer.ConfigureCallCount()).To(Equal(1))
+			rootFSPath, configuredImage := fakeConfigurer.ConfigureArgsForCall(0)
+			Expect(rootFSPath).To(Equal("/images/my-image/diff"))
+			Expect(configuredImage).To(Equal(baseImage))
+		}
+	})
+
+	Context("when a configurer fails", func() {
+		BeforeEach(func() {
+			fakeConfigurer1.ConfigureReturns(errors.New("failed to configure"))
+		})
+
+		It("returns the error without running the next ones", func() {
+			Expect(chain.Configure("/images/my-image/diff", baseImage)).To(MatchError("failed to configure"))
+			Expect(fakeConfigurer2.ConfigureCallCount()).To(Equal(0))
+		})
+	})
+})
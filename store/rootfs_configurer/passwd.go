@@ -0,0 +1,117 @@
+package rootfs_configurer
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	errorspkg "github.com/pkg/errors"
+)
+
+const passwdPath = "/etc/passwd"
+
+// PasswdConfigurer adds an /etc/passwd entry for a numeric image user that
+// doesn't have one, as some programs fail to run as a user they can't look
+// up. The primary group is the numeric group of the image user, or root.
+type PasswdConfigurer struct {
+	logger lager.Logger
+	rootFS *RootFS
+}
+
+func NewPasswdConfigurer(logger lager.Logger, rootFS *RootFS) *PasswdConfigurer {
+	return &PasswdConfigurer{
+		logger: logger,
+		rootFS: rootFS,
+	}
+}
+
+func (c *PasswdConfigurer) Configure(rootFSPath string, baseImage *specsv1.Image) error {
+	uid, gid, ok := numericUser(baseImage.Config.User)
+	if !ok {
+		return nil
+	}
+
+	logger := c.logger.Session("configuring-passwd", lager.Data{"rootFSPath": rootFSPath, "user": baseImage.Config.User})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	image, err := c.rootFS.open(rootFSPath)
+	if err != nil {
+		return err
+	}
+
+	passwd, info, err := image.readFile(passwdPath)
+	if err != nil {
+		return err
+	}
+	if info != nil && !info.Mode().IsRegular() {
+		logger.Info("passwd-is-not-a-regular-file")
+		return nil
+	}
+	if hasPasswdEntry(passwd, uid) {
+		return nil
+	}
+
+	entries, ok, err := image.missingDirs("/etc")
+	if err != nil {
+		return errorspkg.Wrap(err, "creating /etc")
+	}
+	if !ok {
+		logger.Info("etc-is-not-a-directory")
+		return nil
+	}
+
+	mode := int64(0644)
+	if info != nil {
+		mode = int64(info.Mode().Perm())
+	}
+	if len(passwd) > 0 && !bytes.HasSuffix(passwd, []byte("\n")) {
+		passwd = append(passwd, '\n')
+	}
+	passwd = append(passwd, fmt.Sprintf("user%d:x:%d:%d::/:/sbin/nologin\n", uid, uid, gid)...)
+
+	entries = append(entries, entry{
+		header: tar.Header{
+			Name:     strings.TrimPrefix(passwdPath, "/"),
+			Typeflag: tar.TypeReg,
+			Mode:     mode,
+		},
+		contents: passwd,
+	})
+
+	return image.write(logger, entries)
+}
+
+// numericUser parses a `uid` or `uid:group` image user. A group that isn't
+// numeric is resolved by the runtime, so the entry gets root as its group.
+func numericUser(user string) (uint64, uint64, bool) {
+	parts := strings.SplitN(user, ":", 2)
+	uid, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	var gid uint64
+	if len(parts) == 2 {
+		if parsed, err := strconv.ParseUint(parts[1], 10, 32); err == nil {
+			gid = parsed
+		}
+	}
+
+	return uid, gid, true
+}
+
+func hasPasswdEntry(passwd []byte, uid uint64) bool {
+	for _, line := range strings.Split(string(passwd), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) >= 3 && fields[2] == strconv.FormatUint(uid, 10) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,160 @@
+package rootfs_configurer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/rootfs_configurer"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/docker/docker/pkg/system"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var _ = Describe("PasswdConfigurer", func() {
+	var (
+		upperDir        string
+		lowerDir        string
+		fakeUnpacker    *base_image_pullerfakes.FakeUnpacker
+		unpackedEntries []tarEntry
+		baseImage       *specsv1.Image
+		configurer      *rootfs_configurer.PasswdConfigurer
+	)
+
+	BeforeEach(func() {
+		var err error
+		upperDir, err = ioutil.TempDir("", "upper")
+		Expect(err).NotTo(HaveOccurred())
+		lowerDir, err = ioutil.TempDir("", "lower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(lowerDir, "etc"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(lowerDir, "etc", "passwd"),
+			[]byte("root:x:0:0:root:/root:/bin/sh\nalice:x:1000:1000::/home/alice:/bin/sh"), 0640)).To(Succeed())
+
+		unpackedEntries = []tarEntry{}
+		fakeUnpacker = new(base_image_pullerfakes.FakeUnpacker)
+		unpackInto(fakeUnpacker, &unpackedEntries)
+
+		baseImage = &specsv1.Image{}
+		baseImage.Config.User = "1001"
+
+		rootFS := rootfs_configurer.NewRootFS(fakeUnpacker, groot.IDMappings{}, func(dir string) ([]string, error) {
+			return []string{upperDir, lowerDir}, nil
+		})
+		configurer = rootfs_configurer.NewPasswdConfigurer(lagertest.NewTestLogger("passwd"), rootFS)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(upperDir)).To(Succeed())
+		Expect(os.RemoveAll(lowerDir)).To(Succeed())
+	})
+
+	It("appends an entry for the user to the image's /etc/passwd, keeping its mode", func() {
+		Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+
+		Expect(unpackedEntries).To(HaveLen(1))
+		Expect(unpackedEntries[0].header.Name).To(Equal("etc/passwd"))
+		Expect(unpackedEntries[0].header.Mode).To(Equal(int64(0640)))
+		Expect(unpackedEntries[0].contents).To(Equal(
+			"root:x:0:0:root:/root:/bin/sh\nalice:x:1000:1000::/home/alice:/bin/sh\nuser1001:x:1001:0::/:/sbin/nologin\n",
+		))
+	})
+
+	It("reads the topmost /etc/passwd", func() {
+		Expect(os.MkdirAll(filepath.Join(upperDir, "etc"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(upperDir, "etc", "passwd"), []byte("root:x:0:0:root:/root:/bin/sh\n"), 0644)).To(Succeed())
+
+		Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+		Expect(unpackedEntries[0].contents).To(Equal("root:x:0:0:root:/root:/bin/sh\nuser1001:x:1001:0::/:/sbin/nologin\n"))
+	})
+
+	Context("when the user has a numeric group", func() {
+		BeforeEach(func() {
+			baseImage.Config.User = "1001:50"
+		})
+
+		It("uses it as the primary group", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(unpackedEntries[0].contents).To(HaveSuffix("user1001:x:1001:50::/:/sbin/nologin\n"))
+		})
+	})
+
+	Context("when the user already has an entry", func() {
+		BeforeEach(func() {
+			baseImage.Config.User = "1000:staff"
+		})
+
+		It("doesn't write anything", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the user is a name", func() {
+		BeforeEach(func() {
+			baseImage.Config.User = "bob"
+		})
+
+		It("doesn't write anything", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when an upper layer has an opaque /etc", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(upperDir, "etc"), 0755)).To(Succeed())
+			Expect(system.Lsetxattr(filepath.Join(upperDir, "etc"), "trusted.overlay.opaque", []byte("y"), 0)).To(Succeed())
+		})
+
+		It("doesn't read /etc/passwd from the layers below it", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+
+			Expect(entryNames(unpackedEntries)).To(Equal([]string{"etc/passwd"}))
+			Expect(unpackedEntries[0].contents).To(Equal("user1001:x:1001:0::/:/sbin/nologin\n"))
+		})
+	})
+
+	Context("when /etc/passwd is a symlink", func() {
+		BeforeEach(func() {
+			Expect(os.Remove(filepath.Join(lowerDir, "etc", "passwd"))).To(Succeed())
+			Expect(os.Symlink("/etc/passwd", filepath.Join(lowerDir, "etc", "passwd"))).To(Succeed())
+		})
+
+		It("leaves the image alone", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when /etc is a symlink", func() {
+		BeforeEach(func() {
+			Expect(os.RemoveAll(filepath.Join(lowerDir, "etc"))).To(Succeed())
+			Expect(os.Symlink("/etc", filepath.Join(lowerDir, "etc"))).To(Succeed())
+		})
+
+		It("doesn't follow it", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(MatchError(ContainSubstring("`/etc` is a symlink")))
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the image has no /etc/passwd", func() {
+		BeforeEach(func() {
+			Expect(os.RemoveAll(filepath.Join(lowerDir, "etc"))).To(Succeed())
+		})
+
+		It("creates it with /etc", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+
+			Expect(entryNames(unpackedEntries)).To(Equal([]string{"etc/", "etc/passwd"}))
+			Expect(unpackedEntries[1].header.Mode).To(Equal(int64(0644)))
+			Expect(unpackedEntries[1].contents).To(Equal("user1001:x:1001:0::/:/sbin/nologin\n"))
+		})
+	})
+})
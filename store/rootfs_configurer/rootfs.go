@@ -0,0 +1,243 @@
+package rootfs_configurer // import "code.cloudfoundry.org/grootfs/store/rootfs_configurer"
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/lager"
+	"github.com/docker/docker/pkg/system"
+	errorspkg "github.com/pkg/errors"
+)
+
+// LookupDirsFunc returns the directories the files of the image with the
+// given upper directory are looked up in, top first.
+type LookupDirsFunc func(upperDir string) ([]string, error)
+
+// RootFS is shared by the built-in configurers. It reads an image through its
+// layers, whether or not it's mounted, and writes to its upper directory
+// through the store's unpacker, so new files get the store's id mappings
+// exactly like layer files.
+type RootFS struct {
+	unpacker   base_image_puller.Unpacker
+	idMappings groot.IDMappings
+	lookupDirs LookupDirsFunc
+}
+
+func NewRootFS(unpacker base_image_puller.Unpacker, idMappings groot.IDMappings, lookupDirs LookupDirsFunc) *RootFS {
+	return &RootFS{
+		unpacker:   unpacker,
+		idMappings: idMappings,
+		lookupDirs: lookupDirs,
+	}
+}
+
+type image struct {
+	rootFS     *RootFS
+	upperDir   string
+	lookupDirs []string
+}
+
+type entry struct {
+	header   tar.Header
+	contents []byte
+}
+
+func (r *RootFS) open(upperDir string) (*image, error) {
+	lookupDirs, err := r.lookupDirs(upperDir)
+	if err != nil {
+		return nil, errorspkg.Wrap(err, "looking up image layers")
+	}
+
+	return &image{rootFS: r, upperDir: upperDir, lookupDirs: lookupDirs}, nil
+}
+
+// lstat returns the topmost version of an image path and where it was found,
+// or nil when no layer has it, or it was removed by a whiteout or hidden by an
+// opaque directory.
+func (i *image) lstat(path string) (os.FileInfo, string, error) {
+	return lookupPath(i.lookupDirs, path)
+}
+
+// lookupPath resolves path in the layer directories lookupDirs, top first, a
+// component at a time without following symlinks, as they would point outside
+// of the image. A symlinked parent directory is an error.
+func lookupPath(lookupDirs []string, path string) (os.FileInfo, string, error) {
+	components := strings.Split(strings.Trim(filepath.Clean(path), "/"), "/")
+
+	for index, dir := range lookupDirs {
+		hostPath := dir
+		opaque := false
+		for n, component := range components {
+			hostPath = filepath.Join(hostPath, component)
+			info, err := os.Lstat(hostPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					break
+				}
+				return nil, "", errorspkg.Wrapf(err, "looking up `%s`", path)
+			}
+
+			if isWhiteout(info) {
+				return nil, "", nil
+			}
+			if n == len(components)-1 {
+				return info, hostPath, nil
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				return nil, "", errorspkg.Errorf("looking up `%s`: `%s` is a symlink", path, strings.TrimPrefix(hostPath, dir))
+			}
+			if !info.IsDir() {
+				return nil, "", nil
+			}
+
+			// Opaque directories only matter when there are lower layers.
+			if index < len(lookupDirs)-1 && !opaque {
+				if opaque, err = isOpaque(hostPath); err != nil {
+					return nil, "", errorspkg.Wrapf(err, "looking up `%s`", path)
+				}
+			}
+		}
+
+		if opaque {
+			return nil, "", nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+// missingDirs returns entries for the directories of path, and path itself,
+// that the image doesn't have yet. It returns false when a component exists
+// but isn't a directory.
+func (i *image) missingDirs(path string) ([]entry, bool, error) {
+	dirs, ok, err := MissingDirs(i.lookupDirs, path)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	entries := []entry{}
+	for _, dir := range dirs {
+		entries = append(entries, entry{header: tar.Header{
+			Name:     strings.TrimPrefix(dir, "/") + "/",
+			Typeflag: tar.TypeDir,
+			Mode:     0755,
+		}})
+	}
+
+	return entries, true, nil
+}
+
+// MissingDirs returns the directories of path, and path itself, that no layer
+// in lookupDirs has, from the root down. Existing directories are left alone so that
+// their mode and owner aren't shadowed by the upper directory. It returns
+// false when a component exists but isn't a directory, as a symlink can't be
+// followed safely outside of the image.
+func MissingDirs(lookupDirs []string, path string) ([]string, bool, error) {
+	dirs := []string{}
+	dir := "/"
+	for _, component := range strings.Split(strings.Trim(filepath.Clean(path), "/"), "/") {
+		if component == "" {
+			continue
+		}
+		dir = filepath.Join(dir, component)
+
+		info, _, err := lookupPath(lookupDirs, dir)
+		if err != nil {
+			return nil, false, err
+		}
+		if info == nil {
+			dirs = append(dirs, dir)
+			continue
+		}
+		if !info.IsDir() {
+			return nil, false, nil
+		}
+	}
+
+	return dirs, true, nil
+}
+
+// readFile only reads regular files, other files are returned without their
+// contents.
+func (i *image) readFile(path string) ([]byte, os.FileInfo, error) {
+	info, hostPath, err := i.lstat(path)
+	if err != nil || info == nil || !info.Mode().IsRegular() {
+		return nil, info, err
+	}
+
+	contents, err := ioutil.ReadFile(hostPath)
+	if err != nil {
+		return nil, nil, errorspkg.Wrapf(err, "reading `%s`", path)
+	}
+
+	return contents, info, nil
+}
+
+func (i *image) write(logger lager.Logger, entries []entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tarReader, tarWriter := io.Pipe()
+	go func() {
+		tarWriter.CloseWithError(writeTar(tarWriter, entries))
+	}()
+	defer tarReader.Close()
+
+	if _, err := i.rootFS.unpacker.Unpack(logger, base_image_puller.UnpackSpec{
+		Stream:      tarReader,
+		TargetPath:  i.upperDir,
+		UIDMappings: i.rootFS.idMappings.UIDMappings,
+		GIDMappings: i.rootFS.idMappings.GIDMappings,
+	}); err != nil {
+		logger.Error("unpacking-entries-failed", err)
+		return errorspkg.Wrap(err, "writing to the image")
+	}
+
+	return nil
+}
+
+func writeTar(writer io.Writer, entries []entry) error {
+	tarWriter := tar.NewWriter(writer)
+	for _, entry := range entries {
+		header := entry.header
+		header.Size = int64(len(entry.contents))
+		if err := tarWriter.WriteHeader(&header); err != nil {
+			return errorspkg.Wrapf(err, "writing `/%s`", header.Name)
+		}
+		if _, err := io.Copy(tarWriter, bytes.NewReader(entry.contents)); err != nil {
+			return errorspkg.Wrapf(err, "writing `/%s`", header.Name)
+		}
+	}
+
+	return tarWriter.Close()
+}
+
+// isWhiteout matches the character devices overlay uses for removed files.
+func isWhiteout(info os.FileInfo) bool {
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Rdev == 0
+}
+
+// isOpaque matches the directories that hide the layers below them, as marked
+// by the driver when the layer had an opaque whiteout.
+func isOpaque(path string) (bool, error) {
+	value, err := system.Lgetxattr(path, "trusted.overlay.opaque")
+	if err != nil {
+		return false, err
+	}
+
+	return string(value) == "y", nil
+}
@@ -0,0 +1,55 @@
+package rootfs_configurer_test
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/lager"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRootFSConfigurer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RootFS Configurer Suite")
+}
+
+type tarEntry struct {
+	header   tar.Header
+	contents string
+}
+
+// unpackInto makes the fake unpacker record the entries of each stream.
+func unpackInto(fakeUnpacker *base_image_pullerfakes.FakeUnpacker, entries *[]tarEntry) {
+	fakeUnpacker.UnpackStub = func(_ lager.Logger, spec base_image_puller.UnpackSpec) (base_image_puller.UnpackOutput, error) {
+		tarReader := tar.NewReader(spec.Stream)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadAll(tarReader)
+			Expect(err).NotTo(HaveOccurred())
+			*entries = append(*entries, tarEntry{header: *header, contents: string(contents)})
+		}
+
+		return base_image_puller.UnpackOutput{}, nil
+	}
+}
+
+func entryNames(entries []tarEntry) []string {
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.header.Name)
+	}
+
+	return names
+}
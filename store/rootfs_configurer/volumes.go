@@ -0,0 +1,67 @@
+package rootfs_configurer
+
+import (
+	"sort"
+
+	"code.cloudfoundry.org/lager"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	errorspkg "github.com/pkg/errors"
+)
+
+// VolumesConfigurer creates the mount points of the image's volumes, so the
+// bind mounts in the returned spec don't depend on the runtime creating them.
+type VolumesConfigurer struct {
+	logger lager.Logger
+	rootFS *RootFS
+}
+
+func NewVolumesConfigurer(logger lager.Logger, rootFS *RootFS) *VolumesConfigurer {
+	return &VolumesConfigurer{
+		logger: logger,
+		rootFS: rootFS,
+	}
+}
+
+func (c *VolumesConfigurer) Configure(rootFSPath string, baseImage *specsv1.Image) error {
+	if len(baseImage.Config.Volumes) == 0 {
+		return nil
+	}
+
+	volumes := []string{}
+	for volume := range baseImage.Config.Volumes {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+
+	logger := c.logger.Session("configuring-volumes", lager.Data{"rootFSPath": rootFSPath, "volumes": volumes})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	image, err := c.rootFS.open(rootFSPath)
+	if err != nil {
+		return err
+	}
+
+	entries := []entry{}
+	created := map[string]bool{}
+	for _, volume := range volumes {
+		volumeEntries, ok, err := image.missingDirs(volume)
+		if err != nil {
+			return errorspkg.Wrapf(err, "creating mount point for volume `%s`", volume)
+		}
+		if !ok {
+			logger.Info("volume-is-not-a-directory", lager.Data{"volume": volume})
+			continue
+		}
+
+		// Nested volumes share their parent directories.
+		for _, volumeEntry := range volumeEntries {
+			if !created[volumeEntry.header.Name] {
+				created[volumeEntry.header.Name] = true
+				entries = append(entries, volumeEntry)
+			}
+		}
+	}
+
+	return image.write(logger, entries)
+}
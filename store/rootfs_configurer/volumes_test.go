@@ -0,0 +1,79 @@
+package rootfs_configurer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/rootfs_configurer"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var _ = Describe("VolumesConfigurer", func() {
+	var (
+		upperDir        string
+		lowerDir        string
+		fakeUnpacker    *base_image_pullerfakes.FakeUnpacker
+		unpackedEntries []tarEntry
+		baseImage       *specsv1.Image
+		configurer      *rootfs_configurer.VolumesConfigurer
+	)
+
+	BeforeEach(func() {
+		var err error
+		upperDir, err = ioutil.TempDir("", "upper")
+		Expect(err).NotTo(HaveOccurred())
+		lowerDir, err = ioutil.TempDir("", "lower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(lowerDir, "var", "lib"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(upperDir, "data"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(lowerDir, "config"), []byte{}, 0644)).To(Succeed())
+
+		unpackedEntries = []tarEntry{}
+		fakeUnpacker = new(base_image_pullerfakes.FakeUnpacker)
+		unpackInto(fakeUnpacker, &unpackedEntries)
+
+		baseImage = &specsv1.Image{}
+		baseImage.Config.Volumes = map[string]struct{}{
+			"/var/lib/db":       struct{}{},
+			"/var/lib/db/index": {},
+			"/data":             struct{}{},
+			"/config":           struct{}{},
+			"/cache":            struct{}{},
+		}
+
+		rootFS := rootfs_configurer.NewRootFS(fakeUnpacker, groot.IDMappings{}, func(dir string) ([]string, error) {
+			return []string{upperDir, lowerDir}, nil
+		})
+		configurer = rootfs_configurer.NewVolumesConfigurer(lagertest.NewTestLogger("volumes"), rootFS)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(upperDir)).To(Succeed())
+		Expect(os.RemoveAll(lowerDir)).To(Succeed())
+	})
+
+	It("creates the missing mount points once, in a single unpack", func() {
+		Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+
+		Expect(fakeUnpacker.UnpackCallCount()).To(Equal(1))
+		Expect(entryNames(unpackedEntries)).To(Equal([]string{"cache/", "var/lib/db/", "var/lib/db/index/"}))
+	})
+
+	Context("when the image has no volumes", func() {
+		BeforeEach(func() {
+			baseImage.Config.Volumes = nil
+		})
+
+		It("doesn't write anything", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+})
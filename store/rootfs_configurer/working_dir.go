@@ -0,0 +1,48 @@
+package rootfs_configurer
+
+import (
+	"code.cloudfoundry.org/lager"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	errorspkg "github.com/pkg/errors"
+)
+
+// WorkingDirConfigurer creates the image's working directory, which images
+// built with a WORKDIR of a missing directory don't have.
+type WorkingDirConfigurer struct {
+	logger lager.Logger
+	rootFS *RootFS
+}
+
+func NewWorkingDirConfigurer(logger lager.Logger, rootFS *RootFS) *WorkingDirConfigurer {
+	return &WorkingDirConfigurer{
+		logger: logger,
+		rootFS: rootFS,
+	}
+}
+
+func (c *WorkingDirConfigurer) Configure(rootFSPath string, baseImage *specsv1.Image) error {
+	workingDir := baseImage.Config.WorkingDir
+	if workingDir == "" {
+		return nil
+	}
+
+	logger := c.logger.Session("configuring-working-dir", lager.Data{"rootFSPath": rootFSPath, "workingDir": workingDir})
+	logger.Info("starting")
+	defer logger.Info("ending")
+
+	image, err := c.rootFS.open(rootFSPath)
+	if err != nil {
+		return err
+	}
+
+	entries, ok, err := image.missingDirs(workingDir)
+	if err != nil {
+		return errorspkg.Wrap(err, "creating working directory")
+	}
+	if !ok {
+		logger.Info("working-dir-is-not-a-directory")
+		return nil
+	}
+
+	return image.write(logger, entries)
+}
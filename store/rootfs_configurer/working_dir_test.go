@@ -0,0 +1,152 @@
+package rootfs_configurer_test
+
+import (
+	"archive/tar"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/grootfs/base_image_puller"
+	"code.cloudfoundry.org/grootfs/base_image_puller/base_image_pullerfakes"
+	"code.cloudfoundry.org/grootfs/groot"
+	"code.cloudfoundry.org/grootfs/store/rootfs_configurer"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	specsv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var _ = Describe("WorkingDirConfigurer", func() {
+	var (
+		upperDir        string
+		lowerDir        string
+		fakeUnpacker    *base_image_pullerfakes.FakeUnpacker
+		idMappings      groot.IDMappings
+		unpackedEntries []tarEntry
+		lookupErr       error
+		baseImage       *specsv1.Image
+		configurer      *rootfs_configurer.WorkingDirConfigurer
+	)
+
+	BeforeEach(func() {
+		var err error
+		upperDir, err = ioutil.TempDir("", "upper")
+		Expect(err).NotTo(HaveOccurred())
+		lowerDir, err = ioutil.TempDir("", "lower")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(lowerDir, "home", "app"), 0700)).To(Succeed())
+
+		idMappings = groot.IDMappings{
+			UIDMappings: []groot.IDMappingSpec{{HostID: 1000, NamespaceID: 0, Size: 1}},
+			GIDMappings: []groot.IDMappingSpec{{HostID: 1001, NamespaceID: 0, Size: 1}},
+		}
+		unpackedEntries = []tarEntry{}
+		fakeUnpacker = new(base_image_pullerfakes.FakeUnpacker)
+		unpackInto(fakeUnpacker, &unpackedEntries)
+		lookupErr = nil
+
+		baseImage = &specsv1.Image{}
+		baseImage.Config.WorkingDir = "/home/app/src/bin"
+	})
+
+	JustBeforeEach(func() {
+		rootFS := rootfs_configurer.NewRootFS(fakeUnpacker, idMappings, func(dir string) ([]string, error) {
+			Expect(dir).To(Equal(upperDir))
+			return []string{upperDir, lowerDir}, lookupErr
+		})
+		configurer = rootfs_configurer.NewWorkingDirConfigurer(lagertest.NewTestLogger("working-dir"), rootFS)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(upperDir)).To(Succeed())
+		Expect(os.RemoveAll(lowerDir)).To(Succeed())
+	})
+
+	It("creates the missing directories into the upper directory with the store id mappings", func() {
+		Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+
+		Expect(fakeUnpacker.UnpackCallCount()).To(Equal(1))
+		_, unpackSpec := fakeUnpacker.UnpackArgsForCall(0)
+		Expect(unpackSpec.TargetPath).To(Equal(upperDir))
+		Expect(unpackSpec.UIDMappings).To(Equal(idMappings.UIDMappings))
+		Expect(unpackSpec.GIDMappings).To(Equal(idMappings.GIDMappings))
+
+		Expect(entryNames(unpackedEntries)).To(Equal([]string{"home/app/src/", "home/app/src/bin/"}))
+		for _, entry := range unpackedEntries {
+			Expect(entry.header.Typeflag).To(Equal(byte(tar.TypeDir)))
+			Expect(entry.header.Mode).To(Equal(int64(0755)))
+			Expect(entry.header.Uid).To(Equal(0))
+			Expect(entry.header.Gid).To(Equal(0))
+		}
+	})
+
+	Context("when the working directory exists", func() {
+		BeforeEach(func() {
+			baseImage.Config.WorkingDir = "/home/app"
+		})
+
+		It("doesn't write anything", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when an upper layer replaced the working directory", func() {
+		BeforeEach(func() {
+			baseImage.Config.WorkingDir = "/home/app"
+			Expect(os.MkdirAll(filepath.Join(upperDir, "home"), 0755)).To(Succeed())
+			Expect(os.Symlink("/dev/null", filepath.Join(upperDir, "home", "app"))).To(Succeed())
+		})
+
+		It("leaves the image alone", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when a parent of the working directory is a symlink", func() {
+		BeforeEach(func() {
+			Expect(os.Symlink("/tmp", filepath.Join(lowerDir, "opt"))).To(Succeed())
+			baseImage.Config.WorkingDir = "/opt/app"
+		})
+
+		It("leaves the image alone", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the image has no working directory", func() {
+		BeforeEach(func() {
+			baseImage.Config.WorkingDir = ""
+		})
+
+		It("doesn't write anything", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(Succeed())
+			Expect(fakeUnpacker.UnpackCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the image layers can't be looked up", func() {
+		BeforeEach(func() {
+			lookupErr = errors.New("no layers")
+		})
+
+		It("returns an error", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(MatchError(ContainSubstring("no layers")))
+		})
+	})
+
+	Context("when unpacking fails", func() {
+		BeforeEach(func() {
+			fakeUnpacker.UnpackStub = nil
+			fakeUnpacker.UnpackReturns(base_image_puller.UnpackOutput{}, errors.New("failed to unpack"))
+		})
+
+		It("returns an error", func() {
+			Expect(configurer.Configure(upperDir, baseImage)).To(MatchError(ContainSubstring("failed to unpack")))
+		})
+	})
+})
@@ -8,6 +8,11 @@ const (
 	MetaDirName      = "meta"
 	TempDirName      = "tmp"
 	DefaultStorePath = "/var/lib/grootfs"
+
+	// TrashDirName holds images deleted asynchronously until they are reaped.
+	// It's created on demand, so it isn't one of the StoreFolders that
+	// initialized stores must have.
+	TrashDirName = "trash"
 )
 
 var StoreFolders []string = []string{